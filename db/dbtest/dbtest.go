@@ -0,0 +1,56 @@
+// Package dbtest gives tests a migrated goauth database. Tests that need one
+// are skipped unless GOAUTH_TEST_DATABASE_URL names a Postgres database they
+// may write to.
+package dbtest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/initialization"
+	"github.com/google/uuid"
+)
+
+// Store connects to GOAUTH_TEST_DATABASE_URL and creates the schema, or
+// skips the test when it is not set. The connection closes when the test
+// ends.
+func Store(t testing.TB) *db.Store {
+	t.Helper()
+	dsn := os.Getenv("GOAUTH_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("GOAUTH_TEST_DATABASE_URL is not set")
+	}
+	store := initialization.Database(dsn, false)
+	t.Cleanup(store.Close)
+	return store
+}
+
+// User creates a user to hang sessions and tokens on, and deletes it, with
+// everything that references it, when the test ends.
+func User(t testing.TB, store *db.Store) uuid.UUID {
+	t.Helper()
+	return NewUser(t, store, db.GoAuthRegisterParams{}).ID
+}
+
+// NewUser creates a user from params, with a unique email and the USER role
+// unless they are set, and deletes it when the test ends.
+func NewUser(t testing.TB, store *db.Store, params db.GoAuthRegisterParams) db.GoauthUser {
+	t.Helper()
+	if params.Email == "" {
+		params.Email = "dbtest-" + uuid.NewString() + "@example.com"
+	}
+	if params.RoleName == "" {
+		params.RoleName = "USER"
+	}
+	if params.Metadata == nil {
+		params.Metadata = []byte("{}")
+	}
+	user, err := store.GoAuthRegister(context.Background(), params)
+	if err != nil {
+		t.Fatalf("dbtest: create user: %v", err)
+	}
+	t.Cleanup(func() { _ = store.DeleteUser(context.Background(), user.ID) })
+	return user
+}
@@ -53,6 +53,11 @@ SET
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdateUserPassword :exec
+UPDATE goauth_user
+SET hash_password = @hash_password, updated_at = NOW()
+WHERE id = @id;
+
 -- name: UpdateUserEmailVerified :exec
 UPDATE goauth_user
 SET email_verified = @email_verified, updated_at = NOW()
@@ -102,6 +107,16 @@ DELETE FROM goauth_session WHERE user_id = $1;
 -- name: DeleteExpiredSessions :exec
 DELETE FROM goauth_session WHERE expires_at <= NOW();
 
+-- name: TouchSession :exec
+UPDATE goauth_session
+SET expires_at = @expires_at, last_seen_at = NOW()
+WHERE id = @id;
+
+-- name: ListUserSessions :many
+SELECT * FROM goauth_session
+WHERE user_id = @user_id AND expires_at > NOW()
+ORDER BY last_seen_at DESC;
+
 -- sql/queries/accounts.sql
 -- name: CreateAccount :one
 INSERT INTO goauth_account (
@@ -119,10 +134,54 @@ SELECT a.*, u.* FROM goauth_account a
                          JOIN goauth_user u ON a.user_id = u.id
 WHERE a.provider = @provider AND a.provider_id = @provider_id;
 
--- name: DeleteAccount :exec
+-- name: DeleteAccount :execrows
 DELETE FROM goauth_account
 WHERE user_id = @user_id AND provider = @provider;
 
+-- name: ListUserAccounts :many
+SELECT * FROM goauth_account
+WHERE user_id = @user_id
+ORDER BY created_at;
+
+-- name: CountUserAccounts :one
+SELECT COUNT(*) FROM goauth_account
+WHERE user_id = @user_id;
+
+-- name: LockUser :one
+SELECT id FROM goauth_user
+WHERE id = @id
+FOR UPDATE;
+
+-- name: UpsertAccountToken :exec
+INSERT INTO goauth_account_token (
+    account_id,
+    access_token,
+    refresh_token,
+    token_type,
+    scopes,
+    expires_at
+) VALUES (
+             @account_id,
+             @access_token,
+             @refresh_token,
+             @token_type,
+             @scopes,
+             @expires_at
+         )
+ON CONFLICT (account_id) DO UPDATE SET
+    access_token = EXCLUDED.access_token,
+    refresh_token = COALESCE(EXCLUDED.refresh_token, goauth_account_token.refresh_token),
+    token_type = EXCLUDED.token_type,
+    scopes = EXCLUDED.scopes,
+    expires_at = EXCLUDED.expires_at,
+    updated_at = NOW();
+
+-- name: GetAccountTokenForUpdate :one
+SELECT t.* FROM goauth_account_token t
+                    JOIN goauth_account a ON a.id = t.account_id
+WHERE a.user_id = @user_id AND a.provider = @provider
+FOR UPDATE OF t;
+
 -- sql/queries/password_reset.sql
 -- name: CreatePasswordResetToken :one
 INSERT INTO goauth_password_reset (
@@ -139,6 +198,17 @@ INSERT INTO goauth_password_reset (
 SELECT * FROM goauth_password_reset
 WHERE token = @token AND expires_at > NOW();
 
+-- name: GetLatestPasswordResetToken :one
+SELECT * FROM goauth_password_reset
+WHERE user_id = @user_id AND expires_at > NOW()
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ConsumePasswordResetToken :one
+DELETE FROM goauth_password_reset
+WHERE token = @token AND expires_at > NOW()
+RETURNING *;
+
 -- name: DeletePasswordResetToken :exec
 DELETE FROM goauth_password_reset WHERE token = @token;
 
@@ -164,6 +234,17 @@ INSERT INTO goauth_email_verification (
 SELECT * FROM goauth_email_verification
 WHERE token = @token AND expires_at > NOW();
 
+-- name: GetLatestEmailVerificationToken :one
+SELECT * FROM goauth_email_verification
+WHERE user_id = @user_id AND expires_at > NOW()
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ConsumeEmailVerificationToken :one
+DELETE FROM goauth_email_verification
+WHERE token = @token AND expires_at > NOW()
+RETURNING *;
+
 -- name: DeleteEmailVerificationToken :exec
 DELETE FROM goauth_email_verification WHERE token = @token;
 
@@ -171,4 +252,376 @@ DELETE FROM goauth_email_verification WHERE token = @token;
 DELETE FROM goauth_email_verification WHERE user_id = @user_id;
 
 -- name: DeleteExpiredEmailVerificationTokens :exec
-DELETE FROM goauth_email_verification WHERE expires_at <= NOW();
\ No newline at end of file
+DELETE FROM goauth_email_verification WHERE expires_at <= NOW();
+
+-- sql/queries/mfa_challenge.sql
+-- name: CreateMfaChallenge :one
+INSERT INTO goauth_mfa_challenge (
+    user_id,
+    token,
+    expires_at
+) VALUES (
+             @user_id,
+             @token,
+             @expires_at
+         ) RETURNING *;
+
+-- name: GetMfaChallenge :one
+SELECT * FROM goauth_mfa_challenge
+WHERE token = @token AND expires_at > NOW();
+
+-- name: GetLatestMfaChallenge :one
+SELECT * FROM goauth_mfa_challenge
+WHERE user_id = @user_id AND expires_at > NOW()
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ConsumeMfaChallenge :one
+DELETE FROM goauth_mfa_challenge
+WHERE token = @token AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteMfaChallenge :exec
+DELETE FROM goauth_mfa_challenge WHERE token = @token;
+
+-- name: DeleteUserMfaChallenges :exec
+DELETE FROM goauth_mfa_challenge WHERE user_id = @user_id;
+
+-- sql/queries/account_unlock.sql
+-- name: CreateAccountUnlockToken :one
+INSERT INTO goauth_account_unlock (
+    user_id,
+    token,
+    expires_at
+) VALUES (
+             @user_id,
+             @token,
+             @expires_at
+         ) RETURNING *;
+
+-- name: GetAccountUnlockToken :one
+SELECT * FROM goauth_account_unlock
+WHERE token = @token AND expires_at > NOW();
+
+-- name: GetLatestAccountUnlockToken :one
+SELECT * FROM goauth_account_unlock
+WHERE user_id = @user_id AND expires_at > NOW()
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ConsumeAccountUnlockToken :one
+DELETE FROM goauth_account_unlock
+WHERE token = @token AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteAccountUnlockToken :exec
+DELETE FROM goauth_account_unlock WHERE token = @token;
+
+-- name: DeleteUserAccountUnlockTokens :exec
+DELETE FROM goauth_account_unlock WHERE user_id = @user_id;
+
+-- sql/queries/login_attempt.sql
+-- name: RecordLoginFailure :one
+INSERT INTO goauth_login_attempt (
+    key,
+    failures,
+    last_failed_at
+) VALUES (
+             @key,
+             1,
+             NOW()
+         ) ON CONFLICT (key) DO UPDATE
+    SET failures = CASE
+            WHEN goauth_login_attempt.last_failed_at <= @window_start THEN 1
+            ELSE goauth_login_attempt.failures + 1
+        END,
+        previous_failed_at = goauth_login_attempt.last_failed_at,
+        last_failed_at = NOW()
+RETURNING *;
+
+-- name: GetLoginAttempt :one
+SELECT * FROM goauth_login_attempt
+WHERE key = @key;
+
+-- name: LockLoginAttempt :exec
+INSERT INTO goauth_login_attempt (
+    key,
+    locked_until
+) VALUES (
+             @key,
+             @locked_until
+         ) ON CONFLICT (key) DO UPDATE
+    SET locked_until = EXCLUDED.locked_until;
+
+-- name: ForgiveLoginFailure :exec
+UPDATE goauth_login_attempt
+SET failures = GREATEST(failures - 1, 0)
+WHERE key = @key;
+
+-- name: DeleteLoginAttempt :exec
+DELETE FROM goauth_login_attempt WHERE key = @key;
+
+-- sql/queries/totp.sql
+-- name: UseTotpStep :execrows
+INSERT INTO goauth_totp_step (
+    user_id,
+    last_step
+) VALUES (
+             @user_id,
+             @last_step
+         ) ON CONFLICT (user_id) DO UPDATE
+    SET last_step = EXCLUDED.last_step
+    WHERE goauth_totp_step.last_step < EXCLUDED.last_step;
+
+-- name: DeleteTotpStep :exec
+DELETE FROM goauth_totp_step WHERE user_id = @user_id;
+
+-- sql/queries/recovery_codes.sql
+-- name: CreateRecoveryCodes :exec
+INSERT INTO goauth_recovery_code (
+    user_id,
+    code_hash
+)
+SELECT @user_id, unnest(@code_hashes::text[]);
+
+-- name: UseRecoveryCode :execrows
+UPDATE goauth_recovery_code
+SET used_at = NOW()
+WHERE user_id = @user_id AND code_hash = @code_hash AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*) FROM goauth_recovery_code
+WHERE user_id = @user_id AND used_at IS NULL;
+
+-- name: DeleteUserRecoveryCodes :exec
+DELETE FROM goauth_recovery_code WHERE user_id = @user_id;
+
+-- sql/queries/webauthn.sql
+-- name: CreateWebauthnCredential :one
+INSERT INTO goauth_webauthn_credential (
+    user_id,
+    credential_id,
+    name,
+    credential,
+    sign_count
+) VALUES (
+             @user_id,
+             @credential_id,
+             @name,
+             @credential,
+             @sign_count
+         ) RETURNING *;
+
+-- name: ListUserWebauthnCredentials :many
+SELECT * FROM goauth_webauthn_credential
+WHERE user_id = @user_id
+ORDER BY created_at;
+
+-- name: CountUserWebauthnCredentials :one
+SELECT COUNT(*) FROM goauth_webauthn_credential
+WHERE user_id = @user_id;
+
+-- name: UpdateWebauthnCredentialUse :execrows
+UPDATE goauth_webauthn_credential
+SET credential = @credential, sign_count = @sign_count, last_used_at = NOW()
+WHERE credential_id = @credential_id
+  AND (sign_count < @sign_count OR (sign_count = 0 AND @sign_count = 0));
+
+-- name: DeleteWebauthnCredential :execrows
+DELETE FROM goauth_webauthn_credential
+WHERE id = @id AND user_id = @user_id;
+
+-- name: CreateWebauthnChallenge :exec
+INSERT INTO goauth_webauthn_challenge (
+    challenge,
+    session_data,
+    expires_at
+) VALUES (
+             @challenge,
+             @session_data,
+             @expires_at
+         );
+
+-- name: ConsumeWebauthnChallenge :one
+DELETE FROM goauth_webauthn_challenge
+WHERE challenge = @challenge AND expires_at > NOW()
+RETURNING session_data;
+
+-- sql/queries/refresh_tokens.sql
+-- name: CreateRefreshToken :one
+INSERT INTO goauth_refresh_token (
+    id,
+    family_id,
+    user_id,
+    expires_at
+) VALUES (
+             @id,
+             @family_id,
+             @user_id,
+             @expires_at
+         ) RETURNING *;
+
+-- name: GetRefreshTokenForUpdate :one
+SELECT * FROM goauth_refresh_token
+WHERE id = $1
+FOR UPDATE;
+
+-- name: RotateRefreshToken :exec
+UPDATE goauth_refresh_token
+SET replaced_by = @replaced_by, revoked_at = NOW()
+WHERE id = $1;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE goauth_refresh_token
+SET revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE goauth_refresh_token
+SET revoked_at = NOW()
+WHERE user_id = @user_id AND created_at < @created_before AND revoked_at IS NULL;
+
+-- sql/queries/revocation.sql
+-- name: RevokeToken :exec
+INSERT INTO goauth_revoked_token (
+    jti,
+    expires_at
+) VALUES (
+             @jti,
+             @expires_at
+         ) ON CONFLICT (jti) DO NOTHING;
+
+-- name: IsTokenRevoked :one
+SELECT EXISTS (
+    SELECT 1 FROM goauth_revoked_token WHERE jti = @jti
+) AS revoked;
+
+-- name: RevokeUserTokensBefore :exec
+INSERT INTO goauth_user_revocation (
+    user_id,
+    revoked_before
+) VALUES (
+             @user_id,
+             @revoked_before
+         ) ON CONFLICT (user_id) DO UPDATE
+    SET revoked_before = GREATEST(goauth_user_revocation.revoked_before, EXCLUDED.revoked_before);
+
+-- name: GetUserRevocation :one
+SELECT revoked_before FROM goauth_user_revocation
+WHERE user_id = $1;
+
+-- sql/queries/audit_log.sql
+-- name: CreateAuditLog :exec
+INSERT INTO goauth_audit_log (
+    event_type,
+    log_entry
+) VALUES (
+             @event_type,
+             @log_entry
+         );
+
+-- sql/queries/janitor.sql
+-- name: PurgeExpiredSessions :execrows
+DELETE FROM goauth_session
+WHERE id IN (
+    SELECT id FROM goauth_session
+    WHERE expires_at <= NOW()
+    LIMIT @batch_size
+);
+
+-- name: PurgeExpiredPasswordResetTokens :execrows
+DELETE FROM goauth_password_reset
+WHERE id IN (
+    SELECT id FROM goauth_password_reset
+    WHERE expires_at <= NOW()
+    LIMIT @batch_size
+);
+
+-- name: PurgeExpiredEmailVerificationTokens :execrows
+DELETE FROM goauth_email_verification
+WHERE id IN (
+    SELECT id FROM goauth_email_verification
+    WHERE expires_at <= NOW()
+    LIMIT @batch_size
+);
+
+-- name: PurgeExpiredAccountUnlockTokens :execrows
+DELETE FROM goauth_account_unlock
+WHERE id IN (
+    SELECT id FROM goauth_account_unlock
+    WHERE expires_at <= NOW()
+    LIMIT @batch_size
+);
+
+-- name: PurgeExpiredMfaChallenges :execrows
+DELETE FROM goauth_mfa_challenge
+WHERE id IN (
+    SELECT id FROM goauth_mfa_challenge
+    WHERE expires_at <= NOW()
+    LIMIT @batch_size
+);
+
+-- name: PurgeExpiredRefreshTokens :execrows
+DELETE FROM goauth_refresh_token
+WHERE id IN (
+    SELECT id FROM goauth_refresh_token
+    WHERE expires_at <= NOW()
+    LIMIT @batch_size
+);
+
+-- name: PurgeExpiredRevokedTokens :execrows
+DELETE FROM goauth_revoked_token
+WHERE jti IN (
+    SELECT jti FROM goauth_revoked_token
+    WHERE expires_at <= NOW()
+    LIMIT @batch_size
+);
+
+-- name: PurgeExpiredWebauthnChallenges :execrows
+DELETE FROM goauth_webauthn_challenge
+WHERE challenge IN (
+    SELECT challenge FROM goauth_webauthn_challenge
+    WHERE expires_at <= NOW()
+    LIMIT @batch_size
+);
+
+-- name: PurgeLoginAttempts :execrows
+DELETE FROM goauth_login_attempt
+WHERE key IN (
+    SELECT key FROM goauth_login_attempt
+    WHERE last_failed_at < @failed_before
+      AND (locked_until IS NULL OR locked_until <= NOW())
+    LIMIT @batch_size
+);
+
+-- name: PurgeUserRevocations :execrows
+DELETE FROM goauth_user_revocation
+WHERE user_id IN (
+    SELECT user_id FROM goauth_user_revocation
+    WHERE revoked_before < @revoked_before
+    LIMIT @batch_size
+);
+
+-- name: CreatePasswordHistory :exec
+INSERT INTO goauth_password_history (
+    user_id,
+    hash_password
+) VALUES (
+             @user_id,
+             @hash_password
+         );
+
+-- name: ListPasswordHistory :many
+SELECT hash_password FROM goauth_password_history
+WHERE user_id = @user_id
+ORDER BY created_at DESC
+LIMIT @max_entries;
+
+-- name: PrunePasswordHistory :exec
+DELETE FROM goauth_password_history
+WHERE user_id = @user_id AND id NOT IN (
+    SELECT id FROM goauth_password_history
+    WHERE user_id = @user_id
+    ORDER BY created_at DESC
+    LIMIT @keep
+);
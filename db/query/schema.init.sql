@@ -25,6 +25,25 @@ CREATE TABLE IF NOT EXISTS goauth_account (
                                               UNIQUE(provider, provider_id)
 );
 
+-- name: CreateAccountTokenTable :exec
+CREATE TABLE IF NOT EXISTS goauth_account_token (
+                                                    account_id UUID PRIMARY KEY REFERENCES goauth_account(id) ON DELETE CASCADE,
+                                                    access_token BYTEA NOT NULL,
+                                                    refresh_token BYTEA,
+                                                    token_type TEXT NOT NULL DEFAULT '',
+                                                    scopes TEXT NOT NULL DEFAULT '',
+                                                    expires_at TIMESTAMP WITH TIME ZONE,
+                                                    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- name: CreatePasswordHistoryTable :exec
+CREATE TABLE IF NOT EXISTS goauth_password_history (
+                                                       id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                       user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                       hash_password TEXT NOT NULL,
+                                                       created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
 -- name: CreateSessionTable :exec
 CREATE TABLE IF NOT EXISTS goauth_session (
                                               id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -33,9 +52,13 @@ CREATE TABLE IF NOT EXISTS goauth_session (
                                               expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
                                               user_agent TEXT,
                                               ip_address INET,
-                                              created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+                                              created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+                                              last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+-- name: AddSessionLastSeenColumn :exec
+ALTER TABLE goauth_session ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT NOW();
+
 -- name: CreatePasswordResetTable :exec
 CREATE TABLE IF NOT EXISTS goauth_password_reset (
                                                      id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -54,6 +77,98 @@ CREATE TABLE IF NOT EXISTS goauth_email_verification (
                                                          created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+-- name: CreateRefreshTokenTable :exec
+CREATE TABLE IF NOT EXISTS goauth_refresh_token (
+                                                    id UUID PRIMARY KEY,
+                                                    family_id UUID NOT NULL,
+                                                    user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                                    replaced_by UUID,
+                                                    revoked_at TIMESTAMP WITH TIME ZONE,
+                                                    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- name: CreateRevokedTokenTable :exec
+CREATE TABLE IF NOT EXISTS goauth_revoked_token (
+                                                    jti TEXT PRIMARY KEY,
+                                                    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                                    revoked_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- name: CreateUserRevocationTable :exec
+CREATE TABLE IF NOT EXISTS goauth_user_revocation (
+                                                      user_id UUID PRIMARY KEY REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                      revoked_before TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- name: CreateMfaChallengeTable :exec
+CREATE TABLE IF NOT EXISTS goauth_mfa_challenge (
+                                                   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                   user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                   token TEXT UNIQUE NOT NULL,
+                                                   expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                                   created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- name: CreateAccountUnlockTable :exec
+CREATE TABLE IF NOT EXISTS goauth_account_unlock (
+                                                      id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                      user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                      token TEXT UNIQUE NOT NULL,
+                                                      expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                                      created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- name: CreateLoginAttemptTable :exec
+CREATE TABLE IF NOT EXISTS goauth_login_attempt (
+                                                     key TEXT PRIMARY KEY,
+                                                     failures INTEGER NOT NULL DEFAULT 0,
+                                                     last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+                                                     locked_until TIMESTAMP WITH TIME ZONE,
+                                                     previous_failed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- name: AddLoginAttemptPreviousFailedColumn :exec
+ALTER TABLE goauth_login_attempt ADD COLUMN IF NOT EXISTS previous_failed_at TIMESTAMP WITH TIME ZONE;
+
+-- name: CreateTotpStepTable :exec
+CREATE TABLE IF NOT EXISTS goauth_totp_step (
+                                                user_id UUID PRIMARY KEY REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                last_step BIGINT NOT NULL
+);
+
+-- name: CreateRecoveryCodeTable :exec
+CREATE TABLE IF NOT EXISTS goauth_recovery_code (
+                                                    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                    user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                    code_hash TEXT NOT NULL,
+                                                    used_at TIMESTAMP WITH TIME ZONE,
+                                                    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+                                                    UNIQUE(user_id, code_hash)
+);
+
+-- name: CreateWebauthnCredentialTable :exec
+CREATE TABLE IF NOT EXISTS goauth_webauthn_credential (
+                                                          id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                          user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                          credential_id BYTEA UNIQUE NOT NULL,
+                                                          name TEXT NOT NULL DEFAULT '',
+                                                          credential JSONB NOT NULL,
+                                                          sign_count BIGINT NOT NULL DEFAULT 0,
+                                                          created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+                                                          last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+-- name: CreateWebauthnCredentialIndexes :exec
+CREATE INDEX IF NOT EXISTS idx_goauth_webauthn_credential_user_id ON goauth_webauthn_credential(user_id);
+
+-- name: CreateWebauthnChallengeTable :exec
+CREATE TABLE IF NOT EXISTS goauth_webauthn_challenge (
+                                                         challenge TEXT PRIMARY KEY,
+                                                         session_data JSONB NOT NULL,
+                                                         expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
 -- name: CreateAuditLogTable :exec
 CREATE  TABLE  IF NOT EXISTS  goauth_audit_log(
                                                   id serial primary key ,
@@ -74,6 +189,15 @@ CREATE INDEX IF NOT EXISTS idx_goauth_session_expires_at ON goauth_session(expir
 CREATE INDEX IF NOT EXISTS idx_goauth_account_user_id ON goauth_account(user_id);
 CREATE INDEX IF NOT EXISTS idx_goauth_account_provider ON goauth_account(provider, provider_id);
 
+-- name: CreatePasswordHistoryIndexes :exec
+CREATE INDEX IF NOT EXISTS idx_goauth_password_history_user_id ON goauth_password_history(user_id, created_at DESC);
+
+-- name: CreateRefreshTokenIndexes :exec
+CREATE INDEX IF NOT EXISTS idx_goauth_refresh_token_family_id ON goauth_refresh_token(family_id);
+
+-- name: CreateRefreshTokenUserIndex :exec
+CREATE INDEX IF NOT EXISTS idx_goauth_refresh_token_user_id ON goauth_refresh_token(user_id);
+
 -- name: SetupAuthTables :exec
 -- Complete setup in one command
 CREATE TABLE IF NOT EXISTS goauth_user (
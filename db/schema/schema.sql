@@ -31,6 +31,27 @@ CREATE TABLE IF NOT EXISTS goauth_account (
                                               UNIQUE(provider, provider_id)
 );
 
+-- Provider tokens of social accounts, encrypted by the application
+CREATE TABLE IF NOT EXISTS goauth_account_token (
+                                                    account_id UUID PRIMARY KEY REFERENCES goauth_account(id) ON DELETE CASCADE,
+                                                    access_token BYTEA NOT NULL,
+                                                    refresh_token BYTEA,
+                                                    token_type TEXT NOT NULL DEFAULT '',
+                                                    scopes TEXT NOT NULL DEFAULT '',
+                                                    expires_at TIMESTAMP WITH TIME ZONE,
+                                                    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Previous password hashes, so they cannot be reused
+CREATE TABLE IF NOT EXISTS goauth_password_history (
+                                                       id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                       user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                       hash_password TEXT NOT NULL,
+                                                       created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_goauth_password_history_user_id ON goauth_password_history(user_id, created_at DESC);
+
 -- Create sessions table
 CREATE TABLE IF NOT EXISTS goauth_session (
                                               id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -39,7 +60,8 @@ CREATE TABLE IF NOT EXISTS goauth_session (
                                               expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
                                               user_agent TEXT,
                                               ip_address INET,
-                                              created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+                                              created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+                                              last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
 -- Create password reset tokens table
@@ -60,6 +82,92 @@ CREATE TABLE IF NOT EXISTS goauth_email_verification (
                                                          created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+-- Create refresh tokens table
+CREATE TABLE IF NOT EXISTS goauth_refresh_token (
+                                                    id UUID PRIMARY KEY,
+                                                    family_id UUID NOT NULL,
+                                                    user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                                    replaced_by UUID,
+                                                    revoked_at TIMESTAMP WITH TIME ZONE,
+                                                    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create revoked access tokens table
+CREATE TABLE IF NOT EXISTS goauth_revoked_token (
+                                                    jti TEXT PRIMARY KEY,
+                                                    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                                    revoked_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create per-user revocation cutoff table
+CREATE TABLE IF NOT EXISTS goauth_user_revocation (
+                                                      user_id UUID PRIMARY KEY REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                      revoked_before TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Create MFA login challenge table
+CREATE TABLE IF NOT EXISTS goauth_mfa_challenge (
+                                                   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                   user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                   token TEXT UNIQUE NOT NULL,
+                                                   expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                                   created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create account unlock tokens table
+CREATE TABLE IF NOT EXISTS goauth_account_unlock (
+                                                      id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                      user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                      token TEXT UNIQUE NOT NULL,
+                                                      expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                                      created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Failed login counters and lockouts, by account and by IP address
+CREATE TABLE IF NOT EXISTS goauth_login_attempt (
+                                                     key TEXT PRIMARY KEY,
+                                                     failures INTEGER NOT NULL DEFAULT 0,
+                                                     last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+                                                     locked_until TIMESTAMP WITH TIME ZONE,
+                                                     previous_failed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create last accepted TOTP step table
+CREATE TABLE IF NOT EXISTS goauth_totp_step (
+                                                user_id UUID PRIMARY KEY REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                last_step BIGINT NOT NULL
+);
+
+-- Create two-factor recovery codes table
+CREATE TABLE IF NOT EXISTS goauth_recovery_code (
+                                                    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                    user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                    code_hash TEXT NOT NULL,
+                                                    used_at TIMESTAMP WITH TIME ZONE,
+                                                    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+                                                    UNIQUE(user_id, code_hash)
+);
+
+-- Create WebAuthn credentials table
+CREATE TABLE IF NOT EXISTS goauth_webauthn_credential (
+                                                          id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                          user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                          credential_id BYTEA UNIQUE NOT NULL,
+                                                          name TEXT NOT NULL DEFAULT '',
+                                                          credential JSONB NOT NULL,
+                                                          sign_count BIGINT NOT NULL DEFAULT 0,
+                                                          created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+                                                          last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create pending WebAuthn ceremonies table
+CREATE TABLE IF NOT EXISTS goauth_webauthn_challenge (
+                                                         challenge TEXT PRIMARY KEY,
+                                                         session_data JSONB NOT NULL,
+                                                         expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
 
 CREATE INDEX IF NOT EXISTS idx_goauth_user_email ON goauth_user(email);
 CREATE INDEX IF NOT EXISTS idx_goauth_session_user_id ON goauth_session(user_id);
@@ -67,3 +175,6 @@ CREATE INDEX IF NOT EXISTS idx_goauth_session_token ON goauth_session(token);
 CREATE INDEX IF NOT EXISTS idx_goauth_session_expires_at ON goauth_session(expires_at);
 CREATE INDEX IF NOT EXISTS idx_goauth_account_user_id ON goauth_account(user_id);
 CREATE INDEX IF NOT EXISTS idx_goauth_account_provider ON goauth_account(provider, provider_id);
+CREATE INDEX IF NOT EXISTS idx_goauth_refresh_token_family_id ON goauth_refresh_token(family_id);
+CREATE INDEX IF NOT EXISTS idx_goauth_refresh_token_user_id ON goauth_refresh_token(user_id);
+CREATE INDEX IF NOT EXISTS idx_goauth_webauthn_credential_user_id ON goauth_webauthn_credential(user_id);
@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// BeginLinkAccount starts attaching a provider account to the signed-in
+// user. The user re-authenticates first, so a stolen access token cannot
+// plant an attacker's provider account as a way back in.
+func (s Service) BeginLinkAccount(userId uuid.UUID, provider string, req *framework.ReauthRequest) (authURL string, binding string, err error) {
+	client, ok := s.oauthProviders[provider]
+	if !ok || s.oauthState == nil {
+		return "", "", ErrOAuthProviderUnknown
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.reauthenticate(ctx, userId, req); err != nil {
+		return "", "", err
+	}
+	flow, err := s.oauthState.BeginLink(provider, userId.String())
+	if err != nil {
+		log.Error().Err(err).Msg("failed to start oauth link flow")
+		return "", "", err
+	}
+	return client.AuthCodeURL(flow), flow.Binding, nil
+}
+
+// LinkAccount completes a flow started by BeginLinkAccount. Each provider can
+// be linked once per user, and a provider account only to one user.
+func (s Service) LinkAccount(req *framework.OAuthCallbackRequest) (framework.LinkedAccount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	identity, flow, err := s.oauthIdentity(ctx, req)
+	if err != nil {
+		return framework.LinkedAccount{}, err
+	}
+	userID, err := uuid.Parse(flow.LinkUserID)
+	if err != nil {
+		return framework.LinkedAccount{}, ErrInvalidOAuthState
+	}
+
+	existing, err := s.Store.GetAccountByProvider(ctx, db.GetAccountByProviderParams{
+		Provider:   identity.Provider,
+		ProviderID: identity.Subject,
+	})
+	switch {
+	case err == nil && existing.UserID == userID:
+		s.storeProviderToken(ctx, existing.ID, identity)
+		return framework.LinkedAccount{
+			ID:        existing.ID.String(),
+			Provider:  existing.Provider,
+			CreatedAt: existing.CreatedAt.Time,
+		}, nil
+	case err == nil:
+		return framework.LinkedAccount{}, ErrOAuthAccountInUse
+	case !errors.Is(err, pgx.ErrNoRows):
+		log.Error().Err(err).Msg("failed to look up oauth account")
+		return framework.LinkedAccount{}, err
+	}
+
+	accounts, err := s.Store.ListUserAccounts(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to list linked accounts")
+		return framework.LinkedAccount{}, err
+	}
+	for _, account := range accounts {
+		if account.Provider == identity.Provider {
+			return framework.LinkedAccount{}, ErrProviderAlreadyLinked
+		}
+	}
+
+	account, err := s.Store.CreateAccount(ctx, db.CreateAccountParams{
+		UserID:     userID,
+		Provider:   identity.Provider,
+		ProviderID: identity.Subject,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to link oauth account")
+		return framework.LinkedAccount{}, err
+	}
+	s.storeProviderToken(ctx, account.ID, identity)
+	s.audit(ctx, s.Store.Queries, AuditAccountLinked, map[string]interface{}{
+		"user_id":  userID.String(),
+		"provider": identity.Provider,
+		"ip":       req.IPAddress,
+	})
+	return linkedAccount(account), nil
+}
+
+// ListAccounts returns the provider accounts linked to the user.
+func (s Service) ListAccounts(userId uuid.UUID) ([]framework.LinkedAccount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	accounts, err := s.Store.ListUserAccounts(ctx, userId)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to list linked accounts")
+		return nil, err
+	}
+	linked := make([]framework.LinkedAccount, 0, len(accounts))
+	for _, account := range accounts {
+		linked = append(linked, linkedAccount(account))
+	}
+	return linked, nil
+}
+
+// UnlinkAccount detaches the user's account with provider, deleting its
+// stored provider token with it. It is refused when the user would be left
+// without a password, passkey or other provider to sign in with.
+func (s Service) UnlinkAccount(userId uuid.UUID, provider string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := s.Store.WithTx(ctx, func(q *db.Queries) error {
+		// Serialises concurrent unlinks, which could otherwise each see the
+		// other's account and together remove both.
+		if _, err := q.LockUser(ctx, userId); err != nil {
+			return err
+		}
+		deleted, err := q.DeleteAccount(ctx, db.DeleteAccountParams{
+			UserID:   userId,
+			Provider: provider,
+		})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return ErrAccountNotFound
+		}
+		remaining, err := s.loginMethods(ctx, q, userId)
+		if err != nil {
+			return err
+		}
+		if remaining == 0 {
+			return ErrLastLoginMethod
+		}
+		return nil
+	})
+	if errors.Is(err, ErrAccountNotFound) || errors.Is(err, ErrLastLoginMethod) {
+		return err
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to unlink account")
+		return err
+	}
+
+	s.audit(ctx, s.Store.Queries, AuditAccountUnlinked, map[string]interface{}{
+		"user_id":  userId.String(),
+		"provider": provider,
+	})
+	return nil
+}
+
+// loginMethods counts the ways the user can sign in without help: a
+// password, passkeys when WebAuthn is on, and linked provider accounts.
+func (s Service) loginMethods(ctx context.Context, q *db.Queries, userID uuid.UUID) (int64, error) {
+	user, err := q.GetUserByID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	var methods int64
+	if user.HashPassword != "" {
+		methods++
+	}
+	accounts, err := q.CountUserAccounts(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	methods += accounts
+	if s.webAuthn != nil {
+		passkeys, err := q.CountUserWebauthnCredentials(ctx, userID)
+		if err != nil {
+			return 0, err
+		}
+		methods += passkeys
+	}
+	return methods, nil
+}
+
+// reauthenticate checks the user's password or, for users without one, a
+// two-factor code. A wrong password counts against the login lockout and a
+// wrong code against the user's two-factor one, so a stolen session cannot
+// be used to guess either.
+func (s Service) reauthenticate(ctx context.Context, userID uuid.UUID, req *framework.ReauthRequest) error {
+	user, err := s.Store.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up user to re-authenticate")
+		return err
+	}
+	if user.HashPassword == "" && !user.TwoFactorEnabled.Bool {
+		return ErrReauthUnavailable
+	}
+
+	if user.HashPassword == "" {
+		err := s.verifyTOTP(ctx, user, req.Code, req.IPAddress)
+		if errors.Is(err, ErrInvalidTOTPCode) {
+			return ErrReauthFailed
+		}
+		return err
+	}
+
+	attempt, err := s.beginLoginAttempt(ctx, user.Email, req.IPAddress)
+	if err != nil {
+		return err
+	}
+	if !s.verifyPassword(ctx, userID, user.HashPassword, req.Password) {
+		s.loginFailed(ctx, attempt, userID, user.Email)
+		return ErrReauthFailed
+	}
+	s.loginSucceeded(ctx, attempt)
+	return nil
+}
+
+func linkedAccount(account db.GoauthAccount) framework.LinkedAccount {
+	return framework.LinkedAccount{
+		ID:        account.ID.String(),
+		Provider:  account.Provider,
+		CreatedAt: account.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/SwanHtetAungPhyo/go-auth/third-party/oauth"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestBeginLinkAccountLockout(t *testing.T) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		user  db.GetUserByIDRow
+		wrong framework.ReauthRequest
+	}{
+		{
+			name:  "password",
+			user:  db.GetUserByIDRow{Email: "link-password@example.com"},
+			wrong: framework.ReauthRequest{Password: "wrong"},
+		},
+		{
+			name: "two-factor code",
+			user: db.GetUserByIDRow{
+				Email:            "link-totp@example.com",
+				TwoFactorEnabled: pgtype.Bool{Bool: true, Valid: true},
+				TwoFactorSecret:  pgtype.Text{String: secret, Valid: true},
+			},
+			wrong: framework.ReauthRequest{Code: "000000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := newPasskeyDB(t)
+			s := newLockoutTestService(t, database, 2)
+			WithOAuth(newTestSigner(t), stubProvider{name: oauth.ProviderGitHub})(&s)
+
+			userID := uuid.New()
+			tt.user.ID, tt.user.RoleName = userID, "USER"
+			if tt.name == "password" {
+				hash, err := s.passwords.Hash("current password")
+				if err != nil {
+					t.Fatal(err)
+				}
+				tt.user.HashPassword = hash
+			}
+			database.users[userID] = tt.user
+
+			for range 2 {
+				if _, _, err := s.BeginLinkAccount(userID, oauth.ProviderGitHub, &tt.wrong); !errors.Is(err, ErrReauthFailed) {
+					t.Fatalf("wrong %s: %v, want ErrReauthFailed", tt.name, err)
+				}
+			}
+			right := framework.ReauthRequest{Password: "current password"}
+			var throttled *LoginThrottledError
+			if _, _, err := s.BeginLinkAccount(userID, oauth.ProviderGitHub, &right); !errors.As(err, &throttled) {
+				t.Fatalf("after 2 wrong attempts: %v, want a LoginThrottledError", err)
+			}
+		})
+	}
+}
+
+// newLinkTestService returns a Service over the dbtest database whose users
+// re-authenticate with a bcrypt password.
+func newLinkTestService(t *testing.T) Service {
+	t.Helper()
+	return Service{
+		Store:     dbtest.Store(t),
+		passwords: utils.NewBcryptHasher(4),
+		policy:    utils.DefaultPasswordPolicy(),
+	}
+}
+
+// link runs a whole linking flow for userID against provider.
+func link(t *testing.T, s Service, userID uuid.UUID, provider stubProvider, reauth framework.ReauthRequest) (framework.LinkedAccount, error) {
+	t.Helper()
+	WithOAuth(newTestSigner(t), provider)(&s)
+	authURL, binding, err := s.BeginLinkAccount(userID, provider.name, &reauth)
+	if err != nil {
+		return framework.LinkedAccount{}, err
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.LinkAccount(&framework.OAuthCallbackRequest{
+		Provider: provider.name,
+		Code:     "code",
+		State:    parsed.Query().Get("state"),
+		Binding:  binding,
+	})
+}
+
+func githubIdentity(subject string) stubProvider {
+	return stubProvider{
+		name:     oauth.ProviderGitHub,
+		identity: oauth.Identity{Provider: oauth.ProviderGitHub, Subject: subject, Email: subject + "@example.com", EmailVerified: true},
+	}
+}
+
+func TestLinkAccount(t *testing.T) {
+	s := newLinkTestService(t)
+	hash, err := s.passwords.Hash("current password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := dbtest.NewUser(t, s.Store, db.GoAuthRegisterParams{HashPassword: hash})
+	other := dbtest.NewUser(t, s.Store, db.GoAuthRegisterParams{HashPassword: hash})
+	reauth := framework.ReauthRequest{Password: "current password"}
+	subject := uuid.NewString()
+
+	if _, err := link(t, s, user.ID, githubIdentity(subject), framework.ReauthRequest{Password: "wrong"}); !errors.Is(err, ErrReauthFailed) {
+		t.Fatalf("link with a wrong password: %v, want ErrReauthFailed", err)
+	}
+	linked, err := link(t, s, user.ID, githubIdentity(subject), reauth)
+	if err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	if linked.Provider != oauth.ProviderGitHub {
+		t.Errorf("linked = %+v", linked)
+	}
+	accounts, err := s.ListAccounts(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0].ID != linked.ID {
+		t.Errorf("ListAccounts = %+v, want the linked account", accounts)
+	}
+
+	// Linking the same provider account again is a no-op.
+	if again, err := link(t, s, user.ID, githubIdentity(subject), reauth); err != nil || again.ID != linked.ID {
+		t.Errorf("linking again = %+v, %v, want the existing account", again, err)
+	}
+	if _, err := link(t, s, user.ID, githubIdentity(uuid.NewString()), reauth); !errors.Is(err, ErrProviderAlreadyLinked) {
+		t.Errorf("second GitHub account: %v, want ErrProviderAlreadyLinked", err)
+	}
+	if _, err := link(t, s, other.ID, githubIdentity(subject), reauth); !errors.Is(err, ErrOAuthAccountInUse) {
+		t.Errorf("another user's GitHub account: %v, want ErrOAuthAccountInUse", err)
+	}
+
+	passwordless := dbtest.NewUser(t, s.Store, db.GoAuthRegisterParams{})
+	if _, err := link(t, s, passwordless.ID, githubIdentity(uuid.NewString()), reauth); !errors.Is(err, ErrReauthUnavailable) {
+		t.Errorf("user without password or two-factor: %v, want ErrReauthUnavailable", err)
+	}
+}
+
+func TestUnlinkAccount(t *testing.T) {
+	s := newLinkTestService(t)
+	ctx := context.Background()
+	// A user who signed up with GitHub has no password to fall back on.
+	user := dbtest.NewUser(t, s.Store, db.GoAuthRegisterParams{})
+	for _, provider := range []string{oauth.ProviderGitHub, oauth.ProviderGoogle} {
+		if _, err := s.Store.CreateAccount(ctx, db.CreateAccountParams{
+			UserID:     user.ID,
+			Provider:   provider,
+			ProviderID: uuid.NewString(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.UnlinkAccount(user.ID, "okta"); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("unlinking an unlinked provider: %v, want ErrAccountNotFound", err)
+	}
+	if err := s.UnlinkAccount(user.ID, oauth.ProviderGitHub); err != nil {
+		t.Fatalf("unlinking one of two providers: %v", err)
+	}
+	if err := s.UnlinkAccount(user.ID, oauth.ProviderGoogle); !errors.Is(err, ErrLastLoginMethod) {
+		t.Fatalf("unlinking the last provider: %v, want ErrLastLoginMethod", err)
+	}
+	accounts, err := s.ListAccounts(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0].Provider != oauth.ProviderGoogle {
+		t.Errorf("after a refused unlink, accounts = %+v, want Google kept", accounts)
+	}
+
+	// With a password, the last provider may go.
+	hash, err := s.passwords.Hash("current password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withPassword := dbtest.NewUser(t, s.Store, db.GoAuthRegisterParams{HashPassword: hash})
+	if _, err := s.Store.CreateAccount(ctx, db.CreateAccountParams{
+		UserID:     withPassword.ID,
+		Provider:   oauth.ProviderGitHub,
+		ProviderID: uuid.NewString(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UnlinkAccount(withPassword.ID, oauth.ProviderGitHub); err != nil {
+		t.Errorf("unlinking the only provider of a user with a password: %v", err)
+	}
+}
+
+func TestLinkAccountExpiredState(t *testing.T) {
+	s := Service{Store: &db.Store{Queries: db.New(emptyDB{t})}}
+	signer, err := oauth.NewStateSigner([]byte("test-secret-test-secret-test-sec"), -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	WithOAuth(signer, githubIdentity("1"))(&s)
+	flow, err := signer.BeginLink(oauth.ProviderGitHub, uuid.NewString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.LinkAccount(&framework.OAuthCallbackRequest{
+		Provider: oauth.ProviderGitHub,
+		Code:     "code",
+		State:    flow.State,
+		Binding:  flow.Binding,
+	}); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Errorf("expired link flow: %v, want ErrInvalidOAuthState", err)
+	}
+}
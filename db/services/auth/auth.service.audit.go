@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/rs/zerolog/log"
+)
+
+// Audit event types written to goauth_audit_log.
+const (
+	AuditRefreshTokenReuse = "refresh_token_reuse"
+	AuditRecoveryCodeUsed  = "recovery_code_used"
+	AuditPasskeyCloned     = "passkey_clone_warning"
+	AuditAccountLinked     = "account_linked"
+	AuditAccountUnlinked   = "account_unlinked"
+	AuditUsersImported     = "users_imported"
+	AuditPasswordChanged   = "password_changed"
+	AuditAccountLocked     = "account_locked"
+	AuditIPLocked          = "ip_locked"
+	AuditAccountUnlocked   = "account_unlocked"
+)
+
+// audit records a security event in goauth_audit_log. Failures are logged
+// rather than returned so a broken audit table never blocks authentication.
+func (s Service) audit(ctx context.Context, q *db.Queries, eventType string, entry map[string]interface{}) {
+	logEntry, err := json.Marshal(entry)
+	if err != nil {
+		log.Err(err).Str("event", eventType).Msg("failed to encode audit entry")
+		return
+	}
+	if err := q.CreateAuditLog(ctx, db.CreateAuditLogParams{
+		EventType: eventType,
+		LogEntry:  logEntry,
+	}); err != nil {
+		log.Err(err).Str("event", eventType).Msg("failed to write audit entry")
+	}
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ChangePassword sets a new password for a signed-in user who knows the
+// current one. Every session and token of the user is revoked, and the
+// response carries fresh credentials for the device that made the change.
+//
+// A wrong current password counts against the same lockout as a failed
+// login, so a stolen session cannot be used to guess the password.
+func (s Service) ChangePassword(userId uuid.UUID, req *framework.ChangePasswordRequest) (framework.AuthResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := s.Store.GetUserByID(ctx, userId)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up user to change password")
+		return framework.AuthResponse{}, err
+	}
+	if user.HashPassword == "" {
+		return framework.AuthResponse{}, ErrPasswordNotSet
+	}
+	attempt, err := s.beginLoginAttempt(ctx, user.Email, req.IPAddress)
+	if err != nil {
+		return framework.AuthResponse{}, err
+	}
+	if !s.verifyPassword(ctx, userId, user.HashPassword, req.CurrentPassword) {
+		s.loginFailed(ctx, attempt, userId, user.Email)
+		return framework.AuthResponse{}, ErrWrongPassword
+	}
+	s.loginSucceeded(ctx, attempt)
+	if err := s.checkPasswordPolicy(req.NewPassword, user.Email, user.Name.String); err != nil {
+		return framework.AuthResponse{}, err
+	}
+	if err := s.checkPasswordReuse(ctx, userId, user.HashPassword, req.NewPassword); err != nil {
+		return framework.AuthResponse{}, err
+	}
+
+	hash, err := s.passwords.Hash(req.NewPassword)
+	if err != nil {
+		log.Err(err).Msg("failed to hash password")
+		return framework.AuthResponse{}, err
+	}
+	if err := s.setPassword(ctx, userId, hash); err != nil {
+		log.Error().Err(err).Msg("failed to update password")
+		return framework.AuthResponse{}, err
+	}
+	changedAt := time.Now()
+	s.audit(ctx, s.Store.Queries, AuditPasswordChanged, map[string]interface{}{
+		"user_id":    userId.String(),
+		"ip_address": req.IPAddress,
+	})
+	s.notifyPasswordChanged(user.Email, req.IPAddress, changedAt)
+
+	if err := s.LogoutAll(userId); err != nil {
+		return framework.AuthResponse{}, err
+	}
+	return s.completeLogin(ctx, userId, user.RoleName, req.UserAgent, req.IPAddress)
+}
+
+func (s Service) notifyPasswordChanged(email, ipAddress string, changedAt time.Time) {
+	if s.emailType == nil {
+		log.Error().Msg("password changed but no email service is configured")
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.emailType.SendPasswordChangedEmail(ctx, email, ipAddress, changedAt); err != nil {
+			log.Error().Err(err).Msg("failed to send password changed notification")
+		}
+	}()
+}
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/lockout"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// newLockoutTestService returns a Service over database that makes an
+// account wait an hour after delayAfter failures, counted in miniredis.
+func newLockoutTestService(t *testing.T, database *passkeyDB, delayAfter int) Service {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	s := Service{
+		Store:     &db.Store{Queries: db.New(database)},
+		passwords: utils.NewBcryptHasher(4),
+		policy:    utils.DefaultPasswordPolicy(),
+		lockoutPolicy: &lockout.Policy{
+			Window:     time.Hour,
+			DelayAfter: delayAfter,
+			BaseDelay:  time.Hour,
+		},
+	}
+	WithLockoutStore(lockout.NewRedisStore(client), nil)(&s)
+	return s
+}
+
+func TestChangePasswordLockout(t *testing.T) {
+	database := newPasskeyDB(t)
+	s := newLockoutTestService(t, database, 2)
+
+	hash, err := s.passwords.Hash("current password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := uuid.New()
+	database.users[userID] = db.GetUserByIDRow{ID: userID, Email: "change@example.com", HashPassword: hash, RoleName: "USER"}
+
+	ctx := context.Background()
+	failures := func() int {
+		t.Helper()
+		state, err := s.lockouts.Get(ctx, lockout.AccountKey("change@example.com"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return state.Failures
+	}
+	change := func(current string) error {
+		// The new password is too short for the policy, so a right
+		// current password stops there instead of changing anything.
+		_, err := s.ChangePassword(userID, &framework.ChangePasswordRequest{
+			CurrentPassword: current,
+			NewPassword:     "short",
+			IPAddress:       "192.0.2.1",
+		})
+		return err
+	}
+
+	if err := change("wrong"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("wrong password: %v, want ErrWrongPassword", err)
+	}
+	if got := failures(); got != 1 {
+		t.Errorf("failures after a wrong password = %d, want 1", got)
+	}
+	var policyErr *utils.PasswordPolicyError
+	if err := change("current password"); !errors.As(err, &policyErr) {
+		t.Fatalf("right password: %v, want a PasswordPolicyError", err)
+	}
+	if got := failures(); got != 0 {
+		t.Errorf("failures after the right password = %d, want 0", got)
+	}
+
+	for range 2 {
+		if err := change("wrong"); !errors.Is(err, ErrWrongPassword) {
+			t.Fatalf("wrong password: %v, want ErrWrongPassword", err)
+		}
+	}
+	var throttled *LoginThrottledError
+	if err := change("current password"); !errors.As(err, &throttled) || throttled.RetryAfter <= 0 {
+		t.Fatalf("after %d wrong passwords: %v, want a LoginThrottledError", failures(), err)
+	}
+	if got := failures(); got != 2 {
+		t.Errorf("a throttled attempt changed the count to %d", got)
+	}
+}
+
+func TestChangePasswordParallelGuesses(t *testing.T) {
+	database := newPasskeyDB(t)
+	s := newLockoutTestService(t, database, 3)
+
+	hash, err := s.passwords.Hash("current password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := uuid.New()
+	database.users[userID] = db.GetUserByIDRow{ID: userID, Email: "burst@example.com", HashPassword: hash, RoleName: "USER"}
+
+	// Every guess in the burst starts before any has been answered; only
+	// the first DelayAfter may reach the password check.
+	const guesses = 20
+	errs := make(chan error, guesses)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for range guesses {
+		wg.Go(func() {
+			<-start
+			_, err := s.ChangePassword(userID, &framework.ChangePasswordRequest{
+				CurrentPassword: "wrong",
+				NewPassword:     "a new password that is long enough",
+			})
+			errs <- err
+		})
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	checked := 0
+	for err := range errs {
+		var throttled *LoginThrottledError
+		switch {
+		case errors.Is(err, ErrWrongPassword):
+			checked++
+		case !errors.As(err, &throttled):
+			t.Errorf("guess: %v, want ErrWrongPassword or a LoginThrottledError", err)
+		}
+	}
+	if checked != 3 {
+		t.Errorf("%d of %d parallel guesses were checked, want 3", checked, guesses)
+	}
+	state, err := s.lockouts.Get(context.Background(), lockout.AccountKey("burst@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Failures != 3 {
+		t.Errorf("failures after the burst = %d, want the 3 checked guesses", state.Failures)
+	}
+}
+
+func TestChangePasswordRefuses(t *testing.T) {
+	database := newPasskeyDB(t)
+	s := newLockoutTestService(t, database, 5)
+	hash, err := s.passwords.Hash("current password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, passwordless := uuid.New(), uuid.New()
+	database.users[userID] = db.GetUserByIDRow{ID: userID, Email: "refuse@example.com", HashPassword: hash, RoleName: "USER"}
+	database.users[passwordless] = db.GetUserByIDRow{ID: passwordless, Email: "passkey@example.com", RoleName: "USER"}
+
+	// Neither reaches the point of writing anything.
+	if _, err := s.ChangePassword(passwordless, &framework.ChangePasswordRequest{
+		CurrentPassword: "",
+		NewPassword:     "a new password that is long enough",
+	}); !errors.Is(err, ErrPasswordNotSet) {
+		t.Errorf("passwordless user: %v, want ErrPasswordNotSet", err)
+	}
+	if _, err := s.ChangePassword(userID, &framework.ChangePasswordRequest{
+		CurrentPassword: "current password",
+		NewPassword:     "current password",
+	}); !errors.Is(err, ErrPasswordReused) {
+		t.Errorf("the current password again: %v, want ErrPasswordReused", err)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	s := newTokenTestService(t)
+	s.cfg.PasswordHistory = 1
+	ctx := context.Background()
+	hash, err := s.passwords.Hash("first password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := dbtest.NewUser(t, s.Store, db.GoAuthRegisterParams{HashPassword: hash})
+
+	// Signed in on another device before the change.
+	other, err := s.completeLogin(ctx, user.ID, user.RoleName, "other device", "192.0.2.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForNextSecond()
+
+	response, err := s.ChangePassword(user.ID, &framework.ChangePasswordRequest{
+		CurrentPassword: "first password",
+		NewPassword:     "second password",
+		IPAddress:       "192.0.2.1",
+	})
+	if err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	stored, err := s.Store.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.passwords.Verify("second password", stored.HashPassword); !ok {
+		t.Error("the new password does not match the stored hash")
+	}
+
+	// The other device is signed out everywhere it could be.
+	if !accessTokenRevoked(t, s, other.AccessToken) {
+		t.Error("the other device's access token is still accepted")
+	}
+	if _, err := s.Refresh(other.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("the other device's refresh token: %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, _, err := s.sessions.Resolve(ctx, other.SessionToken); err == nil {
+		t.Error("the other device's session survived")
+	}
+
+	// The device that made the change carries on with fresh credentials.
+	if response.AccessToken == "" || response.RefreshToken == "" || response.SessionToken == "" {
+		t.Fatalf("response = %+v, want fresh credentials", response)
+	}
+	if accessTokenRevoked(t, s, response.AccessToken) {
+		t.Error("the new access token is revoked")
+	}
+	if _, _, err := s.sessions.Resolve(ctx, response.SessionToken); err != nil {
+		t.Errorf("the new session: %v", err)
+	}
+	if _, err := s.Refresh(response.RefreshToken); err != nil {
+		t.Errorf("the new refresh token: %v", err)
+	}
+
+	// With a history of one, neither the current password nor the one
+	// before it may come back.
+	for _, password := range []string{"second password", "first password"} {
+		if _, err := s.ChangePassword(user.ID, &framework.ChangePasswordRequest{
+			CurrentPassword: "second password",
+			NewPassword:     password,
+		}); !errors.Is(err, ErrPasswordReused) {
+			t.Errorf("changing back to %q: %v, want ErrPasswordReused", password, err)
+		}
+	}
+	if _, err := s.ChangePassword(user.ID, &framework.ChangePasswordRequest{
+		CurrentPassword: "second password",
+		NewPassword:     "third password",
+	}); err != nil {
+		t.Errorf("a password not used before: %v", err)
+	}
+}
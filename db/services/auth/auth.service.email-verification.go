@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/tokenstore"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/initialization"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+// VerifyEmail redeems the token from a verification link and marks the
+// user's email as verified.
+func (s Service) VerifyEmail(req *framework.VerifyEmailRequest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	record, err := s.verificationTokens.Consume(ctx, tokenstore.HashToken(req.Token))
+	if errors.Is(err, tokenstore.ErrTokenNotFound) {
+		return ErrInvalidVerifyToken
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to redeem email verification token")
+		return err
+	}
+
+	if err := s.Store.UpdateUserEmailVerified(ctx, db.UpdateUserEmailVerifiedParams{
+		ID:            record.UserID,
+		EmailVerified: pgtype.Bool{Bool: true, Valid: true},
+	}); err != nil {
+		log.Error().Err(err).Msg("failed to mark email verified")
+		return err
+	}
+	if err := s.verificationTokens.DeleteUser(ctx, record.UserID); err != nil {
+		log.Error().Err(err).Msg("failed to delete email verification tokens")
+	}
+	return nil
+}
+
+// ResendVerification sends a new verification link. Like password reset it
+// answers nil for unknown or already verified addresses, and it silently
+// skips users who got a link within GOAUTH_EMAIL_VERIFICATION_RESEND_INTERVAL
+// (default 1m).
+func (s Service) ResendVerification(req *framework.ResendVerificationRequest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := s.Store.GetUserByEmail(ctx, req.Email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up user for verification resend")
+		return err
+	}
+	if user.EmailVerified.Bool {
+		return nil
+	}
+
+	interval := initialization.GetEnvDuration("GOAUTH_EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute)
+	latest, err := s.verificationTokens.Latest(ctx, user.ID)
+	if err == nil && time.Since(latest.CreatedAt) < interval {
+		log.Debug().Str("user_id", user.ID.String()).Msg("verification resend throttled")
+		return nil
+	}
+	if err != nil && !errors.Is(err, tokenstore.ErrTokenNotFound) {
+		log.Error().Err(err).Msg("failed to look up latest verification token")
+		return err
+	}
+
+	return s.sendVerification(ctx, user.ID, user.Email)
+}
+
+// sendVerification replaces the user's verification token and emails the
+// link: GOAUTH_EMAIL_VERIFICATION_URL with ?token=..., valid for
+// GOAUTH_EMAIL_VERIFICATION_DURATION (default 24h).
+func (s Service) sendVerification(ctx context.Context, userID uuid.UUID, email string) error {
+	token, tokenHash, err := tokenstore.NewToken()
+	if err != nil {
+		return err
+	}
+	expiry := initialization.GetEnvDuration("GOAUTH_EMAIL_VERIFICATION_DURATION", 24*time.Hour)
+
+	if err := s.verificationTokens.DeleteUser(ctx, userID); err != nil {
+		log.Error().Err(err).Msg("failed to delete old email verification tokens")
+		return err
+	}
+	if err := s.verificationTokens.Create(ctx, userID, tokenHash, time.Now().Add(expiry)); err != nil {
+		log.Error().Err(err).Msg("failed to store email verification token")
+		return err
+	}
+
+	link, err := tokenLink(initialization.GetEnv("GOAUTH_EMAIL_VERIFICATION_URL", ""), token)
+	if err != nil {
+		log.Error().Err(err).Msg("GOAUTH_EMAIL_VERIFICATION_URL is not a valid URL")
+		return err
+	}
+	if s.emailType == nil {
+		log.Error().Msg("email verification issued but no email service is configured")
+		return nil
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.emailType.SendVerificationEmail(ctx, email, link, expiry); err != nil {
+			log.Error().Err(err).Msg("failed to send verification email")
+		}
+	}()
+	return nil
+}
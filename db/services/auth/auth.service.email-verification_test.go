@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/tokenstore"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// newVerificationTestService returns a Service over the test database that
+// keeps verification tokens in Postgres and sends no email.
+func newVerificationTestService(t *testing.T) Service {
+	t.Helper()
+	store := dbtest.Store(t)
+	s := Service{Store: store}
+	WithEmailVerificationStore(tokenstore.NewPostgresStore(store, tokenstore.EmailVerification))(&s)
+	return s
+}
+
+// emailVerified reports whether the user's email is marked as verified.
+func emailVerified(t *testing.T, s Service, userID uuid.UUID) bool {
+	t.Helper()
+	user, err := s.Store.GetUserByID(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.EmailVerified.Bool
+}
+
+func TestVerifyEmail(t *testing.T) {
+	s := newVerificationTestService(t)
+	userID, bystander := dbtest.User(t, s.Store), dbtest.User(t, s.Store)
+	verify := func(token string) error {
+		t.Helper()
+		return s.VerifyEmail(&framework.VerifyEmailRequest{Token: token})
+	}
+
+	expired := newStoredToken(t, s.verificationTokens, userID, time.Now().Add(-time.Minute))
+	if err := verify(expired); !errors.Is(err, ErrInvalidVerifyToken) {
+		t.Errorf("an expired link: %v, want ErrInvalidVerifyToken", err)
+	}
+	if err := verify("not a token"); !errors.Is(err, ErrInvalidVerifyToken) {
+		t.Errorf("an unknown link: %v, want ErrInvalidVerifyToken", err)
+	}
+	if emailVerified(t, s, userID) {
+		t.Fatal("a refused link verified the email")
+	}
+
+	// An older link, still unexpired, that verifying must also retire.
+	older := newStoredToken(t, s.verificationTokens, userID, time.Now().Add(time.Hour))
+	token := newStoredToken(t, s.verificationTokens, userID, time.Now().Add(time.Hour))
+	if err := verify(token); err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	if !emailVerified(t, s, userID) {
+		t.Error("the email is not verified")
+	}
+	for name, link := range map[string]string{"the used link": token, "an older link": older} {
+		if err := verify(link); !errors.Is(err, ErrInvalidVerifyToken) {
+			t.Errorf("%s after verifying: %v, want ErrInvalidVerifyToken", name, err)
+		}
+	}
+
+	if emailVerified(t, s, bystander) {
+		t.Error("verifying one user verified another")
+	}
+}
+
+func TestResendVerification(t *testing.T) {
+	t.Setenv("GOAUTH_EMAIL_VERIFICATION_URL", "https://app.example.com/verify")
+	t.Setenv("GOAUTH_EMAIL_VERIFICATION_RESEND_INTERVAL", "1h")
+	s := newVerificationTestService(t)
+	ctx := context.Background()
+	user := dbtest.NewUser(t, s.Store, db.GoAuthRegisterParams{})
+	resend := func(email string) error {
+		t.Helper()
+		return s.ResendVerification(&framework.ResendVerificationRequest{Email: email})
+	}
+	latest := func(userID uuid.UUID) (tokenstore.Record, error) {
+		t.Helper()
+		return s.verificationTokens.Latest(ctx, userID)
+	}
+
+	if err := resend(user.Email); err != nil {
+		t.Fatalf("ResendVerification: %v", err)
+	}
+	first, err := latest(user.ID)
+	if err != nil {
+		t.Fatalf("no link was issued: %v", err)
+	}
+	if time.Until(first.ExpiresAt) < 23*time.Hour {
+		t.Errorf("link expires at %v, want in 24h", first.ExpiresAt)
+	}
+
+	// Within the interval the link stands.
+	if err := resend(user.Email); err != nil {
+		t.Fatal(err)
+	}
+	if record, err := latest(user.ID); err != nil || record.TokenHash != first.TokenHash {
+		t.Errorf("resend within the interval replaced the link: %+v, %v", record, err)
+	}
+
+	// After it, a new link replaces the old one.
+	t.Setenv("GOAUTH_EMAIL_VERIFICATION_RESEND_INTERVAL", "0s")
+	if err := resend(user.Email); err != nil {
+		t.Fatal(err)
+	}
+	second, err := latest(user.ID)
+	if err != nil || second.TokenHash == first.TokenHash {
+		t.Fatalf("resend after the interval = %+v, %v, want a new link", second, err)
+	}
+	if _, err := s.verificationTokens.Get(ctx, first.TokenHash); !errors.Is(err, tokenstore.ErrTokenNotFound) {
+		t.Errorf("the replaced link: %v, want ErrTokenNotFound", err)
+	}
+
+	// Unknown and verified addresses get the same answer and no link.
+	if err := resend("nobody-" + uuid.NewString() + "@example.com"); err != nil {
+		t.Errorf("an unknown address: %v", err)
+	}
+	verified := dbtest.NewUser(t, s.Store, db.GoAuthRegisterParams{})
+	if err := s.Store.UpdateUserEmailVerified(ctx, db.UpdateUserEmailVerifiedParams{
+		ID:            verified.ID,
+		EmailVerified: pgtype.Bool{Bool: true, Valid: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := resend(verified.Email); err != nil {
+		t.Errorf("a verified address: %v", err)
+	}
+	if _, err := latest(verified.ID); !errors.Is(err, tokenstore.ErrTokenNotFound) {
+		t.Errorf("a verified address got a link: %v", err)
+	}
+}
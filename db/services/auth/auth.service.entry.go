@@ -1,20 +1,67 @@
 package auth
 
 import (
+	"crypto/rand"
+	"sync"
+	"time"
+
 	goauth "github.com/SwanHtetAungPhyo/go-auth"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/lockout"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/passkey"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/revocation"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/session"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/tokenstore"
 	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
 	"github.com/SwanHtetAungPhyo/go-auth/framework"
 	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
 	"github.com/SwanHtetAungPhyo/go-auth/third-party/email"
+	"github.com/SwanHtetAungPhyo/go-auth/third-party/oauth"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
 )
 
 type AuthService interface {
 	Login(req *framework.LoginRequest) (framework.AuthResponse, error)
 	Register(req *framework.RegisterRequest) (framework.AuthResponse, error)
+	Refresh(refreshToken string) (framework.AuthResponse, error)
+	Logout(req *framework.LogoutRequest) error
+	LogoutAll(userId uuid.UUID) error
+	ListSessions(userId uuid.UUID, currentSessionId string) ([]framework.SessionInfo, error)
+	RevokeSession(userId uuid.UUID, sessionId uuid.UUID) error
+	RequestPasswordReset(req *framework.PasswordResetRequest) error
+	ConfirmPasswordReset(req *framework.PasswordResetConfirmRequest) error
+	VerifyEmail(req *framework.VerifyEmailRequest) error
+	ResendVerification(req *framework.ResendVerificationRequest) error
+	EnrollTOTP(userId uuid.UUID) (framework.TOTPEnrollResponse, error)
+	ConfirmTOTP(userId uuid.UUID, code string) error
+	DisableTOTP(userId uuid.UUID, code string) error
+	RegenerateRecoveryCodes(userId uuid.UUID, code string) ([]string, error)
+	VerifyMFA(req *framework.MFAVerifyRequest) (framework.AuthResponse, error)
+	BeginPasskeyRegistration(userId uuid.UUID) (*protocol.CredentialCreation, error)
+	FinishPasskeyRegistration(userId uuid.UUID, req *framework.PasskeyRegisterRequest) (framework.PasskeyInfo, error)
+	BeginPasskeyLogin() (*protocol.CredentialAssertion, error)
+	FinishPasskeyLogin(req *framework.PasskeyLoginRequest) (framework.AuthResponse, error)
+	BeginPasskeyMFA(mfaToken string) (*protocol.CredentialAssertion, error)
+	FinishPasskeyMFA(req *framework.PasskeyLoginRequest) (framework.AuthResponse, error)
+	ListPasskeys(userId uuid.UUID) ([]framework.PasskeyInfo, error)
+	DeletePasskey(userId uuid.UUID, passkeyId uuid.UUID) error
+	BeginOAuth(provider string) (authURL string, binding string, err error)
+	OAuthLogin(req *framework.OAuthCallbackRequest) (framework.AuthResponse, error)
+	BeginLinkAccount(userId uuid.UUID, provider string, req *framework.ReauthRequest) (authURL string, binding string, err error)
+	LinkAccount(req *framework.OAuthCallbackRequest) (framework.LinkedAccount, error)
+	ListAccounts(userId uuid.UUID) ([]framework.LinkedAccount, error)
+	UnlinkAccount(userId uuid.UUID, provider string) error
+	GetProviderToken(userId uuid.UUID, provider string) (framework.ProviderToken, error)
+	ImportUsers(users []framework.ImportUser) (framework.ImportResult, error)
+	ChangePassword(userId uuid.UUID, req *framework.ChangePasswordRequest) (framework.AuthResponse, error)
+	UnlockAccount(req *framework.UnlockAccountRequest) error
 	Me(userId uuid.UUID) (utils.GeneralResponse, error)
+	RevokeToken(jti string, expiresAt time.Time) error
+	RevokeUserTokens(userId uuid.UUID, before time.Time) error
 }
 
 type Service struct {
@@ -22,6 +69,41 @@ type Service struct {
 	cfg       goauth.Config
 	client    redis.Client
 	emailType *email.EmailService
+	// revocations blocks access tokens before their exp claim. Nil disables
+	// access token revocation; refresh tokens are still revoked in Postgres.
+	revocations revocation.Store
+	// sessions backs Config.Session. Nil when sessions are kept elsewhere.
+	sessions *session.Manager
+	// resetTokens and verificationTokens hold password reset and email
+	// verification tokens, in Postgres or Redis.
+	resetTokens        tokenstore.Store
+	verificationTokens tokenstore.Store
+	// mfaChallenges holds the tokens Login hands out to users with
+	// two-factor authentication on, redeemed by VerifyMFA.
+	mfaChallenges tokenstore.Store
+	// webAuthn and passkeyChallenges back the passkey ceremonies. Nil
+	// webAuthn disables them.
+	webAuthn          *webauthn.WebAuthn
+	passkeyChallenges passkey.ChallengeStore
+	// oauthProviders are keyed by Provider.Name; oauthState signs the state
+	// parameter of their flows.
+	oauthProviders map[string]oauth.Provider
+	oauthState     *oauth.StateSigner
+	// passwords hashes new passwords; Config.PasswordHasher or Argon2id.
+	passwords utils.PasswordHasher
+	// policy checks new passwords; Config.PasswordPolicy or the default.
+	policy *utils.PasswordPolicy
+	// tokenCipher encrypts the provider tokens kept for GetProviderToken.
+	// Nil means they are not kept.
+	tokenCipher *oauth.TokenCipher
+	// lockouts counts failed logins under lockoutPolicy; nil disables
+	// lockout. unlockTokens holds the links emailed when an account locks.
+	lockouts      lockout.Store
+	lockoutPolicy *lockout.Policy
+	unlockTokens  tokenstore.Store
+	// dummyHash is verified against when a login names an unknown email,
+	// so the response takes as long as for a wrong password.
+	dummyHash func() string
 }
 
 type Option func(*Service)
@@ -29,10 +111,29 @@ type Option func(*Service)
 func NewAuthService(conn *pgxpool.Pool, cfg goauth.Config, opts ...Option) Service {
 	store := db.NewStore(conn)
 	service := Service{
-		Store:     store,
-		cfg:       cfg,
-		emailType: cfg.EmailService,
+		Store:         store,
+		cfg:           cfg,
+		emailType:     cfg.EmailService,
+		passwords:     cfg.PasswordHasher,
+		policy:        cfg.PasswordPolicy,
+		lockoutPolicy: cfg.LoginLockout,
+	}
+	if service.passwords == nil {
+		service.passwords = utils.NewArgon2idHasher(utils.DefaultArgon2idParams())
+	}
+	if service.policy == nil {
+		service.policy = utils.DefaultPasswordPolicy()
 	}
+	if service.lockoutPolicy == nil {
+		service.lockoutPolicy = lockout.DefaultPolicy()
+	}
+	service.dummyHash = sync.OnceValue(func() string {
+		hash, err := service.passwords.Hash(rand.Text())
+		if err != nil {
+			log.Error().Err(err).Msg("failed to hash dummy password")
+		}
+		return hash
+	})
 	for _, opt := range opts {
 		opt(&service)
 	}
@@ -44,4 +145,66 @@ func WithRedisClient(client redis.Client) Option {
 	}
 }
 
+func WithRevocationStore(store revocation.Store) Option {
+	return func(s *Service) {
+		s.revocations = store
+	}
+}
+
+func WithSessionManager(manager *session.Manager) Option {
+	return func(s *Service) {
+		s.sessions = manager
+	}
+}
+
+func WithPasswordResetStore(store tokenstore.Store) Option {
+	return func(s *Service) {
+		s.resetTokens = store
+	}
+}
+
+func WithEmailVerificationStore(store tokenstore.Store) Option {
+	return func(s *Service) {
+		s.verificationTokens = store
+	}
+}
+
+func WithMFAChallengeStore(store tokenstore.Store) Option {
+	return func(s *Service) {
+		s.mfaChallenges = store
+	}
+}
+
+func WithWebAuthn(relyingParty *webauthn.WebAuthn, challenges passkey.ChallengeStore) Option {
+	return func(s *Service) {
+		s.webAuthn = relyingParty
+		s.passkeyChallenges = challenges
+	}
+}
+
+func WithOAuth(state *oauth.StateSigner, providers ...oauth.Provider) Option {
+	return func(s *Service) {
+		s.oauthState = state
+		s.oauthProviders = make(map[string]oauth.Provider, len(providers))
+		for _, provider := range providers {
+			s.oauthProviders[provider.Name()] = provider
+		}
+	}
+}
+
+func WithProviderTokenCipher(cipher *oauth.TokenCipher) Option {
+	return func(s *Service) {
+		s.tokenCipher = cipher
+	}
+}
+
+// WithLockoutStore counts failed logins in store and keeps the unlock links
+// emailed to locked accounts in unlockTokens.
+func WithLockoutStore(store lockout.Store, unlockTokens tokenstore.Store) Option {
+	return func(s *Service) {
+		s.lockouts = store
+		s.unlockTokens = unlockTokens
+	}
+}
+
 var _ AuthService = (*Service)(nil)
@@ -0,0 +1,42 @@
+package auth
+
+import "errors"
+
+var (
+	ErrInvalidRefreshToken   = errors.New("invalid refresh token")
+	ErrRefreshTokenReused    = errors.New("refresh token reuse detected")
+	ErrSessionNotFound       = errors.New("session not found")
+	ErrInvalidResetToken     = errors.New("invalid or expired password reset token")
+	ErrInvalidVerifyToken    = errors.New("invalid or expired email verification token")
+	ErrEmailNotVerified      = errors.New("email address has not been verified")
+	ErrInvalidMFAToken       = errors.New("invalid or expired mfa token")
+	ErrInvalidTOTPCode       = errors.New("invalid two-factor code")
+	ErrTOTPAlreadyEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled       = errors.New("two-factor authentication has not been set up")
+	ErrWebAuthnDisabled      = errors.New("webauthn is not configured")
+	ErrInvalidPasskey        = errors.New("passkey verification failed")
+	ErrPasskeyNotFound       = errors.New("passkey not found")
+	ErrOAuthProviderUnknown  = errors.New("oauth provider is not configured")
+	ErrInvalidOAuthState     = errors.New("invalid or expired oauth state")
+	ErrOAuthFailed           = errors.New("oauth provider authentication failed")
+	ErrOAuthEmailRequired    = errors.New("oauth provider did not return an email address")
+	ErrOAuthEmailInUse       = errors.New("an account with this email already exists")
+	ErrOAuthEmailUnverified  = errors.New("oauth provider has not verified the email address")
+	ErrOAuthAccountInUse     = errors.New("this provider account is linked to another user")
+	ErrProviderAlreadyLinked = errors.New("an account from this provider is already linked")
+	ErrAccountNotFound       = errors.New("linked account not found")
+	ErrLastLoginMethod       = errors.New("cannot remove the last way to sign in")
+	ErrReauthFailed          = errors.New("re-authentication failed")
+	ErrReauthUnavailable     = errors.New("set a password or two-factor authentication before linking accounts")
+	ErrProviderTokenDisabled = errors.New("provider tokens are not stored; set GOAUTH_PROVIDER_TOKEN_KEY")
+	ErrProviderTokenNotFound = errors.New("no provider token for this account")
+	ErrProviderTokenExpired  = errors.New("provider token expired and cannot be refreshed")
+	ErrInvalidImport         = errors.New("invalid import")
+	ErrPasswordReused        = errors.New("password was used recently; choose another")
+	ErrWrongPassword         = errors.New("current password is incorrect")
+	ErrPasswordNotSet        = errors.New("account has no password; set one through a password reset")
+	ErrInvalidCredentials    = errors.New("invalid email or password")
+	ErrLoginThrottled        = errors.New("too many failed login attempts; try again later")
+	ErrResetThrottled        = errors.New("too many password reset requests; try again later")
+	ErrInvalidUnlockToken    = errors.New("invalid or expired account unlock token")
+)
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+// importTimeout is longer than a request's, as an import inserts every user
+// of another system in one transaction.
+const importTimeout = 5 * time.Minute
+
+// ImportUsers inserts users brought over from another system, with their
+// provider accounts and metadata, in one transaction: if any user cannot be
+// imported, e.g. because the email is taken, none are. Imported password
+// hashes are verified as they are and replaced on each user's first login.
+func (s Service) ImportUsers(users []framework.ImportUser) (framework.ImportResult, error) {
+	for i, user := range users {
+		if err := validateImportUser(user); err != nil {
+			return framework.ImportResult{}, fmt.Errorf("%w: user %d (%s): %v", ErrInvalidImport, i+1, user.Email, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), importTimeout)
+	defer cancel()
+
+	var result framework.ImportResult
+	err := s.Store.WithTx(ctx, func(q *db.Queries) error {
+		result = framework.ImportResult{}
+		for i, user := range users {
+			accounts, err := importUser(ctx, q, user)
+			if err != nil {
+				return fmt.Errorf("user %d (%s): %w", i+1, user.Email, err)
+			}
+			result.Users++
+			result.Accounts += accounts
+		}
+		s.audit(ctx, q, AuditUsersImported, map[string]interface{}{
+			"users":    result.Users,
+			"accounts": result.Accounts,
+		})
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to import users")
+		return framework.ImportResult{}, err
+	}
+	return result, nil
+}
+
+func validateImportUser(user framework.ImportUser) error {
+	if err := framework.ValidateStruct(user); err != nil {
+		return err
+	}
+	if user.PasswordHash != "" {
+		if err := utils.ValidatePasswordHash(user.PasswordHash); err != nil {
+			return err
+		}
+	}
+	if user.PasswordHash == "" && len(user.Accounts) == 0 {
+		return errors.New("no password hash or provider account to sign in with")
+	}
+	if len(user.Metadata) > 0 {
+		var metadata map[string]interface{}
+		if err := json.Unmarshal(user.Metadata, &metadata); err != nil {
+			return errors.New("metadata must be a JSON object")
+		}
+	}
+	return nil
+}
+
+// importUser inserts one user and returns how many accounts it linked.
+func importUser(ctx context.Context, q *db.Queries, user framework.ImportUser) (int, error) {
+	role := user.Role
+	if role == "" {
+		role = oauthDefaultRole
+	}
+	metadata := []byte(user.Metadata)
+	if len(metadata) == 0 {
+		metadata = []byte("{}")
+	}
+	created, err := q.GoAuthRegister(ctx, db.GoAuthRegisterParams{
+		Email:        user.Email,
+		HashPassword: user.PasswordHash,
+		Name:         pgtype.Text{String: user.Name, Valid: user.Name != ""},
+		RoleName:     role,
+		Metadata:     metadata,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if user.Image != "" {
+		if _, err := q.UpdateUser(ctx, db.UpdateUserParams{
+			ID:    created.ID,
+			Image: pgtype.Text{String: user.Image, Valid: true},
+		}); err != nil {
+			return 0, err
+		}
+	}
+	if user.EmailVerified {
+		if err := q.UpdateUserEmailVerified(ctx, db.UpdateUserEmailVerifiedParams{
+			ID:            created.ID,
+			EmailVerified: pgtype.Bool{Bool: true, Valid: true},
+		}); err != nil {
+			return 0, err
+		}
+	}
+	for _, account := range user.Accounts {
+		if _, err := q.CreateAccount(ctx, db.CreateAccountParams{
+			UserID:     created.ID,
+			Provider:   account.Provider,
+			ProviderID: account.ProviderID,
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return len(user.Accounts), nil
+}
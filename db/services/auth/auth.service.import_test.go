@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/google/uuid"
+)
+
+// djangoHash is Django 3.2's own test of make_password, for "lètmein".
+const djangoHash = "pbkdf2_sha256$260000$seasalt$YlZ2Vggtqdc61YjArZuoApoBh9JNGYoDRBUGu6tcJQo="
+
+func TestImportUsersValidates(t *testing.T) {
+	// Every user is checked before the database is touched.
+	s := Service{Store: &db.Store{Queries: db.New(emptyDB{t})}}
+	tests := []struct {
+		name  string
+		user  framework.ImportUser
+		cause error
+	}{
+		{"unknown hash", framework.ImportUser{Email: "a@example.com", PasswordHash: "md5$abc"}, utils.ErrUnknownPasswordHash},
+		{"costly django hash", framework.ImportUser{Email: "a@example.com", PasswordHash: "pbkdf2_sha256$2000000000$salt$AAAA"}, utils.ErrInvalidDjangoPBKDF2Hash},
+		{"costly argon2id hash", framework.ImportUser{Email: "a@example.com", PasswordHash: "$argon2id$v=19$m=4194304,t=3,p=4$c29tZXNhbHRzb21lc2FsdA$c29tZWtleXNvbWVrZXlzb21la2V5c29tZWtleQ"}, utils.ErrInvalidArgon2idHash},
+		{"no way to sign in", framework.ImportUser{Email: "a@example.com"}, nil},
+		{"metadata not an object", framework.ImportUser{Email: "a@example.com", PasswordHash: djangoHash, Metadata: []byte(`[1]`)}, nil},
+		{"bad email", framework.ImportUser{Email: "nope", PasswordHash: djangoHash}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := []framework.ImportUser{{Email: "ok@example.com", PasswordHash: djangoHash}, tt.user}
+			_, err := s.ImportUsers(users)
+			if !errors.Is(err, ErrInvalidImport) {
+				t.Errorf("ImportUsers: %v, want ErrInvalidImport", err)
+			}
+			if err := validateImportUser(tt.user); err == nil || (tt.cause != nil && !errors.Is(err, tt.cause)) {
+				t.Errorf("validateImportUser: %v, want %v", err, tt.cause)
+			}
+		})
+	}
+}
+
+func TestImportUsers(t *testing.T) {
+	store := dbtest.Store(t)
+	s := Service{Store: store}
+	ctx := context.Background()
+	suffix := uuid.NewString()
+	email := func(name string) string { return name + "-" + suffix + "@example.com" }
+	cleanup := func(emails ...string) {
+		t.Cleanup(func() {
+			for _, address := range emails {
+				if user, err := store.GetUserByEmail(ctx, address); err == nil {
+					_ = store.DeleteUser(ctx, user.ID)
+				}
+			}
+		})
+	}
+	cleanup(email("django"), email("auth0"), email("rolled-back"))
+
+	result, err := s.ImportUsers([]framework.ImportUser{
+		{Email: email("django"), EmailVerified: true, PasswordHash: djangoHash, Metadata: []byte(`{"plan":"pro"}`)},
+		{Email: email("auth0"), PasswordHash: "$2b$06$If6bvum7DFjUnE9p2uDeDu0YHzrHM6tf.iqN8.yx.jNN1ILEf7h0i", Accounts: []framework.ImportAccount{{Provider: "github", ProviderID: suffix}}},
+	})
+	if err != nil {
+		t.Fatalf("ImportUsers: %v", err)
+	}
+	if result.Users != 2 || result.Accounts != 1 {
+		t.Errorf("result = %+v, want 2 users and 1 account", result)
+	}
+
+	django, err := store.GetUserByEmail(ctx, email("django"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !django.EmailVerified.Bool || django.RoleName != oauthDefaultRole {
+		t.Errorf("imported user = %+v", django)
+	}
+	if ok, err := utils.VerifyPassword("lètmein", django.HashPassword); !ok || err != nil {
+		t.Errorf("VerifyPassword on the stored hash = %v, %v", ok, err)
+	}
+	auth0, err := store.GetUserByEmail(ctx, email("auth0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts, err := store.ListUserAccounts(ctx, auth0.ID)
+	if err != nil || len(accounts) != 1 || accounts[0].ProviderID != suffix {
+		t.Errorf("imported accounts = %+v, %v", accounts, err)
+	}
+
+	// A taken email rolls the whole import back.
+	if _, err := s.ImportUsers([]framework.ImportUser{
+		{Email: email("rolled-back"), PasswordHash: djangoHash},
+		{Email: email("django"), PasswordHash: djangoHash},
+	}); err == nil {
+		t.Fatal("ImportUsers accepted a taken email")
+	}
+	if _, err := store.GetUserByEmail(ctx, email("rolled-back")); err == nil {
+		t.Error("a user imported before the failure was kept")
+	}
+}
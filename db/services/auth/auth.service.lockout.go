@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/lockout"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/tokenstore"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/SwanHtetAungPhyo/go-auth/initialization"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// LoginThrottledError refuses a login while the account or IP address is
+// backing off after failed logins, or is locked. It matches
+// ErrLoginThrottled with errors.Is.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string { return ErrLoginThrottled.Error() }
+func (e *LoginThrottledError) Unwrap() error { return ErrLoginThrottled }
+
+// ResetThrottledError refuses a password reset request while the
+// email or IP address has asked for too many. It matches
+// ErrResetThrottled with errors.Is.
+type ResetThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ResetThrottledError) Error() string { return ErrResetThrottled.Error() }
+func (e *ResetThrottledError) Unwrap() error { return ErrResetThrottled }
+
+// loginKeys are the lockout keys a login attempt counts against.
+func loginKeys(email, ipAddress string) []string {
+	keys := []string{lockout.AccountKey(email)}
+	if ipAddress != "" {
+		keys = append(keys, lockout.IPKey(ipAddress))
+	}
+	return keys
+}
+
+// twoFactorKeys are the lockout keys a second-factor code counts against.
+func twoFactorKeys(userID uuid.UUID, ipAddress string) []string {
+	keys := []string{lockout.TwoFactorKey(userID.String())}
+	if ipAddress != "" {
+		keys = append(keys, lockout.IPKey(ipAddress))
+	}
+	return keys
+}
+
+// resetKeys are the lockout keys a password reset request counts against.
+func resetKeys(email, ipAddress string) []string {
+	keys := []string{lockout.ResetKey(email)}
+	if ipAddress != "" {
+		keys = append(keys, lockout.ResetIPKey(ipAddress))
+	}
+	return keys
+}
+
+// passwordResetPolicy allows GOAUTH_PASSWORD_RESET_LIMIT reset requests per
+// email (default 3) and GOAUTH_PASSWORD_RESET_IP_LIMIT per IP address
+// (default 20) within GOAUTH_PASSWORD_RESET_WINDOW (default 1h). Past a
+// limit, the next request waits a whole window from the last one. A limit of
+// 0 turns it off.
+func passwordResetPolicy() *lockout.Policy {
+	window := initialization.GetEnvDuration("GOAUTH_PASSWORD_RESET_WINDOW", time.Hour)
+	return &lockout.Policy{
+		Window:       window,
+		DelayAfter:   initialization.GetEnvInt("GOAUTH_PASSWORD_RESET_LIMIT", 3),
+		BaseDelay:    window,
+		MaxDelay:     window,
+		IPDelayAfter: initialization.GetEnvInt("GOAUTH_PASSWORD_RESET_IP_LIMIT", 20),
+	}
+}
+
+// loginAttempt is a password or second-factor check that has already been
+// counted as a failure against its lockout keys. states holds each key's
+// state with the attempt counted; a key whose store failed is missing.
+type loginAttempt struct {
+	email     string
+	ipAddress string
+	keys      []string
+	states    map[string]lockout.State
+}
+
+// beginLoginAttempt counts an attempt against the account and the IP address
+// before the password is checked, and refuses it with a *LoginThrottledError
+// while any key was locked or had to wait. Counting first makes the check
+// and the count one step, so a parallel burst of guesses cannot all pass
+// before the first is counted. A refused attempt is forgiven; the caller
+// ends an admitted one with loginSucceeded, loginFailed or
+// forgiveLoginAttempt. A failing store is logged and lets the attempt
+// through, so an outage does not lock everyone out.
+func (s Service) beginLoginAttempt(ctx context.Context, email, ipAddress string) (*loginAttempt, error) {
+	return s.beginAttempt(ctx, s.lockoutPolicy, email, ipAddress, loginKeys(email, ipAddress))
+}
+
+// beginTwoFactorAttempt is beginLoginAttempt for a TOTP or recovery code,
+// counted against the user's two-factor key and the IP address, if known.
+func (s Service) beginTwoFactorAttempt(ctx context.Context, userID uuid.UUID, email, ipAddress string) (*loginAttempt, error) {
+	return s.beginAttempt(ctx, s.lockoutPolicy, email, ipAddress, twoFactorKeys(userID, ipAddress))
+}
+
+// beginPasswordResetRequest counts a reset request against the email and the
+// IP address under passwordResetPolicy and refuses it with a
+// *ResetThrottledError past either limit. Admitted requests stay
+// counted whether or not a user has the email, so the limit is reached the
+// same way for unknown addresses and tells nothing about them.
+func (s Service) beginPasswordResetRequest(ctx context.Context, email, ipAddress string) error {
+	_, err := s.beginAttempt(ctx, passwordResetPolicy(), email, ipAddress, resetKeys(email, ipAddress))
+	var throttled *LoginThrottledError
+	if errors.As(err, &throttled) {
+		return &ResetThrottledError{RetryAfter: throttled.RetryAfter}
+	}
+	return err
+}
+
+func (s Service) beginAttempt(ctx context.Context, policy *lockout.Policy, email, ipAddress string, keys []string) (*loginAttempt, error) {
+	attempt := &loginAttempt{email: email, ipAddress: ipAddress, keys: keys, states: make(map[string]lockout.State)}
+	if s.lockouts == nil {
+		return attempt, nil
+	}
+	now := time.Now()
+	var throttled LoginThrottledError
+	for _, key := range keys {
+		state, err := s.lockouts.Fail(ctx, key, policy.Window)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to count login attempt")
+			continue
+		}
+		attempt.states[key] = state
+		// The attempt is judged on the state it found, not the one it
+		// left.
+		previous := state.Previous()
+		if wait := policy.RetryAfter(key, previous, now); wait > throttled.RetryAfter {
+			throttled.RetryAfter = wait
+		}
+		throttled.Locked = throttled.Locked || previous.Locked(now)
+	}
+	if throttled.RetryAfter > 0 {
+		s.forgiveLoginAttempt(ctx, attempt)
+		return nil, &throttled
+	}
+	return attempt, nil
+}
+
+// forgiveLoginAttempt takes back an attempt that was refused or could not be
+// checked, so it does not count as a failure.
+func (s Service) forgiveLoginAttempt(ctx context.Context, attempt *loginAttempt) {
+	for key := range attempt.states {
+		if err := s.lockouts.Forgive(ctx, key); err != nil {
+			log.Error().Err(err).Msg("failed to forgive login attempt")
+		}
+	}
+}
+
+// loginFailed leaves a wrong password or code counted against its keys,
+// locking whichever reached its limit. userID is uuid.Nil when no user has
+// the email; the counters do not depend on it, and the unlock email is sent
+// in the background, so neither the response nor its timing tells the two
+// apart.
+func (s Service) loginFailed(ctx context.Context, attempt *loginAttempt, userID uuid.UUID, userEmail string) {
+	now := time.Now()
+	for _, key := range attempt.keys {
+		state, ok := attempt.states[key]
+		if !ok || !s.lockoutPolicy.ShouldLock(key, state, now) {
+			continue
+		}
+		until := now.Add(s.lockoutPolicy.LockDuration)
+		if err := s.lockouts.Lock(ctx, key, until); err != nil {
+			log.Error().Err(err).Msg("failed to lock login")
+			continue
+		}
+
+		if lockout.IsIPKey(key) {
+			s.audit(ctx, s.Store.Queries, AuditIPLocked, map[string]interface{}{
+				"ip":           attempt.ipAddress,
+				"failures":     state.Failures,
+				"locked_until": until,
+			})
+			continue
+		}
+		entry := map[string]interface{}{
+			"email":        attempt.email,
+			"ip":           attempt.ipAddress,
+			"failures":     state.Failures,
+			"locked_until": until,
+		}
+		if userID != uuid.Nil {
+			entry["user_id"] = userID.String()
+			go s.sendUnlockEmail(userID, userEmail, attempt.ipAddress, until)
+		}
+		s.audit(ctx, s.Store.Queries, AuditAccountLocked, entry)
+	}
+}
+
+// loginSucceeded clears the account's or the user's two-factor counter
+// after a right password or code. The IP address only has this attempt taken
+// back and is otherwise left to run out with the window, or a stuffing
+// attacker could clear it by signing in to an account of their own.
+func (s Service) loginSucceeded(ctx context.Context, attempt *loginAttempt) {
+	for key := range attempt.states {
+		if lockout.IsIPKey(key) {
+			if err := s.lockouts.Forgive(ctx, key); err != nil {
+				log.Error().Err(err).Msg("failed to forgive login attempt")
+			}
+			continue
+		}
+		if err := s.lockouts.Reset(ctx, key); err != nil {
+			log.Error().Err(err).Msg("failed to reset failed login counter")
+		}
+	}
+}
+
+// burnPasswordCheck verifies password against a throwaway hash, so a login
+// for an unknown email costs as much as one with a wrong password.
+func (s Service) burnPasswordCheck(password string) {
+	if hash := s.dummyHash(); hash != "" {
+		_, _ = utils.VerifyPassword(password, hash)
+	}
+}
+
+// sendUnlockEmail emails the owner of a locked account a single-use link
+// that unlocks it at once. The link is GOAUTH_ACCOUNT_UNLOCK_URL with
+// ?token=... appended and is valid for GOAUTH_ACCOUNT_UNLOCK_DURATION
+// (default 1h).
+func (s Service) sendUnlockEmail(userID uuid.UUID, to, ipAddress string, lockedUntil time.Time) {
+	if s.emailType == nil || s.unlockTokens == nil {
+		log.Error().Msg("account locked but no email service or unlock token store is configured")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	token, tokenHash, err := tokenstore.NewToken()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to generate account unlock token")
+		return
+	}
+	expiry := initialization.GetEnvDuration("GOAUTH_ACCOUNT_UNLOCK_DURATION", time.Hour)
+
+	// Only the newest link works.
+	if err := s.unlockTokens.DeleteUser(ctx, userID); err != nil {
+		log.Error().Err(err).Msg("failed to delete old account unlock tokens")
+		return
+	}
+	if err := s.unlockTokens.Create(ctx, userID, tokenHash, time.Now().Add(expiry)); err != nil {
+		log.Error().Err(err).Msg("failed to store account unlock token")
+		return
+	}
+	link, err := tokenLink(initialization.GetEnv("GOAUTH_ACCOUNT_UNLOCK_URL", ""), token)
+	if err != nil {
+		log.Error().Err(err).Msg("GOAUTH_ACCOUNT_UNLOCK_URL is not a valid URL")
+		return
+	}
+	if err := s.emailType.SendAccountLockedEmail(ctx, to, link, ipAddress, lockedUntil); err != nil {
+		log.Error().Err(err).Msg("failed to send account locked email")
+	}
+}
+
+// UnlockAccount redeems the link emailed when an account locked and clears
+// its failed logins and second-factor codes, so the user can sign in at
+// once. Locks on IP addresses are left alone.
+func (s Service) UnlockAccount(req *framework.UnlockAccountRequest) error {
+	if s.lockouts == nil || s.unlockTokens == nil {
+		return ErrInvalidUnlockToken
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	record, err := s.unlockTokens.Consume(ctx, tokenstore.HashToken(req.Token))
+	if errors.Is(err, tokenstore.ErrTokenNotFound) {
+		return ErrInvalidUnlockToken
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to redeem account unlock token")
+		return err
+	}
+	user, err := s.Store.GetUserByID(ctx, record.UserID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up user to unlock")
+		return err
+	}
+	for _, key := range []string{lockout.AccountKey(user.Email), lockout.TwoFactorKey(user.ID.String())} {
+		if err := s.lockouts.Reset(ctx, key); err != nil {
+			log.Error().Err(err).Msg("failed to unlock account")
+			return err
+		}
+	}
+
+	s.audit(ctx, s.Store.Queries, AuditAccountUnlocked, map[string]interface{}{
+		"user_id": user.ID.String(),
+		"ip":      req.IPAddress,
+	})
+	return nil
+}
@@ -7,26 +7,49 @@ import (
 
 	"github.com/SwanHtetAungPhyo/go-auth/framework"
 	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog/log"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// Login checks an email and password. Unknown emails and wrong passwords
+// both return ErrInvalidCredentials and count towards the lockout policy
+// alike; while the account or IP address has to wait, the attempt is
+// refused with a *LoginThrottledError before the password is checked.
 func (s Service) Login(req *framework.LoginRequest) (framework.AuthResponse, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	user, err := s.Store.GetUserByEmail(ctx, req.Email)
+	attempt, err := s.beginLoginAttempt(ctx, req.Email, req.IPAddress)
 	if err != nil {
-		log.Error().Err(err).Str("email", req.Email).Msg("user not found")
-		return framework.AuthResponse{}, errors.New("user not found")
+		return framework.AuthResponse{}, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.HashPassword), []byte(req.Password)); err != nil {
-		log.Error().Err(err).Str("email", req.Email).Msg("wrong password")
+	user, err := s.Store.GetUserByEmail(ctx, req.Email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Error().Str("email", req.Email).Msg("user not found")
+		s.burnPasswordCheck(req.Password)
+		s.loginFailed(ctx, attempt, uuid.Nil, "")
+		return framework.AuthResponse{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up user to log in")
+		s.forgiveLoginAttempt(ctx, attempt)
 		return framework.AuthResponse{}, fiber.ErrInternalServerError
 	}
 
+	if !s.verifyPassword(ctx, user.ID, user.HashPassword, req.Password) {
+		log.Error().Str("email", req.Email).Msg("wrong password")
+		s.loginFailed(ctx, attempt, user.ID, user.Email)
+		return framework.AuthResponse{}, ErrInvalidCredentials
+	}
+	s.loginSucceeded(ctx, attempt)
+
+	if s.cfg.RequireEmailVerification && !user.EmailVerified.Bool {
+		return framework.AuthResponse{}, ErrEmailNotVerified
+	}
+
 	//userInfo := framework.GoAuthUserInfo{
 	//	UserId:   user.ID.String(),
 	//	Email:    user.Email,
@@ -34,23 +57,38 @@ func (s Service) Login(req *framework.LoginRequest) (framework.AuthResponse, err
 	//	CreateAt: user.CreatedAt.Time,
 	//}
 
-	if s.cfg.JwtAuth {
-		token, err := s.generateToken(user.Email, user.RoleName, map[string]interface{}{})
+	methods, err := s.mfaMethods(ctx, user.ID, user.TwoFactorEnabled.Bool)
+	if err != nil {
+		return framework.AuthResponse{}, fiber.ErrInternalServerError
+	}
+	if len(methods) > 0 {
+		return s.mfaChallenge(ctx, user.ID, methods)
+	}
+	return s.completeLogin(ctx, user.ID, user.RoleName, req.UserAgent, req.IPAddress)
+}
+
+// completeLogin issues whatever credentials the config enables once the user
+// has fully authenticated.
+func (s Service) completeLogin(ctx context.Context, userID uuid.UUID, role, userAgent, ipAddress string) (framework.AuthResponse, error) {
+	var response framework.AuthResponse
+	if s.cfg.JwtAuth || s.cfg.PestoAuth {
+		token, err := s.issueTokens(ctx, s.Store.Queries, userID, role, uuid.New())
 		if err != nil {
 			log.Error().Err(err).Msg("failed to generate token")
 			return framework.AuthResponse{}, fiber.ErrInternalServerError
 		}
+		response.AccessToken = token.AccessToken
+		response.RefreshToken = token.RefreshToken
+	}
 
-		return framework.AuthResponse{
-			//UserInfo:     userInfo,
-			AccessToken:  token.AccessToken,
-			RefreshToken: token.RefreshToken,
-		}, nil
+	if s.cfg.Session && s.sessions != nil {
+		sessionToken, _, err := s.sessions.Create(ctx, userID, userAgent, ipAddress)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to create session")
+			return framework.AuthResponse{}, fiber.ErrInternalServerError
+		}
+		response.SessionToken = sessionToken
 	}
 
-	return framework.AuthResponse{
-		//UserInfo:     userInfo,
-		AccessToken:  "",
-		RefreshToken: "",
-	}, nil
+	return response, nil
 }
@@ -1 +1,75 @@
 package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Logout ends the login the request belongs to: the access token is revoked,
+// the refresh token's family is revoked so no rotated copy survives, and the
+// server session is deleted. Credentials that fail validation are skipped, as
+// they can no longer be used anyway.
+func (s Service) Logout(req *framework.LogoutRequest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if req.AccessToken != "" {
+		if claims, err := utils.ValidateClaims(req.AccessToken, s.tokenType()); err == nil {
+			jti, _ := claims[utils.Jti].(string)
+			expiresAt, ok := utils.ClaimTime(claims, utils.Exp)
+			if jti != "" && ok {
+				if err := s.RevokeToken(jti, expiresAt); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if req.RefreshToken != "" {
+		if claims, err := utils.ValidateClaims(req.RefreshToken, s.tokenType()); err == nil {
+			if familyID, err := claimUUID(claims, utils.FamilyId); err == nil {
+				if err := s.Store.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+					log.Error().Err(err).Msg("failed to revoke refresh token family")
+					return err
+				}
+			}
+		}
+	}
+
+	if req.SessionID != "" && s.sessions != nil {
+		sessionID, err := uuid.Parse(req.SessionID)
+		if err != nil {
+			return nil
+		}
+		if err := s.sessions.Revoke(ctx, sessionID); err != nil {
+			log.Error().Err(err).Msg("failed to delete session")
+			return err
+		}
+	} else if req.SessionToken != "" && s.sessions != nil {
+		if err := s.sessions.RevokeToken(ctx, req.SessionToken); err != nil {
+			log.Error().Err(err).Msg("failed to delete session")
+			return err
+		}
+	}
+	return nil
+}
+
+// LogoutAll signs the user out on every device: all server sessions are
+// deleted and every token issued so far is revoked.
+func (s Service) LogoutAll(userId uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if s.sessions != nil {
+		if err := s.sessions.RevokeUser(ctx, userId); err != nil {
+			log.Error().Err(err).Msg("failed to delete user sessions")
+			return err
+		}
+	}
+	return s.RevokeUserTokens(userId, time.Now())
+}
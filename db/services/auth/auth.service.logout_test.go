@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	goauth "github.com/SwanHtetAungPhyo/go-auth"
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/revocation"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLogoutAccessToken(t *testing.T) {
+	useTestKeys(t)
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	// Without a refresh token or session, Logout never reaches Postgres.
+	s := Service{Store: &db.Store{Queries: db.New(emptyDB{t})}, cfg: goauth.Config{JwtAuth: true}}
+	WithRevocationStore(revocation.NewRedisStore(client, time.Hour))(&s)
+
+	tokens, err := s.generateToken(uuid.NewString(), "USER", uuid.NewString(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.generateToken(uuid.NewString(), "USER", uuid.NewString(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Logout(&framework.LogoutRequest{AccessToken: tokens.AccessToken}); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if !accessTokenRevoked(t, s, tokens.AccessToken) {
+		t.Error("the access token is still accepted after Logout")
+	}
+	if accessTokenRevoked(t, s, other.AccessToken) {
+		t.Error("Logout revoked another user's access token")
+	}
+
+	// Credentials that do not validate are skipped, not an error.
+	if err := s.Logout(&framework.LogoutRequest{AccessToken: "nope", RefreshToken: "nope", SessionID: "nope"}); err != nil {
+		t.Errorf("Logout with invalid credentials: %v", err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	s := newTokenTestService(t)
+	ctx := context.Background()
+	userID := dbtest.User(t, s.Store)
+
+	login := func(device string) framework.AuthResponse {
+		t.Helper()
+		response, err := s.completeLogin(ctx, userID, "USER", device, "192.0.2.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+	phone, laptop := login("phone"), login("laptop")
+	// The phone has rotated its refresh token once; logging out with the
+	// new one must also retire the one it replaced.
+	rotated, err := s.Refresh(phone.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Logout(&framework.LogoutRequest{
+		AccessToken:  rotated.AccessToken,
+		RefreshToken: rotated.RefreshToken,
+		SessionToken: phone.SessionToken,
+	}); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if !accessTokenRevoked(t, s, rotated.AccessToken) {
+		t.Error("the phone's access token is still accepted")
+	}
+	for _, token := range []string{phone.RefreshToken, rotated.RefreshToken} {
+		record, err := s.Store.GetRefreshTokenForUpdate(ctx, tokenID(t, token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !record.RevokedAt.Valid {
+			t.Errorf("refresh token %s of the phone's family survived", record.ID)
+		}
+	}
+	if _, _, err := s.sessions.Resolve(ctx, phone.SessionToken); err == nil {
+		t.Error("the phone's session survived")
+	}
+
+	// The laptop is another login and stays signed in.
+	if accessTokenRevoked(t, s, laptop.AccessToken) {
+		t.Error("Logout revoked the laptop's access token")
+	}
+	if _, _, err := s.sessions.Resolve(ctx, laptop.SessionToken); err != nil {
+		t.Errorf("the laptop's session: %v", err)
+	}
+	mustRefresh(t, s, laptop.RefreshToken)
+
+	// A session can also be ended by its id.
+	sessions, err := s.sessions.List(ctx, userID)
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("sessions = %+v, %v, want the laptop's", sessions, err)
+	}
+	if err := s.Logout(&framework.LogoutRequest{SessionID: sessions[0].ID.String()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.sessions.Resolve(ctx, laptop.SessionToken); err == nil {
+		t.Error("the session ended by id survived")
+	}
+}
+
+// mustRefresh rotates a refresh token that must still be live and returns
+// its successor.
+func mustRefresh(t *testing.T, s Service, token string) string {
+	t.Helper()
+	response, err := s.Refresh(token)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	return response.RefreshToken
+}
+
+func TestLogoutAll(t *testing.T) {
+	s := newTokenTestService(t)
+	ctx := context.Background()
+	userID := dbtest.User(t, s.Store)
+	bystander := dbtest.User(t, s.Store)
+
+	var logins []framework.AuthResponse
+	for _, device := range []string{"phone", "laptop"} {
+		response, err := s.completeLogin(ctx, userID, "USER", device, "192.0.2.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		logins = append(logins, response)
+	}
+	other, err := s.completeLogin(ctx, bystander, "USER", "phone", "192.0.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForNextSecond()
+
+	if err := s.LogoutAll(userID); err != nil {
+		t.Fatalf("LogoutAll: %v", err)
+	}
+	for i, login := range logins {
+		if !accessTokenRevoked(t, s, login.AccessToken) {
+			t.Errorf("access token %d is still accepted", i)
+		}
+		if _, err := s.Refresh(login.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("refresh token %d: %v, want ErrInvalidRefreshToken", i, err)
+		}
+		if _, _, err := s.sessions.Resolve(ctx, login.SessionToken); err == nil {
+			t.Errorf("session %d survived", i)
+		}
+	}
+
+	// Another user is untouched, and the user can sign in again.
+	if accessTokenRevoked(t, s, other.AccessToken) {
+		t.Error("LogoutAll revoked another user's access token")
+	}
+	mustRefresh(t, s, other.RefreshToken)
+	again, err := s.completeLogin(ctx, userID, "USER", "phone", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accessTokenRevoked(t, s, again.AccessToken) {
+		t.Error("a login after LogoutAll is revoked")
+	}
+	mustRefresh(t, s, again.RefreshToken)
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/third-party/oauth"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+// oauthDefaultRole matches the goauth_user.role_name column default.
+const oauthDefaultRole = "USER"
+
+// BeginOAuth starts a login with provider. The browser is redirected to
+// authURL and must keep binding, e.g. in a cookie, until the callback.
+func (s Service) BeginOAuth(provider string) (authURL string, binding string, err error) {
+	client, ok := s.oauthProviders[provider]
+	if !ok || s.oauthState == nil {
+		return "", "", ErrOAuthProviderUnknown
+	}
+	flow, err := s.oauthState.Begin(provider)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to start oauth flow")
+		return "", "", err
+	}
+	return client.AuthCodeURL(flow), flow.Binding, nil
+}
+
+// OAuthLogin completes a login started by BeginOAuth. The provider account is
+// looked up by its subject; on first login a user is created from the
+// identity. The first login is refused unless the provider has verified the
+// email, or anyone could claim an address they do not own. An email that
+// already belongs to a user is refused, as linking it would hand that user's
+// account to whoever controls the provider account, unless WithOAuthAutoLink
+// opted in and goauth verified it too.
+func (s Service) OAuthLogin(req *framework.OAuthCallbackRequest) (framework.AuthResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	identity, flow, err := s.oauthIdentity(ctx, req)
+	if err != nil {
+		return framework.AuthResponse{}, err
+	}
+	if flow.LinkUserID != "" {
+		return framework.AuthResponse{}, ErrInvalidOAuthState
+	}
+
+	var (
+		userID                uuid.UUID
+		role                  string
+		emailVerified, totpOn bool
+	)
+	account, err := s.Store.GetAccountByProvider(ctx, db.GetAccountByProviderParams{
+		Provider:   identity.Provider,
+		ProviderID: identity.Subject,
+	})
+	switch {
+	case err == nil:
+		userID, role = account.UserID, account.RoleName
+		emailVerified, totpOn = account.EmailVerified.Bool, account.TwoFactorEnabled.Bool
+		s.storeProviderToken(ctx, account.ID, identity)
+	case errors.Is(err, pgx.ErrNoRows) && identity.Email == "":
+		return framework.AuthResponse{}, ErrOAuthEmailRequired
+	case errors.Is(err, pgx.ErrNoRows) && !identity.EmailVerified:
+		return framework.AuthResponse{}, ErrOAuthEmailUnverified
+	case errors.Is(err, pgx.ErrNoRows):
+		existing, err := s.Store.GetUserByEmail(ctx, identity.Email)
+		switch {
+		case err == nil:
+			if !s.cfg.OAuthAutoLink || !existing.EmailVerified.Bool {
+				return framework.AuthResponse{}, ErrOAuthEmailInUse
+			}
+			linked, err := s.Store.CreateAccount(ctx, db.CreateAccountParams{
+				UserID:     existing.ID,
+				Provider:   identity.Provider,
+				ProviderID: identity.Subject,
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("failed to link oauth account")
+				return framework.AuthResponse{}, err
+			}
+			s.storeProviderToken(ctx, linked.ID, identity)
+			s.audit(ctx, s.Store.Queries, AuditAccountLinked, map[string]interface{}{
+				"user_id":  existing.ID.String(),
+				"provider": identity.Provider,
+				"auto":     true,
+			})
+			userID, role = existing.ID, existing.RoleName
+			emailVerified, totpOn = true, existing.TwoFactorEnabled.Bool
+		case errors.Is(err, pgx.ErrNoRows):
+			user, err := s.createOAuthUser(ctx, identity)
+			if err != nil {
+				return framework.AuthResponse{}, err
+			}
+			userID, role = user.ID, user.RoleName
+			emailVerified = true
+		default:
+			log.Error().Err(err).Msg("failed to look up user by email")
+			return framework.AuthResponse{}, err
+		}
+	default:
+		log.Error().Err(err).Msg("failed to look up oauth account")
+		return framework.AuthResponse{}, err
+	}
+
+	if s.cfg.RequireEmailVerification && !emailVerified {
+		return framework.AuthResponse{}, ErrEmailNotVerified
+	}
+	methods, err := s.mfaMethods(ctx, userID, totpOn)
+	if err != nil {
+		return framework.AuthResponse{}, err
+	}
+	if len(methods) > 0 {
+		return s.mfaChallenge(ctx, userID, methods)
+	}
+	return s.completeLogin(ctx, userID, role, req.UserAgent, req.IPAddress)
+}
+
+// oauthIdentity checks the state of a provider callback and redeems its code.
+func (s Service) oauthIdentity(ctx context.Context, req *framework.OAuthCallbackRequest) (oauth.Identity, oauth.Flow, error) {
+	client, ok := s.oauthProviders[req.Provider]
+	if !ok || s.oauthState == nil {
+		return oauth.Identity{}, oauth.Flow{}, ErrOAuthProviderUnknown
+	}
+	flow, err := s.oauthState.Resume(req.Provider, req.State, req.Binding)
+	if err != nil {
+		return oauth.Identity{}, oauth.Flow{}, ErrInvalidOAuthState
+	}
+	identity, err := client.Authenticate(ctx, req.Code, flow)
+	if err != nil {
+		log.Error().Err(err).Str("provider", req.Provider).Msg("oauth authentication failed")
+		return oauth.Identity{}, oauth.Flow{}, ErrOAuthFailed
+	}
+	return identity, flow, nil
+}
+
+// createOAuthUser creates the user and provider account for a first login,
+// with the email the provider verified marked verified. The user gets no
+// password, so only the provider can sign them in until they set one
+// through a password reset.
+func (s Service) createOAuthUser(ctx context.Context, identity oauth.Identity) (db.GoauthUser, error) {
+	var user db.GoauthUser
+	err := s.Store.WithTx(ctx, func(q *db.Queries) error {
+		var err error
+		user, err = q.GoAuthRegister(ctx, db.GoAuthRegisterParams{
+			Email:    identity.Email,
+			RoleName: oauthDefaultRole,
+			Name:     pgtype.Text{String: identity.Name, Valid: identity.Name != ""},
+			Metadata: []byte("{}"),
+		})
+		if err != nil {
+			return err
+		}
+		if identity.Picture != "" {
+			if user, err = q.UpdateUser(ctx, db.UpdateUserParams{
+				ID:    user.ID,
+				Image: pgtype.Text{String: identity.Picture, Valid: true},
+			}); err != nil {
+				return err
+			}
+		}
+		if err := q.UpdateUserEmailVerified(ctx, db.UpdateUserEmailVerifiedParams{
+			ID:            user.ID,
+			EmailVerified: pgtype.Bool{Bool: true, Valid: true},
+		}); err != nil {
+			return err
+		}
+		user.EmailVerified = pgtype.Bool{Bool: true, Valid: true}
+		account, err := q.CreateAccount(ctx, db.CreateAccountParams{
+			UserID:     user.ID,
+			Provider:   identity.Provider,
+			ProviderID: identity.Subject,
+		})
+		if err != nil {
+			return err
+		}
+		return s.saveProviderToken(ctx, q, account.ID, identity.Token)
+	})
+	if err != nil {
+		log.Error().Err(err).Str("provider", identity.Provider).Msg("failed to create oauth user")
+		return db.GoauthUser{}, err
+	}
+	return user, nil
+}
+
+// storeProviderToken keeps the token of a login for GetProviderToken. A
+// failure only costs the stored token, so it does not fail the login.
+func (s Service) storeProviderToken(ctx context.Context, accountID uuid.UUID, identity oauth.Identity) {
+	if err := s.saveProviderToken(ctx, s.Store.Queries, accountID, identity.Token); err != nil {
+		log.Error().Err(err).Str("provider", identity.Provider).Msg("failed to store provider token")
+	}
+}
@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/third-party/oauth"
+	"github.com/SwanHtetAungPhyo/go-auth/third-party/oauth/oauthtest"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/oauth2"
+)
+
+// emptyDB is a database without rows. Anything but a single-row lookup
+// fails the test, so a refused login is known not to have written.
+type emptyDB struct{ t *testing.T }
+
+func (d emptyDB) Exec(_ context.Context, sql string, _ ...interface{}) (pgconn.CommandTag, error) {
+	d.t.Errorf("unexpected Exec: %s", sql)
+	return pgconn.CommandTag{}, errors.New("emptyDB: unexpected Exec")
+}
+
+func (d emptyDB) Query(_ context.Context, sql string, _ ...interface{}) (pgx.Rows, error) {
+	d.t.Errorf("unexpected Query: %s", sql)
+	return nil, errors.New("emptyDB: unexpected Query")
+}
+
+func (d emptyDB) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return noRow{}
+}
+
+type noRow struct{}
+
+func (noRow) Scan(...any) error { return pgx.ErrNoRows }
+
+// stubProvider hands out a fixed identity for any code.
+type stubProvider struct {
+	name     string
+	identity oauth.Identity
+	err      error
+}
+
+func (p stubProvider) Name() string { return p.name }
+
+func (p stubProvider) AuthCodeURL(flow oauth.Flow) string {
+	return "https://provider.example.com/authorize?state=" + url.QueryEscape(flow.State)
+}
+
+func (p stubProvider) Authenticate(context.Context, string, oauth.Flow) (oauth.Identity, error) {
+	return p.identity, p.err
+}
+
+func (p stubProvider) Refresh(context.Context, *oauth2.Token) (*oauth2.Token, error) {
+	return nil, oauth.ErrNoRefreshToken
+}
+
+func newTestSigner(t *testing.T) *oauth.StateSigner {
+	t.Helper()
+	signer, err := oauth.NewStateSigner([]byte("test-secret-test-secret-test-sec"), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func newOAuthTestService(t *testing.T, providers ...oauth.Provider) Service {
+	t.Helper()
+	s := Service{Store: &db.Store{Queries: db.New(emptyDB{t})}}
+	WithOAuth(newTestSigner(t), providers...)(&s)
+	return s
+}
+
+func TestOAuthLoginRefusals(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider stubProvider
+		// otherBrowser completes the flow with another flow's binding, as a
+		// forged callback link would.
+		otherBrowser bool
+		want         error
+	}{
+		{
+			name:         "state from another browser",
+			provider:     stubProvider{name: oauth.ProviderGitHub, identity: oauth.Identity{Provider: oauth.ProviderGitHub, Subject: "1", Email: "a@example.com", EmailVerified: true}},
+			otherBrowser: true,
+			want:         ErrInvalidOAuthState,
+		},
+		{
+			name:     "provider error",
+			provider: stubProvider{name: oauth.ProviderGitHub, err: errors.New("bad_verification_code")},
+			want:     ErrOAuthFailed,
+		},
+		{
+			// GitHub leaves the email empty when the primary one is not
+			// verified.
+			name:     "no verified email",
+			provider: stubProvider{name: oauth.ProviderGitHub, identity: oauth.Identity{Provider: oauth.ProviderGitHub, Subject: "2"}},
+			want:     ErrOAuthEmailRequired,
+		},
+		{
+			name:     "unverified email",
+			provider: stubProvider{name: "okta", identity: oauth.Identity{Provider: "okta", Subject: "3", Email: "victim@example.com"}},
+			want:     ErrOAuthEmailUnverified,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newOAuthTestService(t, tt.provider)
+			flow, err := s.oauthState.Begin(tt.provider.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			binding := flow.Binding
+			if tt.otherBrowser {
+				other, err := s.oauthState.Begin(tt.provider.name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				binding = other.Binding
+			}
+
+			_, err = s.OAuthLogin(&framework.OAuthCallbackRequest{
+				Provider: tt.provider.name,
+				Code:     "code",
+				State:    flow.State,
+				Binding:  binding,
+			})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("OAuthLogin error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func newStandInGoogle(standIn *oauthtest.Google) *oauth.Google {
+	return oauth.NewGoogle(oauth.GoogleConfig{
+		ClientID:     standIn.ClientID,
+		ClientSecret: standIn.ClientSecret,
+		RedirectURL:  "https://app.example.com/auth/google/callback",
+		AuthURL:      standIn.AuthURL(),
+		TokenURL:     standIn.TokenURL(),
+		JWKSURL:      standIn.JWKSURL(),
+		Issuer:       standIn.URL,
+		HTTPClient:   standIn.Client(),
+	})
+}
+
+// googleCallback runs BeginOAuth and the consent step at the stand-in, and
+// returns the request the callback handler would pass to OAuthLogin.
+func googleCallback(t *testing.T, s Service, standIn *oauthtest.Google) framework.OAuthCallbackRequest {
+	t.Helper()
+	authURL, binding, err := s.BeginOAuth(oauth.ProviderGoogle)
+	if err != nil {
+		t.Fatalf("BeginOAuth: %v", err)
+	}
+	callback, err := standIn.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	return framework.OAuthCallbackRequest{
+		Provider: oauth.ProviderGoogle,
+		Code:     callback.Query().Get("code"),
+		State:    callback.Query().Get("state"),
+		Binding:  binding,
+	}
+}
+
+func TestOAuthLoginGoogleRefusals(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(*oauthtest.Google, *framework.OAuthCallbackRequest)
+		want    error
+	}{
+		{
+			name:    "bad state",
+			prepare: func(_ *oauthtest.Google, req *framework.OAuthCallbackRequest) { req.State += "x" },
+			want:    ErrInvalidOAuthState,
+		},
+		{
+			name:    "no binding cookie",
+			prepare: func(_ *oauthtest.Google, req *framework.OAuthCallbackRequest) { req.Binding = "" },
+			want:    ErrInvalidOAuthState,
+		},
+		{
+			name:    "forged id token",
+			prepare: func(g *oauthtest.Google, _ *framework.OAuthCallbackRequest) { g.ForgeSignature = true },
+			want:    ErrOAuthFailed,
+		},
+		{
+			name:    "unverified email",
+			prepare: func(g *oauthtest.Google, _ *framework.OAuthCallbackRequest) { g.EmailVerified = false },
+			want:    ErrOAuthEmailUnverified,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := oauthtest.NewGoogle(t)
+			s := newOAuthTestService(t, newStandInGoogle(standIn))
+			req := googleCallback(t, s, standIn)
+			tt.prepare(standIn, &req)
+
+			if _, err := s.OAuthLogin(&req); !errors.Is(err, tt.want) {
+				t.Errorf("OAuthLogin error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOAuthLoginGoogleFirstLogin(t *testing.T) {
+	store := dbtest.Store(t)
+	ctx := context.Background()
+
+	standIn := oauthtest.NewGoogle(t)
+	standIn.Subject = uuid.NewString()
+	standIn.Email = "google-" + standIn.Subject + "@example.com"
+	s := Service{Store: store}
+	WithOAuth(newTestSigner(t), newStandInGoogle(standIn))(&s)
+
+	req := googleCallback(t, s, standIn)
+	if _, err := s.OAuthLogin(&req); err != nil {
+		t.Fatalf("first OAuthLogin: %v", err)
+	}
+
+	user, err := store.GetUserByEmail(ctx, standIn.Email)
+	if err != nil {
+		t.Fatalf("no user was created: %v", err)
+	}
+	t.Cleanup(func() { _ = store.DeleteUser(context.Background(), user.ID) })
+	if !user.EmailVerified.Bool || user.Name.String != standIn.Name || user.RoleName != oauthDefaultRole {
+		t.Errorf("created user = %+v", user)
+	}
+	if user.HashPassword != "" {
+		t.Error("created user has a password")
+	}
+	account, err := store.GetAccountByProvider(ctx, db.GetAccountByProviderParams{
+		Provider:   oauth.ProviderGoogle,
+		ProviderID: standIn.Subject,
+	})
+	if err != nil {
+		t.Fatalf("no account was created: %v", err)
+	}
+	if account.UserID != user.ID {
+		t.Errorf("account belongs to %s, want %s", account.UserID, user.ID)
+	}
+
+	// The next login finds the account instead of creating another user.
+	standIn.Email = "changed-" + standIn.Email
+	req = googleCallback(t, s, standIn)
+	if _, err := s.OAuthLogin(&req); err != nil {
+		t.Fatalf("second OAuthLogin: %v", err)
+	}
+	if _, err := store.GetUserByEmail(ctx, standIn.Email); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("second login created a user for the changed email: %v", err)
+	}
+}
@@ -1 +1,152 @@
 package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/tokenstore"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/initialization"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// RequestPasswordReset emails a single-use reset link. It returns nil whether
+// or not the address belongs to a user. Storing the token and sending the
+// email both happen in the background, so a known address takes as long to
+// answer as an unknown one and the response does not reveal which addresses
+// are registered.
+//
+// The link is GOAUTH_PASSWORD_RESET_URL with ?token=... appended and is valid
+// for GOAUTH_PASSWORD_RESET_DURATION (default 1h). Requests are limited per
+// email and per IP address, see passwordResetPolicy, and refused past a
+// limit with a *ResetThrottledError.
+func (s Service) RequestPasswordReset(req *framework.PasswordResetRequest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.beginPasswordResetRequest(ctx, req.Email, req.IPAddress); err != nil {
+		return err
+	}
+	user, err := s.Store.GetUserByEmail(ctx, req.Email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Debug().Msg("password reset requested for unknown email")
+		return nil
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up user for password reset")
+		return err
+	}
+	if s.emailType == nil {
+		log.Error().Msg("password reset requested but no email service is configured")
+		return nil
+	}
+
+	go s.sendPasswordReset(user.ID, user.Email)
+	return nil
+}
+
+// sendPasswordReset replaces the user's reset tokens with a new one and emails
+// its link. It runs after RequestPasswordReset has answered, so failures are
+// only logged.
+func (s Service) sendPasswordReset(userID uuid.UUID, to string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	token, tokenHash, err := tokenstore.NewToken()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to generate password reset token")
+		return
+	}
+	expiry := initialization.GetEnvDuration("GOAUTH_PASSWORD_RESET_DURATION", time.Hour)
+
+	// Only the newest link works.
+	if err := s.resetTokens.DeleteUser(ctx, userID); err != nil {
+		log.Error().Err(err).Msg("failed to delete old password reset tokens")
+		return
+	}
+	if err := s.resetTokens.Create(ctx, userID, tokenHash, time.Now().Add(expiry)); err != nil {
+		log.Error().Err(err).Msg("failed to store password reset token")
+		return
+	}
+
+	link, err := tokenLink(initialization.GetEnv("GOAUTH_PASSWORD_RESET_URL", ""), token)
+	if err != nil {
+		log.Error().Err(err).Msg("GOAUTH_PASSWORD_RESET_URL is not a valid URL")
+		return
+	}
+	if err := s.emailType.SendPasswordResetEmail(ctx, to, link, expiry); err != nil {
+		log.Error().Err(err).Msg("failed to send password reset email")
+	}
+}
+
+// ConfirmPasswordReset redeems a reset token and sets the new password. Every
+// outstanding reset token, session and issued token of the user is revoked,
+// so whoever knew the old password is signed out.
+func (s Service) ConfirmPasswordReset(req *framework.PasswordResetConfirmRequest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The password is checked before the token is redeemed, so a refused
+	// one leaves the link usable for another try.
+	tokenHash := tokenstore.HashToken(req.Token)
+	record, err := s.resetTokens.Get(ctx, tokenHash)
+	if errors.Is(err, tokenstore.ErrTokenNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up password reset token")
+		return err
+	}
+	user, err := s.Store.GetUserByID(ctx, record.UserID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up user for password reset")
+		return err
+	}
+	if err := s.checkPasswordPolicy(req.NewPassword, user.Email, user.Name.String); err != nil {
+		return err
+	}
+	if err := s.checkPasswordReuse(ctx, record.UserID, user.HashPassword, req.NewPassword); err != nil {
+		return err
+	}
+
+	record, err = s.resetTokens.Consume(ctx, tokenHash)
+	if errors.Is(err, tokenstore.ErrTokenNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to redeem password reset token")
+		return err
+	}
+
+	hash, err := s.passwords.Hash(req.NewPassword)
+	if err != nil {
+		log.Err(err).Msg("failed to hash password")
+		return err
+	}
+	if err := s.setPassword(ctx, record.UserID, hash); err != nil {
+		log.Error().Err(err).Msg("failed to update password")
+		return err
+	}
+
+	if err := s.resetTokens.DeleteUser(ctx, record.UserID); err != nil {
+		log.Error().Err(err).Msg("failed to delete password reset tokens")
+		return err
+	}
+	return s.LogoutAll(record.UserID)
+}
+
+// tokenLink appends the token to base as a query parameter.
+func tokenLink(base, token string) (string, error) {
+	link, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/lockout"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/tokenstore"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRequestPasswordResetThrottle(t *testing.T) {
+	t.Setenv("GOAUTH_PASSWORD_RESET_LIMIT", "2")
+	t.Setenv("GOAUTH_PASSWORD_RESET_IP_LIMIT", "3")
+	t.Setenv("GOAUTH_PASSWORD_RESET_WINDOW", "1h")
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	// No address is registered; unknown ones are limited all the same.
+	s := Service{Store: &db.Store{Queries: db.New(emptyDB{t})}, lockoutPolicy: lockout.DefaultPolicy()}
+	WithLockoutStore(lockout.NewRedisStore(client), nil)(&s)
+
+	request := func(email, ipAddress string) error {
+		t.Helper()
+		return s.RequestPasswordReset(&framework.PasswordResetRequest{Email: email, IPAddress: ipAddress})
+	}
+	throttled := func(err error) bool {
+		var throttled *ResetThrottledError
+		return errors.As(err, &throttled) && throttled.RetryAfter > 59*time.Minute && errors.Is(err, ErrResetThrottled)
+	}
+
+	for range 2 {
+		if err := request("a@example.com", "192.0.2.1"); err != nil {
+			t.Fatalf("request within the email limit: %v", err)
+		}
+	}
+	if err := request("A@example.com", "192.0.2.9"); !throttled(err) {
+		t.Errorf("third request for the email: %v, want a ResetThrottledError for the window", err)
+	}
+	if err := request("b@example.com", "192.0.2.1"); err != nil {
+		t.Errorf("another email from the same address: %v", err)
+	}
+	if err := request("c@example.com", "192.0.2.1"); !throttled(err) {
+		t.Errorf("fourth request from the address: %v, want a ResetThrottledError for the window", err)
+	}
+	if err := request("c@example.com", "192.0.2.2"); err != nil {
+		t.Errorf("the refused email from another address: %v", err)
+	}
+
+	// Asking for resets does not count as failed logins.
+	ctx := context.Background()
+	for _, key := range []string{lockout.IPKey("192.0.2.1"), lockout.AccountKey("a@example.com")} {
+		if state, err := s.lockouts.Get(ctx, key); err != nil || state.Failures != 0 {
+			t.Errorf("login state of %s = %+v, %v, want none", key, state, err)
+		}
+	}
+
+	t.Setenv("GOAUTH_PASSWORD_RESET_LIMIT", "0")
+	t.Setenv("GOAUTH_PASSWORD_RESET_IP_LIMIT", "0")
+	if err := request("a@example.com", "192.0.2.1"); err != nil {
+		t.Errorf("with the limits off: %v", err)
+	}
+}
+
+// newStoredToken stores a one-time token for userID in tokens and returns it
+// as the emailed link would carry it.
+func newStoredToken(t *testing.T, tokens tokenstore.Store, userID uuid.UUID, expiresAt time.Time) string {
+	t.Helper()
+	token, tokenHash, err := tokenstore.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.Create(context.Background(), userID, tokenHash, expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestConfirmPasswordReset(t *testing.T) {
+	s := newTokenTestService(t)
+	s.cfg.PasswordHistory = 1
+	WithPasswordResetStore(tokenstore.NewPostgresStore(s.Store, tokenstore.PasswordReset))(&s)
+	ctx := context.Background()
+	hash, err := s.passwords.Hash("forgotten password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := dbtest.NewUser(t, s.Store, db.GoAuthRegisterParams{HashPassword: hash})
+
+	signedIn, err := s.completeLogin(ctx, user.ID, user.RoleName, "old device", "192.0.2.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := newStoredToken(t, s.resetTokens, user.ID, time.Now().Add(time.Hour))
+	// An older link, still unexpired, that the reset must also retire.
+	older := newStoredToken(t, s.resetTokens, user.ID, time.Now().Add(time.Hour))
+	expired := newStoredToken(t, s.resetTokens, user.ID, time.Now().Add(-time.Minute))
+	confirm := func(token, password string) error {
+		t.Helper()
+		return s.ConfirmPasswordReset(&framework.PasswordResetConfirmRequest{Token: token, NewPassword: password})
+	}
+
+	// Refused passwords leave the link usable for another try.
+	var policyErr *utils.PasswordPolicyError
+	if err := confirm(token, "short"); !errors.As(err, &policyErr) {
+		t.Errorf("a password the policy refuses: %v, want a PasswordPolicyError", err)
+	}
+	if err := confirm(token, "forgotten password"); !errors.Is(err, ErrPasswordReused) {
+		t.Errorf("the current password: %v, want ErrPasswordReused", err)
+	}
+	if err := confirm(expired, "a brand new password"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("an expired link: %v, want ErrInvalidResetToken", err)
+	}
+	if err := confirm("not a token", "a brand new password"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("an unknown link: %v, want ErrInvalidResetToken", err)
+	}
+
+	waitForNextSecond()
+	if err := confirm(token, "a brand new password"); err != nil {
+		t.Fatalf("ConfirmPasswordReset: %v", err)
+	}
+	stored, err := s.Store.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.passwords.Verify("a brand new password", stored.HashPassword); !ok {
+		t.Error("the new password does not match the stored hash")
+	}
+
+	// Every link of the user is spent.
+	for name, link := range map[string]string{"the used link": token, "an older link": older} {
+		if err := confirm(link, "yet another password"); !errors.Is(err, ErrInvalidResetToken) {
+			t.Errorf("%s after the reset: %v, want ErrInvalidResetToken", name, err)
+		}
+	}
+
+	// Whoever was signed in is signed out.
+	if !accessTokenRevoked(t, s, signedIn.AccessToken) {
+		t.Error("an access token from before the reset is still accepted")
+	}
+	if _, err := s.Refresh(signedIn.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("a refresh token from before the reset: %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, _, err := s.sessions.Resolve(ctx, signedIn.SessionToken); err == nil {
+		t.Error("a session from before the reset survived")
+	}
+
+	// The replaced password went into the history.
+	if err := confirm(newStoredToken(t, s.resetTokens, user.ID, time.Now().Add(time.Hour)), "forgotten password"); !errors.Is(err, ErrPasswordReused) {
+		t.Errorf("the password before the reset: %v, want ErrPasswordReused", err)
+	}
+}
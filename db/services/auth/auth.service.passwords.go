@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// verifyPassword checks password against the user's stored hash. A match on
+// a hash the configured hasher would no longer produce, from an older
+// algorithm or cost, is upgraded while the plaintext is at hand.
+func (s Service) verifyPassword(ctx context.Context, userID uuid.UUID, encoded, password string) bool {
+	ok, err := s.passwords.Verify(password, encoded)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("failed to verify password")
+		return false
+	}
+	if ok && s.passwords.NeedsRehash(encoded) {
+		s.rehashPassword(ctx, userID, encoded, password)
+	}
+	return ok
+}
+
+// rehashPassword replaces the user's hash, unless the password changed since
+// it was checked. Failing only postpones the upgrade to the next login.
+func (s Service) rehashPassword(ctx context.Context, userID uuid.UUID, encoded, password string) {
+	hash, err := s.passwords.Hash(password)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to hash password")
+		return
+	}
+	err = s.Store.WithTx(ctx, func(q *db.Queries) error {
+		if _, err := q.LockUser(ctx, userID); err != nil {
+			return err
+		}
+		user, err := q.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.HashPassword != encoded {
+			return nil
+		}
+		return q.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+			HashPassword: hash,
+			ID:           userID,
+		})
+	})
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("failed to upgrade password hash")
+	}
+}
+
+// checkPasswordPolicy returns a *utils.PasswordPolicyError for a password the
+// policy refuses. userInputs are the user's email and name.
+func (s Service) checkPasswordPolicy(password string, userInputs ...string) error {
+	err := s.policy.Check(password, userInputs...)
+	var policyErr *utils.PasswordPolicyError
+	if err != nil && !errors.As(err, &policyErr) {
+		log.Error().Err(err).Msg("failed to check breached passwords")
+	}
+	return err
+}
+
+// checkPasswordReuse refuses the user's current password and, with
+// Config.PasswordHistory, the ones before it.
+func (s Service) checkPasswordReuse(ctx context.Context, userID uuid.UUID, currentHash, password string) error {
+	hashes := []string{currentHash}
+	if s.cfg.PasswordHistory > 0 {
+		history, err := s.Store.ListPasswordHistory(ctx, db.ListPasswordHistoryParams{
+			UserID:     userID,
+			MaxEntries: int32(s.cfg.PasswordHistory),
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to load password history")
+			return err
+		}
+		hashes = append(hashes, history...)
+	}
+	for _, hash := range hashes {
+		// A hash in a format no longer understood cannot be compared, and
+		// so does not block the password.
+		if reused, _ := s.passwords.Verify(password, hash); reused {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// setPassword stores hash as the user's password. The hash it replaces goes
+// into the history, which is trimmed to Config.PasswordHistory entries.
+func (s Service) setPassword(ctx context.Context, userID uuid.UUID, hash string) error {
+	return s.Store.WithTx(ctx, func(q *db.Queries) error {
+		if _, err := q.LockUser(ctx, userID); err != nil {
+			return err
+		}
+		user, err := q.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := q.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+			HashPassword: hash,
+			ID:           userID,
+		}); err != nil {
+			return err
+		}
+		if s.cfg.PasswordHistory <= 0 || user.HashPassword == "" {
+			return nil
+		}
+		if err := q.CreatePasswordHistory(ctx, db.CreatePasswordHistoryParams{
+			UserID:       userID,
+			HashPassword: user.HashPassword,
+		}); err != nil {
+			return err
+		}
+		return q.PrunePasswordHistory(ctx, db.PrunePasswordHistoryParams{
+			UserID: userID,
+			Keep:   int32(s.cfg.PasswordHistory),
+		})
+	})
+}
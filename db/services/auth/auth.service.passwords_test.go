@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/google/uuid"
+)
+
+func TestVerifyPasswordUnknownHash(t *testing.T) {
+	// Neither a mismatch nor an unreadable hash reaches the database.
+	s := Service{Store: &db.Store{Queries: db.New(emptyDB{t})}, passwords: utils.NewBcryptHasher(4)}
+	hash, err := utils.NewBcryptHasher(4).Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, encoded := range map[string]string{"wrong password": hash, "unknown format": "md5$abc", "no password": ""} {
+		if s.verifyPassword(context.Background(), uuid.New(), encoded, "Password") {
+			t.Errorf("%s: verifyPassword = true", name)
+		}
+	}
+}
+
+func TestVerifyPasswordRehashes(t *testing.T) {
+	store := dbtest.Store(t)
+	ctx := context.Background()
+	// Logins with the old bcrypt hashes move to argon2id.
+	s := Service{Store: store, passwords: utils.NewArgon2idHasher(utils.Argon2idParams{Memory: 8 * 1024, Time: 1, Parallelism: 1})}
+	legacy, err := utils.NewBcryptHasher(4).Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := dbtest.NewUser(t, store, db.GoAuthRegisterParams{HashPassword: legacy})
+	storedHash := func() string {
+		t.Helper()
+		stored, err := store.GetUserByID(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored.HashPassword
+	}
+
+	if s.verifyPassword(ctx, user.ID, legacy, "wrong password") {
+		t.Fatal("verifyPassword accepted the wrong password")
+	}
+	if storedHash() != legacy {
+		t.Fatal("a failed login replaced the hash")
+	}
+
+	if !s.verifyPassword(ctx, user.ID, legacy, "password") {
+		t.Fatal("verifyPassword refused the password")
+	}
+	upgraded := storedHash()
+	if !strings.HasPrefix(upgraded, "$argon2id$") || s.passwords.NeedsRehash(upgraded) {
+		t.Fatalf("hash after login = %s, want the configured argon2id", upgraded)
+	}
+	if ok, err := s.passwords.Verify("password", upgraded); !ok || err != nil {
+		t.Fatalf("the upgraded hash does not verify: %v, %v", ok, err)
+	}
+
+	// A current hash is left alone.
+	if !s.verifyPassword(ctx, user.ID, upgraded, "password") || storedHash() != upgraded {
+		t.Error("a current hash was rehashed")
+	}
+
+	// The password changed between the check and the upgrade: the upgrade
+	// of the old hash must not undo the change.
+	s.rehashPassword(ctx, user.ID, legacy, "password")
+	if storedHash() != upgraded {
+		t.Error("rehashPassword overwrote a hash it had not checked")
+	}
+}
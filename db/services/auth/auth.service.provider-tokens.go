@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
+)
+
+// providerTokenExpiryDelta refreshes tokens slightly early so the caller does
+// not receive one that expires on its way to the provider.
+const providerTokenExpiryDelta = time.Minute
+
+// GetProviderToken returns the user's token for provider, for calling the
+// provider's APIs on their behalf. An expired token is refreshed and stored
+// first; a token that cannot be refreshed needs the user to sign in with the
+// provider again.
+func (s Service) GetProviderToken(userId uuid.UUID, provider string) (framework.ProviderToken, error) {
+	if s.tokenCipher == nil {
+		return framework.ProviderToken{}, ErrProviderTokenDisabled
+	}
+	client, ok := s.oauthProviders[provider]
+	if !ok {
+		return framework.ProviderToken{}, ErrOAuthProviderUnknown
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var token *oauth2.Token
+	// The row stays locked through a refresh, so concurrent callers wait for
+	// it instead of spending a refresh token the provider may only accept
+	// once.
+	err := s.Store.WithTx(ctx, func(q *db.Queries) error {
+		row, err := q.GetAccountTokenForUpdate(ctx, db.GetAccountTokenForUpdateParams{
+			UserID:   userId,
+			Provider: provider,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrProviderTokenNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if token, err = s.openProviderToken(row); err != nil {
+			return err
+		}
+		if token.Expiry.IsZero() || time.Until(token.Expiry) > providerTokenExpiryDelta {
+			return nil
+		}
+
+		if token.RefreshToken == "" {
+			return ErrProviderTokenExpired
+		}
+		refreshed, err := client.Refresh(ctx, token)
+		if err != nil {
+			log.Warn().Err(err).Str("provider", provider).Msg("failed to refresh provider token")
+			return ErrProviderTokenExpired
+		}
+		// Providers may leave the scope out when it has not changed.
+		if scope, _ := refreshed.Extra("scope").(string); scope == "" {
+			refreshed = refreshed.WithExtra(map[string]interface{}{"scope": row.Scopes})
+		}
+		token = refreshed
+		return s.saveProviderToken(ctx, q, row.AccountID, token)
+	})
+	if errors.Is(err, ErrProviderTokenNotFound) || errors.Is(err, ErrProviderTokenExpired) {
+		return framework.ProviderToken{}, err
+	}
+	if err != nil {
+		log.Error().Err(err).Str("provider", provider).Msg("failed to load provider token")
+		return framework.ProviderToken{}, err
+	}
+
+	providerToken := framework.ProviderToken{
+		AccessToken: token.AccessToken,
+		TokenType:   token.Type(),
+		Scopes:      tokenScopes(token),
+	}
+	if !token.Expiry.IsZero() {
+		providerToken.ExpiresAt = &token.Expiry
+	}
+	return providerToken, nil
+}
+
+// saveProviderToken encrypts token and stores it for accountID. Without a
+// token cipher provider tokens are not kept at all.
+func (s Service) saveProviderToken(ctx context.Context, q *db.Queries, accountID uuid.UUID, token *oauth2.Token) error {
+	if s.tokenCipher == nil || token == nil || token.AccessToken == "" {
+		return nil
+	}
+	accessToken, err := s.tokenCipher.Seal(token.AccessToken, accountID[:])
+	if err != nil {
+		return err
+	}
+	var refreshToken []byte
+	if token.RefreshToken != "" {
+		if refreshToken, err = s.tokenCipher.Seal(token.RefreshToken, accountID[:]); err != nil {
+			return err
+		}
+	}
+	return q.UpsertAccountToken(ctx, db.UpsertAccountTokenParams{
+		AccountID:    accountID,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    token.TokenType,
+		Scopes:       strings.Join(tokenScopes(token), " "),
+		ExpiresAt:    pgtype.Timestamptz{Time: token.Expiry, Valid: !token.Expiry.IsZero()},
+	})
+}
+
+func (s Service) openProviderToken(row db.GoauthAccountToken) (*oauth2.Token, error) {
+	accessToken, err := s.tokenCipher.Open(row.AccessToken, row.AccountID[:])
+	if err != nil {
+		return nil, err
+	}
+	token := &oauth2.Token{
+		AccessToken: accessToken,
+		TokenType:   row.TokenType,
+		Expiry:      row.ExpiresAt.Time,
+	}
+	if row.RefreshToken != nil {
+		if token.RefreshToken, err = s.tokenCipher.Open(row.RefreshToken, row.AccountID[:]); err != nil {
+			return nil, err
+		}
+	}
+	return token.WithExtra(map[string]interface{}{"scope": row.Scopes}), nil
+}
+
+// tokenScopes reads the granted scopes, which providers separate with spaces
+// or, like GitHub, commas.
+func tokenScopes(token *oauth2.Token) []string {
+	scope, _ := token.Extra("scope").(string)
+	return strings.FieldsFunc(scope, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+}
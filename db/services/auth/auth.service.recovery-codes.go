@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/tokenstore"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// recoveryCodeCount codes of recoveryCodeLength characters (80 bits each)
+// are issued per set, shown as groups of four: abcd-efgh-ijkl-mnop.
+const (
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 16
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// RegenerateRecoveryCodes replaces the user's recovery codes with a new set,
+// invalidating every old one, used or not. Like DisableTOTP it requires a
+// current TOTP code.
+func (s Service) RegenerateRecoveryCodes(userId uuid.UUID, code string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := s.Store.GetUserByID(ctx, userId)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up user to regenerate recovery codes")
+		return nil, err
+	}
+	if !user.TwoFactorEnabled.Bool {
+		return nil, ErrTOTPNotEnrolled
+	}
+	if err := s.verifyTOTP(ctx, user, code, ""); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, userId)
+}
+
+// replaceRecoveryCodes swaps the user's stored recovery codes for a fresh set
+// in one transaction and returns the new codes in plain text.
+func (s Service) replaceRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, recoveryCodeLength*5/8)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		encoded := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))
+		codes[i] = encoded[0:4] + "-" + encoded[4:8] + "-" + encoded[8:12] + "-" + encoded[12:16]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	err := s.Store.WithTx(ctx, func(q *db.Queries) error {
+		if err := q.DeleteUserRecoveryCodes(ctx, userID); err != nil {
+			return err
+		}
+		return q.CreateRecoveryCodes(ctx, db.CreateRecoveryCodesParams{
+			UserID:     userID,
+			CodeHashes: hashes,
+		})
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to store recovery codes")
+		return nil, err
+	}
+	return codes, nil
+}
+
+// useRecoveryCode redeems one of the user's recovery codes. Each use is
+// audited and the user is emailed, since it means their authenticator was
+// bypassed.
+func (s Service) useRecoveryCode(ctx context.Context, userID uuid.UUID, email, code, ipAddress string) error {
+	used, err := s.Store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: hashRecoveryCode(code),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to redeem recovery code")
+		return err
+	}
+	if used == 0 {
+		return ErrInvalidTOTPCode
+	}
+
+	remaining, err := s.Store.CountUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to count recovery codes")
+	}
+	s.audit(ctx, s.Store.Queries, AuditRecoveryCodeUsed, map[string]interface{}{
+		"user_id":    userID.String(),
+		"ip_address": ipAddress,
+		"remaining":  remaining,
+	})
+
+	if s.emailType == nil {
+		log.Error().Msg("recovery code used but no email service is configured")
+		return nil
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.emailType.SendRecoveryCodeUsedEmail(ctx, email, int(remaining)); err != nil {
+			log.Error().Err(err).Msg("failed to send recovery code notification")
+		}
+	}()
+	return nil
+}
+
+// hashRecoveryCode hashes a code after dropping the separators and case a
+// user may or may not type.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return tokenstore.HashToken(normalized)
+}
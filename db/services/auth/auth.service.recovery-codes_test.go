@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+)
+
+func TestHashRecoveryCode(t *testing.T) {
+	want := hashRecoveryCode("abcd-efgh-ijkl-mnop")
+	// However the user types it.
+	for _, typed := range []string{"ABCD-EFGH-IJKL-MNOP", "abcdefghijklmnop", "abcd efgh ijkl mnop", " abcd-efgh-ijkl-mnop "} {
+		if got := hashRecoveryCode(typed); got != want {
+			t.Errorf("hashRecoveryCode(%q) differs from the displayed code's", typed)
+		}
+	}
+	if hashRecoveryCode("abcd-efgh-ijkl-mnoq") == want {
+		t.Error("different codes hash the same")
+	}
+}
+
+var recoveryCodeFormat = regexp.MustCompile(`^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`)
+
+func TestRecoveryCodes(t *testing.T) {
+	// Each TOTP code below needs a step nobody redeemed yet.
+	t.Setenv("GOAUTH_TOTP_SKEW", "2")
+	s := newMFATestService(t)
+	ctx := context.Background()
+	user := dbtest.NewUser(t, s.Store, db.GoAuthRegisterParams{})
+
+	enrollment, err := s.EnrollTOTP(user.ID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP: %v", err)
+	}
+	if err := s.ConfirmTOTP(user.ID, totpCode(t, enrollment.Secret, currentTOTPStep(t))); err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+	codes := enrollment.RecoveryCodes
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("%d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if !recoveryCodeFormat.MatchString(code) || seen[code] {
+			t.Errorf("recovery code %q is malformed or repeated", code)
+		}
+		seen[code] = true
+	}
+
+	use := func(code string) error {
+		t.Helper()
+		return s.useRecoveryCode(ctx, user.ID, user.Email, code, "192.0.2.1")
+	}
+	remaining := func() int64 {
+		t.Helper()
+		count, err := s.Store.CountUnusedRecoveryCodes(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	// Each code works once, however it is typed.
+	if err := use(strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := use(codes[0]); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Errorf("second use: %v, want ErrInvalidTOTPCode", err)
+	}
+	if err := use("aaaa-aaaa-aaaa-aaaa"); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Errorf("a made-up code: %v, want ErrInvalidTOTPCode", err)
+	}
+	if got := remaining(); got != recoveryCodeCount-1 {
+		t.Errorf("%d unused codes, want %d", got, recoveryCodeCount-1)
+	}
+
+	// Codes belong to their user.
+	other := dbtest.User(t, s.Store)
+	if err := s.useRecoveryCode(ctx, other, "other@example.com", codes[1], "192.0.2.1"); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Errorf("another user's code: %v, want ErrInvalidTOTPCode", err)
+	}
+	if _, err := s.RegenerateRecoveryCodes(other, totpCode(t, enrollment.Secret, currentTOTPStep(t))); !errors.Is(err, ErrTOTPNotEnrolled) {
+		t.Errorf("regenerating without two-factor: %v, want ErrTOTPNotEnrolled", err)
+	}
+
+	// Regenerating takes a current TOTP code, and a wrong one keeps the
+	// old set.
+	if _, err := s.RegenerateRecoveryCodes(user.ID, wrongTOTPCode(t, enrollment.Secret)); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("regenerating with a wrong code: %v, want ErrInvalidTOTPCode", err)
+	}
+	if got := remaining(); got != recoveryCodeCount-1 {
+		t.Errorf("%d unused codes after a refused regeneration, want %d", got, recoveryCodeCount-1)
+	}
+	fresh, err := s.RegenerateRecoveryCodes(user.ID, totpCode(t, enrollment.Secret, currentTOTPStep(t)+1))
+	if err != nil {
+		t.Fatalf("RegenerateRecoveryCodes: %v", err)
+	}
+	if len(fresh) != recoveryCodeCount || remaining() != recoveryCodeCount {
+		t.Errorf("regenerated %d codes with %d unused, want %d", len(fresh), remaining(), recoveryCodeCount)
+	}
+	for _, code := range fresh {
+		if seen[code] {
+			t.Errorf("regenerated code %q repeats an old one", code)
+		}
+	}
+	// The old set is gone, used or not.
+	for _, code := range codes[:2] {
+		if err := use(code); !errors.Is(err, ErrInvalidTOTPCode) {
+			t.Errorf("old code after regenerating: %v, want ErrInvalidTOTPCode", err)
+		}
+	}
+	if err := use(fresh[0]); err != nil {
+		t.Errorf("new code: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+// Refresh exchanges a refresh token for a new access/refresh pair and retires
+// the presented token. Presenting a token that was already rotated out means
+// it was copied, so the whole family is revoked and ErrRefreshTokenReused is
+// returned. A token revoked any other way is simply rejected.
+func (s Service) Refresh(refreshToken string) (framework.AuthResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	claims, err := utils.ValidateClaims(refreshToken, s.tokenType())
+	if err != nil {
+		log.Error().Err(err).Msg("refresh token validation failed")
+		return framework.AuthResponse{}, ErrInvalidRefreshToken
+	}
+	if tokenType, _ := claims[utils.Type].(string); tokenType != string(utils.JWT_REFRESH_TOKEN) {
+		return framework.AuthResponse{}, ErrInvalidRefreshToken
+	}
+	tokenID, err := claimUUID(claims, utils.Jti)
+	if err != nil {
+		return framework.AuthResponse{}, ErrInvalidRefreshToken
+	}
+	userID, err := claimUUID(claims, utils.UserId)
+	if err != nil {
+		return framework.AuthResponse{}, ErrInvalidRefreshToken
+	}
+
+	var (
+		response framework.AuthResponse
+		reused   bool
+	)
+	err = s.Store.WithTx(ctx, func(q *db.Queries) error {
+		record, err := q.GetRefreshTokenForUpdate(ctx, tokenID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+		if record.UserID != userID {
+			return ErrInvalidRefreshToken
+		}
+
+		if record.RevokedAt.Valid && !record.ReplacedBy.Valid {
+			return ErrInvalidRefreshToken
+		}
+		if record.RevokedAt.Valid {
+			reused = true
+			if err := q.RevokeRefreshTokenFamily(ctx, record.FamilyID); err != nil {
+				return err
+			}
+			s.audit(ctx, q, AuditRefreshTokenReuse, map[string]interface{}{
+				"user_id":   record.UserID,
+				"family_id": record.FamilyID,
+				"token_id":  record.ID,
+			})
+			return nil
+		}
+
+		user, err := q.GetUserByID(ctx, record.UserID)
+		if err != nil {
+			return err
+		}
+		token, err := s.issueTokens(ctx, q, user.ID, user.RoleName, record.FamilyID)
+		if err != nil {
+			return err
+		}
+		newID, err := uuid.Parse(token.RefreshTokenID)
+		if err != nil {
+			return err
+		}
+		if err := q.RotateRefreshToken(ctx, db.RotateRefreshTokenParams{
+			ID:         record.ID,
+			ReplacedBy: pgtype.UUID{Bytes: newID, Valid: true},
+		}); err != nil {
+			return err
+		}
+
+		response = framework.AuthResponse{
+			AccessToken:  token.AccessToken,
+			RefreshToken: token.RefreshToken,
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrInvalidRefreshToken) {
+			log.Error().Err(err).Msg("failed to rotate refresh token")
+		}
+		return framework.AuthResponse{}, err
+	}
+	if reused {
+		log.Warn().Str("user_id", userID.String()).Msg("refresh token reuse detected, token family revoked")
+		return framework.AuthResponse{}, ErrRefreshTokenReused
+	}
+
+	return response, nil
+}
+
+func claimUUID(claims map[string]interface{}, key string) (uuid.UUID, error) {
+	value, _ := claims[key].(string)
+	return uuid.Parse(value)
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	goauth "github.com/SwanHtetAungPhyo/go-auth"
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/revocation"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/session"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// testSecret signs the JWTs of the tests that issue tokens.
+var testSecret = []byte("test-secret")
+
+// useTestKeys signs the test's JWTs with testSecret.
+func useTestKeys(t *testing.T) {
+	t.Helper()
+	provider, err := utils.NewHMACKeyProvider("HS256", testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utils.SetKeyProvider(provider)
+	t.Cleanup(func() { utils.SetKeyProvider(nil) })
+}
+
+// newTokenTestService returns a Service over the test database that issues
+// JWTs and server sessions, and revokes access tokens in Postgres.
+func newTokenTestService(t *testing.T) Service {
+	t.Helper()
+	useTestKeys(t)
+	store := dbtest.Store(t)
+	s := Service{
+		Store:     store,
+		cfg:       goauth.Config{JwtAuth: true, Session: true},
+		passwords: utils.NewBcryptHasher(4),
+		policy:    utils.DefaultPasswordPolicy(),
+	}
+	WithRevocationStore(revocation.NewPostgresStore(store))(&s)
+	WithSessionManager(session.NewManager(session.NewPostgresStore(store), time.Hour))(&s)
+	return s
+}
+
+// waitForNextSecond returns once the clock has moved into a new second, so
+// tokens issued before a revocation cutoff are older than it: iat has second
+// precision, and a token from the cutoff's own second is not revoked.
+func waitForNextSecond() {
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+}
+
+// accessTokenRevoked reports whether the access token would be refused by
+// the middleware.
+func accessTokenRevoked(t *testing.T, s Service, token string) bool {
+	t.Helper()
+	claims, err := utils.ValidateClaims(token, utils.JWT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jti, _ := claims[utils.Jti].(string)
+	userID, _ := claims[utils.UserId].(string)
+	issuedAt, _ := utils.ClaimTime(claims, utils.IssuedAt)
+	revoked, err := revocation.IsTokenRevoked(context.Background(), s.revocations, jti, userID, issuedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return revoked
+}
+
+// tokenID returns the jti of a token the test issued.
+func tokenID(t *testing.T, token string) uuid.UUID {
+	t.Helper()
+	claims, err := utils.ValidateClaims(token, utils.JWT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := claimUUID(claims, utils.Jti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestRefreshRejectsInvalidTokens(t *testing.T) {
+	useTestKeys(t)
+	// None of these reach the database.
+	s := Service{Store: &db.Store{Queries: db.New(emptyDB{t})}, cfg: goauth.Config{JwtAuth: true}}
+	tokens, err := s.generateToken(uuid.NewString(), "USER", uuid.NewString(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := utils.NewHMACKeyProvider("HS256", []byte("other-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	utils.SetKeyProvider(other)
+	forged, err := s.generateToken(uuid.NewString(), "USER", uuid.NewString(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useTestKeys(t)
+
+	for name, token := range map[string]string{
+		"not a token":        "nope",
+		"access token":       tokens.AccessToken,
+		"signed by another":  forged.RefreshToken,
+		"no user or jti":     mustSign(t, jwt.MapClaims{utils.Type: string(utils.JWT_REFRESH_TOKEN)}),
+		"jti is not a uuid":  mustSign(t, jwt.MapClaims{utils.Type: string(utils.JWT_REFRESH_TOKEN), utils.Jti: "1", utils.UserId: uuid.NewString()}),
+		"user is not a uuid": mustSign(t, jwt.MapClaims{utils.Type: string(utils.JWT_REFRESH_TOKEN), utils.Jti: uuid.NewString(), utils.UserId: "1"}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.Refresh(token); !errors.Is(err, ErrInvalidRefreshToken) {
+				t.Errorf("Refresh = %v, want ErrInvalidRefreshToken", err)
+			}
+		})
+	}
+}
+
+// mustSign signs exactly claims with testSecret, where generateToken would
+// fill in what the test leaves out.
+func mustSign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	s := newTokenTestService(t)
+	ctx := context.Background()
+	userID := dbtest.User(t, s.Store)
+
+	issue := func() string {
+		t.Helper()
+		tokens, err := s.issueTokens(ctx, s.Store.Queries, userID, "USER", uuid.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tokens.RefreshToken
+	}
+	refresh := func(token string) string {
+		t.Helper()
+		response, err := s.Refresh(token)
+		if err != nil {
+			t.Fatalf("Refresh: %v", err)
+		}
+		if response.AccessToken == "" || response.RefreshToken == "" || response.RefreshToken == token {
+			t.Fatalf("Refresh = %+v", response)
+		}
+		return response.RefreshToken
+	}
+
+	first := issue()
+	second := refresh(first)
+	third := refresh(second)
+	family := []string{first, second, third}
+	// Another login of the same user, which reuse must leave alone.
+	elsewhere := issue()
+
+	records := make([]db.GoauthRefreshToken, len(family))
+	for i, token := range family {
+		record, err := s.Store.GetRefreshTokenForUpdate(ctx, tokenID(t, token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		records[i] = record
+	}
+	for i, record := range records[:2] {
+		if !record.RevokedAt.Valid || record.ReplacedBy.Bytes != records[i+1].ID {
+			t.Errorf("token %d = %+v, want revoked and replaced by token %d", i, record, i+1)
+		}
+	}
+	if records[2].RevokedAt.Valid || records[2].FamilyID != records[0].FamilyID {
+		t.Errorf("latest token = %+v, want live and in the first token's family", records[2])
+	}
+
+	// The first token turns up again: someone kept a copy.
+	if _, err := s.Refresh(first); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replaying a rotated token: %v, want ErrRefreshTokenReused", err)
+	}
+	for i, token := range family {
+		record, err := s.Store.GetRefreshTokenForUpdate(ctx, tokenID(t, token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !record.RevokedAt.Valid {
+			t.Errorf("token %d of the family survived the reuse", i)
+		}
+	}
+	// The holder of the latest token is signed out too, and replaying
+	// again is still reuse.
+	if _, err := s.Refresh(third); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("latest token after reuse: %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := s.Refresh(second); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("replaying another rotated token: %v, want ErrRefreshTokenReused", err)
+	}
+
+	refresh(elsewhere)
+}
+
+func TestRefreshRejectsAnotherUsersRecord(t *testing.T) {
+	s := newTokenTestService(t)
+	ctx := context.Background()
+	owner := dbtest.User(t, s.Store)
+
+	tokens, err := s.issueTokens(ctx, s.Store.Queries, owner, "USER", uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Same jti, another user: the record decides, not the claims.
+	forged := mustSign(t, jwt.MapClaims{
+		utils.Type:   string(utils.JWT_REFRESH_TOKEN),
+		utils.Jti:    tokenID(t, tokens.RefreshToken).String(),
+		utils.UserId: dbtest.User(t, s.Store).String(),
+		utils.Exp:    time.Now().Add(time.Hour).Unix(),
+	})
+	if _, err := s.Refresh(forged); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Refresh = %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := s.Refresh(tokens.RefreshToken); err != nil {
+		t.Errorf("the owner's refresh after the forgery: %v", err)
+	}
+}
@@ -2,21 +2,23 @@ package auth
 
 import (
 	"context"
-	"sync"
 	"time"
 
 	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
 	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/rs/zerolog/log"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func (s Service) Register(req *framework.RegisterRequest) (framework.AuthResponse, error) {
 	databaseCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err := s.checkPasswordPolicy(req.Password, req.Email, req.Name); err != nil {
+		return framework.AuthResponse{}, err
+	}
+	hash, err := s.passwords.Hash(req.Password)
 	if err != nil {
 		log.Err(err).Msg("failed to hash password")
 		return framework.AuthResponse{}, err
@@ -24,7 +26,7 @@ func (s Service) Register(req *framework.RegisterRequest) (framework.AuthRespons
 
 	user, err := s.Store.GoAuthRegister(databaseCtx, db.GoAuthRegisterParams{
 		Email:        req.Email,
-		HashPassword: string(hash),
+		HashPassword: hash,
 		RoleName:     req.RoleName,
 		Name:         pgtype.Text{String: req.Name},
 	})
@@ -33,26 +35,16 @@ func (s Service) Register(req *framework.RegisterRequest) (framework.AuthRespons
 		return framework.AuthResponse{}, err
 	}
 
-	if s.cfg.IsProduction {
-		var wg sync.WaitGroup
-		wg.Go(func() {
-			//s.emailType.SendNotificationEmail()
-
-		})
-	} else {
-		err := s.Store.UpdateUserEmailVerified(databaseCtx, db.UpdateUserEmailVerifiedParams{
-			ID:            user.ID,
-			EmailVerified: pgtype.Bool{Bool: true},
-		})
-		if err != nil {
-			log.Err(err).Str("GOAUTH", "register_service").Msg("failed to update user email verified")
-			return framework.AuthResponse{}, err
-		}
+	// A failed send is not fatal: the account exists and the user can ask
+	// for another link through ResendVerification.
+	if err := s.sendVerification(databaseCtx, user.ID, user.Email); err != nil {
+		log.Err(err).Str("GOAUTH", "register_service").Msg("failed to issue email verification")
 	}
-	if s.cfg.TokenMetaData != nil {
-
+	if s.cfg.RequireEmailVerification {
+		// No tokens until the address is verified, same as Login.
+		return framework.AuthResponse{}, nil
 	}
-	token, err := s.generateToken(user.ID.String(), req.RoleName, nil)
+	token, err := s.issueTokens(databaseCtx, s.Store.Queries, user.ID, user.RoleName, uuid.New())
 	if err != nil {
 		log.Err(err).Msg("failed to generate token")
 		return framework.AuthResponse{}, err
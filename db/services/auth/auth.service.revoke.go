@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+// RevokeToken blocks a single access token until it would have expired anyway.
+func (s Service) RevokeToken(jti string, expiresAt time.Time) error {
+	if s.revocations == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.revocations.Revoke(ctx, jti, expiresAt); err != nil {
+		log.Error().Err(err).Msg("failed to revoke token")
+		return err
+	}
+	return nil
+}
+
+// RevokeUserTokens invalidates every access and refresh token issued to the
+// user before the given time.
+func (s Service) RevokeUserTokens(userId uuid.UUID, before time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.Store.RevokeUserRefreshTokens(ctx, db.RevokeUserRefreshTokensParams{
+		UserID:        userId,
+		CreatedBefore: pgtype.Timestamptz{Time: before, Valid: true},
+	}); err != nil {
+		log.Error().Err(err).Msg("failed to revoke refresh tokens")
+		return err
+	}
+	if s.revocations == nil {
+		return nil
+	}
+	if err := s.revocations.RevokeUser(ctx, userId.String(), before); err != nil {
+		log.Error().Err(err).Msg("failed to revoke user tokens")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/session"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ListSessions returns the user's active sessions. currentSessionId marks
+// the session the request was made with.
+func (s Service) ListSessions(userId uuid.UUID, currentSessionId string) ([]framework.SessionInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sessions, err := s.sessions.List(ctx, userId)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to list sessions")
+		return nil, err
+	}
+
+	infos := make([]framework.SessionInfo, 0, len(sessions))
+	for _, sess := range sessions {
+		ua := session.ParseUserAgent(sess.UserAgent)
+		infos = append(infos, framework.SessionInfo{
+			ID:         sess.ID.String(),
+			Browser:    ua.Browser,
+			OS:         ua.OS,
+			Device:     ua.Device,
+			UserAgent:  sess.UserAgent,
+			IPAddress:  sess.IPAddress,
+			CreatedAt:  sess.CreatedAt,
+			LastSeenAt: sess.LastSeenAt,
+			ExpiresAt:  sess.ExpiresAt,
+			Current:    sess.ID.String() == currentSessionId,
+		})
+	}
+	return infos, nil
+}
+
+// RevokeSession signs out one of the user's sessions, e.g. a lost laptop.
+// Sessions of other users are reported as not found.
+func (s Service) RevokeSession(userId uuid.UUID, sessionId uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.sessions.RevokeOwned(ctx, userId, sessionId)
+	if errors.Is(err, session.ErrSessionNotFound) || errors.Is(err, session.ErrNotSessionOwner) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to revoke session")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/lockout"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/tokenstore"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/SwanHtetAungPhyo/go-auth/initialization"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+	"github.com/skip2/go-qrcode"
+)
+
+// EnrollTOTP generates a new secret and set of recovery codes for the user
+// and returns them for an authenticator app. Two-factor stays off until
+// ConfirmTOTP sees a code for it, so enrolling again simply replaces an
+// unconfirmed secret. The issuer
+// shown in the app is GOAUTH_TOTP_ISSUER (default "GoAuth").
+func (s Service) EnrollTOTP(userId uuid.UUID) (framework.TOTPEnrollResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := s.Store.GetUserByID(ctx, userId)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up user for totp enrollment")
+		return framework.TOTPEnrollResponse{}, err
+	}
+	if user.TwoFactorEnabled.Bool {
+		return framework.TOTPEnrollResponse{}, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return framework.TOTPEnrollResponse{}, err
+	}
+	uri := utils.TOTPURI(initialization.GetEnv("GOAUTH_TOTP_ISSUER", "GoAuth"), user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to render totp qr code")
+		return framework.TOTPEnrollResponse{}, err
+	}
+
+	if err := s.Store.UpdateUserTwoFactor(ctx, db.UpdateUserTwoFactorParams{
+		ID:               userId,
+		TwoFactorEnabled: pgtype.Bool{Bool: false, Valid: true},
+		TwoFactorSecret:  pgtype.Text{String: secret, Valid: true},
+	}); err != nil {
+		log.Error().Err(err).Msg("failed to store totp secret")
+		return framework.TOTPEnrollResponse{}, err
+	}
+	recoveryCodes, err := s.replaceRecoveryCodes(ctx, userId)
+	if err != nil {
+		return framework.TOTPEnrollResponse{}, err
+	}
+
+	return framework.TOTPEnrollResponse{
+		Secret:        secret,
+		OTPAuthURI:    uri,
+		QRCode:        base64.StdEncoding.EncodeToString(png),
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// ConfirmTOTP turns two-factor on once the user proves their app produces
+// codes for the enrolled secret.
+func (s Service) ConfirmTOTP(userId uuid.UUID, code string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := s.Store.GetUserByID(ctx, userId)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up user for totp confirmation")
+		return err
+	}
+	if user.TwoFactorEnabled.Bool {
+		return ErrTOTPAlreadyEnabled
+	}
+	if !user.TwoFactorSecret.Valid {
+		return ErrTOTPNotEnrolled
+	}
+	if err := s.verifyTOTP(ctx, user, code, ""); err != nil {
+		return err
+	}
+
+	if err := s.Store.UpdateUserTwoFactor(ctx, db.UpdateUserTwoFactorParams{
+		ID:               userId,
+		TwoFactorEnabled: pgtype.Bool{Bool: true, Valid: true},
+		TwoFactorSecret:  user.TwoFactorSecret,
+	}); err != nil {
+		log.Error().Err(err).Msg("failed to enable totp")
+		return err
+	}
+	return nil
+}
+
+// DisableTOTP turns two-factor off and forgets the secret and recovery codes.
+// A current code is required so a stolen access token alone cannot remove
+// the second factor.
+func (s Service) DisableTOTP(userId uuid.UUID, code string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := s.Store.GetUserByID(ctx, userId)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up user to disable totp")
+		return err
+	}
+	if !user.TwoFactorEnabled.Bool {
+		return ErrTOTPNotEnrolled
+	}
+	if err := s.verifyTOTP(ctx, user, code, ""); err != nil {
+		return err
+	}
+
+	if err := s.Store.UpdateUserTwoFactor(ctx, db.UpdateUserTwoFactorParams{
+		ID:               userId,
+		TwoFactorEnabled: pgtype.Bool{Bool: false, Valid: true},
+	}); err != nil {
+		log.Error().Err(err).Msg("failed to disable totp")
+		return err
+	}
+	if err := s.Store.DeleteTotpStep(ctx, userId); err != nil {
+		log.Error().Err(err).Msg("failed to delete totp step")
+	}
+	if err := s.Store.DeleteUserRecoveryCodes(ctx, userId); err != nil {
+		log.Error().Err(err).Msg("failed to delete recovery codes")
+	}
+	if s.mfaChallenges != nil {
+		if err := s.mfaChallenges.DeleteUser(ctx, userId); err != nil {
+			log.Error().Err(err).Msg("failed to delete mfa challenges")
+		}
+	}
+	return nil
+}
+
+// maxMFAAttempts is how many codes one MFA challenge takes before it is
+// deleted and the user has to log in with their password again.
+const maxMFAAttempts = 5
+
+// VerifyMFA completes a login that stopped at the second factor, taking a
+// TOTP code or one of the user's recovery codes. A right code consumes the
+// challenge. A wrong one leaves it usable until it expires, for at most
+// maxMFAAttempts codes in all; after that the challenge is deleted and
+// ErrInvalidMFAToken returned. Each code also counts against the user's
+// two-factor lockout key, which a new challenge does not reset.
+func (s Service) VerifyMFA(req *framework.MFAVerifyRequest) (framework.AuthResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if s.mfaChallenges == nil {
+		return framework.AuthResponse{}, ErrInvalidMFAToken
+	}
+	challengeHash := tokenstore.HashToken(req.MFAToken)
+	challenge, err := s.mfaChallenges.Get(ctx, challengeHash)
+	if errors.Is(err, tokenstore.ErrTokenNotFound) {
+		return framework.AuthResponse{}, ErrInvalidMFAToken
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up mfa challenge")
+		return framework.AuthResponse{}, err
+	}
+
+	lastAttempt, err := s.countMFAAttempt(ctx, challengeHash, challenge.ExpiresAt)
+	if err != nil {
+		return framework.AuthResponse{}, err
+	}
+
+	user, err := s.Store.GetUserByID(ctx, challenge.UserID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up user for mfa challenge")
+		return framework.AuthResponse{}, err
+	}
+	if !user.TwoFactorEnabled.Bool {
+		return framework.AuthResponse{}, ErrInvalidTOTPCode
+	}
+	if len(req.Code) == utils.TOTPDigits {
+		err = s.verifyTOTP(ctx, user, req.Code, req.IPAddress)
+	} else {
+		err = s.verifySecondFactor(ctx, user, req.IPAddress, func() error {
+			return s.useRecoveryCode(ctx, user.ID, user.Email, req.Code, req.IPAddress)
+		})
+	}
+	if err != nil {
+		if lastAttempt {
+			s.deleteMFAChallenge(ctx, challengeHash)
+		}
+		return framework.AuthResponse{}, err
+	}
+
+	if _, err := s.mfaChallenges.Consume(ctx, challengeHash); errors.Is(err, tokenstore.ErrTokenNotFound) {
+		return framework.AuthResponse{}, ErrInvalidMFAToken
+	} else if err != nil {
+		log.Error().Err(err).Msg("failed to redeem mfa challenge")
+		return framework.AuthResponse{}, err
+	}
+	if s.lockouts != nil {
+		if err := s.lockouts.Reset(ctx, lockout.MFAKey(challengeHash)); err != nil {
+			log.Error().Err(err).Msg("failed to reset mfa attempt counter")
+		}
+	}
+
+	return s.completeLogin(ctx, user.ID, user.RoleName, req.UserAgent, req.IPAddress)
+}
+
+// countMFAAttempt counts a code submitted for the challenge before it is
+// checked, so concurrent guesses cannot slip past the limit. last is set for
+// the final permitted code, after which a wrong one deletes the challenge.
+// Without a lockout store nothing can be counted, and every code is the last.
+func (s Service) countMFAAttempt(ctx context.Context, challengeHash string, expiresAt time.Time) (last bool, err error) {
+	if s.lockouts == nil {
+		return true, nil
+	}
+	state, err := s.lockouts.Fail(ctx, lockout.MFAKey(challengeHash), time.Until(expiresAt))
+	if err != nil {
+		log.Error().Err(err).Msg("failed to count mfa attempt")
+		return false, err
+	}
+	if state.Failures > maxMFAAttempts {
+		s.deleteMFAChallenge(ctx, challengeHash)
+		return false, ErrInvalidMFAToken
+	}
+	return state.Failures == maxMFAAttempts, nil
+}
+
+func (s Service) deleteMFAChallenge(ctx context.Context, challengeHash string) {
+	if err := s.mfaChallenges.Delete(ctx, challengeHash); err != nil {
+		log.Error().Err(err).Msg("failed to delete exhausted mfa challenge")
+	}
+}
+
+// mfaMethods lists the second factors the user has set up. A registered
+// passkey counts as one even without TOTP.
+func (s Service) mfaMethods(ctx context.Context, userID uuid.UUID, totpEnabled bool) ([]string, error) {
+	var methods []string
+	if totpEnabled {
+		methods = append(methods, "totp", "recovery_code")
+	}
+	if s.webAuthn != nil {
+		passkeys, err := s.Store.CountUserWebauthnCredentials(ctx, userID)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to count passkeys")
+			return nil, err
+		}
+		if passkeys > 0 {
+			methods = append(methods, "passkey")
+		}
+	}
+	return methods, nil
+}
+
+// mfaChallenge answers a correct password for a user with a second factor
+// with a challenge token valid for GOAUTH_MFA_CHALLENGE_DURATION (default
+// 5m).
+func (s Service) mfaChallenge(ctx context.Context, userID uuid.UUID, methods []string) (framework.AuthResponse, error) {
+	if s.mfaChallenges == nil {
+		log.Error().Msg("user has two-factor enabled but no mfa challenge store is configured")
+		return framework.AuthResponse{}, fiber.ErrInternalServerError
+	}
+	token, tokenHash, err := tokenstore.NewToken()
+	if err != nil {
+		return framework.AuthResponse{}, err
+	}
+	expiry := initialization.GetEnvDuration("GOAUTH_MFA_CHALLENGE_DURATION", 5*time.Minute)
+	if err := s.mfaChallenges.Create(ctx, userID, tokenHash, time.Now().Add(expiry)); err != nil {
+		log.Error().Err(err).Msg("failed to store mfa challenge")
+		return framework.AuthResponse{}, fiber.ErrInternalServerError
+	}
+	return framework.AuthResponse{MFARequired: true, MFAToken: token, MFAMethods: methods}, nil
+}
+
+// verifyTOTP is checkTOTP counted against the user's two-factor lockout key
+// and ipAddress, if known.
+func (s Service) verifyTOTP(ctx context.Context, user db.GetUserByIDRow, code, ipAddress string) error {
+	return s.verifySecondFactor(ctx, user, ipAddress, func() error {
+		return s.checkTOTP(ctx, user.ID, user.TwoFactorSecret.String, code)
+	})
+}
+
+// verifySecondFactor runs check, which returns ErrInvalidTOTPCode for a wrong
+// code, as a two-factor attempt: counted before it runs, refused with a
+// *LoginThrottledError while the user or IP address is throttled, and locked
+// at the policy's limit. Any other error from check is not counted.
+func (s Service) verifySecondFactor(ctx context.Context, user db.GetUserByIDRow, ipAddress string, check func() error) error {
+	attempt, err := s.beginTwoFactorAttempt(ctx, user.ID, user.Email, ipAddress)
+	if err != nil {
+		return err
+	}
+	switch err := check(); {
+	case errors.Is(err, ErrInvalidTOTPCode):
+		s.loginFailed(ctx, attempt, user.ID, user.Email)
+		return err
+	case err != nil:
+		s.forgiveLoginAttempt(ctx, attempt)
+		return err
+	}
+	s.loginSucceeded(ctx, attempt)
+	return nil
+}
+
+// checkTOTP accepts a code from up to GOAUTH_TOTP_SKEW (default 1) steps
+// either side of now, and only if its step is later than the last one the
+// user redeemed, so a code cannot be used twice within its window.
+func (s Service) checkTOTP(ctx context.Context, userID uuid.UUID, secret, code string) error {
+	skew := initialization.GetEnvInt("GOAUTH_TOTP_SKEW", 1)
+	step, ok := utils.ValidateTOTP(secret, code, time.Now(), skew)
+	if !ok {
+		return ErrInvalidTOTPCode
+	}
+	updated, err := s.Store.UseTotpStep(ctx, db.UseTotpStepParams{
+		UserID:   userID,
+		LastStep: step,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to record totp step")
+		return err
+	}
+	if updated == 0 {
+		return ErrInvalidTOTPCode
+	}
+	return nil
+}
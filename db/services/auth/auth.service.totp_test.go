@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/lockout"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/tokenstore"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+)
+
+// currentTOTPStep returns the step the service will see as now, waiting out
+// the end of a step first so a test cannot straddle two.
+func currentTOTPStep(t *testing.T) int64 {
+	t.Helper()
+	if left := time.Duration(utils.TOTPPeriod-time.Now().Unix()%utils.TOTPPeriod) * time.Second; left < 2*time.Second {
+		time.Sleep(left)
+	}
+	return utils.TOTPStep(time.Now())
+}
+
+func totpCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	code, err := utils.TOTPCode(secret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// wrongTOTPCode returns a code that matches no step near now.
+func wrongTOTPCode(t *testing.T, secret string) string {
+	t.Helper()
+	step := utils.TOTPStep(time.Now())
+	for _, code := range []string{"000000", "111111", "222222"} {
+		matches := false
+		for offset := int64(-2); offset <= 2; offset++ {
+			matches = matches || totpCode(t, secret, step+offset) == code
+		}
+		if !matches {
+			return code
+		}
+	}
+	t.Fatal("no wrong code found")
+	return ""
+}
+
+// newTOTPUser adds a user with secret to database, with two-factor on or
+// only enrolled.
+func newTOTPUser(t *testing.T, database *passkeyDB, enabled bool) (uuid.UUID, string) {
+	t.Helper()
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := uuid.New()
+	database.users[userID] = db.GetUserByIDRow{
+		ID:               userID,
+		Email:            userID.String() + "@example.com",
+		RoleName:         "USER",
+		TwoFactorEnabled: pgtype.Bool{Bool: enabled, Valid: true},
+		TwoFactorSecret:  pgtype.Text{String: secret, Valid: true},
+	}
+	return userID, secret
+}
+
+func TestCheckTOTPSkewAndReplay(t *testing.T) {
+	database := newPasskeyDB(t)
+	s := Service{Store: &db.Store{Queries: db.New(database)}}
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		skew   string
+		offset int64
+		ok     bool
+	}{
+		{"two steps back, skew 1", "1", -2, false},
+		{"one step back, skew 1", "1", -1, true},
+		{"replayed", "1", -1, false},
+		{"current", "1", 0, true},
+		{"older than the last redeemed", "1", -1, false},
+		{"one step ahead, skew 0", "0", 1, false},
+		{"one step ahead, skew 1", "1", 1, true},
+		{"two steps ahead, skew 2", "2", 2, true},
+		{"current after a later step", "2", 0, false},
+	}
+	userID, secret := newTOTPUser(t, database, true)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOAUTH_TOTP_SKEW", tt.skew)
+			code := totpCode(t, secret, currentTOTPStep(t)+tt.offset)
+			err := s.checkTOTP(ctx, userID, secret, code)
+			if tt.ok && err != nil {
+				t.Errorf("checkTOTP: %v, want the code accepted", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidTOTPCode) {
+				t.Errorf("checkTOTP: %v, want ErrInvalidTOTPCode", err)
+			}
+		})
+	}
+}
+
+func TestTOTPCodesCountAgainstLockout(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		call    func(s Service, userID uuid.UUID, code string) error
+	}{
+		{"ConfirmTOTP", false, func(s Service, userID uuid.UUID, code string) error {
+			return s.ConfirmTOTP(userID, code)
+		}},
+		{"DisableTOTP", true, func(s Service, userID uuid.UUID, code string) error {
+			return s.DisableTOTP(userID, code)
+		}},
+		{"RegenerateRecoveryCodes", true, func(s Service, userID uuid.UUID, code string) error {
+			_, err := s.RegenerateRecoveryCodes(userID, code)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := newPasskeyDB(t)
+			s := newLockoutTestService(t, database, 2)
+			userID, secret := newTOTPUser(t, database, tt.enabled)
+
+			for range 2 {
+				if err := tt.call(s, userID, wrongTOTPCode(t, secret)); !errors.Is(err, ErrInvalidTOTPCode) {
+					t.Fatalf("wrong code: %v, want ErrInvalidTOTPCode", err)
+				}
+			}
+			right := totpCode(t, secret, currentTOTPStep(t))
+			var throttled *LoginThrottledError
+			if err := tt.call(s, userID, right); !errors.As(err, &throttled) {
+				t.Fatalf("after 2 wrong codes: %v, want a LoginThrottledError", err)
+			}
+			state, err := s.lockouts.Get(context.Background(), lockout.TwoFactorKey(userID.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state.Failures != 2 {
+				t.Errorf("two-factor failures = %d, want 2", state.Failures)
+			}
+		})
+	}
+}
+
+func TestVerifyMFALockoutOutlastsChallenge(t *testing.T) {
+	database := newPasskeyDB(t)
+	// Three wrong codes throttle the user, fewer than one challenge takes.
+	s := newLockoutTestService(t, database, 3)
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	WithMFAChallengeStore(tokenstore.NewRedisStore(client, tokenstore.MFAChallenge))(&s)
+	userID, secret := newTOTPUser(t, database, true)
+	ctx := context.Background()
+
+	challenge := func() string {
+		t.Helper()
+		response, err := s.mfaChallenge(ctx, userID, []string{"totp"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response.MFAToken
+	}
+	verify := func(token, code string) error {
+		_, err := s.VerifyMFA(&framework.MFAVerifyRequest{MFAToken: token, Code: code, IPAddress: "192.0.2.1"})
+		return err
+	}
+
+	first := challenge()
+	for range 3 {
+		if err := verify(first, wrongTOTPCode(t, secret)); !errors.Is(err, ErrInvalidTOTPCode) {
+			t.Fatalf("wrong code: %v, want ErrInvalidTOTPCode", err)
+		}
+	}
+	// Logging in again hands out a new challenge but no new guesses.
+	second := challenge()
+	var throttled *LoginThrottledError
+	if err := verify(second, totpCode(t, secret, currentTOTPStep(t))); !errors.As(err, &throttled) {
+		t.Fatalf("right code on a new challenge: %v, want a LoginThrottledError", err)
+	}
+	if err := s.lockouts.Reset(ctx, lockout.TwoFactorKey(userID.String())); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(second, totpCode(t, secret, currentTOTPStep(t))); err != nil {
+		t.Fatalf("right code once the lockout is cleared: %v", err)
+	}
+}
+
+// newMFATestService returns a Service over the dbtest database with MFA
+// challenges in Postgres and a lockout that never throttles, so only the
+// per-challenge limit applies.
+func newMFATestService(t *testing.T) Service {
+	t.Helper()
+	store := dbtest.Store(t)
+	s := Service{
+		Store:         store,
+		lockoutPolicy: &lockout.Policy{Window: time.Hour},
+	}
+	WithMFAChallengeStore(tokenstore.NewPostgresStore(store, tokenstore.MFAChallenge))(&s)
+	WithLockoutStore(lockout.NewPostgresStore(store), nil)(&s)
+	return s
+}
+
+func TestVerifyMFA(t *testing.T) {
+	// Each subtest needs a step nobody redeemed yet.
+	t.Setenv("GOAUTH_TOTP_SKEW", "2")
+	s := newMFATestService(t)
+	ctx := context.Background()
+	user := dbtest.NewUser(t, s.Store, db.GoAuthRegisterParams{})
+
+	enrollment, err := s.EnrollTOTP(user.ID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP: %v", err)
+	}
+	if err := s.ConfirmTOTP(user.ID, totpCode(t, enrollment.Secret, currentTOTPStep(t))); err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+	challenge := func() string {
+		t.Helper()
+		response, err := s.mfaChallenge(ctx, user.ID, []string{"totp", "recovery_code"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response.MFAToken
+	}
+	verify := func(token, code string) error {
+		_, err := s.VerifyMFA(&framework.MFAVerifyRequest{MFAToken: token, Code: code})
+		return err
+	}
+
+	t.Run("wrong then right code", func(t *testing.T) {
+		token := challenge()
+		if err := verify(token, wrongTOTPCode(t, enrollment.Secret)); !errors.Is(err, ErrInvalidTOTPCode) {
+			t.Fatalf("wrong code: %v, want ErrInvalidTOTPCode", err)
+		}
+		if err := verify(token, totpCode(t, enrollment.Secret, currentTOTPStep(t)+1)); err != nil {
+			t.Fatalf("right code after a wrong one: %v", err)
+		}
+		if err := verify(token, totpCode(t, enrollment.Secret, currentTOTPStep(t)+1)); !errors.Is(err, ErrInvalidMFAToken) {
+			t.Errorf("redeemed challenge: %v, want ErrInvalidMFAToken", err)
+		}
+	})
+
+	t.Run("recovery code", func(t *testing.T) {
+		code := enrollment.RecoveryCodes[0]
+		if err := verify(challenge(), code); err != nil {
+			t.Fatalf("recovery code: %v", err)
+		}
+		if err := verify(challenge(), code); !errors.Is(err, ErrInvalidTOTPCode) {
+			t.Errorf("reused recovery code: %v, want ErrInvalidTOTPCode", err)
+		}
+	})
+
+	t.Run("challenge exhausted", func(t *testing.T) {
+		token := challenge()
+		for i := range maxMFAAttempts {
+			if err := verify(token, wrongTOTPCode(t, enrollment.Secret)); !errors.Is(err, ErrInvalidTOTPCode) {
+				t.Fatalf("wrong code %d: %v, want ErrInvalidTOTPCode", i+1, err)
+			}
+		}
+		if err := verify(token, totpCode(t, enrollment.Secret, currentTOTPStep(t)+2)); !errors.Is(err, ErrInvalidMFAToken) {
+			t.Errorf("right code after %d wrong ones: %v, want ErrInvalidMFAToken", maxMFAAttempts, err)
+		}
+	})
+
+	t.Run("unknown challenge", func(t *testing.T) {
+		if err := verify("not-a-challenge", "123456"); !errors.Is(err, ErrInvalidMFAToken) {
+			t.Errorf("unknown challenge: %v, want ErrInvalidMFAToken", err)
+		}
+	})
+}
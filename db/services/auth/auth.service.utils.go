@@ -1,27 +1,65 @@
 package auth
 
 import (
+	"context"
 	"time"
 
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
 	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
 	"github.com/SwanHtetAungPhyo/go-auth/initialization"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-func (s Service) generateToken(userId string, role string, meta map[string]interface{}) (*utils.TokenContextContainer, error) {
+func (s Service) tokenType() utils.TokenType {
+	if s.cfg.PestoAuth && !s.cfg.JwtAuth {
+		return utils.PASETO
+	}
+	return utils.JWT
+}
+
+func (s Service) generateToken(userId string, role string, familyId string, meta map[string]interface{}) (*utils.TokenContextContainer, error) {
 	duration := initialization.GetEnvDuration("GOAUTH_TOKEN_DURATION", 3*time.Minute)
 
 	claims := utils.Claims{
 		UserID:   userId,
 		Role:     role,
+		FamilyID: familyId,
 		MetaData: meta,
 	}
 
 	if s.cfg.JwtAuth {
 		return utils.GenerateToken(claims, utils.JWT, duration)
-		//} else if s.cfg.PestoAuth {
-		//	return utils.GenerateToken(claims, utils.PASETO, duration)
-		//}
+	} else if s.cfg.PestoAuth {
+		return utils.GenerateToken(claims, utils.PASETO, duration)
+	}
+
+	return &utils.TokenContextContainer{}, nil
+}
+
+// issueTokens generates an access/refresh pair and records the refresh token
+// so it can later be rotated or revoked. familyID is uuid.New() for a fresh
+// login and the parent's family when rotating.
+func (s Service) issueTokens(ctx context.Context, q *db.Queries, userID uuid.UUID, role string, familyID uuid.UUID) (*utils.TokenContextContainer, error) {
+	token, err := s.generateToken(userID.String(), role, familyID.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshTokenID == "" {
+		return token, nil
 	}
 
-	return nil, nil
+	refreshID, err := uuid.Parse(token.RefreshTokenID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := q.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{
+		ID:        refreshID,
+		FamilyID:  familyID,
+		UserID:    userID,
+		ExpiresAt: pgtype.Timestamptz{Time: token.RefreshExpiresAt, Valid: true},
+	}); err != nil {
+		return nil, err
+	}
+	return token, nil
 }
@@ -0,0 +1,408 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/passkey"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/tokenstore"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// passkeyCeremonyTTL bounds a ceremony when the relying party does not
+// enforce its own timeouts.
+const passkeyCeremonyTTL = 5 * time.Minute
+
+// BeginPasskeyRegistration starts adding a passkey to the signed-in user's
+// account. The options ask for a discoverable credential so it can later be
+// used without an email, and exclude the user's existing passkeys.
+func (s Service) BeginPasskeyRegistration(userId uuid.UUID) (*protocol.CredentialCreation, error) {
+	if s.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, _, err := s.passkeyUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	creation, session, err := s.webAuthn.BeginRegistration(user,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(webauthn.Credentials(user.Credentials).CredentialDescriptors()),
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to begin passkey registration")
+		return nil, err
+	}
+	if err := s.saveCeremony(ctx, session); err != nil {
+		return nil, err
+	}
+	return creation, nil
+}
+
+// FinishPasskeyRegistration verifies the authenticator's attestation and
+// stores the new credential.
+func (s Service) FinishPasskeyRegistration(userId uuid.UUID, req *framework.PasskeyRegisterRequest) (framework.PasskeyInfo, error) {
+	if s.webAuthn == nil {
+		return framework.PasskeyInfo{}, ErrWebAuthnDisabled
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		log.Debug().Err(err).Msg("malformed passkey registration")
+		return framework.PasskeyInfo{}, ErrInvalidPasskey
+	}
+	session, err := s.consumeCeremony(ctx, parsed.Response.CollectedClientData.Challenge)
+	if err != nil {
+		return framework.PasskeyInfo{}, err
+	}
+	if !bytes.Equal(session.UserID, userId[:]) {
+		return framework.PasskeyInfo{}, ErrInvalidPasskey
+	}
+
+	user, _, err := s.passkeyUser(ctx, userId)
+	if err != nil {
+		return framework.PasskeyInfo{}, err
+	}
+	credential, err := s.webAuthn.CreateCredential(user, session, parsed)
+	if err != nil {
+		log.Debug().Err(err).Msg("passkey registration rejected")
+		return framework.PasskeyInfo{}, ErrInvalidPasskey
+	}
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return framework.PasskeyInfo{}, err
+	}
+
+	name := req.Name
+	if name == "" {
+		name = "Passkey"
+	}
+	row, err := s.Store.CreateWebauthnCredential(ctx, db.CreateWebauthnCredentialParams{
+		UserID:       userId,
+		CredentialID: credential.ID,
+		Name:         name,
+		Credential:   data,
+		SignCount:    int64(credential.Authenticator.SignCount),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to store passkey")
+		return framework.PasskeyInfo{}, err
+	}
+	return passkeyInfo(row), nil
+}
+
+// BeginPasskeyLogin starts a passwordless login. No user is named; the
+// authenticator offers whichever passkeys it holds for this site, and user
+// verification is required since the passkey is the only factor.
+func (s Service) BeginPasskeyLogin() (*protocol.CredentialAssertion, error) {
+	if s.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	assertion, session, err := s.webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to begin passkey login")
+		return nil, err
+	}
+	if err := s.saveCeremony(ctx, session); err != nil {
+		return nil, err
+	}
+	return assertion, nil
+}
+
+// FinishPasskeyLogin verifies a discoverable assertion, identifies the user
+// from its user handle and signs them in without a password or second
+// factor.
+func (s Service) FinishPasskeyLogin(req *framework.PasskeyLoginRequest) (framework.AuthResponse, error) {
+	if s.webAuthn == nil {
+		return framework.AuthResponse{}, ErrWebAuthnDisabled
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		log.Debug().Err(err).Msg("malformed passkey assertion")
+		return framework.AuthResponse{}, ErrInvalidPasskey
+	}
+	session, err := s.consumeCeremony(ctx, parsed.Response.CollectedClientData.Challenge)
+	if err != nil {
+		return framework.AuthResponse{}, err
+	}
+	// A ceremony begun for a named user belongs to the MFA step.
+	if len(session.UserID) != 0 {
+		return framework.AuthResponse{}, ErrInvalidPasskey
+	}
+
+	var account db.GetUserByIDRow
+	findUser := func(_, userHandle []byte) (webauthn.User, error) {
+		userID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, err
+		}
+		var user passkey.User
+		user, account, err = s.passkeyUser(ctx, userID)
+		return user, err
+	}
+	_, credential, err := s.webAuthn.ValidatePasskeyLogin(findUser, session, parsed)
+	if err != nil {
+		log.Debug().Err(err).Msg("passkey login rejected")
+		return framework.AuthResponse{}, ErrInvalidPasskey
+	}
+	if err := s.recordPasskeyUse(ctx, account.ID, credential); err != nil {
+		return framework.AuthResponse{}, err
+	}
+
+	if s.cfg.RequireEmailVerification && !account.EmailVerified.Bool {
+		return framework.AuthResponse{}, ErrEmailNotVerified
+	}
+	return s.completeLogin(ctx, account.ID, account.RoleName, req.UserAgent, req.IPAddress)
+}
+
+// BeginPasskeyMFA starts the passkey ceremony for a password login that
+// returned MFARequired, limited to the passkeys of that user.
+func (s Service) BeginPasskeyMFA(mfaToken string) (*protocol.CredentialAssertion, error) {
+	if s.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	challenge, err := s.liveMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	user, _, err := s.passkeyUser(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(user.Credentials) == 0 {
+		return nil, ErrPasskeyNotFound
+	}
+
+	assertion, session, err := s.webAuthn.BeginLogin(user)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to begin passkey mfa")
+		return nil, err
+	}
+	if err := s.saveCeremony(ctx, session); err != nil {
+		return nil, err
+	}
+	return assertion, nil
+}
+
+// FinishPasskeyMFA completes a password login with a passkey assertion in
+// place of a TOTP code, consuming the MFA challenge.
+func (s Service) FinishPasskeyMFA(req *framework.PasskeyLoginRequest) (framework.AuthResponse, error) {
+	if s.webAuthn == nil {
+		return framework.AuthResponse{}, ErrWebAuthnDisabled
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	challenge, err := s.liveMFAChallenge(ctx, req.MFAToken)
+	if err != nil {
+		return framework.AuthResponse{}, err
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		log.Debug().Err(err).Msg("malformed passkey assertion")
+		return framework.AuthResponse{}, ErrInvalidPasskey
+	}
+	session, err := s.consumeCeremony(ctx, parsed.Response.CollectedClientData.Challenge)
+	if err != nil {
+		return framework.AuthResponse{}, err
+	}
+	if !bytes.Equal(session.UserID, challenge.UserID[:]) {
+		return framework.AuthResponse{}, ErrInvalidPasskey
+	}
+
+	user, account, err := s.passkeyUser(ctx, challenge.UserID)
+	if err != nil {
+		return framework.AuthResponse{}, err
+	}
+	credential, err := s.webAuthn.ValidateLogin(user, session, parsed)
+	if err != nil {
+		log.Debug().Err(err).Msg("passkey mfa rejected")
+		return framework.AuthResponse{}, ErrInvalidPasskey
+	}
+	if err := s.recordPasskeyUse(ctx, account.ID, credential); err != nil {
+		return framework.AuthResponse{}, err
+	}
+
+	if _, err := s.mfaChallenges.Consume(ctx, tokenstore.HashToken(req.MFAToken)); errors.Is(err, tokenstore.ErrTokenNotFound) {
+		return framework.AuthResponse{}, ErrInvalidMFAToken
+	} else if err != nil {
+		log.Error().Err(err).Msg("failed to redeem mfa challenge")
+		return framework.AuthResponse{}, err
+	}
+	return s.completeLogin(ctx, account.ID, account.RoleName, req.UserAgent, req.IPAddress)
+}
+
+// ListPasskeys returns the passkeys registered to the user.
+func (s Service) ListPasskeys(userId uuid.UUID) ([]framework.PasskeyInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := s.Store.ListUserWebauthnCredentials(ctx, userId)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to list passkeys")
+		return nil, err
+	}
+	passkeys := make([]framework.PasskeyInfo, 0, len(rows))
+	for _, row := range rows {
+		passkeys = append(passkeys, passkeyInfo(row))
+	}
+	return passkeys, nil
+}
+
+// DeletePasskey removes one of the user's passkeys. Other users' passkeys
+// report ErrPasskeyNotFound.
+func (s Service) DeletePasskey(userId uuid.UUID, passkeyId uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	deleted, err := s.Store.DeleteWebauthnCredential(ctx, db.DeleteWebauthnCredentialParams{
+		ID:     passkeyId,
+		UserID: userId,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to delete passkey")
+		return err
+	}
+	if deleted == 0 {
+		return ErrPasskeyNotFound
+	}
+	return nil
+}
+
+// passkeyUser loads the user and their stored credentials.
+func (s Service) passkeyUser(ctx context.Context, userID uuid.UUID) (passkey.User, db.GetUserByIDRow, error) {
+	account, err := s.Store.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up user for passkey")
+		return passkey.User{}, db.GetUserByIDRow{}, err
+	}
+	rows, err := s.Store.ListUserWebauthnCredentials(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load passkeys")
+		return passkey.User{}, db.GetUserByIDRow{}, err
+	}
+
+	user := passkey.User{
+		ID:          account.ID,
+		Email:       account.Email,
+		DisplayName: account.Name.String,
+		Credentials: make([]webauthn.Credential, 0, len(rows)),
+	}
+	for _, row := range rows {
+		var credential webauthn.Credential
+		if err := json.Unmarshal(row.Credential, &credential); err != nil {
+			log.Error().Err(err).Str("passkey_id", row.ID.String()).Msg("failed to decode passkey")
+			continue
+		}
+		user.Credentials = append(user.Credentials, credential)
+	}
+	return user, account, nil
+}
+
+// recordPasskeyUse stores the credential's new sign count. A count that did
+// not advance means the private key may have been copied, so the login is
+// refused and audited. The update itself only succeeds while the stored
+// count is lower, which also stops two concurrent logins with one assertion.
+func (s Service) recordPasskeyUse(ctx context.Context, userID uuid.UUID, credential *webauthn.Credential) error {
+	cloned := credential.Authenticator.CloneWarning
+	if !cloned {
+		data, err := json.Marshal(credential)
+		if err != nil {
+			return err
+		}
+		updated, err := s.Store.UpdateWebauthnCredentialUse(ctx, db.UpdateWebauthnCredentialUseParams{
+			Credential:   data,
+			SignCount:    int64(credential.Authenticator.SignCount),
+			CredentialID: credential.ID,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to update passkey sign count")
+			return err
+		}
+		cloned = updated == 0
+	}
+	if cloned {
+		s.audit(ctx, s.Store.Queries, AuditPasskeyCloned, map[string]interface{}{
+			"user_id":       userID.String(),
+			"credential_id": base64.RawURLEncoding.EncodeToString(credential.ID),
+			"sign_count":    credential.Authenticator.SignCount,
+		})
+		return ErrInvalidPasskey
+	}
+	return nil
+}
+
+// liveMFAChallenge looks up an MFA challenge without consuming it.
+func (s Service) liveMFAChallenge(ctx context.Context, mfaToken string) (tokenstore.Record, error) {
+	if s.mfaChallenges == nil {
+		return tokenstore.Record{}, ErrInvalidMFAToken
+	}
+	challenge, err := s.mfaChallenges.Get(ctx, tokenstore.HashToken(mfaToken))
+	if errors.Is(err, tokenstore.ErrTokenNotFound) {
+		return tokenstore.Record{}, ErrInvalidMFAToken
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up mfa challenge")
+		return tokenstore.Record{}, err
+	}
+	return challenge, nil
+}
+
+func (s Service) saveCeremony(ctx context.Context, session *webauthn.SessionData) error {
+	if session.Expires.IsZero() {
+		session.Expires = time.Now().Add(passkeyCeremonyTTL)
+	}
+	if err := s.passkeyChallenges.Save(ctx, *session); err != nil {
+		log.Error().Err(err).Msg("failed to store webauthn challenge")
+		return err
+	}
+	return nil
+}
+
+func (s Service) consumeCeremony(ctx context.Context, challenge string) (webauthn.SessionData, error) {
+	session, err := s.passkeyChallenges.Consume(ctx, challenge)
+	if errors.Is(err, passkey.ErrChallengeNotFound) {
+		return webauthn.SessionData{}, ErrInvalidPasskey
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to redeem webauthn challenge")
+		return webauthn.SessionData{}, err
+	}
+	return session, nil
+}
+
+func passkeyInfo(row db.GoauthWebauthnCredential) framework.PasskeyInfo {
+	info := framework.PasskeyInfo{
+		ID:        row.ID.String(),
+		Name:      row.Name,
+		CreatedAt: row.CreatedAt.Time,
+	}
+	if row.LastUsedAt.Valid {
+		info.LastUsedAt = &row.LastUsedAt.Time
+	}
+	return info
+}
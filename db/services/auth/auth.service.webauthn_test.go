@@ -0,0 +1,451 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/passkey"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://login.example.com"
+)
+
+// passkeyDB keeps the users, passkeys, redeemed TOTP steps and audit events
+// the passkey and two-factor checks read and write, answering the sqlc
+// queries by name.
+type passkeyDB struct {
+	t           *testing.T
+	mu          sync.Mutex
+	users       map[uuid.UUID]db.GetUserByIDRow
+	credentials []db.GoauthWebauthnCredential
+	totpSteps   map[uuid.UUID]int64
+	audits      []string
+}
+
+func newPasskeyDB(t *testing.T) *passkeyDB {
+	return &passkeyDB{
+		t:         t,
+		users:     make(map[uuid.UUID]db.GetUserByIDRow),
+		totpSteps: make(map[uuid.UUID]int64),
+	}
+}
+
+func (d *passkeyDB) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch queryName(sql) {
+	case "CreateAuditLog":
+		d.audits = append(d.audits, args[0].(string))
+		return pgconn.NewCommandTag("INSERT 0 1"), nil
+	case "UpdateWebauthnCredentialUse":
+		data, signCount, credentialID := args[0].([]byte), args[1].(int64), args[2].([]byte)
+		updated := 0
+		for i, row := range d.credentials {
+			if !bytes.Equal(row.CredentialID, credentialID) {
+				continue
+			}
+			if row.SignCount < signCount || (row.SignCount == 0 && signCount == 0) {
+				d.credentials[i].Credential, d.credentials[i].SignCount = data, signCount
+				updated++
+			}
+		}
+		return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", updated)), nil
+	case "UseTotpStep":
+		userID, step := args[0].(uuid.UUID), args[1].(int64)
+		if last, ok := d.totpSteps[userID]; ok && last >= step {
+			return pgconn.NewCommandTag("INSERT 0 0"), nil
+		}
+		d.totpSteps[userID] = step
+		return pgconn.NewCommandTag("INSERT 0 1"), nil
+	}
+	d.t.Errorf("unexpected Exec: %s", queryName(sql))
+	return pgconn.CommandTag{}, errors.New("passkeyDB: unexpected Exec")
+}
+
+func (d *passkeyDB) Query(_ context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if queryName(sql) != "ListUserWebauthnCredentials" {
+		d.t.Errorf("unexpected Query: %s", queryName(sql))
+		return nil, errors.New("passkeyDB: unexpected Query")
+	}
+	var rows structRows
+	for _, row := range d.credentials {
+		if row.UserID == args[0].(uuid.UUID) {
+			rows = append(rows, row)
+		}
+	}
+	return &rows, nil
+}
+
+func (d *passkeyDB) QueryRow(_ context.Context, sql string, args ...interface{}) pgx.Row {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch queryName(sql) {
+	case "GetUserByID":
+		user, ok := d.users[args[0].(uuid.UUID)]
+		if !ok {
+			return noRow{}
+		}
+		return structRow{user}
+	case "CreateWebauthnCredential":
+		row := db.GoauthWebauthnCredential{
+			ID:           uuid.New(),
+			UserID:       args[0].(uuid.UUID),
+			CredentialID: args[1].([]byte),
+			Name:         args[2].(string),
+			Credential:   args[3].([]byte),
+			SignCount:    args[4].(int64),
+		}
+		d.credentials = append(d.credentials, row)
+		return structRow{row}
+	}
+	d.t.Errorf("unexpected QueryRow: %s", queryName(sql))
+	return noRow{}
+}
+
+func (d *passkeyDB) audited(event string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, audit := range d.audits {
+		if audit == event {
+			return true
+		}
+	}
+	return false
+}
+
+// queryName reads the name sqlc puts at the top of every query.
+func queryName(sql string) string {
+	name, _, _ := strings.Cut(strings.TrimPrefix(sql, "-- name: "), " ")
+	return name
+}
+
+// structRow scans the fields of a sqlc row struct, which are in the order of
+// the query's columns.
+type structRow struct{ value any }
+
+func (r structRow) Scan(dest ...any) error {
+	v := reflect.ValueOf(r.value)
+	if v.NumField() != len(dest) {
+		return fmt.Errorf("scan %T: %d columns, %d destinations", r.value, v.NumField(), len(dest))
+	}
+	for i, d := range dest {
+		reflect.ValueOf(d).Elem().Set(v.Field(i))
+	}
+	return nil
+}
+
+type structRows []any
+
+func (r *structRows) Next() bool {
+	return len(*r) > 0
+}
+
+func (r *structRows) Scan(dest ...any) error {
+	row := (*r)[0]
+	*r = (*r)[1:]
+	return structRow{row}.Scan(dest...)
+}
+
+func (r *structRows) Close()                                       {}
+func (r *structRows) Err() error                                   { return nil }
+func (r *structRows) CommandTag() pgconn.CommandTag                { return pgconn.NewCommandTag("SELECT") }
+func (r *structRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *structRows) Values() ([]any, error)                       { return nil, errors.New("not supported") }
+func (r *structRows) RawValues() [][]byte                          { return nil }
+func (r *structRows) Conn() *pgx.Conn                              { return nil }
+
+// memoryChallenges is a passkey.ChallengeStore in a map.
+type memoryChallenges struct {
+	mu       sync.Mutex
+	sessions map[string]webauthn.SessionData
+}
+
+func (m *memoryChallenges) Save(_ context.Context, session webauthn.SessionData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sessions == nil {
+		m.sessions = make(map[string]webauthn.SessionData)
+	}
+	m.sessions[session.Challenge] = session
+	return nil
+}
+
+func (m *memoryChallenges) Consume(_ context.Context, challenge string) (webauthn.SessionData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[challenge]
+	if !ok {
+		return webauthn.SessionData{}, passkey.ErrChallengeNotFound
+	}
+	delete(m.sessions, challenge)
+	return session, nil
+}
+
+// softAuthenticator is a platform authenticator in software: a P-256 key,
+// "none" attestation and a sign count the test sets before each assertion.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	SignCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{key: key, credentialID: credentialID}
+}
+
+// authenticatorData flags: user present, user verified and attested
+// credential data included.
+const (
+	flagUP = 0x01
+	flagUV = 0x04
+	flagAT = 0x40
+)
+
+func (a *softAuthenticator) authenticatorData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.SignCount)
+	return append(data, attested...)
+}
+
+func clientData(t *testing.T, ceremony string, challenge []byte) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    testOrigin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// register answers a navigator.credentials.create() call with creation's
+// options, as the browser would post it to FinishPasskeyRegistration.
+func (a *softAuthenticator) register(t *testing.T, creation *protocol.CredentialCreation, userHandle []byte) []byte {
+	t.Helper()
+	a.userHandle = userHandle
+
+	publicKey, err := a.key.PublicKey.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	coseKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: publicKey[1:33],
+		YCoord: publicKey[33:],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attested := make([]byte, 16) // zero AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, coseKey...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authenticatorData(flagUP|flagUV|flagAT, attested),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    b64(clientData(t, "webauthn.create", creation.Response.Challenge)),
+		"attestationObject": b64(attestationObject),
+	})
+}
+
+// assert answers a navigator.credentials.get() call with a signature over
+// the current sign count.
+func (a *softAuthenticator) assert(t *testing.T, assertion *protocol.CredentialAssertion) []byte {
+	t.Helper()
+	authData := a.authenticatorData(flagUP|flagUV, nil)
+	clientDataJSON := clientData(t, "webauthn.get", assertion.Response.Challenge)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(bytes.Clone(authData), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    b64(clientDataJSON),
+		"authenticatorData": b64(authData),
+		"signature":         b64(signature),
+		"userHandle":        b64(a.userHandle),
+	})
+}
+
+func (a *softAuthenticator) credential(t *testing.T, response map[string]string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{
+		"id":       b64(a.credentialID),
+		"rawId":    b64(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func newPasskeyTestService(t *testing.T) (Service, *passkeyDB) {
+	t.Helper()
+	relyingParty, err := webauthn.New(&webauthn.Config{
+		RPID:          testRPID,
+		RPDisplayName: "goauth test",
+		RPOrigins:     []string{testOrigin},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := newPasskeyDB(t)
+	s := Service{Store: &db.Store{Queries: db.New(database)}}
+	WithWebAuthn(relyingParty, &memoryChallenges{})(&s)
+	return s, database
+}
+
+func TestPasskeyRoundTrip(t *testing.T) {
+	s, database := newPasskeyTestService(t)
+	userID := uuid.New()
+	database.users[userID] = db.GetUserByIDRow{
+		ID:            userID,
+		Email:         "passkey@example.com",
+		RoleName:      "USER",
+		EmailVerified: pgtype.Bool{Bool: true, Valid: true},
+	}
+	authenticator := newSoftAuthenticator(t)
+
+	creation, err := s.BeginPasskeyRegistration(userID)
+	if err != nil {
+		t.Fatalf("BeginPasskeyRegistration: %v", err)
+	}
+	info, err := s.FinishPasskeyRegistration(userID, &framework.PasskeyRegisterRequest{
+		Name:       "Laptop",
+		Credential: authenticator.register(t, creation, userID[:]),
+	})
+	if err != nil {
+		t.Fatalf("FinishPasskeyRegistration: %v", err)
+	}
+	if info.Name != "Laptop" || len(database.credentials) != 1 {
+		t.Fatalf("registered %+v, stored %d passkeys", info, len(database.credentials))
+	}
+
+	login := func() ([]byte, error) {
+		t.Helper()
+		assertion, err := s.BeginPasskeyLogin()
+		if err != nil {
+			t.Fatalf("BeginPasskeyLogin: %v", err)
+		}
+		credential := authenticator.assert(t, assertion)
+		_, err = s.FinishPasskeyLogin(&framework.PasskeyLoginRequest{Credential: credential})
+		return credential, err
+	}
+
+	authenticator.SignCount = 1
+	credential, err := login()
+	if err != nil {
+		t.Fatalf("FinishPasskeyLogin: %v", err)
+	}
+	if got := database.credentials[0].SignCount; got != 1 {
+		t.Errorf("stored sign count = %d, want 1", got)
+	}
+
+	t.Run("replayed assertion", func(t *testing.T) {
+		_, err := s.FinishPasskeyLogin(&framework.PasskeyLoginRequest{Credential: credential})
+		if !errors.Is(err, ErrInvalidPasskey) {
+			t.Errorf("FinishPasskeyLogin error = %v, want ErrInvalidPasskey", err)
+		}
+	})
+
+	authenticator.SignCount = 5
+	if _, err := login(); err != nil {
+		t.Fatalf("FinishPasskeyLogin with a higher sign count: %v", err)
+	}
+
+	t.Run("sign count regression", func(t *testing.T) {
+		// A copy of the key still counting from an older value.
+		authenticator.SignCount = 3
+		if _, err := login(); !errors.Is(err, ErrInvalidPasskey) {
+			t.Errorf("FinishPasskeyLogin error = %v, want ErrInvalidPasskey", err)
+		}
+		if got := database.credentials[0].SignCount; got != 5 {
+			t.Errorf("stored sign count = %d, want it left at 5", got)
+		}
+		if !database.audited(AuditPasskeyCloned) {
+			t.Errorf("no %s audit entry", AuditPasskeyCloned)
+		}
+	})
+}
+
+func TestFinishPasskeyRegistrationOtherUser(t *testing.T) {
+	s, database := newPasskeyTestService(t)
+	owner, other := uuid.New(), uuid.New()
+	for _, id := range []uuid.UUID{owner, other} {
+		database.users[id] = db.GetUserByIDRow{ID: id, Email: id.String() + "@example.com", RoleName: "USER"}
+	}
+
+	creation, err := s.BeginPasskeyRegistration(owner)
+	if err != nil {
+		t.Fatalf("BeginPasskeyRegistration: %v", err)
+	}
+	credential := newSoftAuthenticator(t).register(t, creation, owner[:])
+	_, err = s.FinishPasskeyRegistration(other, &framework.PasskeyRegisterRequest{Credential: credential})
+	if !errors.Is(err, ErrInvalidPasskey) {
+		t.Errorf("FinishPasskeyRegistration error = %v, want ErrInvalidPasskey", err)
+	}
+	if len(database.credentials) != 0 {
+		t.Errorf("stored %d passkeys for a ceremony begun by another user", len(database.credentials))
+	}
+}
@@ -0,0 +1,213 @@
+package janitor
+
+import (
+	"context"
+	"time"
+
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultInterval  = 15 * time.Minute
+	DefaultBatchSize = 1000
+
+	// lockKey is the advisory lock every replica competes for; only the
+	// holder purges in a given round.
+	lockKey int64 = 0x676f61757468 // "goauth"
+)
+
+// MetricsHook receives the number of rows purged from a table in one run,
+// e.g. to feed a Prometheus counter. err is set when that table failed.
+type MetricsHook func(table string, deleted int64, elapsed time.Duration, err error)
+
+// Janitor periodically deletes expired sessions and tokens in batches.
+type Janitor struct {
+	store     *db.Store
+	interval  time.Duration
+	batchSize int32
+	// redisStore skips the tables runMigrations does not create when
+	// sessions, one-time tokens and revocations live in Redis.
+	redisStore bool
+	// maxTokenLifetime bounds how long a user revocation cutoff matters.
+	maxTokenLifetime time.Duration
+	// loginAttemptWindow is how long a failed login counts towards a
+	// lockout; older counters are purged once no lock is left on them.
+	loginAttemptWindow time.Duration
+	metrics            MetricsHook
+}
+
+type Option func(*Janitor)
+
+func New(store *db.Store, opts ...Option) *Janitor {
+	janitor := &Janitor{
+		store:              store,
+		interval:           DefaultInterval,
+		batchSize:          DefaultBatchSize,
+		maxTokenLifetime:   24 * time.Hour,
+		loginAttemptWindow: 24 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(janitor)
+	}
+	return janitor
+}
+
+func WithInterval(interval time.Duration) Option {
+	return func(j *Janitor) {
+		if interval > 0 {
+			j.interval = interval
+		}
+	}
+}
+
+func WithBatchSize(size int32) Option {
+	return func(j *Janitor) {
+		if size > 0 {
+			j.batchSize = size
+		}
+	}
+}
+
+func WithMetricsHook(hook MetricsHook) Option {
+	return func(j *Janitor) {
+		j.metrics = hook
+	}
+}
+
+// WithRedisStore tells the janitor that sessions and one-time tokens are kept
+// in Redis, where they expire on their own.
+func WithRedisStore(redisStore bool) Option {
+	return func(j *Janitor) {
+		j.redisStore = redisStore
+	}
+}
+
+// WithMaxTokenLifetime sets the longest lifetime of any issued token, usually
+// the refresh token's. User revocation cutoffs older than this are purged.
+func WithMaxTokenLifetime(lifetime time.Duration) Option {
+	return func(j *Janitor) {
+		j.maxTokenLifetime = lifetime
+	}
+}
+
+// WithLoginAttemptWindow sets the lockout policy's window, after which failed
+// login counters restart and can be purged.
+func WithLoginAttemptWindow(window time.Duration) Option {
+	return func(j *Janitor) {
+		if window > 0 {
+			j.loginAttemptWindow = window
+		}
+	}
+}
+
+// Start runs the janitor in the background until ctx is cancelled.
+func (j *Janitor) Start(ctx context.Context) {
+	go j.Run(ctx)
+}
+
+// Run purges once immediately and then every interval until ctx is
+// cancelled.
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("goauth janitor run failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce purges every table once, unless another replica holds the lock.
+func (j *Janitor) RunOnce(ctx context.Context) error {
+	unlock, acquired, err := j.store.TryAdvisoryLock(ctx, lockKey)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		log.Debug().Msg("goauth janitor skipped, another replica holds the lock")
+		return nil
+	}
+	defer unlock()
+
+	counts := log.Info()
+	for _, target := range j.targets() {
+		started := time.Now()
+		deleted, err := j.purge(ctx, target.purge)
+		if j.metrics != nil {
+			j.metrics(target.table, deleted, time.Since(started), err)
+		}
+		if err != nil {
+			log.Error().Err(err).Str("table", target.table).Int64("deleted", deleted).Msg("goauth janitor failed to purge table")
+			continue
+		}
+		counts = counts.Int64(target.table, deleted)
+	}
+	counts.Msg("goauth janitor purged expired rows")
+	return nil
+}
+
+type target struct {
+	table string
+	purge func(ctx context.Context, batchSize int32) (int64, error)
+}
+
+func (j *Janitor) targets() []target {
+	targets := []target{
+		{"goauth_refresh_token", j.store.PurgeExpiredRefreshTokens},
+	}
+	if j.redisStore {
+		return targets
+	}
+	return append(targets,
+		target{"goauth_session", j.store.PurgeExpiredSessions},
+		target{"goauth_password_reset", j.store.PurgeExpiredPasswordResetTokens},
+		target{"goauth_email_verification", j.store.PurgeExpiredEmailVerificationTokens},
+		target{"goauth_mfa_challenge", j.store.PurgeExpiredMfaChallenges},
+		target{"goauth_account_unlock", j.store.PurgeExpiredAccountUnlockTokens},
+		target{"goauth_webauthn_challenge", j.store.PurgeExpiredWebauthnChallenges},
+		target{"goauth_revoked_token", j.store.PurgeExpiredRevokedTokens},
+		target{"goauth_user_revocation", j.purgeUserRevocations},
+		target{"goauth_login_attempt", j.purgeLoginAttempts},
+	)
+}
+
+// purge deletes in batches until a short batch shows nothing is left, so no
+// single statement holds locks on a large number of rows.
+func (j *Janitor) purge(ctx context.Context, fn func(context.Context, int32) (int64, error)) (int64, error) {
+	var total int64
+	for {
+		deleted, err := fn(ctx, j.batchSize)
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+		if deleted < int64(j.batchSize) {
+			return total, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
+
+func (j *Janitor) purgeUserRevocations(ctx context.Context, batchSize int32) (int64, error) {
+	return j.store.PurgeUserRevocations(ctx, db.PurgeUserRevocationsParams{
+		RevokedBefore: pgtype.Timestamptz{Time: time.Now().Add(-j.maxTokenLifetime), Valid: true},
+		BatchSize:     batchSize,
+	})
+}
+
+func (j *Janitor) purgeLoginAttempts(ctx context.Context, batchSize int32) (int64, error) {
+	return j.store.PurgeLoginAttempts(ctx, db.PurgeLoginAttemptsParams{
+		FailedBefore: pgtype.Timestamptz{Time: time.Now().Add(-j.loginAttemptWindow), Valid: true},
+		BatchSize:    batchSize,
+	})
+}
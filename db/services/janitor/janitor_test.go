@@ -0,0 +1,246 @@
+package janitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// deleteDB answers every DELETE with the next of deleted and records the
+// arguments it was given.
+type deleteDB struct {
+	t       *testing.T
+	deleted []int64
+	err     error
+	args    [][]interface{}
+}
+
+func (d *deleteDB) Exec(_ context.Context, _ string, args ...interface{}) (pgconn.CommandTag, error) {
+	d.args = append(d.args, args)
+	if len(d.deleted) == 0 {
+		return pgconn.CommandTag{}, d.err
+	}
+	n := d.deleted[0]
+	d.deleted = d.deleted[1:]
+	return pgconn.NewCommandTag(fmt.Sprintf("DELETE %d", n)), nil
+}
+
+func (d *deleteDB) Query(_ context.Context, sql string, _ ...interface{}) (pgx.Rows, error) {
+	d.t.Errorf("unexpected Query: %s", sql)
+	return nil, errors.New("deleteDB: unexpected Query")
+}
+
+func (d *deleteDB) QueryRow(_ context.Context, sql string, _ ...interface{}) pgx.Row {
+	d.t.Errorf("unexpected QueryRow: %s", sql)
+	return nil
+}
+
+func newFakeJanitor(database *deleteDB, opts ...Option) *Janitor {
+	return New(&db.Store{Queries: db.New(database)}, opts...)
+}
+
+func TestPurgeBatches(t *testing.T) {
+	database := &deleteDB{t: t, deleted: []int64{2, 2, 1}}
+	j := newFakeJanitor(database, WithBatchSize(2))
+	deleted, err := j.purge(context.Background(), j.store.PurgeExpiredSessions)
+	if err != nil || deleted != 5 {
+		t.Errorf("purge = %d, %v, want 5", deleted, err)
+	}
+	if len(database.args) != 3 {
+		t.Errorf("purge ran %d batches, want 3", len(database.args))
+	}
+	for i, args := range database.args {
+		if args[0] != int32(2) {
+			t.Errorf("batch %d size = %v, want 2", i, args[0])
+		}
+	}
+
+	// A failing batch stops the purge and keeps what was deleted.
+	database = &deleteDB{t: t, deleted: []int64{2}, err: errors.New("boom")}
+	j = newFakeJanitor(database, WithBatchSize(2))
+	if deleted, err := j.purge(context.Background(), j.store.PurgeExpiredSessions); err == nil || deleted != 2 {
+		t.Errorf("purge with a failing batch = %d, %v, want 2 and the error", deleted, err)
+	}
+
+	// So does a cancelled context, between full batches.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	database = &deleteDB{t: t, deleted: []int64{2, 2, 2}}
+	j = newFakeJanitor(database, WithBatchSize(2))
+	if deleted, err := j.purge(ctx, j.store.PurgeExpiredSessions); !errors.Is(err, context.Canceled) || deleted != 2 {
+		t.Errorf("purge after cancel = %d, %v, want 2 and context.Canceled", deleted, err)
+	}
+}
+
+func TestTargets(t *testing.T) {
+	tables := func(j *Janitor) []string {
+		var tables []string
+		for _, target := range j.targets() {
+			tables = append(tables, target.table)
+		}
+		return tables
+	}
+	if got := tables(newFakeJanitor(&deleteDB{t: t})); len(got) != 10 {
+		t.Errorf("targets = %v, want every table", got)
+	}
+	// Redis keeps sessions and one-time tokens; only refresh tokens are in
+	// Postgres.
+	if got := tables(newFakeJanitor(&deleteDB{t: t}, WithRedisStore(true))); len(got) != 1 || got[0] != "goauth_refresh_token" {
+		t.Errorf("targets with Redis = %v, want goauth_refresh_token", got)
+	}
+}
+
+func TestPurgeCutoffs(t *testing.T) {
+	database := &deleteDB{t: t}
+	j := newFakeJanitor(database, WithMaxTokenLifetime(2*time.Hour), WithLoginAttemptWindow(time.Hour))
+	ctx := context.Background()
+	if _, err := j.purgeUserRevocations(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.purgeLoginAttempts(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []time.Duration{2 * time.Hour, time.Hour} {
+		cutoff, ok := database.args[i][0].(pgtype.Timestamptz)
+		if !ok || !cutoff.Valid {
+			t.Fatalf("cutoff %d = %v", i, database.args[i][0])
+		}
+		if age := time.Since(cutoff.Time); age < want || age > want+time.Minute {
+			t.Errorf("cutoff %d is %v old, want %v", i, age, want)
+		}
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	store := dbtest.Store(t)
+	ctx := context.Background()
+	userID := dbtest.User(t, store)
+	expired := pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+	live := pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true}
+
+	// One expired and one live row in each table.
+	var refreshTokens [2]uuid.UUID
+	for i, expiresAt := range []pgtype.Timestamptz{expired, live} {
+		record, err := store.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{ID: uuid.New(), FamilyID: uuid.New(), UserID: userID, ExpiresAt: expiresAt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		refreshTokens[i] = record.ID
+		token := uuid.NewString()
+		if _, err := store.CreateSession(ctx, db.CreateSessionParams{UserID: userID, Token: token, ExpiresAt: expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CreatePasswordResetToken(ctx, db.CreatePasswordResetTokenParams{UserID: userID, Token: token, ExpiresAt: expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CreateEmailVerificationToken(ctx, db.CreateEmailVerificationTokenParams{UserID: userID, Token: token, ExpiresAt: expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CreateMfaChallenge(ctx, db.CreateMfaChallengeParams{UserID: userID, Token: token, ExpiresAt: expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CreateAccountUnlockToken(ctx, db.CreateAccountUnlockTokenParams{UserID: userID, Token: token, ExpiresAt: expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.CreateWebauthnChallenge(ctx, db.CreateWebauthnChallengeParams{Challenge: token, SessionData: []byte("{}"), ExpiresAt: expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.RevokeToken(ctx, db.RevokeTokenParams{Jti: refreshTokens[i].String(), ExpiresAt: expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A cutoff older than any token is dropped; a recent one is kept.
+	staleUser, recentUser := dbtest.User(t, store), dbtest.User(t, store)
+	for user, revokedBefore := range map[uuid.UUID]time.Time{staleUser: time.Now().Add(-48 * time.Hour), recentUser: time.Now()} {
+		if err := store.RevokeUserTokensBefore(ctx, db.RevokeUserTokensBeforeParams{UserID: user, RevokedBefore: pgtype.Timestamptz{Time: revokedBefore, Valid: true}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Recent failures and locked keys both stay.
+	failedKey, lockedKey := "janitor-test:"+uuid.NewString(), "janitor-test:"+uuid.NewString()
+	if _, err := store.RecordLoginFailure(ctx, db.RecordLoginFailureParams{Key: failedKey, WindowStart: expired}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.LockLoginAttempt(ctx, db.LockLoginAttemptParams{Key: lockedKey, LockedUntil: live}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = store.DeleteLoginAttempt(context.Background(), failedKey)
+		_ = store.DeleteLoginAttempt(context.Background(), lockedKey)
+	})
+
+	purged := map[string]int64{}
+	j := New(store, WithBatchSize(1), WithMetricsHook(func(table string, deleted int64, _ time.Duration, err error) {
+		if err != nil {
+			t.Errorf("purging %s: %v", table, err)
+		}
+		purged[table] += deleted
+	}))
+
+	// While another replica holds the lock, this one leaves everything.
+	unlock, acquired, err := store.TryAdvisoryLock(ctx, lockKey)
+	if err != nil || !acquired {
+		t.Fatalf("TryAdvisoryLock = %v, %v", acquired, err)
+	}
+	if err := j.RunOnce(ctx); err != nil {
+		t.Errorf("RunOnce while locked: %v", err)
+	}
+	if len(purged) != 0 {
+		t.Errorf("RunOnce purged %v while another replica held the lock", purged)
+	}
+	if _, err := store.GetRefreshTokenForUpdate(ctx, refreshTokens[0]); err != nil {
+		t.Errorf("expired refresh token while locked: %v", err)
+	}
+	unlock()
+
+	if err := j.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	// Other tests may leave expired rows too, so counts are a lower bound.
+	// None of the login attempts above is old enough to go.
+	for _, target := range j.targets() {
+		if target.table != "goauth_login_attempt" && purged[target.table] < 1 {
+			t.Errorf("%s: purged %d rows, want at least the expired one", target.table, purged[target.table])
+		}
+	}
+
+	if _, err := store.GetRefreshTokenForUpdate(ctx, refreshTokens[0]); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expired refresh token: %v, want it purged", err)
+	}
+	if _, err := store.GetRefreshTokenForUpdate(ctx, refreshTokens[1]); err != nil {
+		t.Errorf("live refresh token: %v", err)
+	}
+	for i, want := range []bool{false, true} {
+		if revoked, err := store.IsTokenRevoked(ctx, refreshTokens[i].String()); err != nil || revoked != want {
+			t.Errorf("revocation %d = %v, %v, want %v", i, revoked, err, want)
+		}
+	}
+	if _, err := store.GetUserRevocation(ctx, staleUser); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("stale user revocation: %v, want it purged", err)
+	}
+	if _, err := store.GetUserRevocation(ctx, recentUser); err != nil {
+		t.Errorf("recent user revocation: %v", err)
+	}
+	for _, key := range []string{failedKey, lockedKey} {
+		if _, err := store.GetLoginAttempt(ctx, key); err != nil {
+			t.Errorf("login attempt %s: %v", key, err)
+		}
+	}
+
+	// The lock was released, so the next round can take it.
+	unlock, acquired, err = store.TryAdvisoryLock(ctx, lockKey)
+	if err != nil || !acquired {
+		t.Fatalf("TryAdvisoryLock after RunOnce = %v, %v", acquired, err)
+	}
+	unlock()
+}
@@ -0,0 +1,72 @@
+package lockout
+
+import "time"
+
+// maxBackoffShift keeps the doubling delay from overflowing.
+const maxBackoffShift = 30
+
+// Policy decides when failed logins slow down or lock an account or IP
+// address. Zero disables a limit, so the zero Policy never throttles.
+type Policy struct {
+	// Window is how long a failure counts. A key's count restarts after a
+	// Window without failures.
+	Window time.Duration
+	// After DelayAfter failures each further attempt on an account waits,
+	// starting at BaseDelay and doubling with every failure up to MaxDelay.
+	DelayAfter int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// LockAfter failures lock the account for LockDuration and email its
+	// owner an unlock link.
+	LockAfter    int
+	LockDuration time.Duration
+	// IPDelayAfter and IPLockAfter are the same limits for failures from one
+	// IP address across all accounts. Keep them well above the account limits
+	// to spare users behind a shared address.
+	IPDelayAfter int
+	IPLockAfter  int
+}
+
+// DefaultPolicy is used when Config.LoginLockout is nil.
+func DefaultPolicy() *Policy {
+	return &Policy{
+		Window:       time.Hour,
+		DelayAfter:   3,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		LockAfter:    10,
+		LockDuration: 15 * time.Minute,
+		IPDelayAfter: 20,
+		IPLockAfter:  100,
+	}
+}
+
+// RetryAfter returns how long key must wait from now before its next login
+// attempt, or zero if it may try at once.
+func (p *Policy) RetryAfter(key string, state State, now time.Time) time.Duration {
+	if state.Locked(now) {
+		return state.LockedUntil.Sub(now)
+	}
+	delayAfter, _ := p.limits(key)
+	if delayAfter == 0 || p.BaseDelay <= 0 || state.Failures < delayAfter {
+		return 0
+	}
+	delay := p.BaseDelay << min(state.Failures-delayAfter, maxBackoffShift)
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+		delay = p.MaxDelay
+	}
+	return max(state.LastFailedAt.Add(delay).Sub(now), 0)
+}
+
+// ShouldLock reports whether a failure that left key in state locks it.
+func (p *Policy) ShouldLock(key string, state State, now time.Time) bool {
+	_, lockAfter := p.limits(key)
+	return lockAfter > 0 && p.LockDuration > 0 && state.Failures >= lockAfter && !state.Locked(now)
+}
+
+func (p *Policy) limits(key string) (delayAfter, lockAfter int) {
+	if IsIPKey(key) {
+		return p.IPDelayAfter, p.IPLockAfter
+	}
+	return p.DelayAfter, p.LockAfter
+}
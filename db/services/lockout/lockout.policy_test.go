@@ -0,0 +1,118 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicyRetryAfter(t *testing.T) {
+	now := time.Now()
+	policy := &Policy{
+		Window:       time.Hour,
+		DelayAfter:   3,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		LockAfter:    10,
+		LockDuration: 15 * time.Minute,
+		IPDelayAfter: 20,
+		IPLockAfter:  100,
+	}
+	account, ip := AccountKey("a@example.com"), IPKey("192.0.2.1")
+
+	tests := []struct {
+		name   string
+		policy *Policy
+		key    string
+		state  State
+		want   time.Duration
+	}{
+		{"no failures", policy, account, State{}, 0},
+		{"below the delay", policy, account, State{Failures: 2, LastFailedAt: now}, 0},
+		{"first delay", policy, account, State{Failures: 3, LastFailedAt: now}, time.Second},
+		{"doubles", policy, account, State{Failures: 5, LastFailedAt: now}, 4 * time.Second},
+		{"capped", policy, account, State{Failures: 9, LastFailedAt: now}, time.Minute},
+		{"no overflow", policy, account, State{Failures: 1 << 20, LastFailedAt: now}, time.Minute},
+		{"waited out", policy, account, State{Failures: 4, LastFailedAt: now.Add(-3 * time.Second)}, 0},
+		{"partly waited", policy, account, State{Failures: 4, LastFailedAt: now.Add(-500 * time.Millisecond)}, 1500 * time.Millisecond},
+		{"locked", policy, account, State{Failures: 1, LockedUntil: now.Add(10 * time.Minute)}, 10 * time.Minute},
+		{"lock ended", policy, account, State{Failures: 1, LockedUntil: now.Add(-time.Minute)}, 0},
+		{"ip below its delay", policy, ip, State{Failures: 19, LastFailedAt: now}, 0},
+		{"ip delay", policy, ip, State{Failures: 20, LastFailedAt: now}, time.Second},
+		{"zero policy", &Policy{}, account, State{Failures: 1000, LastFailedAt: now}, 0},
+		{"no max delay", &Policy{DelayAfter: 1, BaseDelay: time.Second}, account, State{Failures: 4, LastFailedAt: now}, 8 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.RetryAfter(tt.key, tt.state, now); got != tt.want {
+				t.Errorf("RetryAfter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyShouldLock(t *testing.T) {
+	now := time.Now()
+	policy := DefaultPolicy()
+	account, ip := AccountKey("a@example.com"), IPKey("192.0.2.1")
+
+	tests := []struct {
+		name   string
+		policy *Policy
+		key    string
+		state  State
+		want   bool
+	}{
+		{"below the limit", policy, account, State{Failures: 9}, false},
+		{"at the limit", policy, account, State{Failures: 10}, true},
+		{"already locked", policy, account, State{Failures: 11, LockedUntil: now.Add(time.Minute)}, false},
+		{"ip uses its own limit", policy, ip, State{Failures: 10}, false},
+		{"ip at its limit", policy, ip, State{Failures: 100}, true},
+		{"no lock duration", &Policy{LockAfter: 1}, account, State{Failures: 5}, false},
+		{"zero policy", &Policy{}, account, State{Failures: 1000}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.ShouldLock(tt.key, tt.state, now); got != tt.want {
+				t.Errorf("ShouldLock = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatePrevious(t *testing.T) {
+	now := time.Now()
+	state := State{
+		Failures:         4,
+		LastFailedAt:     now,
+		PreviousFailedAt: now.Add(-time.Second),
+		LockedUntil:      now.Add(time.Minute),
+	}
+	want := State{Failures: 3, LastFailedAt: now.Add(-time.Second), LockedUntil: now.Add(time.Minute)}
+	if got := state.Previous(); got != want {
+		t.Errorf("Previous = %+v, want %+v", got, want)
+	}
+	if got := (State{}).Previous(); got.Failures != 0 {
+		t.Errorf("Previous of the zero State has %d failures", got.Failures)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	if AccountKey("A@Example.com ") != AccountKey("a@example.com") {
+		t.Error("AccountKey depends on case or surrounding space")
+	}
+	if TwoFactorKey("u") == MFAKey("u") {
+		t.Error("TwoFactorKey and MFAKey share a namespace")
+	}
+	if AccountKey("a@example.com") == AccountKey("b@example.com") {
+		t.Error("AccountKey is the same for different addresses")
+	}
+	if ResetKey("a@example.com") == AccountKey("a@example.com") || ResetKey("A@Example.com ") != ResetKey("a@example.com") {
+		t.Error("ResetKey shares the account namespace or depends on case")
+	}
+	if ResetIPKey("192.0.2.1") == IPKey("192.0.2.1") {
+		t.Error("ResetIPKey and IPKey share a namespace")
+	}
+	if !IsIPKey(IPKey("192.0.2.1")) || !IsIPKey(ResetIPKey("192.0.2.1")) || IsIPKey(AccountKey("192.0.2.1")) || IsIPKey(MFAKey("192.0.2.1")) || IsIPKey(TwoFactorKey("192.0.2.1")) || IsIPKey(ResetKey("192.0.2.1")) {
+		t.Error("IsIPKey does not tell IP keys from the others")
+	}
+}
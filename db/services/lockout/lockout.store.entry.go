@@ -0,0 +1,108 @@
+package lockout
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+const (
+	accountPrefix = "account:"
+	ipPrefix      = "ip:"
+	mfaPrefix     = "mfa:"
+	totpPrefix    = "totp:"
+	resetPrefix   = "reset:"
+)
+
+// State is a key's failed logins within the policy window and its lock.
+// PreviousFailedAt is the failure before LastFailedAt, so the state a
+// failure was counted on can be recovered with Previous.
+type State struct {
+	Failures         int
+	LastFailedAt     time.Time
+	LockedUntil      time.Time
+	PreviousFailedAt time.Time
+}
+
+// Store counts failed logins per key, an account or an IP address.
+type Store interface {
+	// Fail records a failed login for key and returns its new state. The
+	// count restarts at one when the previous failure is older than window.
+	// Counting and reading the new state are one atomic step, so parallel
+	// calls each see their own count.
+	Fail(ctx context.Context, key string, window time.Duration) (State, error)
+	// Forgive takes back one failure counted by Fail, for an attempt that
+	// was refused before it ran or that succeeded. The time of the last
+	// failure is kept, so a delay can only grow from it.
+	Forgive(ctx context.Context, key string) error
+	// Get returns key's state, or the zero State if it has none.
+	Get(ctx context.Context, key string) (State, error)
+	// Lock refuses logins for key until the given time.
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Reset forgets key's failures and lock.
+	Reset(ctx context.Context, key string) error
+}
+
+// AccountKey is the key for logins to email. It is derived from the address
+// as typed, whether or not a user has it, so unknown addresses are throttled
+// exactly like registered ones. Only a hash is stored.
+func AccountKey(email string) string {
+	return accountPrefix + hashEmail(email)
+}
+
+// IPKey is the key for logins from ipAddress, to any account.
+func IPKey(ipAddress string) string {
+	return ipPrefix + ipAddress
+}
+
+// MFAKey is the key for second-factor attempts on one MFA challenge,
+// identified by its token hash.
+func MFAKey(challengeHash string) string {
+	return mfaPrefix + challengeHash
+}
+
+// TwoFactorKey is the key for second-factor codes of one user, across all
+// of their MFA challenges. Unlike AccountKey, a right password does not
+// reset it, so logging in again does not buy more guesses.
+func TwoFactorKey(userID string) string {
+	return totpPrefix + userID
+}
+
+// ResetKey is the key for password reset requests for email. Like
+// AccountKey it does not depend on whether a user has the address.
+func ResetKey(email string) string {
+	return resetPrefix + hashEmail(email)
+}
+
+// ResetIPKey is the key for password reset requests from ipAddress. It is
+// kept apart from IPKey, so asking for resets does not slow down logins,
+// but IsIPKey reports true for it and a Policy applies its IP limits.
+func ResetIPKey(ipAddress string) string {
+	return ipPrefix + resetPrefix + ipAddress
+}
+
+func hashEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsIPKey reports whether key came from IPKey.
+func IsIPKey(key string) bool {
+	return strings.HasPrefix(key, ipPrefix)
+}
+
+// Previous is the state before the failure Fail returned s for.
+func (s State) Previous() State {
+	return State{
+		Failures:     max(s.Failures-1, 0),
+		LastFailedAt: s.PreviousFailedAt,
+		LockedUntil:  s.LockedUntil,
+	}
+}
+
+// Locked reports whether the state refuses logins at now.
+func (s State) Locked(now time.Time) bool {
+	return now.Before(s.LockedUntil)
+}
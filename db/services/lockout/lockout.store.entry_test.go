@@ -0,0 +1,152 @@
+package lockout
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// storeHarness runs one Store implementation through testStore, the
+// behaviour every implementation must share.
+type storeHarness struct {
+	store Store
+	// elapse lets d pass as far as the store's window is concerned.
+	elapse func(t *testing.T, d time.Duration)
+}
+
+func testStore(t *testing.T, h storeHarness) {
+	ctx := context.Background()
+	newKey := func() string { return AccountKey(uuid.NewString() + "@example.com") }
+	fail := func(t *testing.T, key string, window time.Duration) State {
+		t.Helper()
+		state, err := h.store.Fail(ctx, key, window)
+		if err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+		return state
+	}
+	get := func(t *testing.T, key string) State {
+		t.Helper()
+		state, err := h.store.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		return state
+	}
+
+	t.Run("unknown key", func(t *testing.T) {
+		if state := get(t, newKey()); state.Failures != 0 || !state.LockedUntil.IsZero() {
+			t.Errorf("Get = %+v, want the zero State", state)
+		}
+	})
+
+	t.Run("fail counts", func(t *testing.T) {
+		key := newKey()
+		first := fail(t, key, time.Hour)
+		if first.Failures != 1 || first.LastFailedAt.IsZero() || !first.PreviousFailedAt.IsZero() {
+			t.Errorf("first Fail = %+v, want one failure and no previous one", first)
+		}
+		second := fail(t, key, time.Hour)
+		if second.Failures != 2 {
+			t.Errorf("second Fail counted %d failures, want 2", second.Failures)
+		}
+		if second.PreviousFailedAt.Sub(first.LastFailedAt).Abs() > time.Millisecond {
+			t.Errorf("PreviousFailedAt = %v, want the first failure at %v", second.PreviousFailedAt, first.LastFailedAt)
+		}
+		if got := get(t, key); got.Failures != 2 {
+			t.Errorf("Get after two failures = %+v", got)
+		}
+	})
+
+	t.Run("parallel fails each see their own count", func(t *testing.T) {
+		key := newKey()
+		const attempts = 20
+		counts := make([]int, attempts)
+		var wg sync.WaitGroup
+		for i := range attempts {
+			wg.Go(func() {
+				state, err := h.store.Fail(ctx, key, time.Hour)
+				if err != nil {
+					t.Errorf("Fail: %v", err)
+					return
+				}
+				counts[i] = state.Failures
+			})
+		}
+		wg.Wait()
+		sort.Ints(counts)
+		for i, count := range counts {
+			if count != i+1 {
+				t.Fatalf("counts = %v, want 1 to %d once each", counts, attempts)
+			}
+		}
+	})
+
+	t.Run("forgive", func(t *testing.T) {
+		key := newKey()
+		fail(t, key, time.Hour)
+		fail(t, key, time.Hour)
+		if err := h.store.Forgive(ctx, key); err != nil {
+			t.Fatalf("Forgive: %v", err)
+		}
+		if got := get(t, key); got.Failures != 1 || got.LastFailedAt.IsZero() {
+			t.Errorf("Get after Forgive = %+v, want one failure with its time kept", got)
+		}
+		for range 2 {
+			if err := h.store.Forgive(ctx, key); err != nil {
+				t.Fatalf("Forgive: %v", err)
+			}
+		}
+		if got := get(t, key); got.Failures != 0 {
+			t.Errorf("Forgive went below zero: %+v", got)
+		}
+
+		unknown := newKey()
+		if err := h.store.Forgive(ctx, unknown); err != nil {
+			t.Fatalf("Forgive of an unknown key: %v", err)
+		}
+		if got := get(t, unknown); got.Failures != 0 {
+			t.Errorf("Forgive of an unknown key left %+v", got)
+		}
+	})
+
+	t.Run("lock and reset", func(t *testing.T) {
+		key := newKey()
+		fail(t, key, time.Hour)
+		until := time.Now().Add(time.Hour)
+		if err := h.store.Lock(ctx, key, until); err != nil {
+			t.Fatalf("Lock: %v", err)
+		}
+		state := get(t, key)
+		if !state.Locked(time.Now()) || state.LockedUntil.Sub(until).Abs() > time.Millisecond {
+			t.Errorf("Get after Lock = %+v, want locked until %v", state, until)
+		}
+		if state.Failures != 1 {
+			t.Errorf("Lock changed the count to %d", state.Failures)
+		}
+		if next := fail(t, key, time.Hour); !next.Locked(time.Now()) {
+			t.Errorf("Fail while locked = %+v, want the lock kept", next)
+		}
+
+		if err := h.store.Reset(ctx, key); err != nil {
+			t.Fatalf("Reset: %v", err)
+		}
+		if state := get(t, key); state.Failures != 0 || state.Locked(time.Now()) {
+			t.Errorf("Get after Reset = %+v, want the zero State", state)
+		}
+	})
+
+	t.Run("window restarts the count", func(t *testing.T) {
+		key := newKey()
+		fail(t, key, time.Second)
+		fail(t, key, time.Second)
+		h.elapse(t, 1500*time.Millisecond)
+		if state := fail(t, key, time.Second); state.Failures != 1 {
+			t.Errorf("Fail after the window = %+v, want the count restarted", state)
+		}
+	})
+}
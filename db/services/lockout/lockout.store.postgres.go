@@ -0,0 +1,68 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PostgresStore keeps failed login counters in goauth_login_attempt.
+type PostgresStore struct {
+	store *db.Store
+}
+
+func NewPostgresStore(store *db.Store) *PostgresStore {
+	return &PostgresStore{store: store}
+}
+
+func (p *PostgresStore) Fail(ctx context.Context, key string, window time.Duration) (State, error) {
+	row, err := p.store.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+		Key:         key,
+		WindowStart: pgtype.Timestamptz{Time: time.Now().Add(-window), Valid: true},
+	})
+	if err != nil {
+		return State{}, err
+	}
+	return toState(row), nil
+}
+
+func (p *PostgresStore) Forgive(ctx context.Context, key string) error {
+	return p.store.ForgiveLoginFailure(ctx, key)
+}
+
+func (p *PostgresStore) Get(ctx context.Context, key string) (State, error) {
+	row, err := p.store.GetLoginAttempt(ctx, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return State{}, nil
+	}
+	if err != nil {
+		return State{}, err
+	}
+	return toState(row), nil
+}
+
+func (p *PostgresStore) Lock(ctx context.Context, key string, until time.Time) error {
+	return p.store.LockLoginAttempt(ctx, db.LockLoginAttemptParams{
+		Key:         key,
+		LockedUntil: pgtype.Timestamptz{Time: until, Valid: true},
+	})
+}
+
+func (p *PostgresStore) Reset(ctx context.Context, key string) error {
+	return p.store.DeleteLoginAttempt(ctx, key)
+}
+
+func toState(row db.GoauthLoginAttempt) State {
+	return State{
+		Failures:         int(row.Failures),
+		LastFailedAt:     row.LastFailedAt.Time,
+		LockedUntil:      row.LockedUntil.Time,
+		PreviousFailedAt: row.PreviousFailedAt.Time,
+	}
+}
+
+var _ Store = (*PostgresStore)(nil)
@@ -0,0 +1,15 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+)
+
+func TestPostgresStore(t *testing.T) {
+	testStore(t, storeHarness{
+		store:  NewPostgresStore(dbtest.Store(t)),
+		elapse: func(_ *testing.T, d time.Duration) { time.Sleep(d) },
+	})
+}
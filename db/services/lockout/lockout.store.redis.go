@@ -0,0 +1,137 @@
+package lockout
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisPrefix = "goauth:lockout:"
+
+// RedisStore keeps each key's counters in a hash that expires a window after
+// its last failure, or when its lock ends if that is later.
+//
+//	goauth:lockout:<key>  failures, last_failed_at, previous_failed_at,
+//	                      locked_until (unix ms)
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// failScript counts a failure, restarting the count when the last one is
+// older than the window, and returns the new state.
+var failScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local last = tonumber(redis.call("HGET", KEYS[1], "last_failed_at") or "0")
+local failures = 1
+if last > now - window then
+	failures = redis.call("HINCRBY", KEYS[1], "failures", 1)
+else
+	redis.call("HSET", KEYS[1], "failures", 1)
+end
+redis.call("HSET", KEYS[1], "last_failed_at", now, "previous_failed_at", last)
+local locked = tonumber(redis.call("HGET", KEYS[1], "locked_until") or "0")
+redis.call("PEXPIRE", KEYS[1], math.max(window, locked - now))
+return {failures, locked, last}
+`)
+
+// forgiveScript takes back one failure without creating the hash or
+// changing its expiry.
+var forgiveScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+if redis.call("HINCRBY", KEYS[1], "failures", -1) < 0 then
+	redis.call("HSET", KEYS[1], "failures", 0)
+end
+return 1
+`)
+
+// lockScript sets the lock and keeps the hash at least until it ends.
+var lockScript = redis.NewScript(`
+redis.call("HSET", KEYS[1], "locked_until", ARGV[1])
+local ttl = tonumber(ARGV[1]) - tonumber(ARGV[2])
+if redis.call("PTTL", KEYS[1]) < ttl then
+	redis.call("PEXPIRE", KEYS[1], ttl)
+end
+return 1
+`)
+
+func (r *RedisStore) Fail(ctx context.Context, key string, window time.Duration) (State, error) {
+	now := time.Now()
+	values, err := failScript.Run(ctx, r.client,
+		[]string{redisPrefix + key},
+		now.UnixMilli(), window.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return State{}, err
+	}
+	state := State{Failures: int(values[0]), LastFailedAt: now}
+	if values[1] > 0 {
+		state.LockedUntil = time.UnixMilli(values[1])
+	}
+	if values[2] > 0 {
+		state.PreviousFailedAt = time.UnixMilli(values[2])
+	}
+	return state, nil
+}
+
+func (r *RedisStore) Forgive(ctx context.Context, key string) error {
+	return forgiveScript.Run(ctx, r.client, []string{redisPrefix + key}).Err()
+}
+
+func (r *RedisStore) Get(ctx context.Context, key string) (State, error) {
+	fields, err := r.client.HGetAll(ctx, redisPrefix+key).Result()
+	if err != nil {
+		return State{}, err
+	}
+	var state State
+	if value, ok := fields["failures"]; ok {
+		if state.Failures, err = strconv.Atoi(value); err != nil {
+			return State{}, err
+		}
+	}
+	if state.LastFailedAt, err = unixMilli(fields["last_failed_at"]); err != nil {
+		return State{}, err
+	}
+	if state.LockedUntil, err = unixMilli(fields["locked_until"]); err != nil {
+		return State{}, err
+	}
+	if state.PreviousFailedAt, err = unixMilli(fields["previous_failed_at"]); err != nil {
+		return State{}, err
+	}
+	return state, nil
+}
+
+func (r *RedisStore) Lock(ctx context.Context, key string, until time.Time) error {
+	if !until.After(time.Now()) {
+		return nil
+	}
+	return lockScript.Run(ctx, r.client,
+		[]string{redisPrefix + key},
+		until.UnixMilli(), time.Now().UnixMilli(),
+	).Err()
+}
+
+func (r *RedisStore) Reset(ctx context.Context, key string) error {
+	return r.client.Del(ctx, redisPrefix+key).Err()
+}
+
+func unixMilli(value string) (time.Time, error) {
+	if value == "" || value == "0" {
+		return time.Time{}, nil
+	}
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(millis), nil
+}
+
+var _ Store = (*RedisStore)(nil)
@@ -0,0 +1,71 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRedisStore(client), server
+}
+
+func TestRedisStore(t *testing.T) {
+	store, server := newTestRedisStore(t)
+	testStore(t, storeHarness{
+		store: store,
+		// The hash expires a window after its last failure, which is what
+		// restarts the count in Redis.
+		elapse: func(_ *testing.T, d time.Duration) { server.FastForward(d) },
+	})
+}
+
+func TestRedisStoreTTL(t *testing.T) {
+	store, server := newTestRedisStore(t)
+	ctx := context.Background()
+	key := AccountKey("ttl@example.com")
+	redisKey := redisPrefix + key
+
+	assertTTL := func(t *testing.T, want time.Duration) {
+		t.Helper()
+		if got := server.TTL(redisKey); (got - want).Abs() > time.Second {
+			t.Errorf("TTL = %v, want %v", got, want)
+		}
+	}
+
+	if _, err := store.Fail(ctx, key, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	assertTTL(t, time.Hour)
+
+	// A lock outlasting the window keeps the hash until it ends, and a
+	// later failure does not cut that short.
+	if err := store.Lock(ctx, key, time.Now().Add(3*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	assertTTL(t, 3*time.Hour)
+	if _, err := store.Fail(ctx, key, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	assertTTL(t, 3*time.Hour)
+
+	if err := store.Forgive(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	assertTTL(t, 3*time.Hour)
+
+	// Forgiving a key without failures creates nothing that never expires.
+	if err := store.Forgive(ctx, AccountKey("nobody@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if keys := server.Keys(); len(keys) != 1 {
+		t.Errorf("keys = %v, want only %s", keys, redisKey)
+	}
+}
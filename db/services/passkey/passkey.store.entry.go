@@ -0,0 +1,51 @@
+package passkey
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+var ErrChallengeNotFound = errors.New("webauthn challenge not found or expired")
+
+// ChallengeStore holds the state of WebAuthn ceremonies between their begin
+// and finish requests, keyed by the challenge the client signs.
+type ChallengeStore interface {
+	// Save keeps session until session.Expires.
+	Save(ctx context.Context, session webauthn.SessionData) error
+	// Consume returns the session and deletes it in one step, so a signed
+	// challenge can be redeemed only once.
+	Consume(ctx context.Context, challenge string) (webauthn.SessionData, error)
+}
+
+// User adapts a goauth_user and its stored credentials to webauthn.User. The
+// user handle is the user's UUID, which carries no personal data.
+type User struct {
+	ID          uuid.UUID
+	Email       string
+	DisplayName string
+	Credentials []webauthn.Credential
+}
+
+func (u User) WebAuthnID() []byte {
+	return u.ID[:]
+}
+
+func (u User) WebAuthnName() string {
+	return u.Email
+}
+
+func (u User) WebAuthnDisplayName() string {
+	if u.DisplayName == "" {
+		return u.Email
+	}
+	return u.DisplayName
+}
+
+func (u User) WebAuthnCredentials() []webauthn.Credential {
+	return u.Credentials
+}
+
+var _ webauthn.User = User{}
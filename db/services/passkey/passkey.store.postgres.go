@@ -0,0 +1,50 @@
+package passkey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PostgresStore keeps ceremonies in goauth_webauthn_challenge.
+type PostgresStore struct {
+	store *db.Store
+}
+
+func NewPostgresStore(store *db.Store) *PostgresStore {
+	return &PostgresStore{store: store}
+}
+
+func (p *PostgresStore) Save(ctx context.Context, session webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return p.store.CreateWebauthnChallenge(ctx, db.CreateWebauthnChallengeParams{
+		Challenge:   session.Challenge,
+		SessionData: data,
+		ExpiresAt:   pgtype.Timestamptz{Time: session.Expires, Valid: true},
+	})
+}
+
+func (p *PostgresStore) Consume(ctx context.Context, challenge string) (webauthn.SessionData, error) {
+	data, err := p.store.ConsumeWebauthnChallenge(ctx, challenge)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return webauthn.SessionData{}, ErrChallengeNotFound
+	}
+	if err != nil {
+		return webauthn.SessionData{}, err
+	}
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return webauthn.SessionData{}, err
+	}
+	return session, nil
+}
+
+var _ ChallengeStore = (*PostgresStore)(nil)
@@ -0,0 +1,63 @@
+package passkey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps each ceremony as goauth:webauthn:<challenge>, expiring
+// with it.
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (r *RedisStore) key(challenge string) string {
+	return "goauth:webauthn:" + challenge
+}
+
+func (r *RedisStore) Save(ctx context.Context, session webauthn.SessionData) error {
+	ttl := time.Until(session.Expires)
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.key(session.Challenge), data, ttl).Err()
+}
+
+func (r *RedisStore) Consume(ctx context.Context, challenge string) (webauthn.SessionData, error) {
+	var get *redis.StringCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, r.key(challenge))
+		pipe.Del(ctx, r.key(challenge))
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return webauthn.SessionData{}, ErrChallengeNotFound
+	}
+	if err != nil {
+		return webauthn.SessionData{}, err
+	}
+	data, err := get.Bytes()
+	if err != nil {
+		return webauthn.SessionData{}, err
+	}
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return webauthn.SessionData{}, err
+	}
+	return session, nil
+}
+
+var _ ChallengeStore = (*RedisStore)(nil)
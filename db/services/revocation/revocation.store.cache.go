@@ -0,0 +1,141 @@
+package revocation
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// CachedStore puts an in-process LRU cache in front of another Store so the
+// middleware does not hit Redis or Postgres on every request.
+//
+// A revoked jti is cached until evicted, since revocation is permanent. Negative
+// answers and user cutoffs are cached for ttl only, which bounds how long a
+// revocation made on another replica can go unnoticed here. Revocations made
+// through this CachedStore take effect locally at once. A ttl of zero or less
+// caches only revoked jtis, so every other check reaches the inner Store.
+type CachedStore struct {
+	inner Store
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type cacheEntry struct {
+	key       string
+	revoked   bool
+	cutoff    time.Time
+	expiresAt time.Time
+}
+
+func NewCachedStore(inner Store, size int, ttl time.Duration) *CachedStore {
+	return &CachedStore{
+		inner:   inner,
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+func (c *CachedStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	if err := c.inner.Revoke(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+	c.put(cacheEntry{key: tokenKey(jti), revoked: true})
+	return nil
+}
+
+func (c *CachedStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	if entry, ok := c.get(tokenKey(jti)); ok {
+		return entry.revoked, nil
+	}
+
+	revoked, err := c.inner.IsRevoked(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+	if revoked {
+		c.put(cacheEntry{key: tokenKey(jti), revoked: true})
+	} else if c.ttl > 0 {
+		c.put(cacheEntry{key: tokenKey(jti), expiresAt: time.Now().Add(c.ttl)})
+	}
+	return revoked, nil
+}
+
+func (c *CachedStore) RevokeUser(ctx context.Context, userID string, before time.Time) error {
+	if err := c.inner.RevokeUser(ctx, userID, before); err != nil {
+		return err
+	}
+	c.remove(userKey(userID))
+	return nil
+}
+
+func (c *CachedStore) UserCutoff(ctx context.Context, userID string) (time.Time, error) {
+	if entry, ok := c.get(userKey(userID)); ok {
+		return entry.cutoff, nil
+	}
+
+	cutoff, err := c.inner.UserCutoff(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if c.ttl > 0 {
+		c.put(cacheEntry{key: userKey(userID), cutoff: cutoff, expiresAt: time.Now().Add(c.ttl)})
+	}
+	return cutoff, nil
+}
+
+func (c *CachedStore) get(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	entry := element.Value.(cacheEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return cacheEntry{}, false
+	}
+	c.order.MoveToFront(element)
+	return entry, true
+}
+
+func (c *CachedStore) put(entry cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[entry.key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(cacheEntry).key)
+	}
+}
+
+func (c *CachedStore) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+func tokenKey(jti string) string   { return "jti:" + jti }
+func userKey(userID string) string { return "user:" + userID }
+
+var _ Store = (*CachedStore)(nil)
@@ -0,0 +1,166 @@
+package revocation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCachedStore(t *testing.T) {
+	testStore(t, storeHarness{
+		store:   NewCachedStore(newMemoryStore(), 100, time.Minute),
+		newUser: func(*testing.T) string { return uuid.NewString() },
+	})
+}
+
+func TestCachedStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	const ttl = 50 * time.Millisecond
+
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		// revoke revokes on another replica, straight in the inner store.
+		revoke func(t *testing.T, inner *memoryStore, jti, userID string)
+		// wait is how long passes before the second check.
+		wait time.Duration
+		want bool
+	}{
+		{
+			name:   "jti revoked elsewhere, within the ttl",
+			ttl:    ttl,
+			revoke: revokeJTI,
+			want:   false,
+		},
+		{
+			name:   "jti revoked elsewhere, after the ttl",
+			ttl:    ttl,
+			revoke: revokeJTI,
+			wait:   2 * ttl,
+			want:   true,
+		},
+		{
+			name:   "user revoked elsewhere, within the ttl",
+			ttl:    ttl,
+			revoke: revokeUser,
+			want:   false,
+		},
+		{
+			name:   "user revoked elsewhere, after the ttl",
+			ttl:    ttl,
+			revoke: revokeUser,
+			wait:   2 * ttl,
+			want:   true,
+		},
+		{
+			name:   "no ttl",
+			ttl:    0,
+			revoke: revokeUser,
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := newMemoryStore()
+			cache := NewCachedStore(inner, 100, tt.ttl)
+			jti, userID := uuid.NewString(), uuid.NewString()
+			issuedAt := time.Now().Add(-time.Minute)
+
+			if revoked, err := IsTokenRevoked(ctx, cache, jti, userID, issuedAt); err != nil || revoked {
+				t.Fatalf("first check = %v, %v", revoked, err)
+			}
+			tt.revoke(t, inner, jti, userID)
+			time.Sleep(tt.wait)
+			if revoked, err := IsTokenRevoked(ctx, cache, jti, userID, issuedAt); err != nil || revoked != tt.want {
+				t.Errorf("second check = %v, %v, want %v", revoked, err, tt.want)
+			}
+		})
+	}
+}
+
+func revokeJTI(t *testing.T, inner *memoryStore, jti, _ string) {
+	t.Helper()
+	if err := inner.Revoke(context.Background(), jti, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func revokeUser(t *testing.T, inner *memoryStore, _, userID string) {
+	t.Helper()
+	if err := inner.RevokeUser(context.Background(), userID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCachedStoreLocalRevocations(t *testing.T) {
+	ctx := context.Background()
+	inner := newMemoryStore()
+	cache := NewCachedStore(inner, 100, time.Hour)
+	jti, userID := uuid.NewString(), uuid.NewString()
+	issuedAt := time.Now().Add(-time.Minute)
+
+	if revoked, err := IsTokenRevoked(ctx, cache, jti, userID, issuedAt); err != nil || revoked {
+		t.Fatalf("first check = %v, %v", revoked, err)
+	}
+	// Revocations through the cache do not wait out its ttl.
+	if err := cache.RevokeUser(ctx, userID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := IsTokenRevoked(ctx, cache, "", userID, issuedAt); !revoked {
+		t.Error("a user revoked through the cache is still served from it")
+	}
+	if err := cache.Revoke(ctx, jti, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := cache.IsRevoked(ctx, jti); !revoked {
+		t.Error("a jti revoked through the cache is still served from it")
+	}
+
+	// A revoked jti stays cached.
+	before := inner.lookups()
+	for range 3 {
+		if revoked, _ := cache.IsRevoked(ctx, jti); !revoked {
+			t.Fatal("revoked jti reads as not revoked")
+		}
+	}
+	if inner.lookups() != before {
+		t.Errorf("revoked jti looked up %d more times", inner.lookups()-before)
+	}
+}
+
+func TestCachedStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	inner := newMemoryStore()
+	cache := NewCachedStore(inner, 2, time.Hour)
+	lookup := func(jti string) {
+		t.Helper()
+		if _, err := cache.IsRevoked(ctx, jti); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cached := func(jti string) bool {
+		t.Helper()
+		before := inner.lookups()
+		lookup(jti)
+		return inner.lookups() == before
+	}
+
+	lookup("a")
+	lookup("b")
+	lookup("a") // a is now the most recently used.
+	lookup("c") // evicts b
+	if !cached("a") {
+		t.Error("a was evicted although it was used last but one")
+	}
+	if !cached("c") {
+		t.Error("c was evicted although it was just added")
+	}
+	if cached("b") {
+		t.Error("b was kept although it was the least recently used")
+	}
+	if len(cache.entries) != 2 || cache.order.Len() != 2 {
+		t.Errorf("cache holds %d entries and %d list elements, want 2", len(cache.entries), cache.order.Len())
+	}
+}
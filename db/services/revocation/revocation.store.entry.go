@@ -0,0 +1,41 @@
+package revocation
+
+import (
+	"context"
+	"time"
+)
+
+// Store records revoked tokens so they can be refused before their exp claim.
+type Store interface {
+	// Revoke blocks a single token, identified by its jti, until expiresAt.
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	// IsRevoked reports whether the jti was revoked individually.
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	// RevokeUser blocks every token issued to userID before the cutoff.
+	// Cutoffs only move forward; an earlier cutoff is ignored.
+	RevokeUser(ctx context.Context, userID string, before time.Time) error
+	// UserCutoff returns the user's cutoff, or the zero time if none is set.
+	UserCutoff(ctx context.Context, userID string) (time.Time, error)
+}
+
+// IsTokenRevoked checks a token against both the per-token list and the
+// user-wide cutoff. Token timestamps have second precision, so the cutoff is
+// compared at second precision too: a token issued in the same second the
+// cutoff was set survives.
+func IsTokenRevoked(ctx context.Context, store Store, jti, userID string, issuedAt time.Time) (bool, error) {
+	if jti != "" {
+		revoked, err := store.IsRevoked(ctx, jti)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+	if userID == "" || issuedAt.IsZero() {
+		return false, nil
+	}
+
+	cutoff, err := store.UserCutoff(ctx, userID)
+	if err != nil || cutoff.IsZero() {
+		return false, err
+	}
+	return issuedAt.Before(cutoff.Truncate(time.Second)), nil
+}
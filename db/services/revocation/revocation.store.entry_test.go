@@ -0,0 +1,163 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// memoryStore is a Store for the tests of what sits in front of one. calls
+// counts the lookups that reached it.
+type memoryStore struct {
+	mu      sync.Mutex
+	revoked map[string]bool
+	cutoffs map[string]time.Time
+	calls   int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{revoked: make(map[string]bool), cutoffs: make(map[string]time.Time)}
+}
+
+func (m *memoryStore) Revoke(_ context.Context, jti string, _ time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revoked[jti] = true
+	return nil
+}
+
+func (m *memoryStore) IsRevoked(_ context.Context, jti string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	return m.revoked[jti], nil
+}
+
+func (m *memoryStore) RevokeUser(_ context.Context, userID string, before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if before.After(m.cutoffs[userID]) {
+		m.cutoffs[userID] = before
+	}
+	return nil
+}
+
+func (m *memoryStore) UserCutoff(_ context.Context, userID string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	return m.cutoffs[userID], nil
+}
+
+func (m *memoryStore) lookups() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+// storeHarness runs one Store implementation through testStore, the
+// behaviour every implementation must share.
+type storeHarness struct {
+	store Store
+	// newUser returns a user ID the store accepts.
+	newUser func(t *testing.T) string
+}
+
+func testStore(t *testing.T, h storeHarness) {
+	ctx := context.Background()
+	store, newUser := h.store, h.newUser
+
+	t.Run("revoke", func(t *testing.T) {
+		jti := uuid.NewString()
+		if revoked, err := store.IsRevoked(ctx, jti); err != nil || revoked {
+			t.Fatalf("IsRevoked before Revoke = %v, %v", revoked, err)
+		}
+		if err := store.Revoke(ctx, jti, time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("Revoke: %v", err)
+		}
+		if revoked, err := store.IsRevoked(ctx, jti); err != nil || !revoked {
+			t.Errorf("IsRevoked after Revoke = %v, %v", revoked, err)
+		}
+		if revoked, err := store.IsRevoked(ctx, uuid.NewString()); err != nil || revoked {
+			t.Errorf("IsRevoked of another jti = %v, %v", revoked, err)
+		}
+	})
+
+	t.Run("user cutoff only moves forward", func(t *testing.T) {
+		userID := newUser(t)
+		if cutoff, err := store.UserCutoff(ctx, userID); err != nil || !cutoff.IsZero() {
+			t.Fatalf("UserCutoff before RevokeUser = %v, %v", cutoff, err)
+		}
+		now := time.Now()
+		if err := store.RevokeUser(ctx, userID, now); err != nil {
+			t.Fatalf("RevokeUser: %v", err)
+		}
+		if err := store.RevokeUser(ctx, userID, now.Add(-time.Hour)); err != nil {
+			t.Fatalf("RevokeUser with an earlier cutoff: %v", err)
+		}
+		cutoff, err := store.UserCutoff(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cutoff.Sub(now).Abs() > time.Millisecond {
+			t.Errorf("UserCutoff = %v, want %v", cutoff, now)
+		}
+		later := now.Add(time.Minute)
+		if err := store.RevokeUser(ctx, userID, later); err != nil {
+			t.Fatal(err)
+		}
+		if cutoff, err := store.UserCutoff(ctx, userID); err != nil || cutoff.Sub(later).Abs() > time.Millisecond {
+			t.Errorf("UserCutoff after a later RevokeUser = %v, %v, want %v", cutoff, err, later)
+		}
+	})
+}
+
+func TestIsTokenRevoked(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	// The cutoff falls 700ms into a second, as a logout at any moment
+	// would.
+	cutoff := time.Date(2026, 1, 2, 3, 4, 5, 700_000_000, time.UTC)
+	userID := uuid.NewString()
+	if err := store.RevokeUser(ctx, userID, cutoff); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Revoke(ctx, "revoked-jti", cutoff.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	second := cutoff.Truncate(time.Second)
+
+	tests := []struct {
+		name     string
+		jti      string
+		userID   string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"issued a second before the cutoff", "", userID, second.Add(-time.Second), true},
+		{"issued long before the cutoff", "", userID, second.Add(-time.Hour), true},
+		// iat has second precision, so a token issued in the same second
+		// as the cutoff, before or after it, reads as issued at second.
+		{"issued in the cutoff's second", "", userID, second, false},
+		{"issued after the cutoff", "", userID, second.Add(time.Second), false},
+		{"user without a cutoff", "", uuid.NewString(), second.Add(-time.Hour), false},
+		{"no issued at", "", userID, time.Time{}, false},
+		{"no user", "", "", second.Add(-time.Hour), false},
+		{"revoked jti", "revoked-jti", uuid.NewString(), second.Add(time.Hour), true},
+		{"other jti after the cutoff", "other-jti", userID, second.Add(time.Second), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := IsTokenRevoked(ctx, store, tt.jti, tt.userID, tt.issuedAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revoked != tt.want {
+				t.Errorf("IsTokenRevoked = %v, want %v", revoked, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PostgresStore keeps revocations in goauth_revoked_token and
+// goauth_user_revocation.
+type PostgresStore struct {
+	store *db.Store
+}
+
+func NewPostgresStore(store *db.Store) *PostgresStore {
+	return &PostgresStore{store: store}
+}
+
+func (p *PostgresStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	return p.store.RevokeToken(ctx, db.RevokeTokenParams{
+		Jti:       jti,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+}
+
+func (p *PostgresStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return p.store.IsTokenRevoked(ctx, jti)
+}
+
+func (p *PostgresStore) RevokeUser(ctx context.Context, userID string, before time.Time) error {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+	return p.store.RevokeUserTokensBefore(ctx, db.RevokeUserTokensBeforeParams{
+		UserID:        id,
+		RevokedBefore: pgtype.Timestamptz{Time: before, Valid: true},
+	})
+}
+
+func (p *PostgresStore) UserCutoff(ctx context.Context, userID string) (time.Time, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	cutoff, err := p.store.GetUserRevocation(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cutoff.Time, nil
+}
+
+var _ Store = (*PostgresStore)(nil)
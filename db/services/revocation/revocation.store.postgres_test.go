@@ -0,0 +1,15 @@
+package revocation
+
+import (
+	"testing"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+)
+
+func TestPostgresStore(t *testing.T) {
+	store := dbtest.Store(t)
+	testStore(t, storeHarness{
+		store:   NewPostgresStore(store),
+		newUser: func(t *testing.T) string { return dbtest.User(t, store).String() },
+	})
+}
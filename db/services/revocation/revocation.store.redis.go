@@ -0,0 +1,80 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisTokenPrefix = "goauth:revoked:jti:"
+	redisUserPrefix  = "goauth:revoked:user:"
+)
+
+// RedisStore keeps revocations as keys that expire together with the tokens
+// they block.
+type RedisStore struct {
+	client redis.UniversalClient
+	// userTTL bounds how long a user cutoff is kept; it only has to outlive
+	// the longest token lifetime.
+	userTTL time.Duration
+}
+
+func NewRedisStore(client redis.UniversalClient, userTTL time.Duration) *RedisStore {
+	return &RedisStore{client: client, userTTL: userTTL}
+}
+
+func (r *RedisStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return r.client.Set(ctx, redisTokenPrefix+jti, 1, ttl).Err()
+}
+
+func (r *RedisStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := r.client.Exists(ctx, redisTokenPrefix+jti).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// revokeUserScript keeps the later of the stored and the new cutoff.
+var revokeUserScript = redis.NewScript(`
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local cutoff = tonumber(ARGV[1])
+if cutoff > current then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+else
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+func (r *RedisStore) RevokeUser(ctx context.Context, userID string, before time.Time) error {
+	return revokeUserScript.Run(ctx, r.client,
+		[]string{redisUserPrefix + userID},
+		before.UnixMilli(), r.userTTL.Milliseconds(),
+	).Err()
+}
+
+func (r *RedisStore) UserCutoff(ctx context.Context, userID string) (time.Time, error) {
+	value, err := r.client.Get(ctx, redisUserPrefix+userID).Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(millis), nil
+}
+
+var _ Store = (*RedisStore)(nil)
@@ -0,0 +1,65 @@
+package revocation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisStore(t *testing.T, userTTL time.Duration) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRedisStore(client, userTTL), server
+}
+
+func TestRedisStore(t *testing.T) {
+	store, _ := newTestRedisStore(t, time.Hour)
+	testStore(t, storeHarness{
+		store:   store,
+		newUser: func(*testing.T) string { return uuid.NewString() },
+	})
+}
+
+func TestRedisStoreTTL(t *testing.T) {
+	store, server := newTestRedisStore(t, 24*time.Hour)
+	ctx := context.Background()
+
+	jti := uuid.NewString()
+	if err := store.Revoke(ctx, jti, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.TTL(redisTokenPrefix + jti); (got - time.Hour).Abs() > time.Second {
+		t.Errorf("jti TTL = %v, want the token's remaining hour", got)
+	}
+	// An expired token needs no entry.
+	expired := uuid.NewString()
+	if err := store.Revoke(ctx, expired, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if server.Exists(redisTokenPrefix + expired) {
+		t.Error("Revoke stored an already expired token")
+	}
+
+	userID := uuid.NewString()
+	if err := store.RevokeUser(ctx, userID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	server.FastForward(12 * time.Hour)
+	// An earlier cutoff keeps the stored one but still renews its TTL.
+	if err := store.RevokeUser(ctx, userID, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.TTL(redisUserPrefix + userID); (got - 24*time.Hour).Abs() > time.Second {
+		t.Errorf("user TTL = %v, want it renewed to 24h", got)
+	}
+	server.FastForward(25 * time.Hour)
+	if cutoff, err := store.UserCutoff(ctx, userID); err != nil || !cutoff.IsZero() {
+		t.Errorf("UserCutoff after the TTL = %v, %v, want none", cutoff, err)
+	}
+}
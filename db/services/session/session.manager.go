@@ -0,0 +1,154 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultIdleTimeout is how long a session survives without being used.
+	DefaultIdleTimeout = 24 * time.Hour
+	// DefaultLastSeenInterval is how stale LastSeenAt may get before a
+	// request writes it again.
+	DefaultLastSeenInterval = 5 * time.Minute
+)
+
+var ErrNotSessionOwner = errors.New("session belongs to another user")
+
+// Manager issues opaque session tokens and resolves them with sliding
+// expiration: every use pushes expiry out to a full idle timeout again.
+type Manager struct {
+	store            Store
+	idleTimeout      time.Duration
+	lastSeenInterval time.Duration
+}
+
+type Option func(*Manager)
+
+func NewManager(store Store, idleTimeout time.Duration, opts ...Option) *Manager {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	manager := &Manager{
+		store:            store,
+		idleTimeout:      idleTimeout,
+		lastSeenInterval: DefaultLastSeenInterval,
+	}
+	for _, opt := range opts {
+		opt(manager)
+	}
+	return manager
+}
+
+// WithLastSeenInterval sets how often a session in use is written back.
+func WithLastSeenInterval(interval time.Duration) Option {
+	return func(m *Manager) {
+		m.lastSeenInterval = interval
+	}
+}
+
+// IdleTimeout is the lifetime of a fresh or just-extended session.
+func (m *Manager) IdleTimeout() time.Duration {
+	return m.idleTimeout
+}
+
+// Create starts a session for the user and returns the token for the cookie.
+// The token is never stored and cannot be recovered later.
+func (m *Manager) Create(ctx context.Context, userID uuid.UUID, userAgent, ipAddress string) (string, Session, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", Session{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	sess, err := m.store.Create(ctx, Session{
+		UserID:    userID,
+		TokenHash: HashToken(token),
+		ExpiresAt: time.Now().Add(m.idleTimeout),
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+	})
+	if err != nil {
+		return "", Session{}, err
+	}
+	return token, sess, nil
+}
+
+// Resolve looks up the session behind a cookie token. The session is only
+// written back once LastSeenAt is older than the last-seen interval or less
+// than half the idle timeout remains, which keeps most requests read-only.
+// extended reports whether the cookie should be re-set.
+func (m *Manager) Resolve(ctx context.Context, token string) (sess Session, extended bool, err error) {
+	if token == "" {
+		return Session{}, false, ErrSessionNotFound
+	}
+	sess, err = m.store.Get(ctx, HashToken(token))
+	if err != nil {
+		return Session{}, false, err
+	}
+
+	now := time.Now()
+	if sess.ExpiresAt.Sub(now) > m.idleTimeout/2 && now.Sub(sess.LastSeenAt) < m.lastSeenInterval {
+		return sess, false, nil
+	}
+	expiresAt := now.Add(m.idleTimeout)
+	if err := m.store.Touch(ctx, sess.ID, expiresAt); err != nil {
+		return Session{}, false, err
+	}
+	sess.ExpiresAt = expiresAt
+	sess.LastSeenAt = now
+	return sess, true, nil
+}
+
+// List returns the user's live sessions, most recently seen first.
+func (m *Manager) List(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	return m.store.List(ctx, userID)
+}
+
+// RevokeOwned deletes a session only if it belongs to userID, so one user
+// cannot sign out another by guessing ids.
+func (m *Manager) RevokeOwned(ctx context.Context, userID, id uuid.UUID) error {
+	sess, err := m.store.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if sess.UserID != userID {
+		return ErrNotSessionOwner
+	}
+	return m.store.Delete(ctx, id)
+}
+
+// Revoke deletes a single session.
+func (m *Manager) Revoke(ctx context.Context, id uuid.UUID) error {
+	return m.store.Delete(ctx, id)
+}
+
+// RevokeToken deletes the session behind a cookie token, if it still exists.
+func (m *Manager) RevokeToken(ctx context.Context, token string) error {
+	sess, err := m.store.Get(ctx, HashToken(token))
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return m.store.Delete(ctx, sess.ID)
+}
+
+// RevokeUser deletes every session belonging to the user.
+func (m *Manager) RevokeUser(ctx context.Context, userID uuid.UUID) error {
+	return m.store.DeleteUser(ctx, userID)
+}
+
+// HashToken is the form a session token is stored and looked up in.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,38 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrSessionNotFound = errors.New("session not found or expired")
+
+// Session is a server-side login. The cookie holds an opaque token; only its
+// SHA-256 hash is stored, so a leaked table cannot be replayed.
+type Session struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	UserAgent string
+	IPAddress string
+	CreatedAt time.Time
+	// LastSeenAt is updated at most every Manager last-seen interval.
+	LastSeenAt time.Time
+}
+
+// Store persists sessions. Get must not return expired sessions.
+type Store interface {
+	Create(ctx context.Context, sess Session) (Session, error)
+	Get(ctx context.Context, tokenHash string) (Session, error)
+	GetByID(ctx context.Context, id uuid.UUID) (Session, error)
+	// List returns the user's live sessions, most recently seen first.
+	List(ctx context.Context, userID uuid.UUID) ([]Session, error)
+	// Touch sets the new expiry and marks the session as seen now.
+	Touch(ctx context.Context, id uuid.UUID, expiresAt time.Time) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	DeleteUser(ctx context.Context, userID uuid.UUID) error
+}
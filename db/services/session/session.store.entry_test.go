@@ -0,0 +1,166 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// storeHarness runs one Store implementation through testStore, the
+// behaviour every implementation must share.
+type storeHarness struct {
+	store Store
+	// newUser returns a user sessions can belong to.
+	newUser func(t *testing.T) uuid.UUID
+	// elapse lets d pass as far as the store's expiry is concerned.
+	elapse func(t *testing.T, d time.Duration)
+}
+
+func newTestSession(userID uuid.UUID, expiresAt time.Time) Session {
+	return Session{
+		UserID:    userID,
+		TokenHash: uuid.NewString(),
+		ExpiresAt: expiresAt,
+		UserAgent: "test-agent",
+		IPAddress: "192.0.2.1",
+	}
+}
+
+func sameTime(a, b time.Time) bool {
+	return a.Sub(b).Abs() < time.Millisecond
+}
+
+func testStore(t *testing.T, h storeHarness) {
+	ctx := context.Background()
+	create := func(t *testing.T, userID uuid.UUID, ttl time.Duration) Session {
+		t.Helper()
+		sess, err := h.store.Create(ctx, newTestSession(userID, time.Now().Add(ttl)))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return sess
+	}
+
+	t.Run("create and get", func(t *testing.T) {
+		want := create(t, h.newUser(t), time.Hour)
+		if want.ID == uuid.Nil || want.CreatedAt.IsZero() {
+			t.Fatalf("Create returned %+v without an id or creation time", want)
+		}
+
+		byToken, err := h.store.Get(ctx, want.TokenHash)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		byID, err := h.store.GetByID(ctx, want.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		for _, got := range []Session{byToken, byID} {
+			if got.ID != want.ID || got.UserID != want.UserID || got.TokenHash != want.TokenHash ||
+				got.UserAgent != want.UserAgent || got.IPAddress != want.IPAddress ||
+				!sameTime(got.ExpiresAt, want.ExpiresAt) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		}
+
+		if _, err := h.store.Get(ctx, "unknown"); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("Get of an unknown token: %v, want ErrSessionNotFound", err)
+		}
+		if _, err := h.store.GetByID(ctx, uuid.New()); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("GetByID of an unknown id: %v, want ErrSessionNotFound", err)
+		}
+	})
+
+	t.Run("touch and list", func(t *testing.T) {
+		userID := h.newUser(t)
+		first := create(t, userID, time.Hour)
+		second := create(t, userID, time.Hour)
+		create(t, h.newUser(t), time.Hour)
+
+		expiresAt := time.Now().Add(2 * time.Hour)
+		if err := h.store.Touch(ctx, first.ID, expiresAt); err != nil {
+			t.Fatalf("Touch: %v", err)
+		}
+		touched, err := h.store.GetByID(ctx, first.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if !sameTime(touched.ExpiresAt, expiresAt) || !touched.LastSeenAt.After(first.LastSeenAt) {
+			t.Errorf("touched session = %+v, want it to expire at %v and be seen after %v",
+				touched, expiresAt, first.LastSeenAt)
+		}
+
+		sessions, err := h.store.List(ctx, userID)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(sessions) != 2 || sessions[0].ID != first.ID || sessions[1].ID != second.ID {
+			t.Errorf("List = %+v, want the touched session, then the other one", sessions)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		userID := h.newUser(t)
+		deleted := create(t, userID, time.Hour)
+		kept := create(t, userID, time.Hour)
+
+		if err := h.store.Delete(ctx, deleted.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := h.store.Get(ctx, deleted.TokenHash); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("Get of a deleted session: %v, want ErrSessionNotFound", err)
+		}
+		if _, err := h.store.Get(ctx, kept.TokenHash); err != nil {
+			t.Errorf("Delete took another session with it: %v", err)
+		}
+		if err := h.store.Delete(ctx, deleted.ID); err != nil {
+			t.Errorf("second Delete: %v", err)
+		}
+	})
+
+	t.Run("delete user", func(t *testing.T) {
+		userID, otherID := h.newUser(t), h.newUser(t)
+		revoked := []Session{create(t, userID, time.Hour), create(t, userID, 2*time.Hour)}
+		other := create(t, otherID, time.Hour)
+
+		if err := h.store.DeleteUser(ctx, userID); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		for _, sess := range revoked {
+			if _, err := h.store.Get(ctx, sess.TokenHash); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("Get of a revoked session: %v, want ErrSessionNotFound", err)
+			}
+		}
+		if sessions, err := h.store.List(ctx, userID); err != nil || len(sessions) != 0 {
+			t.Errorf("List after DeleteUser = %v, %v; want none", sessions, err)
+		}
+		if _, err := h.store.Get(ctx, other.TokenHash); err != nil {
+			t.Errorf("DeleteUser revoked another user's session: %v", err)
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		userID := h.newUser(t)
+		expiring := create(t, userID, time.Second)
+		lasting := create(t, userID, time.Hour)
+
+		h.elapse(t, 1500*time.Millisecond)
+
+		if _, err := h.store.Get(ctx, expiring.TokenHash); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("Get of an expired session: %v, want ErrSessionNotFound", err)
+		}
+		if _, err := h.store.GetByID(ctx, expiring.ID); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("GetByID of an expired session: %v, want ErrSessionNotFound", err)
+		}
+		sessions, err := h.store.List(ctx, userID)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(sessions) != 1 || sessions[0].ID != lasting.ID {
+			t.Errorf("List = %+v, want only the live session", sessions)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"time"
+
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PostgresStore keeps sessions in goauth_session.
+type PostgresStore struct {
+	store *db.Store
+}
+
+func NewPostgresStore(store *db.Store) *PostgresStore {
+	return &PostgresStore{store: store}
+}
+
+func (p *PostgresStore) Create(ctx context.Context, sess Session) (Session, error) {
+	var ip *netip.Addr
+	if addr, err := netip.ParseAddr(sess.IPAddress); err == nil {
+		ip = &addr
+	}
+	row, err := p.store.CreateSession(ctx, db.CreateSessionParams{
+		UserID:    sess.UserID,
+		Token:     sess.TokenHash,
+		ExpiresAt: pgtype.Timestamptz{Time: sess.ExpiresAt, Valid: true},
+		UserAgent: pgtype.Text{String: sess.UserAgent, Valid: sess.UserAgent != ""},
+		IpAddress: ip,
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	return fromRow(row), nil
+}
+
+func (p *PostgresStore) Get(ctx context.Context, tokenHash string) (Session, error) {
+	row, err := p.store.GetSession(ctx, tokenHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Session{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return Session{}, err
+	}
+	return fromRow(row), nil
+}
+
+func (p *PostgresStore) GetByID(ctx context.Context, id uuid.UUID) (Session, error) {
+	row, err := p.store.GetSessionByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Session{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return Session{}, err
+	}
+	return fromRow(row), nil
+}
+
+func (p *PostgresStore) List(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	rows, err := p.store.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, fromRow(row))
+	}
+	return sessions, nil
+}
+
+func (p *PostgresStore) Touch(ctx context.Context, id uuid.UUID, expiresAt time.Time) error {
+	return p.store.TouchSession(ctx, db.TouchSessionParams{
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+		ID:        id,
+	})
+}
+
+func (p *PostgresStore) Delete(ctx context.Context, id uuid.UUID) error {
+	return p.store.DeleteSession(ctx, id)
+}
+
+func (p *PostgresStore) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	return p.store.DeleteUserSessions(ctx, userID)
+}
+
+func fromRow(row db.GoauthSession) Session {
+	sess := Session{
+		ID:         row.ID,
+		UserID:     row.UserID,
+		TokenHash:  row.Token,
+		ExpiresAt:  row.ExpiresAt.Time,
+		UserAgent:  row.UserAgent.String,
+		CreatedAt:  row.CreatedAt.Time,
+		LastSeenAt: row.LastSeenAt.Time,
+	}
+	if row.IpAddress != nil {
+		sess.IPAddress = row.IpAddress.String()
+	}
+	return sess
+}
+
+var _ Store = (*PostgresStore)(nil)
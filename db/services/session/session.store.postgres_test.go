@@ -0,0 +1,18 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+	"github.com/google/uuid"
+)
+
+func TestPostgresStore(t *testing.T) {
+	store := dbtest.Store(t)
+	testStore(t, storeHarness{
+		store:   NewPostgresStore(store),
+		newUser: func(t *testing.T) uuid.UUID { return dbtest.User(t, store) },
+		elapse:  func(_ *testing.T, d time.Duration) { time.Sleep(d) },
+	})
+}
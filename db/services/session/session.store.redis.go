@@ -0,0 +1,218 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisSessionPrefix = "goauth:session:"
+	redisTokenPrefix   = "goauth:session:token:"
+	redisUserPrefix    = "goauth:session:user:"
+)
+
+// RedisStore mirrors goauth_session in Redis. Every key expires with its
+// session, and a per-user set of session ids backs DeleteUser.
+//
+//	goauth:session:<id>            JSON session
+//	goauth:session:token:<hash>    session id
+//	goauth:session:user:<user id>  set of session ids
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+type redisSession struct {
+	UserID     uuid.UUID `json:"user_id"`
+	TokenHash  string    `json:"token_hash"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+func (r *RedisStore) Create(ctx context.Context, sess Session) (Session, error) {
+	sess.ID = uuid.New()
+	sess.CreatedAt = time.Now()
+	sess.LastSeenAt = sess.CreatedAt
+	if err := r.save(ctx, sess); err != nil {
+		return Session{}, err
+	}
+	return sess, nil
+}
+
+func (r *RedisStore) Get(ctx context.Context, tokenHash string) (Session, error) {
+	id, err := r.client.Get(ctx, redisTokenPrefix+tokenHash).Result()
+	if errors.Is(err, redis.Nil) {
+		return Session{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return Session{}, err
+	}
+	sessionID, err := uuid.Parse(id)
+	if err != nil {
+		return Session{}, ErrSessionNotFound
+	}
+	return r.load(ctx, sessionID)
+}
+
+func (r *RedisStore) Touch(ctx context.Context, id uuid.UUID, expiresAt time.Time) error {
+	sess, err := r.load(ctx, id)
+	if err != nil {
+		return err
+	}
+	sess.ExpiresAt = expiresAt
+	sess.LastSeenAt = time.Now()
+	return r.save(ctx, sess)
+}
+
+func (r *RedisStore) GetByID(ctx context.Context, id uuid.UUID) (Session, error) {
+	return r.load(ctx, id)
+}
+
+// List loads every session in the user's index. Ids whose session has
+// expired are dropped from the index on the way.
+func (r *RedisStore) List(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	userKey := redisUserPrefix + userID.String()
+	ids, err := r.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(ids))
+	for _, id := range ids {
+		sessionID, err := uuid.Parse(id)
+		if err != nil {
+			continue
+		}
+		sess, err := r.load(ctx, sessionID)
+		if errors.Is(err, ErrSessionNotFound) {
+			r.client.SRem(ctx, userKey, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sess)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+func (r *RedisStore) Delete(ctx context.Context, id uuid.UUID) error {
+	sess, err := r.load(ctx, id)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, redisSessionPrefix+id.String(), redisTokenPrefix+sess.TokenHash)
+		pipe.SRem(ctx, redisUserPrefix+sess.UserID.String(), id.String())
+		return nil
+	})
+	return err
+}
+
+func (r *RedisStore) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	userKey := redisUserPrefix + userID.String()
+	ids, err := r.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := []string{userKey}
+	for _, id := range ids {
+		keys = append(keys, redisSessionPrefix+id)
+		value, err := r.client.Get(ctx, redisSessionPrefix+id).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var stored redisSession
+		if err := json.Unmarshal(value, &stored); err == nil {
+			keys = append(keys, redisTokenPrefix+stored.TokenHash)
+		}
+	}
+	return r.client.Del(ctx, keys...).Err()
+}
+
+// save writes the session, its token lookup and its index entry with the
+// session's remaining lifetime.
+func (r *RedisStore) save(ctx context.Context, sess Session) error {
+	ttl := time.Until(sess.ExpiresAt)
+	if ttl <= 0 {
+		return ErrSessionNotFound
+	}
+	value, err := json.Marshal(redisSession{
+		UserID:     sess.UserID,
+		TokenHash:  sess.TokenHash,
+		ExpiresAt:  sess.ExpiresAt,
+		UserAgent:  sess.UserAgent,
+		IPAddress:  sess.IPAddress,
+		CreatedAt:  sess.CreatedAt,
+		LastSeenAt: sess.LastSeenAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	userKey := redisUserPrefix + sess.UserID.String()
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, redisSessionPrefix+sess.ID.String(), value, ttl)
+		pipe.Set(ctx, redisTokenPrefix+sess.TokenHash, sess.ID.String(), ttl)
+		pipe.SAdd(ctx, userKey, sess.ID.String())
+		// Only ever lengthen the index TTL; other sessions may outlive this one.
+		pipe.Eval(ctx, `
+if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[1]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return 1`, []string{userKey}, ttl.Milliseconds())
+		return nil
+	})
+	return err
+}
+
+func (r *RedisStore) load(ctx context.Context, id uuid.UUID) (Session, error) {
+	value, err := r.client.Get(ctx, redisSessionPrefix+id.String()).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Session{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return Session{}, err
+	}
+	var stored redisSession
+	if err := json.Unmarshal(value, &stored); err != nil {
+		return Session{}, err
+	}
+	if !stored.ExpiresAt.After(time.Now()) {
+		return Session{}, ErrSessionNotFound
+	}
+	return Session{
+		ID:         id,
+		UserID:     stored.UserID,
+		TokenHash:  stored.TokenHash,
+		ExpiresAt:  stored.ExpiresAt,
+		UserAgent:  stored.UserAgent,
+		IPAddress:  stored.IPAddress,
+		CreatedAt:  stored.CreatedAt,
+		LastSeenAt: stored.LastSeenAt,
+	}, nil
+}
+
+var _ Store = (*RedisStore)(nil)
@@ -0,0 +1,121 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRedisStore(client), server
+}
+
+func TestRedisStore(t *testing.T) {
+	store, server := newTestRedisStore(t)
+	testStore(t, storeHarness{
+		store:   store,
+		newUser: func(*testing.T) uuid.UUID { return uuid.New() },
+		// Redis drops the keys when their TTL runs out, before the
+		// expires_at check on load would.
+		elapse: func(_ *testing.T, d time.Duration) { server.FastForward(d) },
+	})
+}
+
+func TestRedisStoreTTL(t *testing.T) {
+	store, server := newTestRedisStore(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	userKey := redisUserPrefix + userID.String()
+
+	assertTTL := func(t *testing.T, key string, want time.Duration) {
+		t.Helper()
+		if got := server.TTL(key); (got - want).Abs() > time.Second {
+			t.Errorf("TTL of %s = %v, want %v", key, got, want)
+		}
+	}
+
+	short, err := store.Create(ctx, newTestSession(userID, time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTTL(t, redisSessionPrefix+short.ID.String(), time.Hour)
+	assertTTL(t, redisTokenPrefix+short.TokenHash, time.Hour)
+	assertTTL(t, userKey, time.Hour)
+
+	long, err := store.Create(ctx, newTestSession(userID, time.Now().Add(3*time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The index lives as long as the longest session, and a shorter one
+	// touched later does not cut it down.
+	assertTTL(t, userKey, 3*time.Hour)
+	if err := store.Touch(ctx, short.ID, time.Now().Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	assertTTL(t, redisSessionPrefix+short.ID.String(), 2*time.Hour)
+	assertTTL(t, redisTokenPrefix+short.TokenHash, 2*time.Hour)
+	assertTTL(t, userKey, 3*time.Hour)
+
+	server.FastForward(2*time.Hour + time.Minute)
+	sessions, err := store.List(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != long.ID {
+		t.Errorf("List = %+v, want only the longer session", sessions)
+	}
+	members, err := server.SMembers(userKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || members[0] != long.ID.String() {
+		t.Errorf("index = %v, want the expired session dropped", members)
+	}
+
+	server.FastForward(time.Hour)
+	if server.Exists(userKey) {
+		t.Error("index outlived every session")
+	}
+}
+
+func TestRedisStoreDeleteUserLeavesNoKeys(t *testing.T) {
+	store, server := newTestRedisStore(t)
+	ctx := context.Background()
+	userID, otherID := uuid.New(), uuid.New()
+
+	for range 3 {
+		if _, err := store.Create(ctx, newTestSession(userID, time.Now().Add(time.Hour))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other, err := store.Create(ctx, newTestSession(otherID, time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.DeleteUser(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{
+		redisSessionPrefix + other.ID.String(): true,
+		redisTokenPrefix + other.TokenHash:     true,
+		redisUserPrefix + otherID.String():     true,
+	}
+	keys := server.Keys()
+	if len(keys) != len(want) {
+		t.Errorf("keys after DeleteUser = %v, want only the other user's", keys)
+	}
+	for _, key := range keys {
+		if !want[key] {
+			t.Errorf("DeleteUser left %s behind", key)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package session
+
+import "strings"
+
+// UserAgent is the coarse device description shown in session listings.
+type UserAgent struct {
+	Browser string `json:"browser"`
+	OS      string `json:"os"`
+	Device  string `json:"device"`
+}
+
+// Order matters: Edge and Opera also claim Chrome, Chrome also claims Safari.
+var (
+	uaBrowsers = []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	}
+	uaSystems = []struct{ token, name string }{
+		{"Windows", "Windows"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// ParseUserAgent recognises the common browsers and platforms. Anything else
+// is reported as "Unknown"; the raw header is kept on the session regardless.
+func ParseUserAgent(header string) UserAgent {
+	ua := UserAgent{Browser: "Unknown", OS: "Unknown", Device: "Desktop"}
+	for _, b := range uaBrowsers {
+		if strings.Contains(header, b.token) {
+			ua.Browser = b.name
+			break
+		}
+	}
+	for _, s := range uaSystems {
+		if strings.Contains(header, s.token) {
+			ua.OS = s.name
+			break
+		}
+	}
+
+	switch {
+	case strings.Contains(header, "iPad") || strings.Contains(header, "Tablet"):
+		ua.Device = "Tablet"
+	case strings.Contains(header, "Mobile") || strings.Contains(header, "iPhone"):
+		ua.Device = "Mobile"
+	case ua.OS == "Android":
+		ua.Device = "Tablet"
+	case header == "" || ua.Browser == "curl":
+		ua.Device = "Unknown"
+	}
+	return ua
+}
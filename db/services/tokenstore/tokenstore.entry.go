@@ -0,0 +1,64 @@
+package tokenstore
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Purpose selects which kind of one-time token a Store holds.
+type Purpose string
+
+const (
+	PasswordReset     Purpose = "password_reset"
+	EmailVerification Purpose = "email_verification"
+	MFAChallenge      Purpose = "mfa_challenge"
+	AccountUnlock     Purpose = "account_unlock"
+)
+
+var ErrTokenNotFound = errors.New("token not found or expired")
+
+// Record is a single-use token. Only the token's hash is ever stored.
+type Record struct {
+	UserID    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// Store holds one-time tokens for a single Purpose. Get and Consume must not
+// return expired tokens.
+type Store interface {
+	Create(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	Get(ctx context.Context, tokenHash string) (Record, error)
+	// Consume returns the token and deletes it in one step, so it can be
+	// redeemed only once even under concurrent requests.
+	Consume(ctx context.Context, tokenHash string) (Record, error)
+	// Latest returns the user's most recently created live token.
+	Latest(ctx context.Context, userID uuid.UUID) (Record, error)
+	Delete(ctx context.Context, tokenHash string) error
+	DeleteUser(ctx context.Context, userID uuid.UUID) error
+}
+
+// NewToken returns a random 256-bit token for a link or email, and the hash
+// to store for it.
+func NewToken() (token string, tokenHash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(raw)
+	return token, HashToken(token), nil
+}
+
+// HashToken is the form a token is stored and looked up in.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
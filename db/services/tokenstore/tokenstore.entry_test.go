@@ -0,0 +1,133 @@
+package tokenstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var purposes = []Purpose{PasswordReset, EmailVerification, MFAChallenge, AccountUnlock}
+
+// storeHarness runs one Store implementation through testStore, the
+// behaviour every implementation must share.
+type storeHarness struct {
+	store Store
+	// newUser returns a user tokens can belong to.
+	newUser func(t *testing.T) uuid.UUID
+	// elapse lets d pass as far as the store's expiry is concerned.
+	elapse func(t *testing.T, d time.Duration)
+}
+
+func testStore(t *testing.T, h storeHarness) {
+	ctx := context.Background()
+	create := func(t *testing.T, userID uuid.UUID, ttl time.Duration) string {
+		t.Helper()
+		_, tokenHash, err := NewToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.store.Create(ctx, userID, tokenHash, time.Now().Add(ttl)); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return tokenHash
+	}
+	assertGone := func(t *testing.T, tokenHash string) {
+		t.Helper()
+		if _, err := h.store.Get(ctx, tokenHash); !errors.Is(err, ErrTokenNotFound) {
+			t.Errorf("Get: %v, want ErrTokenNotFound", err)
+		}
+		if _, err := h.store.Consume(ctx, tokenHash); !errors.Is(err, ErrTokenNotFound) {
+			t.Errorf("Consume: %v, want ErrTokenNotFound", err)
+		}
+	}
+
+	t.Run("get and consume once", func(t *testing.T) {
+		userID := h.newUser(t)
+		tokenHash := create(t, userID, time.Hour)
+
+		record, err := h.store.Get(ctx, tokenHash)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if record.UserID != userID || record.TokenHash != tokenHash {
+			t.Errorf("Get = %+v, want the token of %s", record, userID)
+		}
+		if record, err = h.store.Consume(ctx, tokenHash); err != nil || record.UserID != userID {
+			t.Fatalf("Consume = %+v, %v", record, err)
+		}
+		assertGone(t, tokenHash)
+	})
+
+	t.Run("latest", func(t *testing.T) {
+		userID := h.newUser(t)
+		create(t, userID, 2*time.Hour)
+		time.Sleep(5 * time.Millisecond)
+		newest := create(t, userID, time.Hour)
+		create(t, h.newUser(t), time.Hour)
+
+		record, err := h.store.Latest(ctx, userID)
+		if err != nil {
+			t.Fatalf("Latest: %v", err)
+		}
+		if record.TokenHash != newest {
+			t.Errorf("Latest = %s, want the newest token %s", record.TokenHash, newest)
+		}
+		if _, err := h.store.Latest(ctx, h.newUser(t)); !errors.Is(err, ErrTokenNotFound) {
+			t.Errorf("Latest of a user without tokens: %v, want ErrTokenNotFound", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		userID := h.newUser(t)
+		deleted := create(t, userID, time.Hour)
+		kept := create(t, userID, time.Hour)
+
+		if err := h.store.Delete(ctx, deleted); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		assertGone(t, deleted)
+		if _, err := h.store.Get(ctx, kept); err != nil {
+			t.Errorf("Delete took another token with it: %v", err)
+		}
+	})
+
+	t.Run("delete user", func(t *testing.T) {
+		userID, otherID := h.newUser(t), h.newUser(t)
+		revoked := []string{create(t, userID, time.Hour), create(t, userID, 2*time.Hour)}
+		other := create(t, otherID, time.Hour)
+
+		if err := h.store.DeleteUser(ctx, userID); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		for _, tokenHash := range revoked {
+			assertGone(t, tokenHash)
+		}
+		if _, err := h.store.Latest(ctx, userID); !errors.Is(err, ErrTokenNotFound) {
+			t.Errorf("Latest after DeleteUser: %v, want ErrTokenNotFound", err)
+		}
+		if _, err := h.store.Get(ctx, other); err != nil {
+			t.Errorf("DeleteUser revoked another user's token: %v", err)
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		userID := h.newUser(t)
+		lasting := create(t, userID, time.Hour)
+		time.Sleep(5 * time.Millisecond)
+		expiring := create(t, userID, time.Second)
+
+		h.elapse(t, 1500*time.Millisecond)
+
+		assertGone(t, expiring)
+		record, err := h.store.Latest(ctx, userID)
+		if err != nil {
+			t.Fatalf("Latest: %v", err)
+		}
+		if record.TokenHash != lasting {
+			t.Errorf("Latest = %s, want the live token %s", record.TokenHash, lasting)
+		}
+	})
+}
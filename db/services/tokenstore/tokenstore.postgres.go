@@ -0,0 +1,146 @@
+package tokenstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PostgresStore keeps tokens in goauth_password_reset,
+// goauth_email_verification, goauth_mfa_challenge or goauth_account_unlock,
+// depending on its purpose.
+type PostgresStore struct {
+	store   *db.Store
+	purpose Purpose
+}
+
+// NewPostgresStore returns a store for purpose, which must be PasswordReset,
+// EmailVerification, MFAChallenge or AccountUnlock.
+func NewPostgresStore(store *db.Store, purpose Purpose) *PostgresStore {
+	return &PostgresStore{store: store, purpose: purpose}
+}
+
+func (p *PostgresStore) Create(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	expires := pgtype.Timestamptz{Time: expiresAt, Valid: true}
+	var err error
+	switch p.purpose {
+	case PasswordReset:
+		_, err = p.store.CreatePasswordResetToken(ctx, db.CreatePasswordResetTokenParams{
+			UserID:    userID,
+			Token:     tokenHash,
+			ExpiresAt: expires,
+		})
+	case MFAChallenge:
+		_, err = p.store.CreateMfaChallenge(ctx, db.CreateMfaChallengeParams{
+			UserID:    userID,
+			Token:     tokenHash,
+			ExpiresAt: expires,
+		})
+	case AccountUnlock:
+		_, err = p.store.CreateAccountUnlockToken(ctx, db.CreateAccountUnlockTokenParams{
+			UserID:    userID,
+			Token:     tokenHash,
+			ExpiresAt: expires,
+		})
+	default:
+		_, err = p.store.CreateEmailVerificationToken(ctx, db.CreateEmailVerificationTokenParams{
+			UserID:    userID,
+			Token:     tokenHash,
+			ExpiresAt: expires,
+		})
+	}
+	return err
+}
+
+func (p *PostgresStore) Get(ctx context.Context, tokenHash string) (Record, error) {
+	switch p.purpose {
+	case PasswordReset:
+		row, err := p.store.GetPasswordResetToken(ctx, tokenHash)
+		return toRecord(db.GoauthEmailVerification(row), err)
+	case MFAChallenge:
+		row, err := p.store.GetMfaChallenge(ctx, tokenHash)
+		return toRecord(db.GoauthEmailVerification(row), err)
+	case AccountUnlock:
+		row, err := p.store.GetAccountUnlockToken(ctx, tokenHash)
+		return toRecord(db.GoauthEmailVerification(row), err)
+	}
+	return toRecord(p.store.GetEmailVerificationToken(ctx, tokenHash))
+}
+
+func (p *PostgresStore) Consume(ctx context.Context, tokenHash string) (Record, error) {
+	switch p.purpose {
+	case PasswordReset:
+		row, err := p.store.ConsumePasswordResetToken(ctx, tokenHash)
+		return toRecord(db.GoauthEmailVerification(row), err)
+	case MFAChallenge:
+		row, err := p.store.ConsumeMfaChallenge(ctx, tokenHash)
+		return toRecord(db.GoauthEmailVerification(row), err)
+	case AccountUnlock:
+		row, err := p.store.ConsumeAccountUnlockToken(ctx, tokenHash)
+		return toRecord(db.GoauthEmailVerification(row), err)
+	}
+	return toRecord(p.store.ConsumeEmailVerificationToken(ctx, tokenHash))
+}
+
+func (p *PostgresStore) Latest(ctx context.Context, userID uuid.UUID) (Record, error) {
+	switch p.purpose {
+	case PasswordReset:
+		row, err := p.store.GetLatestPasswordResetToken(ctx, userID)
+		return toRecord(db.GoauthEmailVerification(row), err)
+	case MFAChallenge:
+		row, err := p.store.GetLatestMfaChallenge(ctx, userID)
+		return toRecord(db.GoauthEmailVerification(row), err)
+	case AccountUnlock:
+		row, err := p.store.GetLatestAccountUnlockToken(ctx, userID)
+		return toRecord(db.GoauthEmailVerification(row), err)
+	}
+	return toRecord(p.store.GetLatestEmailVerificationToken(ctx, userID))
+}
+
+func (p *PostgresStore) Delete(ctx context.Context, tokenHash string) error {
+	switch p.purpose {
+	case PasswordReset:
+		return p.store.DeletePasswordResetToken(ctx, tokenHash)
+	case MFAChallenge:
+		return p.store.DeleteMfaChallenge(ctx, tokenHash)
+	case AccountUnlock:
+		return p.store.DeleteAccountUnlockToken(ctx, tokenHash)
+	}
+	return p.store.DeleteEmailVerificationToken(ctx, tokenHash)
+}
+
+func (p *PostgresStore) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	switch p.purpose {
+	case PasswordReset:
+		return p.store.DeleteUserPasswordResetTokens(ctx, userID)
+	case MFAChallenge:
+		return p.store.DeleteUserMfaChallenges(ctx, userID)
+	case AccountUnlock:
+		return p.store.DeleteUserAccountUnlockTokens(ctx, userID)
+	}
+	return p.store.DeleteUserEmailVerificationTokens(ctx, userID)
+}
+
+// toRecord maps a token row to a Record. The token tables share one
+// shape, so their generated models convert to one another directly.
+func toRecord(row db.GoauthEmailVerification, err error) (Record, error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Record{}, ErrTokenNotFound
+	}
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{
+		UserID:    row.UserID,
+		TokenHash: row.Token,
+		ExpiresAt: row.ExpiresAt.Time,
+		CreatedAt: row.CreatedAt.Time,
+	}, nil
+}
+
+var _ Store = (*PostgresStore)(nil)
@@ -0,0 +1,22 @@
+package tokenstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/dbtest"
+	"github.com/google/uuid"
+)
+
+func TestPostgresStore(t *testing.T) {
+	store := dbtest.Store(t)
+	for _, purpose := range purposes {
+		t.Run(string(purpose), func(t *testing.T) {
+			testStore(t, storeHarness{
+				store:   NewPostgresStore(store, purpose),
+				newUser: func(t *testing.T) uuid.UUID { return dbtest.User(t, store) },
+				elapse:  func(_ *testing.T, d time.Duration) { time.Sleep(d) },
+			})
+		})
+	}
+}
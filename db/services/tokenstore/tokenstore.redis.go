@@ -0,0 +1,173 @@
+package tokenstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps each token as a key expiring with the token, plus a
+// per-user set of token hashes so DeleteUser can find them.
+//
+//	goauth:<purpose>:<hash>          JSON record, TTL until expiry
+//	goauth:<purpose>:user:<user id>  set of hashes, TTL of the newest token
+type RedisStore struct {
+	client  redis.UniversalClient
+	purpose Purpose
+}
+
+func NewRedisStore(client redis.UniversalClient, purpose Purpose) *RedisStore {
+	return &RedisStore{client: client, purpose: purpose}
+}
+
+type redisRecord struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (r *RedisStore) tokenKey(tokenHash string) string {
+	return "goauth:" + string(r.purpose) + ":" + tokenHash
+}
+
+func (r *RedisStore) userKey(userID uuid.UUID) string {
+	return "goauth:" + string(r.purpose) + ":user:" + userID.String()
+}
+
+func (r *RedisStore) Create(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	value, err := json.Marshal(redisRecord{UserID: userID, ExpiresAt: expiresAt, CreatedAt: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	userKey := r.userKey(userID)
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, r.tokenKey(tokenHash), value, ttl)
+		pipe.SAdd(ctx, userKey, tokenHash)
+		extendTTL(ctx, pipe, userKey, ttl)
+		return nil
+	})
+	return err
+}
+
+func (r *RedisStore) Get(ctx context.Context, tokenHash string) (Record, error) {
+	value, err := r.client.Get(ctx, r.tokenKey(tokenHash)).Bytes()
+	return r.decode(tokenHash, value, err)
+}
+
+func (r *RedisStore) Consume(ctx context.Context, tokenHash string) (Record, error) {
+	var get *redis.StringCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, r.tokenKey(tokenHash))
+		pipe.Del(ctx, r.tokenKey(tokenHash))
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return Record{}, err
+	}
+	value, err := get.Bytes()
+	record, err := r.decode(tokenHash, value, err)
+	if err != nil {
+		return Record{}, err
+	}
+	r.client.SRem(ctx, r.userKey(record.UserID), tokenHash)
+	return record, nil
+}
+
+func (r *RedisStore) Latest(ctx context.Context, userID uuid.UUID) (Record, error) {
+	hashes, err := r.client.SMembers(ctx, r.userKey(userID)).Result()
+	if err != nil {
+		return Record{}, err
+	}
+
+	var latest Record
+	for _, hash := range hashes {
+		record, err := r.Get(ctx, hash)
+		if errors.Is(err, ErrTokenNotFound) {
+			continue
+		}
+		if err != nil {
+			return Record{}, err
+		}
+		if record.CreatedAt.After(latest.CreatedAt) {
+			latest = record
+		}
+	}
+	if latest.TokenHash == "" {
+		return Record{}, ErrTokenNotFound
+	}
+	return latest, nil
+}
+
+func (r *RedisStore) Delete(ctx context.Context, tokenHash string) error {
+	record, err := r.Get(ctx, tokenHash)
+	if errors.Is(err, ErrTokenNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, r.tokenKey(tokenHash))
+		pipe.SRem(ctx, r.userKey(record.UserID), tokenHash)
+		return nil
+	})
+	return err
+}
+
+func (r *RedisStore) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	userKey := r.userKey(userID)
+	hashes, err := r.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(hashes)+1)
+	for _, hash := range hashes {
+		keys = append(keys, r.tokenKey(hash))
+	}
+	keys = append(keys, userKey)
+	return r.client.Del(ctx, keys...).Err()
+}
+
+func (r *RedisStore) decode(tokenHash string, value []byte, err error) (Record, error) {
+	if errors.Is(err, redis.Nil) {
+		return Record{}, ErrTokenNotFound
+	}
+	if err != nil {
+		return Record{}, err
+	}
+	var record redisRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return Record{}, err
+	}
+	if !record.ExpiresAt.After(time.Now()) {
+		return Record{}, ErrTokenNotFound
+	}
+	return Record{
+		UserID:    record.UserID,
+		TokenHash: tokenHash,
+		ExpiresAt: record.ExpiresAt,
+		CreatedAt: record.CreatedAt,
+	}, nil
+}
+
+// extendTTL raises a key's TTL but never lowers it, so an index set lives as
+// long as its longest-lived member. PTTL is negative for a key without expiry.
+// EVAL rather than EVALSHA: the NOSCRIPT fallback cannot run inside MULTI.
+func extendTTL(ctx context.Context, pipe redis.Pipeliner, key string, ttl time.Duration) {
+	pipe.Eval(ctx, `
+if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[1]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return 1`, []string{key}, ttl.Milliseconds())
+}
+
+var _ Store = (*RedisStore)(nil)
@@ -0,0 +1,115 @@
+package tokenstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisStore(t *testing.T, purpose Purpose) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRedisStore(client, purpose), server
+}
+
+func TestRedisStore(t *testing.T) {
+	for _, purpose := range purposes {
+		t.Run(string(purpose), func(t *testing.T) {
+			store, server := newTestRedisStore(t, purpose)
+			testStore(t, storeHarness{
+				store:   store,
+				newUser: func(*testing.T) uuid.UUID { return uuid.New() },
+				// Redis drops the keys when their TTL runs out, before
+				// the expires_at check on decode would.
+				elapse: func(_ *testing.T, d time.Duration) { server.FastForward(d) },
+			})
+		})
+	}
+}
+
+func TestRedisStoreTTL(t *testing.T) {
+	store, server := newTestRedisStore(t, PasswordReset)
+	ctx := context.Background()
+	userID := uuid.New()
+	userKey := store.userKey(userID)
+
+	assertTTL := func(t *testing.T, key string, want time.Duration) {
+		t.Helper()
+		if got := server.TTL(key); (got - want).Abs() > time.Second {
+			t.Errorf("TTL of %s = %v, want %v", key, got, want)
+		}
+	}
+	create := func(ttl time.Duration) string {
+		t.Helper()
+		_, tokenHash, err := NewToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Create(ctx, userID, tokenHash, time.Now().Add(ttl)); err != nil {
+			t.Fatal(err)
+		}
+		return tokenHash
+	}
+
+	long := create(2 * time.Hour)
+	short := create(time.Hour)
+	assertTTL(t, store.tokenKey(long), 2*time.Hour)
+	assertTTL(t, store.tokenKey(short), time.Hour)
+	// The index lives as long as its longest-lived token.
+	assertTTL(t, userKey, 2*time.Hour)
+
+	server.FastForward(time.Hour + time.Minute)
+	if server.Exists(store.tokenKey(short)) {
+		t.Error("token outlived its expiry")
+	}
+	if !server.Exists(userKey) {
+		t.Fatal("index expired with the shorter token")
+	}
+	server.FastForward(time.Hour)
+	if server.Exists(userKey) {
+		t.Error("index outlived every token")
+	}
+}
+
+func TestRedisStoreDeleteUserLeavesNoKeys(t *testing.T) {
+	store, server := newTestRedisStore(t, EmailVerification)
+	ctx := context.Background()
+	userID, otherID := uuid.New(), uuid.New()
+
+	for range 3 {
+		_, tokenHash, err := NewToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Create(ctx, userID, tokenHash, time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, otherHash, err := NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(ctx, otherID, otherHash, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.DeleteUser(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{store.tokenKey(otherHash): true, store.userKey(otherID): true}
+	keys := server.Keys()
+	if len(keys) != len(want) {
+		t.Errorf("keys after DeleteUser = %v, want only the other user's", keys)
+	}
+	for _, key := range keys {
+		if !want[key] {
+			t.Errorf("DeleteUser left %s behind", key)
+		}
+	}
+}
@@ -13,6 +13,131 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const consumeAccountUnlockToken = `-- name: ConsumeAccountUnlockToken :one
+DELETE FROM goauth_account_unlock
+WHERE token = $1 AND expires_at > NOW()
+RETURNING id, user_id, token, expires_at, created_at
+`
+
+func (q *Queries) ConsumeAccountUnlockToken(ctx context.Context, token string) (GoauthAccountUnlock, error) {
+	row := q.db.QueryRow(ctx, consumeAccountUnlockToken, token)
+	var i GoauthAccountUnlock
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const consumeEmailVerificationToken = `-- name: ConsumeEmailVerificationToken :one
+DELETE FROM goauth_email_verification
+WHERE token = $1 AND expires_at > NOW()
+RETURNING id, user_id, token, expires_at, created_at
+`
+
+func (q *Queries) ConsumeEmailVerificationToken(ctx context.Context, token string) (GoauthEmailVerification, error) {
+	row := q.db.QueryRow(ctx, consumeEmailVerificationToken, token)
+	var i GoauthEmailVerification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const consumeMfaChallenge = `-- name: ConsumeMfaChallenge :one
+DELETE FROM goauth_mfa_challenge
+WHERE token = $1 AND expires_at > NOW()
+RETURNING id, user_id, token, expires_at, created_at
+`
+
+func (q *Queries) ConsumeMfaChallenge(ctx context.Context, token string) (GoauthMfaChallenge, error) {
+	row := q.db.QueryRow(ctx, consumeMfaChallenge, token)
+	var i GoauthMfaChallenge
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const consumePasswordResetToken = `-- name: ConsumePasswordResetToken :one
+DELETE FROM goauth_password_reset
+WHERE token = $1 AND expires_at > NOW()
+RETURNING id, user_id, token, expires_at, created_at
+`
+
+func (q *Queries) ConsumePasswordResetToken(ctx context.Context, token string) (GoauthPasswordReset, error) {
+	row := q.db.QueryRow(ctx, consumePasswordResetToken, token)
+	var i GoauthPasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const consumeWebauthnChallenge = `-- name: ConsumeWebauthnChallenge :one
+DELETE FROM goauth_webauthn_challenge
+WHERE challenge = $1 AND expires_at > NOW()
+RETURNING session_data
+`
+
+func (q *Queries) ConsumeWebauthnChallenge(ctx context.Context, challenge string) ([]byte, error) {
+	row := q.db.QueryRow(ctx, consumeWebauthnChallenge, challenge)
+	var session_data []byte
+	err := row.Scan(&session_data)
+	return session_data, err
+}
+
+const countUnusedRecoveryCodes = `-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*) FROM goauth_recovery_code
+WHERE user_id = $1 AND used_at IS NULL
+`
+
+func (q *Queries) CountUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countUnusedRecoveryCodes, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUserAccounts = `-- name: CountUserAccounts :one
+SELECT COUNT(*) FROM goauth_account
+WHERE user_id = $1
+`
+
+func (q *Queries) CountUserAccounts(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countUserAccounts, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUserWebauthnCredentials = `-- name: CountUserWebauthnCredentials :one
+SELECT COUNT(*) FROM goauth_webauthn_credential
+WHERE user_id = $1
+`
+
+func (q *Queries) CountUserWebauthnCredentials(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countUserWebauthnCredentials, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createAccount = `-- name: CreateAccount :one
 INSERT INTO goauth_account (
     user_id,
@@ -45,6 +170,59 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (G
 	return i, err
 }
 
+const createAccountUnlockToken = `-- name: CreateAccountUnlockToken :one
+INSERT INTO goauth_account_unlock (
+    user_id,
+    token,
+    expires_at
+) VALUES (
+             $1,
+             $2,
+             $3
+         ) RETURNING id, user_id, token, expires_at, created_at
+`
+
+type CreateAccountUnlockTokenParams struct {
+	UserID    uuid.UUID          `db:"user_id" json:"userId"`
+	Token     string             `db:"token" json:"token"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+}
+
+// sql/queries/account_unlock.sql
+func (q *Queries) CreateAccountUnlockToken(ctx context.Context, arg CreateAccountUnlockTokenParams) (GoauthAccountUnlock, error) {
+	row := q.db.QueryRow(ctx, createAccountUnlockToken, arg.UserID, arg.Token, arg.ExpiresAt)
+	var i GoauthAccountUnlock
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createAuditLog = `-- name: CreateAuditLog :exec
+INSERT INTO goauth_audit_log (
+    event_type,
+    log_entry
+) VALUES (
+             $1,
+             $2
+         )
+`
+
+type CreateAuditLogParams struct {
+	EventType string `db:"event_type" json:"eventType"`
+	LogEntry  []byte `db:"log_entry" json:"logEntry"`
+}
+
+// sql/queries/audit_log.sql
+func (q *Queries) CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) error {
+	_, err := q.db.Exec(ctx, createAuditLog, arg.EventType, arg.LogEntry)
+	return err
+}
+
 const createEmailVerificationToken = `-- name: CreateEmailVerificationToken :one
 INSERT INTO goauth_email_verification (
     user_id,
@@ -77,6 +255,58 @@ func (q *Queries) CreateEmailVerificationToken(ctx context.Context, arg CreateEm
 	return i, err
 }
 
+const createMfaChallenge = `-- name: CreateMfaChallenge :one
+INSERT INTO goauth_mfa_challenge (
+    user_id,
+    token,
+    expires_at
+) VALUES (
+             $1,
+             $2,
+             $3
+         ) RETURNING id, user_id, token, expires_at, created_at
+`
+
+type CreateMfaChallengeParams struct {
+	UserID    uuid.UUID          `db:"user_id" json:"userId"`
+	Token     string             `db:"token" json:"token"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+}
+
+// sql/queries/mfa_challenge.sql
+func (q *Queries) CreateMfaChallenge(ctx context.Context, arg CreateMfaChallengeParams) (GoauthMfaChallenge, error) {
+	row := q.db.QueryRow(ctx, createMfaChallenge, arg.UserID, arg.Token, arg.ExpiresAt)
+	var i GoauthMfaChallenge
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createPasswordHistory = `-- name: CreatePasswordHistory :exec
+INSERT INTO goauth_password_history (
+    user_id,
+    hash_password
+) VALUES (
+             $1,
+             $2
+         )
+`
+
+type CreatePasswordHistoryParams struct {
+	UserID       uuid.UUID `db:"user_id" json:"userId"`
+	HashPassword string    `db:"hash_password" json:"hashPassword"`
+}
+
+func (q *Queries) CreatePasswordHistory(ctx context.Context, arg CreatePasswordHistoryParams) error {
+	_, err := q.db.Exec(ctx, createPasswordHistory, arg.UserID, arg.HashPassword)
+	return err
+}
+
 const createPasswordResetToken = `-- name: CreatePasswordResetToken :one
 INSERT INTO goauth_password_reset (
     user_id,
@@ -109,6 +339,67 @@ func (q *Queries) CreatePasswordResetToken(ctx context.Context, arg CreatePasswo
 	return i, err
 }
 
+const createRecoveryCodes = `-- name: CreateRecoveryCodes :exec
+INSERT INTO goauth_recovery_code (
+    user_id,
+    code_hash
+)
+SELECT $1, unnest($2::text[])
+`
+
+type CreateRecoveryCodesParams struct {
+	UserID     uuid.UUID `db:"user_id" json:"userId"`
+	CodeHashes []string  `db:"code_hashes" json:"codeHashes"`
+}
+
+// sql/queries/recovery_codes.sql
+func (q *Queries) CreateRecoveryCodes(ctx context.Context, arg CreateRecoveryCodesParams) error {
+	_, err := q.db.Exec(ctx, createRecoveryCodes, arg.UserID, arg.CodeHashes)
+	return err
+}
+
+const createRefreshToken = `-- name: CreateRefreshToken :one
+INSERT INTO goauth_refresh_token (
+    id,
+    family_id,
+    user_id,
+    expires_at
+) VALUES (
+             $1,
+             $2,
+             $3,
+             $4
+         ) RETURNING id, family_id, user_id, expires_at, replaced_by, revoked_at, created_at
+`
+
+type CreateRefreshTokenParams struct {
+	ID        uuid.UUID          `db:"id" json:"id"`
+	FamilyID  uuid.UUID          `db:"family_id" json:"familyId"`
+	UserID    uuid.UUID          `db:"user_id" json:"userId"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+}
+
+// sql/queries/refresh_tokens.sql
+func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (GoauthRefreshToken, error) {
+	row := q.db.QueryRow(ctx, createRefreshToken,
+		arg.ID,
+		arg.FamilyID,
+		arg.UserID,
+		arg.ExpiresAt,
+	)
+	var i GoauthRefreshToken
+	err := row.Scan(
+		&i.ID,
+		&i.FamilyID,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.ReplacedBy,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createSession = `-- name: CreateSession :one
 INSERT INTO goauth_session (
     user_id,
@@ -122,7 +413,7 @@ INSERT INTO goauth_session (
              $3,
              $4,
              $5
-         ) RETURNING id, user_id, token, expires_at, user_agent, ip_address, created_at
+         ) RETURNING id, user_id, token, expires_at, user_agent, ip_address, created_at, last_seen_at
 `
 
 type CreateSessionParams struct {
@@ -151,11 +442,82 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (G
 		&i.UserAgent,
 		&i.IpAddress,
 		&i.CreatedAt,
+		&i.LastSeenAt,
 	)
 	return i, err
 }
 
-const deleteAccount = `-- name: DeleteAccount :exec
+const createWebauthnChallenge = `-- name: CreateWebauthnChallenge :exec
+INSERT INTO goauth_webauthn_challenge (
+    challenge,
+    session_data,
+    expires_at
+) VALUES (
+             $1,
+             $2,
+             $3
+         )
+`
+
+type CreateWebauthnChallengeParams struct {
+	Challenge   string             `db:"challenge" json:"challenge"`
+	SessionData []byte             `db:"session_data" json:"sessionData"`
+	ExpiresAt   pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+}
+
+func (q *Queries) CreateWebauthnChallenge(ctx context.Context, arg CreateWebauthnChallengeParams) error {
+	_, err := q.db.Exec(ctx, createWebauthnChallenge, arg.Challenge, arg.SessionData, arg.ExpiresAt)
+	return err
+}
+
+const createWebauthnCredential = `-- name: CreateWebauthnCredential :one
+INSERT INTO goauth_webauthn_credential (
+    user_id,
+    credential_id,
+    name,
+    credential,
+    sign_count
+) VALUES (
+             $1,
+             $2,
+             $3,
+             $4,
+             $5
+         ) RETURNING id, user_id, credential_id, name, credential, sign_count, created_at, last_used_at
+`
+
+type CreateWebauthnCredentialParams struct {
+	UserID       uuid.UUID `db:"user_id" json:"userId"`
+	CredentialID []byte    `db:"credential_id" json:"credentialId"`
+	Name         string    `db:"name" json:"name"`
+	Credential   []byte    `db:"credential" json:"credential"`
+	SignCount    int64     `db:"sign_count" json:"signCount"`
+}
+
+// sql/queries/webauthn.sql
+func (q *Queries) CreateWebauthnCredential(ctx context.Context, arg CreateWebauthnCredentialParams) (GoauthWebauthnCredential, error) {
+	row := q.db.QueryRow(ctx, createWebauthnCredential,
+		arg.UserID,
+		arg.CredentialID,
+		arg.Name,
+		arg.Credential,
+		arg.SignCount,
+	)
+	var i GoauthWebauthnCredential
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.CredentialID,
+		&i.Name,
+		&i.Credential,
+		&i.SignCount,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
+
+const deleteAccount = `-- name: DeleteAccount :execrows
 DELETE FROM goauth_account
 WHERE user_id = $1 AND provider = $2
 `
@@ -165,8 +527,20 @@ type DeleteAccountParams struct {
 	Provider string    `db:"provider" json:"provider"`
 }
 
-func (q *Queries) DeleteAccount(ctx context.Context, arg DeleteAccountParams) error {
-	_, err := q.db.Exec(ctx, deleteAccount, arg.UserID, arg.Provider)
+func (q *Queries) DeleteAccount(ctx context.Context, arg DeleteAccountParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAccount, arg.UserID, arg.Provider)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteAccountUnlockToken = `-- name: DeleteAccountUnlockToken :exec
+DELETE FROM goauth_account_unlock WHERE token = $1
+`
+
+func (q *Queries) DeleteAccountUnlockToken(ctx context.Context, token string) error {
+	_, err := q.db.Exec(ctx, deleteAccountUnlockToken, token)
 	return err
 }
 
@@ -206,6 +580,24 @@ func (q *Queries) DeleteExpiredSessions(ctx context.Context) error {
 	return err
 }
 
+const deleteLoginAttempt = `-- name: DeleteLoginAttempt :exec
+DELETE FROM goauth_login_attempt WHERE key = $1
+`
+
+func (q *Queries) DeleteLoginAttempt(ctx context.Context, key string) error {
+	_, err := q.db.Exec(ctx, deleteLoginAttempt, key)
+	return err
+}
+
+const deleteMfaChallenge = `-- name: DeleteMfaChallenge :exec
+DELETE FROM goauth_mfa_challenge WHERE token = $1
+`
+
+func (q *Queries) DeleteMfaChallenge(ctx context.Context, token string) error {
+	_, err := q.db.Exec(ctx, deleteMfaChallenge, token)
+	return err
+}
+
 const deletePasswordResetToken = `-- name: DeletePasswordResetToken :exec
 DELETE FROM goauth_password_reset WHERE token = $1
 `
@@ -224,6 +616,15 @@ func (q *Queries) DeleteSession(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteTotpStep = `-- name: DeleteTotpStep :exec
+DELETE FROM goauth_totp_step WHERE user_id = $1
+`
+
+func (q *Queries) DeleteTotpStep(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteTotpStep, userID)
+	return err
+}
+
 const deleteUser = `-- name: DeleteUser :exec
 DELETE FROM goauth_user WHERE id = $1
 `
@@ -233,6 +634,15 @@ func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteUserAccountUnlockTokens = `-- name: DeleteUserAccountUnlockTokens :exec
+DELETE FROM goauth_account_unlock WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserAccountUnlockTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUserAccountUnlockTokens, userID)
+	return err
+}
+
 const deleteUserEmailVerificationTokens = `-- name: DeleteUserEmailVerificationTokens :exec
 DELETE FROM goauth_email_verification WHERE user_id = $1
 `
@@ -242,6 +652,15 @@ func (q *Queries) DeleteUserEmailVerificationTokens(ctx context.Context, userID
 	return err
 }
 
+const deleteUserMfaChallenges = `-- name: DeleteUserMfaChallenges :exec
+DELETE FROM goauth_mfa_challenge WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserMfaChallenges(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUserMfaChallenges, userID)
+	return err
+}
+
 const deleteUserPasswordResetTokens = `-- name: DeleteUserPasswordResetTokens :exec
 DELETE FROM goauth_password_reset WHERE user_id = $1
 `
@@ -251,6 +670,15 @@ func (q *Queries) DeleteUserPasswordResetTokens(ctx context.Context, userID uuid
 	return err
 }
 
+const deleteUserRecoveryCodes = `-- name: DeleteUserRecoveryCodes :exec
+DELETE FROM goauth_recovery_code WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUserRecoveryCodes, userID)
+	return err
+}
+
 const deleteUserSessions = `-- name: DeleteUserSessions :exec
 DELETE FROM goauth_session WHERE user_id = $1
 `
@@ -260,6 +688,35 @@ func (q *Queries) DeleteUserSessions(ctx context.Context, userID uuid.UUID) erro
 	return err
 }
 
+const deleteWebauthnCredential = `-- name: DeleteWebauthnCredential :execrows
+DELETE FROM goauth_webauthn_credential
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteWebauthnCredentialParams struct {
+	ID     uuid.UUID `db:"id" json:"id"`
+	UserID uuid.UUID `db:"user_id" json:"userId"`
+}
+
+func (q *Queries) DeleteWebauthnCredential(ctx context.Context, arg DeleteWebauthnCredentialParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWebauthnCredential, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const forgiveLoginFailure = `-- name: ForgiveLoginFailure :exec
+UPDATE goauth_login_attempt
+SET failures = GREATEST(failures - 1, 0)
+WHERE key = $1
+`
+
+func (q *Queries) ForgiveLoginFailure(ctx context.Context, key string) error {
+	_, err := q.db.Exec(ctx, forgiveLoginFailure, key)
+	return err
+}
+
 const getAccountByProvider = `-- name: GetAccountByProvider :one
 SELECT a.id, a.user_id, a.provider, a.provider_id, a.created_at, u.id, u.email, u.hash_password, u.name, u.image, u.role_name, u.email_verified, u.two_factor_enabled, u.two_factor_secret, u.metadata, u.created_at, u.updated_at FROM goauth_account a
                          JOIN goauth_user u ON a.user_id = u.id
@@ -291,39 +748,200 @@ type GetAccountByProviderRow struct {
 	UpdatedAt        pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
 }
 
-func (q *Queries) GetAccountByProvider(ctx context.Context, arg GetAccountByProviderParams) (GetAccountByProviderRow, error) {
-	row := q.db.QueryRow(ctx, getAccountByProvider, arg.Provider, arg.ProviderID)
-	var i GetAccountByProviderRow
+func (q *Queries) GetAccountByProvider(ctx context.Context, arg GetAccountByProviderParams) (GetAccountByProviderRow, error) {
+	row := q.db.QueryRow(ctx, getAccountByProvider, arg.Provider, arg.ProviderID)
+	var i GetAccountByProviderRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Provider,
+		&i.ProviderID,
+		&i.CreatedAt,
+		&i.ID_2,
+		&i.Email,
+		&i.HashPassword,
+		&i.Name,
+		&i.Image,
+		&i.RoleName,
+		&i.EmailVerified,
+		&i.TwoFactorEnabled,
+		&i.TwoFactorSecret,
+		&i.Metadata,
+		&i.CreatedAt_2,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getAccountTokenForUpdate = `-- name: GetAccountTokenForUpdate :one
+SELECT t.account_id, t.access_token, t.refresh_token, t.token_type, t.scopes, t.expires_at, t.updated_at FROM goauth_account_token t
+                    JOIN goauth_account a ON a.id = t.account_id
+WHERE a.user_id = $1 AND a.provider = $2
+FOR UPDATE OF t
+`
+
+type GetAccountTokenForUpdateParams struct {
+	UserID   uuid.UUID `db:"user_id" json:"userId"`
+	Provider string    `db:"provider" json:"provider"`
+}
+
+func (q *Queries) GetAccountTokenForUpdate(ctx context.Context, arg GetAccountTokenForUpdateParams) (GoauthAccountToken, error) {
+	row := q.db.QueryRow(ctx, getAccountTokenForUpdate, arg.UserID, arg.Provider)
+	var i GoauthAccountToken
+	err := row.Scan(
+		&i.AccountID,
+		&i.AccessToken,
+		&i.RefreshToken,
+		&i.TokenType,
+		&i.Scopes,
+		&i.ExpiresAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getAccountUnlockToken = `-- name: GetAccountUnlockToken :one
+SELECT id, user_id, token, expires_at, created_at FROM goauth_account_unlock
+WHERE token = $1 AND expires_at > NOW()
+`
+
+func (q *Queries) GetAccountUnlockToken(ctx context.Context, token string) (GoauthAccountUnlock, error) {
+	row := q.db.QueryRow(ctx, getAccountUnlockToken, token)
+	var i GoauthAccountUnlock
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getEmailVerificationToken = `-- name: GetEmailVerificationToken :one
+SELECT id, user_id, token, expires_at, created_at FROM goauth_email_verification
+WHERE token = $1 AND expires_at > NOW()
+`
+
+func (q *Queries) GetEmailVerificationToken(ctx context.Context, token string) (GoauthEmailVerification, error) {
+	row := q.db.QueryRow(ctx, getEmailVerificationToken, token)
+	var i GoauthEmailVerification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLatestAccountUnlockToken = `-- name: GetLatestAccountUnlockToken :one
+SELECT id, user_id, token, expires_at, created_at FROM goauth_account_unlock
+WHERE user_id = $1 AND expires_at > NOW()
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestAccountUnlockToken(ctx context.Context, userID uuid.UUID) (GoauthAccountUnlock, error) {
+	row := q.db.QueryRow(ctx, getLatestAccountUnlockToken, userID)
+	var i GoauthAccountUnlock
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLatestEmailVerificationToken = `-- name: GetLatestEmailVerificationToken :one
+SELECT id, user_id, token, expires_at, created_at FROM goauth_email_verification
+WHERE user_id = $1 AND expires_at > NOW()
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestEmailVerificationToken(ctx context.Context, userID uuid.UUID) (GoauthEmailVerification, error) {
+	row := q.db.QueryRow(ctx, getLatestEmailVerificationToken, userID)
+	var i GoauthEmailVerification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLatestMfaChallenge = `-- name: GetLatestMfaChallenge :one
+SELECT id, user_id, token, expires_at, created_at FROM goauth_mfa_challenge
+WHERE user_id = $1 AND expires_at > NOW()
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestMfaChallenge(ctx context.Context, userID uuid.UUID) (GoauthMfaChallenge, error) {
+	row := q.db.QueryRow(ctx, getLatestMfaChallenge, userID)
+	var i GoauthMfaChallenge
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLatestPasswordResetToken = `-- name: GetLatestPasswordResetToken :one
+SELECT id, user_id, token, expires_at, created_at FROM goauth_password_reset
+WHERE user_id = $1 AND expires_at > NOW()
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestPasswordResetToken(ctx context.Context, userID uuid.UUID) (GoauthPasswordReset, error) {
+	row := q.db.QueryRow(ctx, getLatestPasswordResetToken, userID)
+	var i GoauthPasswordReset
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
-		&i.Provider,
-		&i.ProviderID,
+		&i.Token,
+		&i.ExpiresAt,
 		&i.CreatedAt,
-		&i.ID_2,
-		&i.Email,
-		&i.HashPassword,
-		&i.Name,
-		&i.Image,
-		&i.RoleName,
-		&i.EmailVerified,
-		&i.TwoFactorEnabled,
-		&i.TwoFactorSecret,
-		&i.Metadata,
-		&i.CreatedAt_2,
-		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const getEmailVerificationToken = `-- name: GetEmailVerificationToken :one
-SELECT id, user_id, token, expires_at, created_at FROM goauth_email_verification
+const getLoginAttempt = `-- name: GetLoginAttempt :one
+SELECT key, failures, last_failed_at, locked_until, previous_failed_at FROM goauth_login_attempt
+WHERE key = $1
+`
+
+func (q *Queries) GetLoginAttempt(ctx context.Context, key string) (GoauthLoginAttempt, error) {
+	row := q.db.QueryRow(ctx, getLoginAttempt, key)
+	var i GoauthLoginAttempt
+	err := row.Scan(
+		&i.Key,
+		&i.Failures,
+		&i.LastFailedAt,
+		&i.LockedUntil,
+		&i.PreviousFailedAt,
+	)
+	return i, err
+}
+
+const getMfaChallenge = `-- name: GetMfaChallenge :one
+SELECT id, user_id, token, expires_at, created_at FROM goauth_mfa_challenge
 WHERE token = $1 AND expires_at > NOW()
 `
 
-func (q *Queries) GetEmailVerificationToken(ctx context.Context, token string) (GoauthEmailVerification, error) {
-	row := q.db.QueryRow(ctx, getEmailVerificationToken, token)
-	var i GoauthEmailVerification
+func (q *Queries) GetMfaChallenge(ctx context.Context, token string) (GoauthMfaChallenge, error) {
+	row := q.db.QueryRow(ctx, getMfaChallenge, token)
+	var i GoauthMfaChallenge
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
@@ -352,8 +970,29 @@ func (q *Queries) GetPasswordResetToken(ctx context.Context, token string) (Goau
 	return i, err
 }
 
+const getRefreshTokenForUpdate = `-- name: GetRefreshTokenForUpdate :one
+SELECT id, family_id, user_id, expires_at, replaced_by, revoked_at, created_at FROM goauth_refresh_token
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetRefreshTokenForUpdate(ctx context.Context, id uuid.UUID) (GoauthRefreshToken, error) {
+	row := q.db.QueryRow(ctx, getRefreshTokenForUpdate, id)
+	var i GoauthRefreshToken
+	err := row.Scan(
+		&i.ID,
+		&i.FamilyID,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.ReplacedBy,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const getSession = `-- name: GetSession :one
-SELECT id, user_id, token, expires_at, user_agent, ip_address, created_at FROM goauth_session
+SELECT id, user_id, token, expires_at, user_agent, ip_address, created_at, last_seen_at FROM goauth_session
 WHERE token = $1 AND expires_at > NOW()
 `
 
@@ -368,12 +1007,13 @@ func (q *Queries) GetSession(ctx context.Context, token string) (GoauthSession,
 		&i.UserAgent,
 		&i.IpAddress,
 		&i.CreatedAt,
+		&i.LastSeenAt,
 	)
 	return i, err
 }
 
 const getSessionByID = `-- name: GetSessionByID :one
-SELECT id, user_id, token, expires_at, user_agent, ip_address, created_at FROM goauth_session
+SELECT id, user_id, token, expires_at, user_agent, ip_address, created_at, last_seen_at FROM goauth_session
 WHERE id = $1 AND expires_at > NOW()
 `
 
@@ -388,6 +1028,7 @@ func (q *Queries) GetSessionByID(ctx context.Context, id uuid.UUID) (GoauthSessi
 		&i.UserAgent,
 		&i.IpAddress,
 		&i.CreatedAt,
+		&i.LastSeenAt,
 	)
 	return i, err
 }
@@ -498,6 +1139,18 @@ func (q *Queries) GetUserByID(ctx context.Context, userID uuid.UUID) (GetUserByI
 	return i, err
 }
 
+const getUserRevocation = `-- name: GetUserRevocation :one
+SELECT revoked_before FROM goauth_user_revocation
+WHERE user_id = $1
+`
+
+func (q *Queries) GetUserRevocation(ctx context.Context, userID uuid.UUID) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, getUserRevocation, userID)
+	var revoked_before pgtype.Timestamptz
+	err := row.Scan(&revoked_before)
+	return revoked_before, err
+}
+
 const goAuthRegister = `-- name: GoAuthRegister :one
 INSERT INTO goauth_user (
     email,
@@ -548,6 +1201,527 @@ func (q *Queries) GoAuthRegister(ctx context.Context, arg GoAuthRegisterParams)
 	return i, err
 }
 
+const isTokenRevoked = `-- name: IsTokenRevoked :one
+SELECT EXISTS (
+    SELECT 1 FROM goauth_revoked_token WHERE jti = $1
+) AS revoked
+`
+
+func (q *Queries) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	row := q.db.QueryRow(ctx, isTokenRevoked, jti)
+	var revoked bool
+	err := row.Scan(&revoked)
+	return revoked, err
+}
+
+const listPasswordHistory = `-- name: ListPasswordHistory :many
+SELECT hash_password FROM goauth_password_history
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2
+`
+
+type ListPasswordHistoryParams struct {
+	UserID     uuid.UUID `db:"user_id" json:"userId"`
+	MaxEntries int32     `db:"max_entries" json:"maxEntries"`
+}
+
+func (q *Queries) ListPasswordHistory(ctx context.Context, arg ListPasswordHistoryParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, listPasswordHistory, arg.UserID, arg.MaxEntries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var hash_password string
+		if err := rows.Scan(&hash_password); err != nil {
+			return nil, err
+		}
+		items = append(items, hash_password)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserAccounts = `-- name: ListUserAccounts :many
+SELECT id, user_id, provider, provider_id, created_at FROM goauth_account
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) ListUserAccounts(ctx context.Context, userID uuid.UUID) ([]GoauthAccount, error) {
+	rows, err := q.db.Query(ctx, listUserAccounts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GoauthAccount{}
+	for rows.Next() {
+		var i GoauthAccount
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Provider,
+			&i.ProviderID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserSessions = `-- name: ListUserSessions :many
+SELECT id, user_id, token, expires_at, user_agent, ip_address, created_at, last_seen_at FROM goauth_session
+WHERE user_id = $1 AND expires_at > NOW()
+ORDER BY last_seen_at DESC
+`
+
+func (q *Queries) ListUserSessions(ctx context.Context, userID uuid.UUID) ([]GoauthSession, error) {
+	rows, err := q.db.Query(ctx, listUserSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GoauthSession{}
+	for rows.Next() {
+		var i GoauthSession
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Token,
+			&i.ExpiresAt,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.CreatedAt,
+			&i.LastSeenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserWebauthnCredentials = `-- name: ListUserWebauthnCredentials :many
+SELECT id, user_id, credential_id, name, credential, sign_count, created_at, last_used_at FROM goauth_webauthn_credential
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) ListUserWebauthnCredentials(ctx context.Context, userID uuid.UUID) ([]GoauthWebauthnCredential, error) {
+	rows, err := q.db.Query(ctx, listUserWebauthnCredentials, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GoauthWebauthnCredential{}
+	for rows.Next() {
+		var i GoauthWebauthnCredential
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.CredentialID,
+			&i.Name,
+			&i.Credential,
+			&i.SignCount,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockLoginAttempt = `-- name: LockLoginAttempt :exec
+INSERT INTO goauth_login_attempt (
+    key,
+    locked_until
+) VALUES (
+             $1,
+             $2
+         ) ON CONFLICT (key) DO UPDATE
+    SET locked_until = EXCLUDED.locked_until
+`
+
+type LockLoginAttemptParams struct {
+	Key         string             `db:"key" json:"key"`
+	LockedUntil pgtype.Timestamptz `db:"locked_until" json:"lockedUntil"`
+}
+
+func (q *Queries) LockLoginAttempt(ctx context.Context, arg LockLoginAttemptParams) error {
+	_, err := q.db.Exec(ctx, lockLoginAttempt, arg.Key, arg.LockedUntil)
+	return err
+}
+
+const lockUser = `-- name: LockUser :one
+SELECT id FROM goauth_user
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockUser(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, lockUser, id)
+	err := row.Scan(&id)
+	return id, err
+}
+
+const prunePasswordHistory = `-- name: PrunePasswordHistory :exec
+DELETE FROM goauth_password_history
+WHERE user_id = $1 AND id NOT IN (
+    SELECT id FROM goauth_password_history
+    WHERE user_id = $1
+    ORDER BY created_at DESC
+    LIMIT $2
+)
+`
+
+type PrunePasswordHistoryParams struct {
+	UserID uuid.UUID `db:"user_id" json:"userId"`
+	Keep   int32     `db:"keep" json:"keep"`
+}
+
+func (q *Queries) PrunePasswordHistory(ctx context.Context, arg PrunePasswordHistoryParams) error {
+	_, err := q.db.Exec(ctx, prunePasswordHistory, arg.UserID, arg.Keep)
+	return err
+}
+
+const purgeExpiredAccountUnlockTokens = `-- name: PurgeExpiredAccountUnlockTokens :execrows
+DELETE FROM goauth_account_unlock
+WHERE id IN (
+    SELECT id FROM goauth_account_unlock
+    WHERE expires_at <= NOW()
+    LIMIT $1
+)
+`
+
+func (q *Queries) PurgeExpiredAccountUnlockTokens(ctx context.Context, batchSize int32) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeExpiredAccountUnlockTokens, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeExpiredEmailVerificationTokens = `-- name: PurgeExpiredEmailVerificationTokens :execrows
+DELETE FROM goauth_email_verification
+WHERE id IN (
+    SELECT id FROM goauth_email_verification
+    WHERE expires_at <= NOW()
+    LIMIT $1
+)
+`
+
+func (q *Queries) PurgeExpiredEmailVerificationTokens(ctx context.Context, batchSize int32) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeExpiredEmailVerificationTokens, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeExpiredMfaChallenges = `-- name: PurgeExpiredMfaChallenges :execrows
+DELETE FROM goauth_mfa_challenge
+WHERE id IN (
+    SELECT id FROM goauth_mfa_challenge
+    WHERE expires_at <= NOW()
+    LIMIT $1
+)
+`
+
+func (q *Queries) PurgeExpiredMfaChallenges(ctx context.Context, batchSize int32) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeExpiredMfaChallenges, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeExpiredPasswordResetTokens = `-- name: PurgeExpiredPasswordResetTokens :execrows
+DELETE FROM goauth_password_reset
+WHERE id IN (
+    SELECT id FROM goauth_password_reset
+    WHERE expires_at <= NOW()
+    LIMIT $1
+)
+`
+
+func (q *Queries) PurgeExpiredPasswordResetTokens(ctx context.Context, batchSize int32) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeExpiredPasswordResetTokens, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeExpiredRefreshTokens = `-- name: PurgeExpiredRefreshTokens :execrows
+DELETE FROM goauth_refresh_token
+WHERE id IN (
+    SELECT id FROM goauth_refresh_token
+    WHERE expires_at <= NOW()
+    LIMIT $1
+)
+`
+
+func (q *Queries) PurgeExpiredRefreshTokens(ctx context.Context, batchSize int32) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeExpiredRefreshTokens, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeExpiredRevokedTokens = `-- name: PurgeExpiredRevokedTokens :execrows
+DELETE FROM goauth_revoked_token
+WHERE jti IN (
+    SELECT jti FROM goauth_revoked_token
+    WHERE expires_at <= NOW()
+    LIMIT $1
+)
+`
+
+func (q *Queries) PurgeExpiredRevokedTokens(ctx context.Context, batchSize int32) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeExpiredRevokedTokens, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeExpiredSessions = `-- name: PurgeExpiredSessions :execrows
+DELETE FROM goauth_session
+WHERE id IN (
+    SELECT id FROM goauth_session
+    WHERE expires_at <= NOW()
+    LIMIT $1
+)
+`
+
+// sql/queries/janitor.sql
+func (q *Queries) PurgeExpiredSessions(ctx context.Context, batchSize int32) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeExpiredSessions, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeExpiredWebauthnChallenges = `-- name: PurgeExpiredWebauthnChallenges :execrows
+DELETE FROM goauth_webauthn_challenge
+WHERE challenge IN (
+    SELECT challenge FROM goauth_webauthn_challenge
+    WHERE expires_at <= NOW()
+    LIMIT $1
+)
+`
+
+func (q *Queries) PurgeExpiredWebauthnChallenges(ctx context.Context, batchSize int32) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeExpiredWebauthnChallenges, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeLoginAttempts = `-- name: PurgeLoginAttempts :execrows
+DELETE FROM goauth_login_attempt
+WHERE key IN (
+    SELECT key FROM goauth_login_attempt
+    WHERE last_failed_at < $1
+      AND (locked_until IS NULL OR locked_until <= NOW())
+    LIMIT $2
+)
+`
+
+type PurgeLoginAttemptsParams struct {
+	FailedBefore pgtype.Timestamptz `db:"failed_before" json:"failedBefore"`
+	BatchSize    int32              `db:"batch_size" json:"batchSize"`
+}
+
+func (q *Queries) PurgeLoginAttempts(ctx context.Context, arg PurgeLoginAttemptsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeLoginAttempts, arg.FailedBefore, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeUserRevocations = `-- name: PurgeUserRevocations :execrows
+DELETE FROM goauth_user_revocation
+WHERE user_id IN (
+    SELECT user_id FROM goauth_user_revocation
+    WHERE revoked_before < $1
+    LIMIT $2
+)
+`
+
+type PurgeUserRevocationsParams struct {
+	RevokedBefore pgtype.Timestamptz `db:"revoked_before" json:"revokedBefore"`
+	BatchSize     int32              `db:"batch_size" json:"batchSize"`
+}
+
+func (q *Queries) PurgeUserRevocations(ctx context.Context, arg PurgeUserRevocationsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeUserRevocations, arg.RevokedBefore, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recordLoginFailure = `-- name: RecordLoginFailure :one
+INSERT INTO goauth_login_attempt (
+    key,
+    failures,
+    last_failed_at
+) VALUES (
+             $1,
+             1,
+             NOW()
+         ) ON CONFLICT (key) DO UPDATE
+    SET failures = CASE
+            WHEN goauth_login_attempt.last_failed_at <= $2 THEN 1
+            ELSE goauth_login_attempt.failures + 1
+        END,
+        previous_failed_at = goauth_login_attempt.last_failed_at,
+        last_failed_at = NOW()
+RETURNING key, failures, last_failed_at, locked_until, previous_failed_at
+`
+
+type RecordLoginFailureParams struct {
+	Key         string             `db:"key" json:"key"`
+	WindowStart pgtype.Timestamptz `db:"window_start" json:"windowStart"`
+}
+
+// sql/queries/login_attempt.sql
+func (q *Queries) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (GoauthLoginAttempt, error) {
+	row := q.db.QueryRow(ctx, recordLoginFailure, arg.Key, arg.WindowStart)
+	var i GoauthLoginAttempt
+	err := row.Scan(
+		&i.Key,
+		&i.Failures,
+		&i.LastFailedAt,
+		&i.LockedUntil,
+		&i.PreviousFailedAt,
+	)
+	return i, err
+}
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+UPDATE goauth_refresh_token
+SET revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, revokeRefreshTokenFamily, familyID)
+	return err
+}
+
+const revokeToken = `-- name: RevokeToken :exec
+INSERT INTO goauth_revoked_token (
+    jti,
+    expires_at
+) VALUES (
+             $1,
+             $2
+         ) ON CONFLICT (jti) DO NOTHING
+`
+
+type RevokeTokenParams struct {
+	Jti       string             `db:"jti" json:"jti"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+}
+
+// sql/queries/revocation.sql
+func (q *Queries) RevokeToken(ctx context.Context, arg RevokeTokenParams) error {
+	_, err := q.db.Exec(ctx, revokeToken, arg.Jti, arg.ExpiresAt)
+	return err
+}
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE goauth_refresh_token
+SET revoked_at = NOW()
+WHERE user_id = $1 AND created_at < $2 AND revoked_at IS NULL
+`
+
+type RevokeUserRefreshTokensParams struct {
+	UserID        uuid.UUID          `db:"user_id" json:"userId"`
+	CreatedBefore pgtype.Timestamptz `db:"created_before" json:"createdBefore"`
+}
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, arg RevokeUserRefreshTokensParams) error {
+	_, err := q.db.Exec(ctx, revokeUserRefreshTokens, arg.UserID, arg.CreatedBefore)
+	return err
+}
+
+const revokeUserTokensBefore = `-- name: RevokeUserTokensBefore :exec
+INSERT INTO goauth_user_revocation (
+    user_id,
+    revoked_before
+) VALUES (
+             $1,
+             $2
+         ) ON CONFLICT (user_id) DO UPDATE
+    SET revoked_before = GREATEST(goauth_user_revocation.revoked_before, EXCLUDED.revoked_before)
+`
+
+type RevokeUserTokensBeforeParams struct {
+	UserID        uuid.UUID          `db:"user_id" json:"userId"`
+	RevokedBefore pgtype.Timestamptz `db:"revoked_before" json:"revokedBefore"`
+}
+
+func (q *Queries) RevokeUserTokensBefore(ctx context.Context, arg RevokeUserTokensBeforeParams) error {
+	_, err := q.db.Exec(ctx, revokeUserTokensBefore, arg.UserID, arg.RevokedBefore)
+	return err
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :exec
+UPDATE goauth_refresh_token
+SET replaced_by = $2, revoked_at = NOW()
+WHERE id = $1
+`
+
+type RotateRefreshTokenParams struct {
+	ID         uuid.UUID   `db:"id" json:"id"`
+	ReplacedBy pgtype.UUID `db:"replaced_by" json:"replacedBy"`
+}
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) error {
+	_, err := q.db.Exec(ctx, rotateRefreshToken, arg.ID, arg.ReplacedBy)
+	return err
+}
+
+const touchSession = `-- name: TouchSession :exec
+UPDATE goauth_session
+SET expires_at = $1, last_seen_at = NOW()
+WHERE id = $2
+`
+
+type TouchSessionParams struct {
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+	ID        uuid.UUID          `db:"id" json:"id"`
+}
+
+func (q *Queries) TouchSession(ctx context.Context, arg TouchSessionParams) error {
+	_, err := q.db.Exec(ctx, touchSession, arg.ExpiresAt, arg.ID)
+	return err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE goauth_user
 SET
@@ -607,6 +1781,22 @@ func (q *Queries) UpdateUserEmailVerified(ctx context.Context, arg UpdateUserEma
 	return err
 }
 
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE goauth_user
+SET hash_password = $1, updated_at = NOW()
+WHERE id = $2
+`
+
+type UpdateUserPasswordParams struct {
+	HashPassword string    `db:"hash_password" json:"hashPassword"`
+	ID           uuid.UUID `db:"id" json:"id"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.Exec(ctx, updateUserPassword, arg.HashPassword, arg.ID)
+	return err
+}
+
 const updateUserTwoFactor = `-- name: UpdateUserTwoFactor :exec
 UPDATE goauth_user
 SET
@@ -626,3 +1816,115 @@ func (q *Queries) UpdateUserTwoFactor(ctx context.Context, arg UpdateUserTwoFact
 	_, err := q.db.Exec(ctx, updateUserTwoFactor, arg.ID, arg.TwoFactorEnabled, arg.TwoFactorSecret)
 	return err
 }
+
+const updateWebauthnCredentialUse = `-- name: UpdateWebauthnCredentialUse :execrows
+UPDATE goauth_webauthn_credential
+SET credential = $1, sign_count = $2, last_used_at = NOW()
+WHERE credential_id = $3
+  AND (sign_count < $2 OR (sign_count = 0 AND $2 = 0))
+`
+
+type UpdateWebauthnCredentialUseParams struct {
+	Credential   []byte `db:"credential" json:"credential"`
+	SignCount    int64  `db:"sign_count" json:"signCount"`
+	CredentialID []byte `db:"credential_id" json:"credentialId"`
+}
+
+func (q *Queries) UpdateWebauthnCredentialUse(ctx context.Context, arg UpdateWebauthnCredentialUseParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateWebauthnCredentialUse, arg.Credential, arg.SignCount, arg.CredentialID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const upsertAccountToken = `-- name: UpsertAccountToken :exec
+INSERT INTO goauth_account_token (
+    account_id,
+    access_token,
+    refresh_token,
+    token_type,
+    scopes,
+    expires_at
+) VALUES (
+             $1,
+             $2,
+             $3,
+             $4,
+             $5,
+             $6
+         )
+ON CONFLICT (account_id) DO UPDATE SET
+    access_token = EXCLUDED.access_token,
+    refresh_token = COALESCE(EXCLUDED.refresh_token, goauth_account_token.refresh_token),
+    token_type = EXCLUDED.token_type,
+    scopes = EXCLUDED.scopes,
+    expires_at = EXCLUDED.expires_at,
+    updated_at = NOW()
+`
+
+type UpsertAccountTokenParams struct {
+	AccountID    uuid.UUID          `db:"account_id" json:"accountId"`
+	AccessToken  []byte             `db:"access_token" json:"accessToken"`
+	RefreshToken []byte             `db:"refresh_token" json:"refreshToken"`
+	TokenType    string             `db:"token_type" json:"tokenType"`
+	Scopes       string             `db:"scopes" json:"scopes"`
+	ExpiresAt    pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+}
+
+func (q *Queries) UpsertAccountToken(ctx context.Context, arg UpsertAccountTokenParams) error {
+	_, err := q.db.Exec(ctx, upsertAccountToken,
+		arg.AccountID,
+		arg.AccessToken,
+		arg.RefreshToken,
+		arg.TokenType,
+		arg.Scopes,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :execrows
+UPDATE goauth_recovery_code
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+`
+
+type UseRecoveryCodeParams struct {
+	UserID   uuid.UUID `db:"user_id" json:"userId"`
+	CodeHash string    `db:"code_hash" json:"codeHash"`
+}
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, useRecoveryCode, arg.UserID, arg.CodeHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const useTotpStep = `-- name: UseTotpStep :execrows
+INSERT INTO goauth_totp_step (
+    user_id,
+    last_step
+) VALUES (
+             $1,
+             $2
+         ) ON CONFLICT (user_id) DO UPDATE
+    SET last_step = EXCLUDED.last_step
+    WHERE goauth_totp_step.last_step < EXCLUDED.last_step
+`
+
+type UseTotpStepParams struct {
+	UserID   uuid.UUID `db:"user_id" json:"userId"`
+	LastStep int64     `db:"last_step" json:"lastStep"`
+}
+
+// sql/queries/totp.sql
+func (q *Queries) UseTotpStep(ctx context.Context, arg UseTotpStepParams) (int64, error) {
+	result, err := q.db.Exec(ctx, useTotpStep, arg.UserID, arg.LastStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
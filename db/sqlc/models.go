@@ -19,6 +19,24 @@ type GoauthAccount struct {
 	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"createdAt"`
 }
 
+type GoauthAccountToken struct {
+	AccountID    uuid.UUID          `db:"account_id" json:"accountId"`
+	AccessToken  []byte             `db:"access_token" json:"accessToken"`
+	RefreshToken []byte             `db:"refresh_token" json:"refreshToken"`
+	TokenType    string             `db:"token_type" json:"tokenType"`
+	Scopes       string             `db:"scopes" json:"scopes"`
+	ExpiresAt    pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+	UpdatedAt    pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
+}
+
+type GoauthAccountUnlock struct {
+	ID        uuid.UUID          `db:"id" json:"id"`
+	UserID    uuid.UUID          `db:"user_id" json:"userId"`
+	Token     string             `db:"token" json:"token"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+}
+
 type GoauthAuditLog struct {
 	ID         int32            `db:"id" json:"id"`
 	EventType  string           `db:"event_type" json:"eventType"`
@@ -34,7 +52,15 @@ type GoauthEmailVerification struct {
 	CreatedAt pgtype.Timestamptz `db:"created_at" json:"createdAt"`
 }
 
-type GoauthPasswordReset struct {
+type GoauthLoginAttempt struct {
+	Key              string             `db:"key" json:"key"`
+	Failures         int32              `db:"failures" json:"failures"`
+	LastFailedAt     pgtype.Timestamptz `db:"last_failed_at" json:"lastFailedAt"`
+	LockedUntil      pgtype.Timestamptz `db:"locked_until" json:"lockedUntil"`
+	PreviousFailedAt pgtype.Timestamptz `db:"previous_failed_at" json:"previousFailedAt"`
+}
+
+type GoauthMfaChallenge struct {
 	ID        uuid.UUID          `db:"id" json:"id"`
 	UserID    uuid.UUID          `db:"user_id" json:"userId"`
 	Token     string             `db:"token" json:"token"`
@@ -42,16 +68,55 @@ type GoauthPasswordReset struct {
 	CreatedAt pgtype.Timestamptz `db:"created_at" json:"createdAt"`
 }
 
-type GoauthSession struct {
+type GoauthPasswordHistory struct {
+	ID           uuid.UUID          `db:"id" json:"id"`
+	UserID       uuid.UUID          `db:"user_id" json:"userId"`
+	HashPassword string             `db:"hash_password" json:"hashPassword"`
+	CreatedAt    pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+}
+
+type GoauthPasswordReset struct {
 	ID        uuid.UUID          `db:"id" json:"id"`
 	UserID    uuid.UUID          `db:"user_id" json:"userId"`
 	Token     string             `db:"token" json:"token"`
 	ExpiresAt pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
-	UserAgent pgtype.Text        `db:"user_agent" json:"userAgent"`
-	IpAddress *netip.Addr        `db:"ip_address" json:"ipAddress"`
 	CreatedAt pgtype.Timestamptz `db:"created_at" json:"createdAt"`
 }
 
+type GoauthRecoveryCode struct {
+	ID        uuid.UUID          `db:"id" json:"id"`
+	UserID    uuid.UUID          `db:"user_id" json:"userId"`
+	CodeHash  string             `db:"code_hash" json:"codeHash"`
+	UsedAt    pgtype.Timestamptz `db:"used_at" json:"usedAt"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+}
+
+type GoauthRefreshToken struct {
+	ID         uuid.UUID          `db:"id" json:"id"`
+	FamilyID   uuid.UUID          `db:"family_id" json:"familyId"`
+	UserID     uuid.UUID          `db:"user_id" json:"userId"`
+	ExpiresAt  pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+	ReplacedBy pgtype.UUID        `db:"replaced_by" json:"replacedBy"`
+	RevokedAt  pgtype.Timestamptz `db:"revoked_at" json:"revokedAt"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+}
+
+type GoauthSession struct {
+	ID         uuid.UUID          `db:"id" json:"id"`
+	UserID     uuid.UUID          `db:"user_id" json:"userId"`
+	Token      string             `db:"token" json:"token"`
+	ExpiresAt  pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+	UserAgent  pgtype.Text        `db:"user_agent" json:"userAgent"`
+	IpAddress  *netip.Addr        `db:"ip_address" json:"ipAddress"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	LastSeenAt pgtype.Timestamptz `db:"last_seen_at" json:"lastSeenAt"`
+}
+
+type GoauthTotpStep struct {
+	UserID   uuid.UUID `db:"user_id" json:"userId"`
+	LastStep int64     `db:"last_step" json:"lastStep"`
+}
+
 type GoauthUser struct {
 	ID               uuid.UUID          `db:"id" json:"id"`
 	Email            string             `db:"email" json:"email"`
@@ -66,3 +131,20 @@ type GoauthUser struct {
 	CreatedAt        pgtype.Timestamptz `db:"created_at" json:"createdAt"`
 	UpdatedAt        pgtype.Timestamptz `db:"updated_at" json:"updatedAt"`
 }
+
+type GoauthWebauthnChallenge struct {
+	Challenge   string             `db:"challenge" json:"challenge"`
+	SessionData []byte             `db:"session_data" json:"sessionData"`
+	ExpiresAt   pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+}
+
+type GoauthWebauthnCredential struct {
+	ID           uuid.UUID          `db:"id" json:"id"`
+	UserID       uuid.UUID          `db:"user_id" json:"userId"`
+	CredentialID []byte             `db:"credential_id" json:"credentialId"`
+	Name         string             `db:"name" json:"name"`
+	Credential   []byte             `db:"credential" json:"credential"`
+	SignCount    int64              `db:"sign_count" json:"signCount"`
+	CreatedAt    pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	LastUsedAt   pgtype.Timestamptz `db:"last_used_at" json:"lastUsedAt"`
+}
@@ -8,50 +8,144 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	AddLoginAttemptPreviousFailedColumn(ctx context.Context) error
+	AddSessionLastSeenColumn(ctx context.Context) error
+	ConsumeAccountUnlockToken(ctx context.Context, token string) (GoauthAccountUnlock, error)
+	ConsumeEmailVerificationToken(ctx context.Context, token string) (GoauthEmailVerification, error)
+	ConsumeMfaChallenge(ctx context.Context, token string) (GoauthMfaChallenge, error)
+	ConsumePasswordResetToken(ctx context.Context, token string) (GoauthPasswordReset, error)
+	ConsumeWebauthnChallenge(ctx context.Context, challenge string) ([]byte, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error)
+	CountUserAccounts(ctx context.Context, userID uuid.UUID) (int64, error)
+	CountUserWebauthnCredentials(ctx context.Context, userID uuid.UUID) (int64, error)
 	// sql/queries/accounts.sql
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (GoauthAccount, error)
 	CreateAccountIndexes(ctx context.Context) error
 	CreateAccountTable(ctx context.Context) error
+	CreateAccountTokenTable(ctx context.Context) error
+	CreateAccountUnlockTable(ctx context.Context) error
+	// sql/queries/account_unlock.sql
+	CreateAccountUnlockToken(ctx context.Context, arg CreateAccountUnlockTokenParams) (GoauthAccountUnlock, error)
+	// sql/queries/audit_log.sql
+	CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) error
 	CreateAuditLogTable(ctx context.Context) error
 	CreateEmailVerificationTable(ctx context.Context) error
 	// sql/queries/email_verification.sql
 	CreateEmailVerificationToken(ctx context.Context, arg CreateEmailVerificationTokenParams) (GoauthEmailVerification, error)
+	CreateLoginAttemptTable(ctx context.Context) error
+	// sql/queries/mfa_challenge.sql
+	CreateMfaChallenge(ctx context.Context, arg CreateMfaChallengeParams) (GoauthMfaChallenge, error)
+	CreateMfaChallengeTable(ctx context.Context) error
+	CreatePasswordHistory(ctx context.Context, arg CreatePasswordHistoryParams) error
+	CreatePasswordHistoryIndexes(ctx context.Context) error
+	CreatePasswordHistoryTable(ctx context.Context) error
 	CreatePasswordResetTable(ctx context.Context) error
 	// sql/queries/password_reset.sql
 	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (GoauthPasswordReset, error)
+	CreateRecoveryCodeTable(ctx context.Context) error
+	// sql/queries/recovery_codes.sql
+	CreateRecoveryCodes(ctx context.Context, arg CreateRecoveryCodesParams) error
+	// sql/queries/refresh_tokens.sql
+	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (GoauthRefreshToken, error)
+	CreateRefreshTokenIndexes(ctx context.Context) error
+	CreateRefreshTokenTable(ctx context.Context) error
+	CreateRefreshTokenUserIndex(ctx context.Context) error
+	CreateRevokedTokenTable(ctx context.Context) error
 	// sql/queries/sessions.sql
 	CreateSession(ctx context.Context, arg CreateSessionParams) (GoauthSession, error)
 	CreateSessionIndexes(ctx context.Context) error
 	CreateSessionTable(ctx context.Context) error
+	CreateTotpStepTable(ctx context.Context) error
 	CreateUserIndexes(ctx context.Context) error
+	CreateUserRevocationTable(ctx context.Context) error
 	CreateUserTable(ctx context.Context) error
-	DeleteAccount(ctx context.Context, arg DeleteAccountParams) error
+	CreateWebauthnChallenge(ctx context.Context, arg CreateWebauthnChallengeParams) error
+	CreateWebauthnChallengeTable(ctx context.Context) error
+	// sql/queries/webauthn.sql
+	CreateWebauthnCredential(ctx context.Context, arg CreateWebauthnCredentialParams) (GoauthWebauthnCredential, error)
+	CreateWebauthnCredentialIndexes(ctx context.Context) error
+	CreateWebauthnCredentialTable(ctx context.Context) error
+	DeleteAccount(ctx context.Context, arg DeleteAccountParams) (int64, error)
+	DeleteAccountUnlockToken(ctx context.Context, token string) error
 	DeleteEmailVerificationToken(ctx context.Context, token string) error
 	DeleteExpiredEmailVerificationTokens(ctx context.Context) error
 	DeleteExpiredPasswordResetTokens(ctx context.Context) error
 	DeleteExpiredSessions(ctx context.Context) error
+	DeleteLoginAttempt(ctx context.Context, key string) error
+	DeleteMfaChallenge(ctx context.Context, token string) error
 	DeletePasswordResetToken(ctx context.Context, token string) error
 	DeleteSession(ctx context.Context, id uuid.UUID) error
+	DeleteTotpStep(ctx context.Context, userID uuid.UUID) error
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	DeleteUserAccountUnlockTokens(ctx context.Context, userID uuid.UUID) error
 	DeleteUserEmailVerificationTokens(ctx context.Context, userID uuid.UUID) error
+	DeleteUserMfaChallenges(ctx context.Context, userID uuid.UUID) error
 	DeleteUserPasswordResetTokens(ctx context.Context, userID uuid.UUID) error
+	DeleteUserRecoveryCodes(ctx context.Context, userID uuid.UUID) error
 	DeleteUserSessions(ctx context.Context, userID uuid.UUID) error
+	DeleteWebauthnCredential(ctx context.Context, arg DeleteWebauthnCredentialParams) (int64, error)
+	ForgiveLoginFailure(ctx context.Context, key string) error
 	GetAccountByProvider(ctx context.Context, arg GetAccountByProviderParams) (GetAccountByProviderRow, error)
+	GetAccountTokenForUpdate(ctx context.Context, arg GetAccountTokenForUpdateParams) (GoauthAccountToken, error)
+	GetAccountUnlockToken(ctx context.Context, token string) (GoauthAccountUnlock, error)
 	GetEmailVerificationToken(ctx context.Context, token string) (GoauthEmailVerification, error)
+	GetLatestAccountUnlockToken(ctx context.Context, userID uuid.UUID) (GoauthAccountUnlock, error)
+	GetLatestEmailVerificationToken(ctx context.Context, userID uuid.UUID) (GoauthEmailVerification, error)
+	GetLatestMfaChallenge(ctx context.Context, userID uuid.UUID) (GoauthMfaChallenge, error)
+	GetLatestPasswordResetToken(ctx context.Context, userID uuid.UUID) (GoauthPasswordReset, error)
+	GetLoginAttempt(ctx context.Context, key string) (GoauthLoginAttempt, error)
+	GetMfaChallenge(ctx context.Context, token string) (GoauthMfaChallenge, error)
 	GetPasswordResetToken(ctx context.Context, token string) (GoauthPasswordReset, error)
+	GetRefreshTokenForUpdate(ctx context.Context, id uuid.UUID) (GoauthRefreshToken, error)
 	GetSession(ctx context.Context, token string) (GoauthSession, error)
 	GetSessionByID(ctx context.Context, id uuid.UUID) (GoauthSession, error)
 	GetUserByEmail(ctx context.Context, email string) (GetUserByEmailRow, error)
 	GetUserByID(ctx context.Context, userID uuid.UUID) (GetUserByIDRow, error)
+	GetUserRevocation(ctx context.Context, userID uuid.UUID) (pgtype.Timestamptz, error)
 	GoAuthRegister(ctx context.Context, arg GoAuthRegisterParams) (GoauthUser, error)
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	ListPasswordHistory(ctx context.Context, arg ListPasswordHistoryParams) ([]string, error)
+	ListUserAccounts(ctx context.Context, userID uuid.UUID) ([]GoauthAccount, error)
+	ListUserSessions(ctx context.Context, userID uuid.UUID) ([]GoauthSession, error)
+	ListUserWebauthnCredentials(ctx context.Context, userID uuid.UUID) ([]GoauthWebauthnCredential, error)
+	LockLoginAttempt(ctx context.Context, arg LockLoginAttemptParams) error
+	LockUser(ctx context.Context, id uuid.UUID) (uuid.UUID, error)
+	PrunePasswordHistory(ctx context.Context, arg PrunePasswordHistoryParams) error
+	PurgeExpiredAccountUnlockTokens(ctx context.Context, batchSize int32) (int64, error)
+	PurgeExpiredEmailVerificationTokens(ctx context.Context, batchSize int32) (int64, error)
+	PurgeExpiredMfaChallenges(ctx context.Context, batchSize int32) (int64, error)
+	PurgeExpiredPasswordResetTokens(ctx context.Context, batchSize int32) (int64, error)
+	PurgeExpiredRefreshTokens(ctx context.Context, batchSize int32) (int64, error)
+	PurgeExpiredRevokedTokens(ctx context.Context, batchSize int32) (int64, error)
+	// sql/queries/janitor.sql
+	PurgeExpiredSessions(ctx context.Context, batchSize int32) (int64, error)
+	PurgeExpiredWebauthnChallenges(ctx context.Context, batchSize int32) (int64, error)
+	PurgeLoginAttempts(ctx context.Context, arg PurgeLoginAttemptsParams) (int64, error)
+	PurgeUserRevocations(ctx context.Context, arg PurgeUserRevocationsParams) (int64, error)
+	// sql/queries/login_attempt.sql
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (GoauthLoginAttempt, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error
+	// sql/queries/revocation.sql
+	RevokeToken(ctx context.Context, arg RevokeTokenParams) error
+	RevokeUserRefreshTokens(ctx context.Context, arg RevokeUserRefreshTokensParams) error
+	RevokeUserTokensBefore(ctx context.Context, arg RevokeUserTokensBeforeParams) error
+	RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) error
 	// Complete setup in one command
 	SetupAuthTables(ctx context.Context) error
+	TouchSession(ctx context.Context, arg TouchSessionParams) error
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (GoauthUser, error)
 	UpdateUserEmailVerified(ctx context.Context, arg UpdateUserEmailVerifiedParams) error
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
 	UpdateUserTwoFactor(ctx context.Context, arg UpdateUserTwoFactorParams) error
+	UpdateWebauthnCredentialUse(ctx context.Context, arg UpdateWebauthnCredentialUseParams) (int64, error)
+	UpsertAccountToken(ctx context.Context, arg UpsertAccountTokenParams) error
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	// sql/queries/totp.sql
+	UseTotpStep(ctx context.Context, arg UseTotpStepParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
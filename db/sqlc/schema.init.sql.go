@@ -9,6 +9,24 @@ import (
 	"context"
 )
 
+const addLoginAttemptPreviousFailedColumn = `-- name: AddLoginAttemptPreviousFailedColumn :exec
+ALTER TABLE goauth_login_attempt ADD COLUMN IF NOT EXISTS previous_failed_at TIMESTAMP WITH TIME ZONE
+`
+
+func (q *Queries) AddLoginAttemptPreviousFailedColumn(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, addLoginAttemptPreviousFailedColumn)
+	return err
+}
+
+const addSessionLastSeenColumn = `-- name: AddSessionLastSeenColumn :exec
+ALTER TABLE goauth_session ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+`
+
+func (q *Queries) AddSessionLastSeenColumn(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, addSessionLastSeenColumn)
+	return err
+}
+
 const createAccountIndexes = `-- name: CreateAccountIndexes :exec
 CREATE INDEX IF NOT EXISTS idx_goauth_account_user_id ON goauth_account(user_id)
 `
@@ -34,6 +52,38 @@ func (q *Queries) CreateAccountTable(ctx context.Context) error {
 	return err
 }
 
+const createAccountTokenTable = `-- name: CreateAccountTokenTable :exec
+CREATE TABLE IF NOT EXISTS goauth_account_token (
+                                                    account_id UUID PRIMARY KEY REFERENCES goauth_account(id) ON DELETE CASCADE,
+                                                    access_token BYTEA NOT NULL,
+                                                    refresh_token BYTEA,
+                                                    token_type TEXT NOT NULL DEFAULT '',
+                                                    scopes TEXT NOT NULL DEFAULT '',
+                                                    expires_at TIMESTAMP WITH TIME ZONE,
+                                                    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+)
+`
+
+func (q *Queries) CreateAccountTokenTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createAccountTokenTable)
+	return err
+}
+
+const createAccountUnlockTable = `-- name: CreateAccountUnlockTable :exec
+CREATE TABLE IF NOT EXISTS goauth_account_unlock (
+                                                   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                   user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                   token TEXT UNIQUE NOT NULL,
+                                                   expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                                   created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+)
+`
+
+func (q *Queries) CreateAccountUnlockTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createAccountUnlockTable)
+	return err
+}
+
 const createAuditLogTable = `-- name: CreateAuditLogTable :exec
 CREATE  TABLE  IF NOT EXISTS  goauth_audit_log(
                                                   id serial primary key ,
@@ -63,6 +113,59 @@ func (q *Queries) CreateEmailVerificationTable(ctx context.Context) error {
 	return err
 }
 
+const createLoginAttemptTable = `-- name: CreateLoginAttemptTable :exec
+CREATE TABLE IF NOT EXISTS goauth_login_attempt (
+                                                     key TEXT PRIMARY KEY,
+                                                     failures INTEGER NOT NULL DEFAULT 0,
+                                                     last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+                                                     locked_until TIMESTAMP WITH TIME ZONE,
+                                                     previous_failed_at TIMESTAMP WITH TIME ZONE
+)
+`
+
+func (q *Queries) CreateLoginAttemptTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createLoginAttemptTable)
+	return err
+}
+
+const createMfaChallengeTable = `-- name: CreateMfaChallengeTable :exec
+CREATE TABLE IF NOT EXISTS goauth_mfa_challenge (
+                                                   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                   user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                   token TEXT UNIQUE NOT NULL,
+                                                   expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                                   created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+)
+`
+
+func (q *Queries) CreateMfaChallengeTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createMfaChallengeTable)
+	return err
+}
+
+const createPasswordHistoryIndexes = `-- name: CreatePasswordHistoryIndexes :exec
+CREATE INDEX IF NOT EXISTS idx_goauth_password_history_user_id ON goauth_password_history(user_id, created_at DESC)
+`
+
+func (q *Queries) CreatePasswordHistoryIndexes(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createPasswordHistoryIndexes)
+	return err
+}
+
+const createPasswordHistoryTable = `-- name: CreatePasswordHistoryTable :exec
+CREATE TABLE IF NOT EXISTS goauth_password_history (
+                                                       id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                       user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                       hash_password TEXT NOT NULL,
+                                                       created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+)
+`
+
+func (q *Queries) CreatePasswordHistoryTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createPasswordHistoryTable)
+	return err
+}
+
 const createPasswordResetTable = `-- name: CreatePasswordResetTable :exec
 CREATE TABLE IF NOT EXISTS goauth_password_reset (
                                                      id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -78,6 +181,70 @@ func (q *Queries) CreatePasswordResetTable(ctx context.Context) error {
 	return err
 }
 
+const createRecoveryCodeTable = `-- name: CreateRecoveryCodeTable :exec
+CREATE TABLE IF NOT EXISTS goauth_recovery_code (
+                                                    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                    user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                    code_hash TEXT NOT NULL,
+                                                    used_at TIMESTAMP WITH TIME ZONE,
+                                                    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+                                                    UNIQUE(user_id, code_hash)
+)
+`
+
+func (q *Queries) CreateRecoveryCodeTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createRecoveryCodeTable)
+	return err
+}
+
+const createRefreshTokenIndexes = `-- name: CreateRefreshTokenIndexes :exec
+CREATE INDEX IF NOT EXISTS idx_goauth_refresh_token_family_id ON goauth_refresh_token(family_id)
+`
+
+func (q *Queries) CreateRefreshTokenIndexes(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createRefreshTokenIndexes)
+	return err
+}
+
+const createRefreshTokenTable = `-- name: CreateRefreshTokenTable :exec
+CREATE TABLE IF NOT EXISTS goauth_refresh_token (
+                                                    id UUID PRIMARY KEY,
+                                                    family_id UUID NOT NULL,
+                                                    user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                                    replaced_by UUID,
+                                                    revoked_at TIMESTAMP WITH TIME ZONE,
+                                                    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+)
+`
+
+func (q *Queries) CreateRefreshTokenTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createRefreshTokenTable)
+	return err
+}
+
+const createRefreshTokenUserIndex = `-- name: CreateRefreshTokenUserIndex :exec
+CREATE INDEX IF NOT EXISTS idx_goauth_refresh_token_user_id ON goauth_refresh_token(user_id)
+`
+
+func (q *Queries) CreateRefreshTokenUserIndex(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createRefreshTokenUserIndex)
+	return err
+}
+
+const createRevokedTokenTable = `-- name: CreateRevokedTokenTable :exec
+CREATE TABLE IF NOT EXISTS goauth_revoked_token (
+                                                    jti TEXT PRIMARY KEY,
+                                                    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                                    revoked_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+)
+`
+
+func (q *Queries) CreateRevokedTokenTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createRevokedTokenTable)
+	return err
+}
+
 const createSessionIndexes = `-- name: CreateSessionIndexes :exec
 CREATE INDEX IF NOT EXISTS idx_goauth_session_user_id ON goauth_session(user_id)
 `
@@ -95,7 +262,8 @@ CREATE TABLE IF NOT EXISTS goauth_session (
                                               expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
                                               user_agent TEXT,
                                               ip_address INET,
-                                              created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+                                              created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+                                              last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 )
 `
 
@@ -104,6 +272,18 @@ func (q *Queries) CreateSessionTable(ctx context.Context) error {
 	return err
 }
 
+const createTotpStepTable = `-- name: CreateTotpStepTable :exec
+CREATE TABLE IF NOT EXISTS goauth_totp_step (
+                                                user_id UUID PRIMARY KEY REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                last_step BIGINT NOT NULL
+)
+`
+
+func (q *Queries) CreateTotpStepTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createTotpStepTable)
+	return err
+}
+
 const createUserIndexes = `-- name: CreateUserIndexes :exec
 CREATE INDEX IF NOT EXISTS idx_goauth_user_email ON goauth_user(email)
 `
@@ -113,6 +293,18 @@ func (q *Queries) CreateUserIndexes(ctx context.Context) error {
 	return err
 }
 
+const createUserRevocationTable = `-- name: CreateUserRevocationTable :exec
+CREATE TABLE IF NOT EXISTS goauth_user_revocation (
+                                                      user_id UUID PRIMARY KEY REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                      revoked_before TIMESTAMP WITH TIME ZONE NOT NULL
+)
+`
+
+func (q *Queries) CreateUserRevocationTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createUserRevocationTable)
+	return err
+}
+
 const createUserTable = `-- name: CreateUserTable :exec
 CREATE TABLE IF NOT EXISTS goauth_user (
                                            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -135,6 +327,46 @@ func (q *Queries) CreateUserTable(ctx context.Context) error {
 	return err
 }
 
+const createWebauthnChallengeTable = `-- name: CreateWebauthnChallengeTable :exec
+CREATE TABLE IF NOT EXISTS goauth_webauthn_challenge (
+                                                         challenge TEXT PRIMARY KEY,
+                                                         session_data JSONB NOT NULL,
+                                                         expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+)
+`
+
+func (q *Queries) CreateWebauthnChallengeTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createWebauthnChallengeTable)
+	return err
+}
+
+const createWebauthnCredentialIndexes = `-- name: CreateWebauthnCredentialIndexes :exec
+CREATE INDEX IF NOT EXISTS idx_goauth_webauthn_credential_user_id ON goauth_webauthn_credential(user_id)
+`
+
+func (q *Queries) CreateWebauthnCredentialIndexes(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createWebauthnCredentialIndexes)
+	return err
+}
+
+const createWebauthnCredentialTable = `-- name: CreateWebauthnCredentialTable :exec
+CREATE TABLE IF NOT EXISTS goauth_webauthn_credential (
+                                                          id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                                          user_id UUID NOT NULL REFERENCES goauth_user(id) ON DELETE CASCADE,
+                                                          credential_id BYTEA UNIQUE NOT NULL,
+                                                          name TEXT NOT NULL DEFAULT '',
+                                                          credential JSONB NOT NULL,
+                                                          sign_count BIGINT NOT NULL DEFAULT 0,
+                                                          created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+                                                          last_used_at TIMESTAMP WITH TIME ZONE
+)
+`
+
+func (q *Queries) CreateWebauthnCredentialTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createWebauthnCredentialTable)
+	return err
+}
+
 const setupAuthTables = `-- name: SetupAuthTables :exec
 CREATE TABLE IF NOT EXISTS goauth_user (
                                            id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -45,3 +46,34 @@ func (s *Store) WithTx(ctx context.Context, fn TxFunc) error {
 
 	return tx.Commit(ctx)
 }
+
+// TryAdvisoryLock takes the session-level advisory lock key on a dedicated
+// connection, so it is held until unlock is called rather than only for the
+// next pooled query. acquired is false when another session holds the lock;
+// unlock is then a no-op.
+func (s *Store) TryAdvisoryLock(ctx context.Context, key int64) (unlock func(), acquired bool, err error) {
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return func() {}, false, fmt.Errorf("acquire conn: %w", err)
+	}
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		conn.Release()
+		return func() {}, false, fmt.Errorf("try advisory lock: %w", err)
+	}
+	if !acquired {
+		conn.Release()
+		return func() {}, false, nil
+	}
+
+	return func() {
+		// The caller's context may already be cancelled; the unlock must
+		// still reach the server or the lock lives as long as the conn.
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.Exec(unlockCtx, "SELECT pg_advisory_unlock($1)", key); err != nil {
+			log.Printf("releasing advisory lock: %v", err)
+			conn.Conn().Close(unlockCtx)
+		}
+		conn.Release()
+	}, true, nil
+}
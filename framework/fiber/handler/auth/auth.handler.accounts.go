@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/auth"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+)
+
+// LinkAccount starts linking the :provider account to the signed-in user
+// after re-authenticating with {"password"} or, without a password,
+// {"code"}. It answers with the authorization URL for the page to navigate
+// to; the provider then returns to the usual callback.
+func (g *GoAuthFiber) LinkAccount(c fiber.Ctx) error {
+	userId, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid token",
+		})
+	}
+	var req framework.ReauthRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	req.IPAddress = c.IP()
+
+	authURL, binding, err := g.srv.BeginLinkAccount(userId, c.Params("provider"), &req)
+	if err != nil {
+		return accountsError(c, err, "failed to start account linking")
+	}
+	g.setOAuthCookie(c, oauthLinkCookie, binding)
+	return c.JSON(utils.GeneralResponse{
+		Data: fiber.Map{"authorization_url": authURL},
+	})
+}
+
+// ListAccounts lists the provider accounts linked to the signed-in user.
+func (g *GoAuthFiber) ListAccounts(c fiber.Ctx) error {
+	userId, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid token",
+		})
+	}
+	accounts, err := g.srv.ListAccounts(userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list linked accounts",
+		})
+	}
+	return c.JSON(utils.GeneralResponse{
+		Data: accounts,
+	})
+}
+
+// UnlinkAccount removes the signed-in user's :provider account.
+func (g *GoAuthFiber) UnlinkAccount(c fiber.Ctx) error {
+	userId, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid token",
+		})
+	}
+	if err := g.srv.UnlinkAccount(userId, c.Params("provider")); err != nil {
+		return accountsError(c, err, "failed to unlink account")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// linkCallback finishes a link flow on the provider's callback, redirecting
+// to the CallBackURL with ?linked=<provider> or ?error=... when there is one.
+func (g *GoAuthFiber) linkCallback(c fiber.Ctx, provider, binding string) error {
+	callbackURL := g.oauthCallbacks[provider]
+	if providerErr := c.Query("error"); providerErr != "" {
+		return g.oauthFailure(c, provider, fiber.StatusUnauthorized, providerErr, "account linking was not completed")
+	}
+
+	account, err := g.srv.LinkAccount(&framework.OAuthCallbackRequest{
+		Provider:  provider,
+		Code:      c.Query("code"),
+		State:     c.Query("state"),
+		Binding:   binding,
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		IPAddress: c.IP(),
+	})
+	if err != nil {
+		if callbackURL != "" {
+			return c.Redirect().To(withQuery(callbackURL, url.Values{"error": {accountsErrorCode(err)}}))
+		}
+		return accountsError(c, err, "failed to link account")
+	}
+
+	if callbackURL != "" {
+		return c.Redirect().To(withQuery(callbackURL, url.Values{"linked": {provider}}))
+	}
+	return c.Status(fiber.StatusCreated).JSON(utils.GeneralResponse{
+		Data: account,
+	})
+}
+
+// accountErrors maps the linking errors to a status and a code the page can
+// switch on, also used as ?error= on the CallBackURL.
+var accountErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{auth.ErrOAuthProviderUnknown, fiber.StatusNotFound, "unknown_provider"},
+	{auth.ErrAccountNotFound, fiber.StatusNotFound, "account_not_found"},
+	{auth.ErrReauthFailed, fiber.StatusUnauthorized, "reauth_failed"},
+	{auth.ErrReauthUnavailable, fiber.StatusBadRequest, "reauth_unavailable"},
+	{auth.ErrInvalidOAuthState, fiber.StatusBadRequest, "invalid_state"},
+	{auth.ErrOAuthFailed, fiber.StatusUnauthorized, "oauth_failed"},
+	{auth.ErrOAuthAccountInUse, fiber.StatusConflict, "account_in_use"},
+	{auth.ErrProviderAlreadyLinked, fiber.StatusConflict, "provider_already_linked"},
+	{auth.ErrLastLoginMethod, fiber.StatusConflict, "last_login_method"},
+}
+
+func accountsError(c fiber.Ctx, err error, message string) error {
+	var throttled *auth.LoginThrottledError
+	if errors.As(err, &throttled) {
+		return loginThrottled(c, throttled)
+	}
+	for _, known := range accountErrors {
+		if errors.Is(err, known.err) {
+			return c.Status(known.status).JSON(fiber.Map{
+				"error": err.Error(),
+				"code":  known.code,
+			})
+		}
+	}
+	log.Error().Err(err).Msg(message)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+func accountsErrorCode(err error) string {
+	for _, known := range accountErrors {
+		if errors.Is(err, known.err) {
+			return known.code
+		}
+	}
+	return "server_error"
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/auth"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/gofiber/fiber/v3"
+)
+
+// ChangePassword sets a new password from {"current_password",
+// "new_password"}. The user is signed out everywhere else and gets new
+// credentials for this device, as from Login.
+func (g *GoAuthFiber) ChangePassword(c fiber.Ctx) error {
+	userId, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid token",
+		})
+	}
+
+	var req framework.ChangePasswordRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := framework.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	req.UserAgent = c.Get(fiber.HeaderUserAgent)
+	req.IPAddress = c.IP()
+
+	authResponse, err := g.srv.ChangePassword(userId, &req)
+	var policyErr *utils.PasswordPolicyError
+	var throttled *auth.LoginThrottledError
+	switch {
+	case err == nil:
+		return g.loginResponse(c, authResponse)
+	case errors.As(err, &throttled):
+		return loginThrottled(c, throttled)
+	case errors.As(err, &policyErr):
+		return passwordPolicyFailure(c, policyErr)
+	case errors.Is(err, auth.ErrWrongPassword):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+			"code":  "wrong_password",
+		})
+	case errors.Is(err, auth.ErrPasswordReused):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+			"code":  "password_reused",
+		})
+	case errors.Is(err, auth.ErrPasswordNotSet):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+			"code":  "password_not_set",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "failed to change password",
+	})
+}
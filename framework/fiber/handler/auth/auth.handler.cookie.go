@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"os"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/gofiber/fiber/v3"
+)
+
+const refreshTokenCookie = "refresh_token"
+
+func setRefreshCookie(c fiber.Ctx, refreshToken string) {
+	lifetime := utils.RefreshTokenDuration()
+	isProduction := os.Getenv("ENVIRONMENT") == "production"
+	refreshCookies := fiber.Cookie{
+		Name:        refreshTokenCookie,
+		Value:       refreshToken,
+		Path:        "/",                         // Set to root path so cookie is available site-wide
+		Domain:      "",                          // Leave empty for same-domain, or set specific domain in production
+		Expires:     time.Now().Add(lifetime),    // Matches the refresh token lifetime
+		MaxAge:      int(lifetime.Seconds()),     // Refresh token lifetime in seconds
+		Secure:      isProduction,                // true in production (HTTPS only)
+		HTTPOnly:    true,                        // Prevents XSS attacks by making cookie inaccessible to JavaScript
+		SameSite:    fiber.CookieSameSiteLaxMode, // CSRF protection
+		Partitioned: false,
+		SessionOnly: false,
+	}
+	c.Cookie(&refreshCookies)
+}
+
+func clearRefreshCookie(c fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     refreshTokenCookie,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		Secure:   os.Getenv("ENVIRONMENT") == "production",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
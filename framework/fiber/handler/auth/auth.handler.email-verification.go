@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/auth"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/gofiber/fiber/v3"
+)
+
+// VerifyEmail marks the user's email verified from a verification link
+// token, sent as {"token": "..."} or ?token=...
+func (g *GoAuthFiber) VerifyEmail(c fiber.Ctx) error {
+	req := framework.VerifyEmailRequest{Token: c.Query("token")}
+	if req.Token == "" {
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+	if err := framework.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := g.srv.VerifyEmail(&req); err != nil {
+		if errors.Is(err, auth.ErrInvalidVerifyToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid or expired verification token",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to verify email",
+		})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ResendVerification emails a new verification link. The response is the
+// same for unknown, verified and throttled addresses.
+func (g *GoAuthFiber) ResendVerification(c fiber.Ctx) error {
+	var req framework.ResendVerificationRequest
+
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := framework.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := g.srv.ResendVerification(&req); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to resend verification email",
+		})
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "if the email needs verifying, a new link has been sent",
+	})
+}
@@ -1,16 +1,51 @@
 package auth
 
 import (
+	"context"
+	"encoding/hex"
+	"time"
+
 	goauth "github.com/SwanHtetAungPhyo/go-auth"
 	"github.com/SwanHtetAungPhyo/go-auth/db/services/auth"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/lockout"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/passkey"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/revocation"
+	authsession "github.com/SwanHtetAungPhyo/go-auth/db/services/session"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/tokenstore"
+	db "github.com/SwanHtetAungPhyo/go-auth/db/sqlc"
 	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/fiber/middleware"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/SwanHtetAungPhyo/go-auth/initialization"
 	"github.com/SwanHtetAungPhyo/go-auth/third-party/email"
+	"github.com/SwanHtetAungPhyo/go-auth/third-party/oauth"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/gofiber/fiber/v3/middleware/session"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	// Defaults of GOAUTH_REVOCATION_CACHE_SIZE and
+	// GOAUTH_REVOCATION_CACHE_TTL. The TTL bounds how long a revocation
+	// made on another replica can go unnoticed.
+	revocationCacheSize = 10000
+	revocationCacheTTL  = 30 * time.Second
+	// oauthStateTTL is how long a user has to get through the provider's
+	// consent screen.
+	oauthStateTTL = 10 * time.Minute
+)
+
+// webAuthnTimeout is how long the browser waits for the authenticator, and
+// how long the server keeps the ceremony.
+var webAuthnTimeout = webauthn.TimeoutConfig{
+	Enforce:    true,
+	Timeout:    5 * time.Minute,
+	TimeoutUVD: 5 * time.Minute,
+}
+
 type Option func(authFiber *GoAuthFiber)
 type GoAuthFiber struct {
 	connPool     *pgxpool.Pool
@@ -19,6 +54,23 @@ type GoAuthFiber struct {
 	redis        *redis.Client
 	session      *session.Session
 	emailManager email.EmailManager
+	revocations  revocation.Store
+	sessions     *authsession.Manager
+	// resetTokens, verificationTokens, mfaChallenges, unlockTokens and the
+	// failed login counters in lockouts live wherever sessions do.
+	resetTokens        tokenstore.Store
+	verificationTokens tokenstore.Store
+	mfaChallenges      tokenstore.Store
+	unlockTokens       tokenstore.Store
+	lockouts           lockout.Store
+	passkeyChallenges  passkey.ChallengeStore
+	webAuthn           *webauthn.WebAuthn
+	// oauthProviders and oauthState back the social logins; oauthCallbacks
+	// maps a provider to the page its callback redirects to, if any.
+	oauthProviders []oauth.Provider
+	oauthState     *oauth.StateSigner
+	oauthCallbacks map[string]string
+	tokenCipher    *oauth.TokenCipher
 }
 
 func NewGoAuthFiber(connPool *pgxpool.Pool, cfg goauth.Config, emailManager email.EmailManager, opts ...Option) *GoAuthFiber {
@@ -27,32 +79,187 @@ func NewGoAuthFiber(connPool *pgxpool.Pool, cfg goauth.Config, emailManager emai
 		return nil
 	}
 
-	srv := auth.NewAuthService(connPool, cfg)
 	goauthFiber := &GoAuthFiber{
 		connPool:     connPool,
 		cfg:          cfg,
-		srv:          srv,
 		emailManager: emailManager,
 	}
 	for _, opt := range opts {
 		opt(goauthFiber)
 	}
+	if cfg.SessionStoreAsRedis && goauthFiber.redis == nil {
+		log.Fatal().Msg("goauthFiber.redis is nil in NewGoAuthFiber")
+		return nil
+	}
+	goauthFiber.newStores()
+	goauthFiber.newOAuthProviders()
+	goauthFiber.srv = auth.NewAuthService(connPool, cfg,
+		auth.WithRevocationStore(goauthFiber.revocations),
+		auth.WithSessionManager(goauthFiber.sessions),
+		auth.WithPasswordResetStore(goauthFiber.resetTokens),
+		auth.WithEmailVerificationStore(goauthFiber.verificationTokens),
+		auth.WithMFAChallengeStore(goauthFiber.mfaChallenges),
+		auth.WithWebAuthn(goauthFiber.webAuthn, goauthFiber.passkeyChallenges),
+		auth.WithOAuth(goauthFiber.oauthState, goauthFiber.oauthProviders...),
+		auth.WithProviderTokenCipher(goauthFiber.tokenCipher),
+		auth.WithLockoutStore(goauthFiber.lockouts, goauthFiber.unlockTokens),
+	)
+
+	return goauthFiber
+}
+
+// newStores picks one backend for sessions, one-time tokens and revocations:
+// Redis when SessionStoreAsRedis is set, as runMigrations skips their tables
+// then, and Postgres otherwise.
+func (g *GoAuthFiber) newStores() {
+	idleTimeout := initialization.GetEnvDuration("GOAUTH_SESSION_DURATION", authsession.DefaultIdleTimeout)
 
-	if !cfg.Session {
-		return goauthFiber
+	var revocations revocation.Store
+	if g.cfg.SessionStoreAsRedis {
+		g.sessions = authsession.NewManager(authsession.NewRedisStore(g.redis), idleTimeout)
+		g.resetTokens = tokenstore.NewRedisStore(g.redis, tokenstore.PasswordReset)
+		g.verificationTokens = tokenstore.NewRedisStore(g.redis, tokenstore.EmailVerification)
+		g.mfaChallenges = tokenstore.NewRedisStore(g.redis, tokenstore.MFAChallenge)
+		g.unlockTokens = tokenstore.NewRedisStore(g.redis, tokenstore.AccountUnlock)
+		g.lockouts = lockout.NewRedisStore(g.redis)
+		g.passkeyChallenges = passkey.NewRedisStore(g.redis)
+		revocations = revocation.NewRedisStore(g.redis, utils.RefreshTokenDuration())
+	} else {
+		store := db.NewStore(g.connPool)
+		g.sessions = authsession.NewManager(authsession.NewPostgresStore(store), idleTimeout)
+		g.resetTokens = tokenstore.NewPostgresStore(store, tokenstore.PasswordReset)
+		g.verificationTokens = tokenstore.NewPostgresStore(store, tokenstore.EmailVerification)
+		g.mfaChallenges = tokenstore.NewPostgresStore(store, tokenstore.MFAChallenge)
+		g.unlockTokens = tokenstore.NewPostgresStore(store, tokenstore.AccountUnlock)
+		g.lockouts = lockout.NewPostgresStore(store)
+		g.passkeyChallenges = passkey.NewPostgresStore(store)
+		revocations = revocation.NewPostgresStore(store)
 	}
-	if !cfg.SessionStoreAsRedis {
-		if goauthFiber.session == nil {
-			log.Fatal().Msg("fiber session is needed to be provided in NewGoAuthFiber")
-			return nil
+	g.revocations = revocation.NewCachedStore(revocations,
+		initialization.GetEnvInt("GOAUTH_REVOCATION_CACHE_SIZE", revocationCacheSize),
+		initialization.GetEnvDuration("GOAUTH_REVOCATION_CACHE_TTL", revocationCacheTTL),
+	)
+
+	if g.cfg.WebAuthn != nil {
+		relyingParty, err := webauthn.New(&webauthn.Config{
+			RPID:          g.cfg.WebAuthn.RPID,
+			RPDisplayName: g.cfg.WebAuthn.RPDisplayName,
+			RPOrigins:     g.cfg.WebAuthn.RPOrigins,
+			Timeouts: webauthn.TimeoutsConfig{
+				Login:        webAuthnTimeout,
+				Registration: webAuthnTimeout,
+			},
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("invalid WebAuthn configuration")
 		}
-	} else {
-		if goauthFiber.redis == nil {
-			log.Fatal().Msg("goauthFiber.redis is nil in NewGoAuthFiber")
-			return nil
+		g.webAuthn = relyingParty
+	}
+}
+
+// newOAuthProviders builds a provider for every social login in the config.
+// NewGoAuth has already filled in and validated their credentials.
+func (g *GoAuthFiber) newOAuthProviders() {
+	g.oauthCallbacks = make(map[string]string)
+	if google := g.cfg.GoogleOauth; google != nil {
+		g.oauthProviders = append(g.oauthProviders, oauth.NewGoogle(oauth.GoogleConfig{
+			ClientID:     google.ClientID,
+			ClientSecret: google.ClientSecret,
+			RedirectURL:  google.RedirectURL,
+			AuthURL:      google.AuthURL,
+			TokenURL:     google.TokenURL,
+			JWKSURL:      google.JWKSURL,
+			Issuer:       google.Issuer,
+		}))
+		g.oauthCallbacks[oauth.ProviderGoogle] = google.CallBackURL
+	}
+	if github := g.cfg.GithubOauth; github != nil {
+		g.oauthProviders = append(g.oauthProviders, oauth.NewGitHub(oauth.GitHubConfig{
+			ClientID:     github.ClientID,
+			ClientSecret: github.ClientSecret,
+			RedirectURL:  github.RedirectURL,
+			BaseURL:      github.BaseURL,
+			APIURL:       github.APIURL,
+		}))
+		g.oauthCallbacks[oauth.ProviderGitHub] = github.CallBackURL
+	}
+	for _, provider := range g.cfg.OIDCProviders {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		oidc, err := oauth.NewOIDC(ctx, oauth.OIDCConfig{
+			Name:         provider.Name,
+			IssuerURL:    provider.IssuerURL,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  provider.RedirectURL,
+			Scopes:       provider.Scopes,
+			Claims:       provider.Claims,
+		})
+		cancel()
+		if err != nil {
+			log.Fatal().Err(err).Str("provider", provider.Name).Msg("failed to load OpenID Connect provider")
 		}
+		g.oauthProviders = append(g.oauthProviders, oidc)
+		g.oauthCallbacks[provider.Name] = provider.CallBackURL
 	}
-	return goauthFiber
+	if len(g.oauthProviders) > 0 {
+		signer, err := oauth.NewStateSigner(
+			[]byte(initialization.GetEnv("GOAUTH_OAUTH_STATE_SECRET", "")),
+			initialization.GetEnvDuration("GOAUTH_OAUTH_STATE_DURATION", oauthStateTTL),
+		)
+		if err != nil {
+			log.Fatal().Err(err).Msg("GOAUTH_OAUTH_STATE_SECRET is required when OAuth providers are configured")
+		}
+		g.oauthState = signer
+	}
+	// Provider tokens are only kept when there is a key to encrypt them with.
+	if rawKey := initialization.GetEnv("GOAUTH_PROVIDER_TOKEN_KEY", ""); rawKey != "" {
+		key, err := hex.DecodeString(rawKey)
+		if err != nil {
+			log.Fatal().Err(err).Msg("GOAUTH_PROVIDER_TOKEN_KEY must be hex encoded")
+		}
+		if g.tokenCipher, err = oauth.NewTokenCipher(key); err != nil {
+			log.Fatal().Err(err).Msg("invalid GOAUTH_PROVIDER_TOKEN_KEY")
+		}
+	}
+}
+
+// ProviderToken returns the user's token for an OAuth provider, refreshed if
+// it had expired, for calling the provider's APIs on their behalf. Tokens are
+// kept only when GOAUTH_PROVIDER_TOKEN_KEY is set.
+func (g *GoAuthFiber) ProviderToken(userID uuid.UUID, provider string) (framework.ProviderToken, error) {
+	return g.srv.GetProviderToken(userID, provider)
+}
+
+// ImportUsers inserts users exported from another system, e.g. read with
+// framework.DecodeFirebaseExport, in one transaction. It is not exposed as a
+// route; call it from a migration command.
+func (g *GoAuthFiber) ImportUsers(users []framework.ImportUser) (framework.ImportResult, error) {
+	return g.srv.ImportUsers(users)
+}
+
+// Middleware returns a middleware.Maker sharing the handler's revocation
+// store and session manager, so FiberAuthMiddleware refuses revoked tokens
+// and FiberSessionMiddleware resolves the handler's sessions without further
+// setup. opts are applied after them.
+func (g *GoAuthFiber) Middleware(opts ...middleware.Option) *middleware.Maker {
+	defaults := []middleware.Option{
+		middleware.WithRevocationStore(g.revocations),
+		middleware.WithSessionManager(g.sessions),
+	}
+	return middleware.NewMaker(g.cfg, append(defaults, opts...)...)
+}
+
+// SessionManager returns the manager behind Config.Session. Pass it to
+// middleware.WithSessionManager to protect routes with the session cookie.
+func (g *GoAuthFiber) SessionManager() *authsession.Manager {
+	return g.sessions
+}
+
+// RevocationStore returns the store the handlers revoke tokens in. Middleware
+// wires it in already; pass it to middleware.WithRevocationStore when
+// building a Maker by hand.
+func (g *GoAuthFiber) RevocationStore() revocation.Store {
+	return g.revocations
 }
 
 func WithFiberSessionStore(sess *session.Session) Option {
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	goauth "github.com/SwanHtetAungPhyo/go-auth"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/revocation"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v3"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMiddlewareRefusesRevokedTokens(t *testing.T) {
+	provider, err := utils.NewHMACKeyProvider("HS256", []byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	utils.SetKeyProvider(provider)
+	t.Cleanup(func() { utils.SetKeyProvider(nil) })
+
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	g := &GoAuthFiber{
+		cfg:         goauth.Config{JwtAuth: true},
+		revocations: revocation.NewRedisStore(client, time.Hour),
+	}
+
+	app := fiber.New()
+	app.Get("/me", g.Middleware().FiberAuthMiddleware(), func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	get := func(t *testing.T, token string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+	issue := func(t *testing.T, userID string) *utils.TokenContextContainer {
+		t.Helper()
+		tokens, err := utils.GenerateToken(utils.Claims{UserID: userID, Role: "USER"}, utils.JWT, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tokens
+	}
+	ctx := context.Background()
+
+	revoked, kept := issue(t, "user-1"), issue(t, "user-1")
+	if err := g.revocations.Revoke(ctx, revoked.AccessTokenID, revoked.AccessExpiresAt); err != nil {
+		t.Fatal(err)
+	}
+	if got := get(t, revoked.AccessToken); got != fiber.StatusUnauthorized {
+		t.Errorf("revoked token: status %d, want 401", got)
+	}
+	if got := get(t, kept.AccessToken); got != fiber.StatusNoContent {
+		t.Errorf("live token: status %d, want 204", got)
+	}
+
+	// Logging out everywhere cuts off every token issued before it.
+	other := issue(t, "user-2")
+	if err := g.revocations.RevokeUser(ctx, "user-2", time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if got := get(t, other.AccessToken); got != fiber.StatusUnauthorized {
+		t.Errorf("token of a user logged out everywhere: status %d, want 401", got)
+	}
+}
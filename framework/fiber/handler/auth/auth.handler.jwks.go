@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+)
+
+// JWKS serves the public verification keys, intended for
+// GET /.well-known/jwks.json. Retired keys stay listed until the tokens they
+// signed have expired, so verifiers can cache the document briefly.
+func (g *GoAuthFiber) JWKS(c fiber.Ctx) error {
+	set, err := utils.JWKS()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load JWKS")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "signing keys unavailable",
+		})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(set)
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/auth"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/gofiber/fiber/v3"
+)
+
+// UnlockAccount unlocks an account from the link emailed when repeated
+// failed logins locked it, sent as {"token": "..."} or ?token=...
+func (g *GoAuthFiber) UnlockAccount(c fiber.Ctx) error {
+	req := framework.UnlockAccountRequest{Token: c.Query("token")}
+	if req.Token == "" {
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+	if err := framework.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	req.IPAddress = c.IP()
+
+	if err := g.srv.UnlockAccount(&req); err != nil {
+		if errors.Is(err, auth.ErrInvalidUnlockToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid or expired unlock token",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to unlock account",
+		})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// loginThrottled answers a login refused by the lockout policy with 429 and
+// a Retry-After header in whole seconds.
+func loginThrottled(c fiber.Ctx, throttled *auth.LoginThrottledError) error {
+	code := "too_many_attempts"
+	if throttled.Locked {
+		code = "account_locked"
+	}
+	return tooManyRequests(c, throttled.RetryAfter, throttled.Error(), code)
+}
+
+// tooManyRequests answers with 429 and a Retry-After header in whole seconds.
+func tooManyRequests(c fiber.Ctx, wait time.Duration, message, code string) error {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":       message,
+		"code":        code,
+		"retry_after": retryAfter,
+	})
+}
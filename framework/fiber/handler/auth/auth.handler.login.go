@@ -1,10 +1,12 @@
 package auth
 
 import (
-	"os"
+	"errors"
 	"time"
 
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/auth"
 	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/fiber/middleware"
 	"github.com/gofiber/fiber/v3"
 	"github.com/rs/zerolog/log"
 )
@@ -24,7 +26,20 @@ func (g *GoAuthFiber) Login(c fiber.Ctx) error {
 		})
 	}
 
+	req.UserAgent = c.Get(fiber.HeaderUserAgent)
+	req.IPAddress = c.IP()
+
 	authResponse, err := g.srv.Login(&req)
+	var throttled *auth.LoginThrottledError
+	if errors.As(err, &throttled) {
+		return loginThrottled(c, throttled)
+	}
+	if errors.Is(err, auth.ErrEmailNotVerified) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "email address has not been verified",
+			"code":  "email_not_verified",
+		})
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("Login failed")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -32,45 +47,22 @@ func (g *GoAuthFiber) Login(c fiber.Ctx) error {
 		})
 	}
 
-	isProduction := os.Getenv("ENVIRONMENT") == "production"
-	refreshCookies := fiber.Cookie{
-		Name:        "refresh_token",
-		Value:       authResponse.RefreshToken,
-		Path:        "/",                                 // Set to root path so cookie is available site-wide
-		Domain:      "",                                  // Leave empty for same-domain, or set specific domain in production
-		Expires:     time.Now().Add(time.Hour * 24 * 7),  // 7 days for refresh token
-		MaxAge:      int((time.Hour * 24 * 7).Seconds()), // 7 days in seconds
-		Secure:      isProduction,                        // true in production (HTTPS only)
-		HTTPOnly:    true,                                // Prevents XSS attacks by making cookie inaccessible to JavaScript
-		SameSite:    fiber.CookieSameSiteLaxMode,         // CSRF protection
-		Partitioned: false,
-		SessionOnly: false,
-	}
-	c.Cookie(&refreshCookies)
-	return c.JSON(authResponse)
-}
-
-func (g *GoAuthFiber) Logout(c fiber.Ctx) error {
-	//TODO implement me
-	panic("implement me")
+	return g.loginResponse(c, authResponse)
 }
 
-func (g *GoAuthFiber) GoogleLogin(c fiber.Ctx) error {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (g *GoAuthFiber) GoogleCallback(c fiber.Ctx) error {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (g *GoAuthFiber) GithubLogin(c fiber.Ctx) error {
-	//TODO implement me
-	panic("implement me")
+// loginResponse sets the refresh and session cookies for whatever
+// credentials a login issued and writes the response body. An MFA challenge
+// carries neither, so it is passed through as is.
+func (g *GoAuthFiber) loginResponse(c fiber.Ctx, authResponse framework.AuthResponse) error {
+	g.setLoginCookies(c, authResponse)
+	return c.JSON(authResponse)
 }
 
-func (g *GoAuthFiber) GithubCallback(c fiber.Ctx) error {
-	//TODO implement me
-	panic("implement me")
+func (g *GoAuthFiber) setLoginCookies(c fiber.Ctx, authResponse framework.AuthResponse) {
+	if authResponse.RefreshToken != "" {
+		setRefreshCookie(c, authResponse.RefreshToken)
+	}
+	if authResponse.SessionToken != "" {
+		middleware.SetSessionCookie(c, authResponse.SessionToken, time.Now().Add(g.sessions.IdleTimeout()))
+	}
 }
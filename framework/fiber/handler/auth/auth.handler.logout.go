@@ -1 +1,75 @@
 package auth
+
+import (
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/fiber/middleware"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/session"
+	"github.com/rs/zerolog/log"
+)
+
+// Logout revokes whatever the client presented: the bearer access token, the
+// refresh_token cookie (or {"refresh_token": "..."}) and the server session.
+// It succeeds even when nothing was presented, so clients can always call it.
+func (g *GoAuthFiber) Logout(c fiber.Ctx) error {
+	var req framework.LogoutRequest
+	req.RefreshToken = c.Cookies(refreshTokenCookie)
+	if req.RefreshToken == "" {
+		_ = c.Bind().Body(&req)
+	}
+	req.AccessToken = utils.ExtractToken(c.Get("Authorization"))
+	if sessionID, ok := c.Locals(utils.SessionId).(string); ok {
+		req.SessionID = sessionID
+	}
+	req.SessionToken = c.Cookies(middleware.SessionCookieName)
+
+	if err := g.srv.Logout(&req); err != nil {
+		log.Error().Err(err).Msg("Logout failed")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "logout failed",
+		})
+	}
+	if err := destroyFiberSession(c); err != nil {
+		log.Error().Err(err).Msg("failed to destroy fiber session")
+	}
+
+	clearRefreshCookie(c)
+	middleware.ClearSessionCookie(c)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// LogoutAll signs the authenticated user out of every device. It must run
+// behind the auth middleware.
+func (g *GoAuthFiber) LogoutAll(c fiber.Ctx) error {
+	userId, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid token",
+		})
+	}
+
+	if err := g.srv.LogoutAll(userId); err != nil {
+		log.Error().Err(err).Msg("LogoutAll failed")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "logout failed",
+		})
+	}
+	if err := destroyFiberSession(c); err != nil {
+		log.Error().Err(err).Msg("failed to destroy fiber session")
+	}
+
+	clearRefreshCookie(c)
+	middleware.ClearSessionCookie(c)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// destroyFiberSession removes the Fiber session, backed by memory or Redis,
+// when the session middleware is installed.
+func destroyFiberSession(c fiber.Ctx) error {
+	sess := session.FromContext(c)
+	if sess == nil {
+		return nil
+	}
+	return sess.Destroy()
+}
@@ -9,7 +9,7 @@ import (
 )
 
 func (g *GoAuthFiber) Me(c fiber.Ctx) error {
-	userId := c.Locals("userId")
+	userId := c.Locals(utils.UserId)
 	if userId == nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(utils.GeneralResponse{
 			Message: "UserId was not found",
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/auth"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/third-party/oauth"
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+)
+
+// oauthBindingCookie ties a callback to the browser that started the flow,
+// so a victim cannot be signed in to an attacker's account through a forged
+// callback link. Link flows use oauthLinkCookie instead, which also tells the
+// shared callback what to do.
+const (
+	oauthBindingCookie = "goauth_oauth_binding"
+	oauthLinkCookie    = "goauth_oauth_link"
+)
+
+// GoogleLogin redirects the browser to Google's consent screen.
+func (g *GoAuthFiber) GoogleLogin(c fiber.Ctx) error {
+	return g.oauthLogin(c, oauth.ProviderGoogle)
+}
+
+// GoogleCallback finishes a Google login. With a CallBackURL configured the
+// browser is redirected there, otherwise it gets the Login response.
+func (g *GoAuthFiber) GoogleCallback(c fiber.Ctx) error {
+	return g.oauthCallback(c, oauth.ProviderGoogle)
+}
+
+// GithubLogin redirects the browser to GitHub's authorization page.
+func (g *GoAuthFiber) GithubLogin(c fiber.Ctx) error {
+	return g.oauthLogin(c, oauth.ProviderGitHub)
+}
+
+// GithubCallback finishes a GitHub login, as GoogleCallback does.
+func (g *GoAuthFiber) GithubCallback(c fiber.Ctx) error {
+	return g.oauthCallback(c, oauth.ProviderGitHub)
+}
+
+// OAuthLogin starts a login with the provider named by the :provider route
+// parameter, e.g. an OIDCProvider, or google and github when configured.
+func (g *GoAuthFiber) OAuthLogin(c fiber.Ctx) error {
+	return g.oauthLogin(c, c.Params("provider"))
+}
+
+// OAuthCallback finishes a login started by OAuthLogin.
+func (g *GoAuthFiber) OAuthCallback(c fiber.Ctx) error {
+	return g.oauthCallback(c, c.Params("provider"))
+}
+
+func (g *GoAuthFiber) oauthLogin(c fiber.Ctx, provider string) error {
+	authURL, binding, err := g.srv.BeginOAuth(provider)
+	if errors.Is(err, auth.ErrOAuthProviderUnknown) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to start oauth login",
+		})
+	}
+
+	g.setOAuthCookie(c, oauthBindingCookie, binding)
+	return c.Redirect().To(authURL)
+}
+
+func (g *GoAuthFiber) oauthCallback(c fiber.Ctx, provider string) error {
+	if linkBinding := c.Cookies(oauthLinkCookie); linkBinding != "" {
+		clearOAuthCookie(c, oauthLinkCookie)
+		return g.linkCallback(c, provider, linkBinding)
+	}
+	binding := c.Cookies(oauthBindingCookie)
+	clearOAuthCookie(c, oauthBindingCookie)
+
+	// The user declined, or the provider refused the request.
+	if providerErr := c.Query("error"); providerErr != "" {
+		return g.oauthFailure(c, provider, fiber.StatusUnauthorized, providerErr, "oauth login was not completed")
+	}
+
+	req := framework.OAuthCallbackRequest{
+		Provider:  provider,
+		Code:      c.Query("code"),
+		State:     c.Query("state"),
+		Binding:   binding,
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		IPAddress: c.IP(),
+	}
+	if err := framework.ValidateStruct(req); err != nil {
+		return g.oauthFailure(c, provider, fiber.StatusBadRequest, "invalid_request", err.Error())
+	}
+
+	authResponse, err := g.srv.OAuthLogin(&req)
+	switch {
+	case err == nil:
+	case errors.Is(err, auth.ErrOAuthProviderUnknown):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, auth.ErrInvalidOAuthState):
+		return g.oauthFailure(c, provider, fiber.StatusBadRequest, "invalid_state", err.Error())
+	case errors.Is(err, auth.ErrOAuthFailed):
+		return g.oauthFailure(c, provider, fiber.StatusUnauthorized, "oauth_failed", err.Error())
+	case errors.Is(err, auth.ErrOAuthEmailRequired):
+		return g.oauthFailure(c, provider, fiber.StatusBadRequest, "email_required", err.Error())
+	case errors.Is(err, auth.ErrOAuthEmailUnverified):
+		return g.oauthFailure(c, provider, fiber.StatusForbidden, "email_unverified", err.Error())
+	case errors.Is(err, auth.ErrOAuthEmailInUse):
+		return g.oauthFailure(c, provider, fiber.StatusConflict, "email_in_use", err.Error())
+	case errors.Is(err, auth.ErrEmailNotVerified):
+		return g.oauthFailure(c, provider, fiber.StatusForbidden, "email_not_verified", err.Error())
+	default:
+		log.Error().Err(err).Str("provider", provider).Msg("oauth login failed")
+		return g.oauthFailure(c, provider, fiber.StatusInternalServerError, "server_error", "oauth login failed")
+	}
+
+	callbackURL := g.oauthCallbacks[provider]
+	if callbackURL == "" {
+		return g.loginResponse(c, authResponse)
+	}
+	g.setLoginCookies(c, authResponse)
+	// The access token is not put in the URL; the page gets one from the
+	// refresh cookie, or finishes the MFA step with the short-lived token.
+	query := url.Values{}
+	if authResponse.MFARequired {
+		query.Set("mfa_token", authResponse.MFAToken)
+		query.Set("mfa_methods", strings.Join(authResponse.MFAMethods, ","))
+	}
+	return c.Redirect().To(withQuery(callbackURL, query))
+}
+
+// oauthFailure redirects to the provider's CallBackURL with an error code, or
+// answers with JSON when there is none.
+func (g *GoAuthFiber) oauthFailure(c fiber.Ctx, provider string, status int, code, message string) error {
+	if callbackURL := g.oauthCallbacks[provider]; callbackURL != "" {
+		return c.Redirect().To(withQuery(callbackURL, url.Values{"error": {code}}))
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": message,
+		"code":  code,
+	})
+}
+
+func (g *GoAuthFiber) setOAuthCookie(c fiber.Ctx, name, binding string) {
+	c.Cookie(&fiber.Cookie{
+		Name:     name,
+		Value:    binding,
+		Path:     "/",
+		MaxAge:   int(g.oauthState.TTL().Seconds()),
+		Secure:   os.Getenv("ENVIRONMENT") == "production",
+		HTTPOnly: true,
+		// Lax, not Strict: the callback is a top-level navigation from the
+		// provider's site.
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+func clearOAuthCookie(c fiber.Ctx, name string) {
+	c.Cookie(&fiber.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		Secure:   os.Getenv("ENVIRONMENT") == "production",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+func withQuery(rawURL string, query url.Values) string {
+	if len(query) == 0 {
+		return rawURL
+	}
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + query.Encode()
+}
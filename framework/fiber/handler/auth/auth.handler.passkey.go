@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/auth"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// BeginPasskeyRegistration returns the options for
+// navigator.credentials.create to add a passkey to the signed-in user.
+func (g *GoAuthFiber) BeginPasskeyRegistration(c fiber.Ctx) error {
+	userId, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid token",
+		})
+	}
+
+	creation, err := g.srv.BeginPasskeyRegistration(userId)
+	if err != nil {
+		return passkeyError(c, err, "failed to start passkey registration")
+	}
+	return c.JSON(creation)
+}
+
+// FinishPasskeyRegistration stores the passkey from
+// {"name": "...", "credential": <PublicKeyCredential>}.
+func (g *GoAuthFiber) FinishPasskeyRegistration(c fiber.Ctx) error {
+	userId, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid token",
+		})
+	}
+	var req framework.PasskeyRegisterRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := framework.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	passkey, err := g.srv.FinishPasskeyRegistration(userId, &req)
+	if err != nil {
+		return passkeyError(c, err, "failed to register passkey")
+	}
+	return c.Status(fiber.StatusCreated).JSON(utils.GeneralResponse{
+		Data: passkey,
+	})
+}
+
+// BeginPasskeyLogin returns the options for navigator.credentials.get for a
+// passwordless login.
+func (g *GoAuthFiber) BeginPasskeyLogin(c fiber.Ctx) error {
+	assertion, err := g.srv.BeginPasskeyLogin()
+	if err != nil {
+		return passkeyError(c, err, "failed to start passkey login")
+	}
+	return c.JSON(assertion)
+}
+
+// FinishPasskeyLogin signs the user in from {"credential":
+// <PublicKeyCredential>} and responds as Login does.
+func (g *GoAuthFiber) FinishPasskeyLogin(c fiber.Ctx) error {
+	req, err := bindPasskeyLogin(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	authResponse, err := g.srv.FinishPasskeyLogin(&req)
+	if err != nil {
+		return passkeyError(c, err, "failed to sign in with passkey")
+	}
+	return g.loginResponse(c, authResponse)
+}
+
+// BeginPasskeyMFA returns the options for navigator.credentials.get to
+// answer a login's {"mfa_token"} with one of the user's passkeys.
+func (g *GoAuthFiber) BeginPasskeyMFA(c fiber.Ctx) error {
+	var req framework.PasskeyMFARequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := framework.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	assertion, err := g.srv.BeginPasskeyMFA(req.MFAToken)
+	if err != nil {
+		return passkeyError(c, err, "failed to start passkey verification")
+	}
+	return c.JSON(assertion)
+}
+
+// FinishPasskeyMFA completes the login from {"mfa_token": "...",
+// "credential": <PublicKeyCredential>}.
+func (g *GoAuthFiber) FinishPasskeyMFA(c fiber.Ctx) error {
+	req, err := bindPasskeyLogin(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if req.MFAToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "mfa_token is required",
+		})
+	}
+
+	authResponse, err := g.srv.FinishPasskeyMFA(&req)
+	if err != nil {
+		return passkeyError(c, err, "failed to verify passkey")
+	}
+	return g.loginResponse(c, authResponse)
+}
+
+// ListPasskeys returns the signed-in user's passkeys.
+func (g *GoAuthFiber) ListPasskeys(c fiber.Ctx) error {
+	userId, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid token",
+		})
+	}
+
+	passkeys, err := g.srv.ListPasskeys(userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list passkeys",
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.GeneralResponse{
+		Data: passkeys,
+	})
+}
+
+// DeletePasskey removes the passkey named by the :id route parameter.
+func (g *GoAuthFiber) DeletePasskey(c fiber.Ctx) error {
+	userId, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid token",
+		})
+	}
+	passkeyId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid passkey id",
+		})
+	}
+
+	if err := g.srv.DeletePasskey(userId, passkeyId); err != nil {
+		return passkeyError(c, err, "failed to delete passkey")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func bindPasskeyLogin(c fiber.Ctx) (framework.PasskeyLoginRequest, error) {
+	var req framework.PasskeyLoginRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return req, err
+	}
+	if err := framework.ValidateStruct(req); err != nil {
+		return req, err
+	}
+	req.UserAgent = c.Get(fiber.HeaderUserAgent)
+	req.IPAddress = c.IP()
+	return req, nil
+}
+
+// passkeyError maps the passkey service errors to responses, falling back to
+// a 500 with message.
+func passkeyError(c fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, auth.ErrWebAuthnDisabled):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "passkeys are not enabled",
+		})
+	case errors.Is(err, auth.ErrInvalidPasskey):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "passkey verification failed",
+		})
+	case errors.Is(err, auth.ErrInvalidMFAToken):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid or expired mfa token",
+		})
+	case errors.Is(err, auth.ErrPasskeyNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "passkey not found",
+		})
+	case errors.Is(err, auth.ErrEmailNotVerified):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "email address has not been verified",
+			"code":  "email_not_verified",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -1 +1,83 @@
 package auth
+
+import (
+	"errors"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/auth"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/gofiber/fiber/v3"
+)
+
+// RequestPasswordReset emails a reset link. The response is the same whether
+// or not the email is registered.
+func (g *GoAuthFiber) RequestPasswordReset(c fiber.Ctx) error {
+	var req framework.PasswordResetRequest
+
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := framework.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	req.IPAddress = c.IP()
+
+	if err := g.srv.RequestPasswordReset(&req); err != nil {
+		var throttled *auth.ResetThrottledError
+		if errors.As(err, &throttled) {
+			return tooManyRequests(c, throttled.RetryAfter, throttled.Error(), "too_many_requests")
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to request password reset",
+		})
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "if the email is registered, a reset link has been sent",
+	})
+}
+
+// ConfirmPasswordReset sets a new password from a reset link token and signs
+// the user out everywhere.
+func (g *GoAuthFiber) ConfirmPasswordReset(c fiber.Ctx) error {
+	var req framework.PasswordResetConfirmRequest
+
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := framework.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := g.srv.ConfirmPasswordReset(&req); err != nil {
+		var policyErr *utils.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			return passwordPolicyFailure(c, policyErr)
+		}
+		if errors.Is(err, auth.ErrPasswordReused) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+				"code":  "password_reused",
+			})
+		}
+		if errors.Is(err, auth.ErrInvalidResetToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid or expired reset token",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to reset password",
+		})
+	}
+
+	clearRefreshCookie(c)
+	return c.SendStatus(fiber.StatusNoContent)
+}
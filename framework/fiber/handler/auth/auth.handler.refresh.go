@@ -1 +1,42 @@
 package auth
+
+import (
+	"errors"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/auth"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/gofiber/fiber/v3"
+)
+
+// Refresh rotates the refresh_token cookie set by Login. Clients that cannot
+// use cookies may send {"refresh_token": "..."} instead.
+func (g *GoAuthFiber) Refresh(c fiber.Ctx) error {
+	refreshToken := c.Cookies(refreshTokenCookie)
+	if refreshToken == "" {
+		var req framework.RefreshRequest
+		if err := c.Bind().Body(&req); err == nil {
+			refreshToken = req.RefreshToken
+		}
+	}
+	if refreshToken == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing refresh token",
+		})
+	}
+
+	authResponse, err := g.srv.Refresh(refreshToken)
+	if err != nil {
+		clearRefreshCookie(c)
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "refresh token reuse detected",
+			})
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid refresh token",
+		})
+	}
+
+	setRefreshCookie(c, authResponse.RefreshToken)
+	return c.JSON(authResponse)
+}
@@ -1,8 +1,12 @@
 package auth
 
 import (
+	"errors"
+
 	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
 	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
 )
 
 func (g *GoAuthFiber) Register(c fiber.Ctx) error {
@@ -13,12 +17,35 @@ func (g *GoAuthFiber) Register(c fiber.Ctx) error {
 		})
 	}
 	if err := framework.ValidateStruct(req); err != nil {
-		return c.JSON(fiber.Map{
-			"error": err,
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	authResponse, err := g.srv.Register(&req)
+	var policyErr *utils.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return passwordPolicyFailure(c, policyErr)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Register failed")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "registration failed",
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
+	if authResponse.RefreshToken != "" {
+		setRefreshCookie(c, authResponse.RefreshToken)
+	}
+	return c.Status(fiber.StatusCreated).JSON(authResponse)
+}
+
+// passwordPolicyFailure lists every rule the password broke, so a frontend
+// can show them all at once by their codes.
+func passwordPolicyFailure(c fiber.Ctx, policyErr *utils.PasswordPolicyError) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":      "password does not meet the policy",
+		"code":       "password_policy",
+		"violations": policyErr.Violations,
 	})
 }
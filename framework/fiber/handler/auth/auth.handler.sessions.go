@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/auth"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// ListSessions returns the signed-in user's active sessions, flagging the one
+// the request came from. It must run behind the auth or session middleware.
+func (g *GoAuthFiber) ListSessions(c fiber.Ctx) error {
+	userId, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid token",
+		})
+	}
+	currentSessionId, _ := c.Locals(utils.SessionId).(string)
+
+	sessions, err := g.srv.ListSessions(userId, currentSessionId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list sessions",
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.GeneralResponse{
+		Data: sessions,
+	})
+}
+
+// RevokeSession signs out the session named by the :id route parameter if
+// it belongs to the signed-in user.
+func (g *GoAuthFiber) RevokeSession(c fiber.Ctx) error {
+	userId, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid token",
+		})
+	}
+	sessionId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid session id",
+		})
+	}
+
+	if err := g.srv.RevokeSession(userId, sessionId); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "session not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to revoke session",
+		})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// currentUserID reads the user id the auth or session middleware stored.
+func currentUserID(c fiber.Ctx) (uuid.UUID, bool) {
+	userId, ok := c.Locals(utils.UserId).(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(userId)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return id, true
+}
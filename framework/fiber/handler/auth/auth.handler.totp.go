@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/auth"
+	"github.com/SwanHtetAungPhyo/go-auth/framework"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/gofiber/fiber/v3"
+)
+
+// EnrollTOTP starts two-factor setup for the signed-in user and returns the
+// secret, otpauth:// URI and QR code for their authenticator app. It must
+// run behind the auth or session middleware.
+func (g *GoAuthFiber) EnrollTOTP(c fiber.Ctx) error {
+	userId, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid token",
+		})
+	}
+
+	enrollment, err := g.srv.EnrollTOTP(userId)
+	if err != nil {
+		if errors.Is(err, auth.ErrTOTPAlreadyEnabled) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "two-factor authentication is already enabled",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to start two-factor enrollment",
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.GeneralResponse{
+		Data: enrollment,
+	})
+}
+
+// ConfirmTOTP enables two-factor once the user submits a first code,
+// {"code": "123456"}, from their authenticator app.
+func (g *GoAuthFiber) ConfirmTOTP(c fiber.Ctx) error {
+	userId, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid token",
+		})
+	}
+	req, err := bindTOTPCode(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := g.srv.ConfirmTOTP(userId, req.Code); err != nil {
+		return totpError(c, err, "failed to enable two-factor authentication")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// DisableTOTP turns two-factor off. It takes a current code like
+// ConfirmTOTP.
+func (g *GoAuthFiber) DisableTOTP(c fiber.Ctx) error {
+	userId, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid token",
+		})
+	}
+	req, err := bindTOTPCode(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := g.srv.DisableTOTP(userId, req.Code); err != nil {
+		return totpError(c, err, "failed to disable two-factor authentication")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes, taking a
+// current {"code"}, and returns the new set once.
+func (g *GoAuthFiber) RegenerateRecoveryCodes(c fiber.Ctx) error {
+	userId, ok := currentUserID(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "missing or invalid token",
+		})
+	}
+	req, err := bindTOTPCode(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	codes, err := g.srv.RegenerateRecoveryCodes(userId, req.Code)
+	if err != nil {
+		return totpError(c, err, "failed to regenerate recovery codes")
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.GeneralResponse{
+		Data: framework.RecoveryCodesResponse{RecoveryCodes: codes},
+	})
+}
+
+// VerifyMFA finishes a login that answered {"mfa_required": true}, taking
+// {"mfa_token": "...", "code": "123456"} and responding as Login does. A
+// recovery code may be sent as the code instead.
+func (g *GoAuthFiber) VerifyMFA(c fiber.Ctx) error {
+	var req framework.MFAVerifyRequest
+
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := framework.ValidateStruct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	req.UserAgent = c.Get(fiber.HeaderUserAgent)
+	req.IPAddress = c.IP()
+
+	authResponse, err := g.srv.VerifyMFA(&req)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidMFAToken) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "invalid or expired mfa token",
+			})
+		}
+		return totpError(c, err, "failed to verify two-factor code")
+	}
+	return g.loginResponse(c, authResponse)
+}
+
+func bindTOTPCode(c fiber.Ctx) (framework.TOTPCodeRequest, error) {
+	var req framework.TOTPCodeRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return req, err
+	}
+	return req, framework.ValidateStruct(req)
+}
+
+// totpError maps the two-factor service errors to responses, answering a
+// throttled code as a throttled login does and falling back to a 500 with
+// message.
+func totpError(c fiber.Ctx, err error, message string) error {
+	var throttled *auth.LoginThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		return loginThrottled(c, throttled)
+	case errors.Is(err, auth.ErrInvalidTOTPCode):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid two-factor code",
+		})
+	case errors.Is(err, auth.ErrTOTPAlreadyEnabled):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "two-factor authentication is already enabled",
+		})
+	case errors.Is(err, auth.ErrTOTPNotEnrolled):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "two-factor authentication has not been set up",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -1,16 +1,24 @@
 package middleware
 
 import (
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/revocation"
 	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
 	"github.com/gofiber/fiber/v3"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
 
 	"net/http"
 )
 
-// FiberAuthMiddleware returns a Fiber middleware that validates JWT or PASETO tokens
-// and stores the user_id and full claims in locals.
+// FiberAuthMiddleware returns a Fiber middleware that validates JWT or PASETO
+// access tokens and stores the user_id and full claims in locals. Refresh
+// tokens are refused. With a revocation store configured, revoked tokens are
+// refused as well; GoAuthFiber.Middleware configures it.
 func (m *Maker) FiberAuthMiddleware() fiber.Handler {
+	if m.revocations == nil {
+		log.Warn().Msg("no revocation store in FiberAuthMiddleware; revoked tokens are accepted until they expire")
+	}
+
 	return func(c fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		tokenString := utils.ExtractToken(authHeader)
@@ -53,6 +61,31 @@ func (m *Maker) FiberAuthMiddleware() fiber.Handler {
 			}
 		}
 
+		// Refresh tokens are signed with the same key; only access tokens
+		// may be used as bearer credentials.
+		if tokenType, _ := claims[utils.Type].(string); tokenType != string(utils.JWT_ACCESS_TOKEN) {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+				"error": "token is not an access token",
+			})
+		}
+
+		if m.revocations != nil {
+			jti, _ := claims[utils.Jti].(string)
+			issuedAt, _ := utils.ClaimTime(claims, utils.IssuedAt)
+			revoked, err := revocation.IsTokenRevoked(c, m.revocations, jti, userID, issuedAt)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to check token revocation")
+				return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{
+					"error": "unable to verify token",
+				})
+			}
+			if revoked {
+				return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+					"error": "token has been revoked",
+				})
+			}
+		}
+
 		c.Locals("user_id", userID)
 		c.Locals("user_claims", claims)
 
@@ -1,11 +1,37 @@
 package middleware
 
-import goauth "github.com/SwanHtetAungPhyo/go-auth"
+import (
+	goauth "github.com/SwanHtetAungPhyo/go-auth"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/revocation"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/session"
+)
 
 type Maker struct {
-	cfg goauth.Config
+	cfg         goauth.Config
+	revocations revocation.Store
+	sessions    *session.Manager
 }
 
-func NewMaker(cfg goauth.Config) *Maker {
-	return &Maker{cfg: cfg}
+type Option func(*Maker)
+
+func NewMaker(cfg goauth.Config, opts ...Option) *Maker {
+	maker := &Maker{cfg: cfg}
+	for _, opt := range opts {
+		opt(maker)
+	}
+	return maker
+}
+
+// WithRevocationStore makes FiberAuthMiddleware refuse revoked tokens.
+func WithRevocationStore(store revocation.Store) Option {
+	return func(m *Maker) {
+		m.revocations = store
+	}
+}
+
+// WithSessionManager enables FiberSessionMiddleware.
+func WithSessionManager(manager *session.Manager) Option {
+	return func(m *Maker) {
+		m.sessions = manager
+	}
 }
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/session"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog/log"
+)
+
+// SessionCookieName is the cookie holding the opaque session token.
+const SessionCookieName = "goauth_session"
+
+// FiberSessionMiddleware resolves the session cookie to a user and stores
+// the user_id and session_id in locals. Each use slides the expiry forward
+// and the cookie is re-issued when that happens.
+func (m *Maker) FiberSessionMiddleware() fiber.Handler {
+	if m.sessions == nil {
+		log.Fatal().Msg("session manager is nil in FiberSessionMiddleware")
+		return nil
+	}
+
+	return func(c fiber.Ctx) error {
+		token := c.Cookies(SessionCookieName)
+		if token == "" {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+				"error": "missing session",
+			})
+		}
+
+		sess, extended, err := m.sessions.Resolve(c, token)
+		if errors.Is(err, session.ErrSessionNotFound) {
+			ClearSessionCookie(c)
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+				"error": "invalid or expired session",
+			})
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("failed to resolve session")
+			return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "unable to verify session",
+			})
+		}
+		if extended {
+			SetSessionCookie(c, token, sess.ExpiresAt)
+		}
+
+		c.Locals(utils.UserId, sess.UserID.String())
+		c.Locals(utils.SessionId, sess.ID.String())
+
+		return c.Next()
+	}
+}
+
+// SetSessionCookie writes the HttpOnly session cookie.
+func SetSessionCookie(c fiber.Ctx, token string, expiresAt time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		MaxAge:   int(time.Until(expiresAt).Seconds()),
+		Secure:   os.Getenv("ENVIRONMENT") == "production",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// ClearSessionCookie expires the session cookie in the browser.
+func ClearSessionCookie(c fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		Secure:   os.Getenv("ENVIRONMENT") == "production",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
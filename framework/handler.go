@@ -12,52 +12,197 @@ import (
 type (
 	Fiber interface {
 		Login(c fiber.Ctx) error
+		Refresh(c fiber.Ctx) error
 		Register(c fiber.Ctx) error
 		Logout(c fiber.Ctx) error
+		LogoutAll(c fiber.Ctx) error
+		ListSessions(c fiber.Ctx) error
+		RevokeSession(c fiber.Ctx) error
+		RequestPasswordReset(c fiber.Ctx) error
+		ConfirmPasswordReset(c fiber.Ctx) error
+		ChangePassword(c fiber.Ctx) error
+		UnlockAccount(c fiber.Ctx) error
+		VerifyEmail(c fiber.Ctx) error
+		ResendVerification(c fiber.Ctx) error
+		EnrollTOTP(c fiber.Ctx) error
+		ConfirmTOTP(c fiber.Ctx) error
+		DisableTOTP(c fiber.Ctx) error
+		RegenerateRecoveryCodes(c fiber.Ctx) error
+		VerifyMFA(c fiber.Ctx) error
+		BeginPasskeyRegistration(c fiber.Ctx) error
+		FinishPasskeyRegistration(c fiber.Ctx) error
+		BeginPasskeyLogin(c fiber.Ctx) error
+		FinishPasskeyLogin(c fiber.Ctx) error
+		BeginPasskeyMFA(c fiber.Ctx) error
+		FinishPasskeyMFA(c fiber.Ctx) error
+		ListPasskeys(c fiber.Ctx) error
+		DeletePasskey(c fiber.Ctx) error
 		GoogleLogin(c fiber.Ctx) error
 		GoogleCallback(c fiber.Ctx) error
 		GithubLogin(c fiber.Ctx) error
 		GithubCallback(c fiber.Ctx) error
+		OAuthLogin(c fiber.Ctx) error
+		OAuthCallback(c fiber.Ctx) error
+		LinkAccount(c fiber.Ctx) error
+		ListAccounts(c fiber.Ctx) error
+		UnlinkAccount(c fiber.Ctx) error
 		Me(c fiber.Ctx) error
+		JWKS(c fiber.Ctx) error
 	}
 
 	Gin interface {
 		Login(ctx *gin.Context)
+		Refresh(ctx *gin.Context)
 		Register(ctx *gin.Context)
 		Logout(ctx *gin.Context)
+		LogoutAll(ctx *gin.Context)
+		ListSessions(ctx *gin.Context)
+		RevokeSession(ctx *gin.Context)
+		RequestPasswordReset(ctx *gin.Context)
+		ConfirmPasswordReset(ctx *gin.Context)
+		ChangePassword(ctx *gin.Context)
+		UnlockAccount(ctx *gin.Context)
+		VerifyEmail(ctx *gin.Context)
+		ResendVerification(ctx *gin.Context)
+		EnrollTOTP(ctx *gin.Context)
+		ConfirmTOTP(ctx *gin.Context)
+		DisableTOTP(ctx *gin.Context)
+		RegenerateRecoveryCodes(ctx *gin.Context)
+		VerifyMFA(ctx *gin.Context)
+		BeginPasskeyRegistration(ctx *gin.Context)
+		FinishPasskeyRegistration(ctx *gin.Context)
+		BeginPasskeyLogin(ctx *gin.Context)
+		FinishPasskeyLogin(ctx *gin.Context)
+		BeginPasskeyMFA(ctx *gin.Context)
+		FinishPasskeyMFA(ctx *gin.Context)
+		ListPasskeys(ctx *gin.Context)
+		DeletePasskey(ctx *gin.Context)
 		GoogleLogin(ctx *gin.Context)
 		GoogleCallback(ctx *gin.Context)
 		GithubLogin(ctx *gin.Context)
 		GithubCallback(ctx *gin.Context)
+		OAuthLogin(ctx *gin.Context)
+		OAuthCallback(ctx *gin.Context)
+		LinkAccount(ctx *gin.Context)
+		ListAccounts(ctx *gin.Context)
+		UnlinkAccount(ctx *gin.Context)
+		JWKS(ctx *gin.Context)
 	}
 
 	Echo interface {
 		Login(c echo.Context) error
+		Refresh(c echo.Context) error
 		Register(c echo.Context) error
 		Logout(c echo.Context) error
+		LogoutAll(c echo.Context) error
+		ListSessions(c echo.Context) error
+		RevokeSession(c echo.Context) error
+		RequestPasswordReset(c echo.Context) error
+		ConfirmPasswordReset(c echo.Context) error
+		ChangePassword(c echo.Context) error
+		UnlockAccount(c echo.Context) error
+		VerifyEmail(c echo.Context) error
+		ResendVerification(c echo.Context) error
+		EnrollTOTP(c echo.Context) error
+		ConfirmTOTP(c echo.Context) error
+		DisableTOTP(c echo.Context) error
+		RegenerateRecoveryCodes(c echo.Context) error
+		VerifyMFA(c echo.Context) error
+		BeginPasskeyRegistration(c echo.Context) error
+		FinishPasskeyRegistration(c echo.Context) error
+		BeginPasskeyLogin(c echo.Context) error
+		FinishPasskeyLogin(c echo.Context) error
+		BeginPasskeyMFA(c echo.Context) error
+		FinishPasskeyMFA(c echo.Context) error
+		ListPasskeys(c echo.Context) error
+		DeletePasskey(c echo.Context) error
 		GoogleLogin(c echo.Context) error
 		GoogleCallback(c echo.Context) error
 		GithubLogin(c echo.Context) error
 		GithubCallback(c echo.Context) error
+		OAuthLogin(c echo.Context) error
+		OAuthCallback(c echo.Context) error
+		LinkAccount(c echo.Context) error
+		ListAccounts(c echo.Context) error
+		UnlinkAccount(c echo.Context) error
+		JWKS(c echo.Context) error
 	}
 
 	HTTP interface {
 		Login(w http.ResponseWriter, r *http.Request)
+		Refresh(w http.ResponseWriter, r *http.Request)
 		Register(w http.ResponseWriter, r *http.Request)
 		Logout(w http.ResponseWriter, r *http.Request)
+		LogoutAll(w http.ResponseWriter, r *http.Request)
+		ListSessions(w http.ResponseWriter, r *http.Request)
+		RevokeSession(w http.ResponseWriter, r *http.Request)
+		RequestPasswordReset(w http.ResponseWriter, r *http.Request)
+		ConfirmPasswordReset(w http.ResponseWriter, r *http.Request)
+		ChangePassword(w http.ResponseWriter, r *http.Request)
+		UnlockAccount(w http.ResponseWriter, r *http.Request)
+		VerifyEmail(w http.ResponseWriter, r *http.Request)
+		ResendVerification(w http.ResponseWriter, r *http.Request)
+		EnrollTOTP(w http.ResponseWriter, r *http.Request)
+		ConfirmTOTP(w http.ResponseWriter, r *http.Request)
+		DisableTOTP(w http.ResponseWriter, r *http.Request)
+		RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request)
+		VerifyMFA(w http.ResponseWriter, r *http.Request)
+		BeginPasskeyRegistration(w http.ResponseWriter, r *http.Request)
+		FinishPasskeyRegistration(w http.ResponseWriter, r *http.Request)
+		BeginPasskeyLogin(w http.ResponseWriter, r *http.Request)
+		FinishPasskeyLogin(w http.ResponseWriter, r *http.Request)
+		BeginPasskeyMFA(w http.ResponseWriter, r *http.Request)
+		FinishPasskeyMFA(w http.ResponseWriter, r *http.Request)
+		ListPasskeys(w http.ResponseWriter, r *http.Request)
+		DeletePasskey(w http.ResponseWriter, r *http.Request)
 		GoogleLogin(w http.ResponseWriter, r *http.Request)
 		GoogleCallback(w http.ResponseWriter, r *http.Request)
 		GithubLogin(w http.ResponseWriter, r *http.Request)
 		GithubCallback(w http.ResponseWriter, r *http.Request)
+		OAuthLogin(w http.ResponseWriter, r *http.Request)
+		OAuthCallback(w http.ResponseWriter, r *http.Request)
+		LinkAccount(w http.ResponseWriter, r *http.Request)
+		ListAccounts(w http.ResponseWriter, r *http.Request)
+		UnlinkAccount(w http.ResponseWriter, r *http.Request)
+		JWKS(w http.ResponseWriter, r *http.Request)
 	}
 
 	FastHTTP interface {
 		Login(ctx *fasthttp.RequestCtx)
+		Refresh(ctx *fasthttp.RequestCtx)
 		Register(ctx *fasthttp.RequestCtx)
 		Logout(ctx *fasthttp.RequestCtx)
+		LogoutAll(ctx *fasthttp.RequestCtx)
+		ListSessions(ctx *fasthttp.RequestCtx)
+		RevokeSession(ctx *fasthttp.RequestCtx)
+		RequestPasswordReset(ctx *fasthttp.RequestCtx)
+		ConfirmPasswordReset(ctx *fasthttp.RequestCtx)
+		ChangePassword(ctx *fasthttp.RequestCtx)
+		UnlockAccount(ctx *fasthttp.RequestCtx)
+		VerifyEmail(ctx *fasthttp.RequestCtx)
+		ResendVerification(ctx *fasthttp.RequestCtx)
+		EnrollTOTP(ctx *fasthttp.RequestCtx)
+		ConfirmTOTP(ctx *fasthttp.RequestCtx)
+		DisableTOTP(ctx *fasthttp.RequestCtx)
+		RegenerateRecoveryCodes(ctx *fasthttp.RequestCtx)
+		VerifyMFA(ctx *fasthttp.RequestCtx)
+		BeginPasskeyRegistration(ctx *fasthttp.RequestCtx)
+		FinishPasskeyRegistration(ctx *fasthttp.RequestCtx)
+		BeginPasskeyLogin(ctx *fasthttp.RequestCtx)
+		FinishPasskeyLogin(ctx *fasthttp.RequestCtx)
+		BeginPasskeyMFA(ctx *fasthttp.RequestCtx)
+		FinishPasskeyMFA(ctx *fasthttp.RequestCtx)
+		ListPasskeys(ctx *fasthttp.RequestCtx)
+		DeletePasskey(ctx *fasthttp.RequestCtx)
 		GoogleLogin(ctx *fasthttp.RequestCtx)
 		GoogleCallback(ctx *fasthttp.RequestCtx)
 		GithubLogin(ctx *fasthttp.RequestCtx)
 		GithubCallback(ctx *fasthttp.RequestCtx)
+		OAuthLogin(ctx *fasthttp.RequestCtx)
+		OAuthCallback(ctx *fasthttp.RequestCtx)
+		LinkAccount(ctx *fasthttp.RequestCtx)
+		ListAccounts(ctx *fasthttp.RequestCtx)
+		UnlinkAccount(ctx *fasthttp.RequestCtx)
+		JWKS(ctx *fasthttp.RequestCtx)
 	}
 )
@@ -0,0 +1,235 @@
+package framework
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+)
+
+// DecodeImportJSON reads a JSON array of ImportUser, the format to convert
+// exports from other systems, e.g. Django's pbkdf2_sha256 users, into.
+func DecodeImportJSON(r io.Reader) ([]ImportUser, error) {
+	var users []ImportUser
+	if err := json.NewDecoder(r).Decode(&users); err != nil {
+		return nil, fmt.Errorf("decode import: %w", err)
+	}
+	return users, nil
+}
+
+// DecodeImportCSV reads ImportUser rows from a CSV file with a header. The
+// columns email, email_verified, name, image, role, password_hash, metadata
+// (a JSON object) and accounts (space separated provider:provider_id pairs)
+// are matched by name and may come in any order; others are ignored.
+func DecodeImportCSV(r io.Reader) ([]ImportUser, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("decode import: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New("decode import: no email column")
+	}
+
+	var users []ImportUser
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return users, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decode import: %w", err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		user := ImportUser{
+			Email:        field("email"),
+			Name:         field("name"),
+			Image:        field("image"),
+			Role:         field("role"),
+			PasswordHash: field("password_hash"),
+		}
+		if verified := field("email_verified"); verified != "" {
+			if user.EmailVerified, err = strconv.ParseBool(verified); err != nil {
+				return nil, fmt.Errorf("decode import: line %d: invalid email_verified %q", line, verified)
+			}
+		}
+		if metadata := field("metadata"); metadata != "" {
+			user.Metadata = json.RawMessage(metadata)
+		}
+		for _, pair := range strings.Fields(field("accounts")) {
+			provider, providerID, ok := strings.Cut(pair, ":")
+			if !ok {
+				return nil, fmt.Errorf("decode import: line %d: account %q is not provider:provider_id", line, pair)
+			}
+			user.Accounts = append(user.Accounts, ImportAccount{Provider: provider, ProviderID: providerID})
+		}
+		users = append(users, user)
+	}
+}
+
+// DecodeFirebaseExport reads the JSON written by
+// "firebase auth:export --format=json". params are the project's password
+// hash parameters from the Firebase console; password hashes are kept in
+// Firebase's modified scrypt until each user's next login.
+func DecodeFirebaseExport(r io.Reader, params utils.FirebaseScryptParams) ([]ImportUser, error) {
+	var export struct {
+		Users []struct {
+			LocalID          string `json:"localId"`
+			Email            string `json:"email"`
+			EmailVerified    bool   `json:"emailVerified"`
+			DisplayName      string `json:"displayName"`
+			PhotoURL         string `json:"photoUrl"`
+			PasswordHash     string `json:"passwordHash"`
+			Salt             string `json:"salt"`
+			CustomAttributes string `json:"customAttributes"`
+			ProviderUserInfo []struct {
+				ProviderID string `json:"providerId"`
+				RawID      string `json:"rawId"`
+			} `json:"providerUserInfo"`
+		} `json:"users"`
+	}
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("decode firebase export: %w", err)
+	}
+
+	users := make([]ImportUser, 0, len(export.Users))
+	for _, exported := range export.Users {
+		user := ImportUser{
+			Email:         exported.Email,
+			EmailVerified: exported.EmailVerified,
+			Name:          exported.DisplayName,
+			Image:         exported.PhotoURL,
+		}
+		if exported.PasswordHash != "" {
+			hash, err := utils.FirebaseScryptHash(params, exported.Salt, exported.PasswordHash)
+			if err != nil {
+				return nil, fmt.Errorf("decode firebase export: user %s: %w", exported.LocalID, err)
+			}
+			user.PasswordHash = hash
+		}
+		if exported.CustomAttributes != "" {
+			user.Metadata = json.RawMessage(exported.CustomAttributes)
+		}
+		for _, info := range exported.ProviderUserInfo {
+			provider := firebaseProvider(info.ProviderID)
+			if provider == "" || info.RawID == "" {
+				continue
+			}
+			user.Accounts = append(user.Accounts, ImportAccount{Provider: provider, ProviderID: info.RawID})
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// firebaseProvider maps a Firebase provider ID onto goauth's provider names.
+// Password and phone sign-ins are not provider accounts.
+func firebaseProvider(providerID string) string {
+	switch {
+	case providerID == "password" || providerID == "phone":
+		return ""
+	case providerID == "google.com":
+		return "google"
+	case providerID == "github.com":
+		return "github"
+	case strings.HasPrefix(providerID, "oidc."):
+		return strings.TrimPrefix(providerID, "oidc.")
+	}
+	return providerID
+}
+
+// DecodeAuth0Export reads Auth0's newline-delimited JSON user export, with
+// the passwordHash field of a password hash export merged in. Auth0 hashes
+// are bcrypt and are used as they are. user_metadata and app_metadata are
+// kept under those keys in the user's metadata.
+func DecodeAuth0Export(r io.Reader) ([]ImportUser, error) {
+	var users []ImportUser
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+		var exported struct {
+			Email         string          `json:"email"`
+			EmailVerified bool            `json:"email_verified"`
+			Name          string          `json:"name"`
+			Picture       string          `json:"picture"`
+			PasswordHash  string          `json:"passwordHash"`
+			UserMetadata  json.RawMessage `json:"user_metadata"`
+			AppMetadata   json.RawMessage `json:"app_metadata"`
+			Identities    []struct {
+				Provider string          `json:"provider"`
+				UserID   json.RawMessage `json:"user_id"`
+			} `json:"identities"`
+		}
+		if err := json.Unmarshal([]byte(raw), &exported); err != nil {
+			return nil, fmt.Errorf("decode auth0 export: line %d: %w", line, err)
+		}
+
+		user := ImportUser{
+			Email:         exported.Email,
+			EmailVerified: exported.EmailVerified,
+			Name:          exported.Name,
+			Image:         exported.Picture,
+			PasswordHash:  exported.PasswordHash,
+		}
+		if len(exported.UserMetadata) > 0 || len(exported.AppMetadata) > 0 {
+			metadata := map[string]json.RawMessage{}
+			if len(exported.UserMetadata) > 0 {
+				metadata["user_metadata"] = exported.UserMetadata
+			}
+			if len(exported.AppMetadata) > 0 {
+				metadata["app_metadata"] = exported.AppMetadata
+			}
+			encoded, err := json.Marshal(metadata)
+			if err != nil {
+				return nil, fmt.Errorf("decode auth0 export: line %d: %w", line, err)
+			}
+			user.Metadata = encoded
+		}
+		for _, identity := range exported.Identities {
+			// GitHub and some other providers' IDs are numbers.
+			providerID := strings.Trim(string(identity.UserID), `"`)
+			provider := auth0Provider(identity.Provider)
+			if provider == "" || providerID == "" || providerID == "null" {
+				continue
+			}
+			user.Accounts = append(user.Accounts, ImportAccount{Provider: provider, ProviderID: providerID})
+		}
+		users = append(users, user)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("decode auth0 export: %w", err)
+	}
+	return users, nil
+}
+
+// auth0Provider maps an Auth0 identity provider onto goauth's provider
+// names. Database, email and SMS connections are not provider accounts.
+func auth0Provider(provider string) string {
+	switch provider {
+	case "auth0", "email", "sms":
+		return ""
+	case "google-oauth2":
+		return "google"
+	}
+	return provider
+}
@@ -0,0 +1,124 @@
+package framework
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+)
+
+// The sample project in the README of github.com/firebase/scrypt.
+var firebaseParams = utils.FirebaseScryptParams{
+	SignerKey:     "jxspr8Ki0RYycVU8zykbdLGjFQ3McFUH0uiiTvC8pVMXAn210wjLNmdZJzxUECKbm0QsEmYUSDzZvpjeJ9WmXA==",
+	SaltSeparator: "Bw==",
+	Rounds:        8,
+	MemCost:       14,
+}
+
+const firebaseExport = `{"users": [
+	{
+		"localId": "u1",
+		"email": "user1@example.com",
+		"emailVerified": true,
+		"displayName": "User One",
+		"passwordHash": "lSrfV15cpx95/sZS2W9c9Kp6i/LVgQNDNC/qzrCnh1SAyZvqmZqAjTdn3aoItz+VHjoZilo78198JAdRuid5lQ==",
+		"salt": "42xEC+ixf3L2lw==",
+		"customAttributes": "{\"plan\":\"pro\"}",
+		"providerUserInfo": [
+			{"providerId": "password", "rawId": "user1@example.com"},
+			{"providerId": "google.com", "rawId": "1234"},
+			{"providerId": "oidc.okta", "rawId": "abcd"}
+		]
+	},
+	{"localId": "u2", "email": "user2@example.com", "providerUserInfo": [{"providerId": "github.com", "rawId": "99"}]}
+]}`
+
+func TestDecodeFirebaseExport(t *testing.T) {
+	users, err := DecodeFirebaseExport(strings.NewReader(firebaseExport), firebaseParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("decoded %d users, want 2", len(users))
+	}
+	first := users[0]
+	if first.Email != "user1@example.com" || !first.EmailVerified || first.Name != "User One" || string(first.Metadata) != `{"plan":"pro"}` {
+		t.Errorf("first user = %+v", first)
+	}
+	wantAccounts := []ImportAccount{{Provider: "google", ProviderID: "1234"}, {Provider: "okta", ProviderID: "abcd"}}
+	if len(first.Accounts) != len(wantAccounts) || first.Accounts[0] != wantAccounts[0] || first.Accounts[1] != wantAccounts[1] {
+		t.Errorf("accounts = %+v, want %+v", first.Accounts, wantAccounts)
+	}
+	if ok, err := utils.VerifyPassword("user1password", first.PasswordHash); !ok || err != nil {
+		t.Errorf("VerifyPassword on the imported hash = %v, %v", ok, err)
+	}
+	if users[1].PasswordHash != "" || len(users[1].Accounts) != 1 || users[1].Accounts[0].Provider != "github" {
+		t.Errorf("second user = %+v", users[1])
+	}
+
+	costly := firebaseParams
+	costly.MemCost = 20
+	if _, err := DecodeFirebaseExport(strings.NewReader(firebaseExport), costly); !errors.Is(err, utils.ErrInvalidFirebaseScryptHash) {
+		t.Errorf("mem cost 20: %v, want ErrInvalidFirebaseScryptHash", err)
+	}
+}
+
+func TestDecodeAuth0Export(t *testing.T) {
+	export := `{"email": "a@example.com", "email_verified": true, "name": "A", "passwordHash": "$2b$06$If6bvum7DFjUnE9p2uDeDu0YHzrHM6tf.iqN8.yx.jNN1ILEf7h0i", "user_metadata": {"theme": "dark"}, "identities": [{"provider": "auth0", "user_id": "1"}, {"provider": "github", "user_id": 42}]}
+
+{"email": "b@example.com"}
+`
+	users, err := DecodeAuth0Export(strings.NewReader(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("decoded %d users, want 2", len(users))
+	}
+	first := users[0]
+	if ok, err := utils.VerifyPassword("abc", first.PasswordHash); !ok || err != nil {
+		t.Errorf("VerifyPassword on the imported hash = %v, %v", ok, err)
+	}
+	var metadata map[string]json.RawMessage
+	if err := json.Unmarshal(first.Metadata, &metadata); err != nil || string(metadata["user_metadata"]) != `{"theme":"dark"}` {
+		t.Errorf("metadata = %s, %v", first.Metadata, err)
+	}
+	if len(first.Accounts) != 1 || first.Accounts[0] != (ImportAccount{Provider: "github", ProviderID: "42"}) {
+		t.Errorf("accounts = %+v, want the github identity only", first.Accounts)
+	}
+
+	if _, err := DecodeAuth0Export(strings.NewReader("{not json}\n")); err == nil {
+		t.Error("DecodeAuth0Export accepted a line that is not JSON")
+	}
+}
+
+func TestDecodeImportCSV(t *testing.T) {
+	csv := "Email,password_hash,email_verified,accounts,metadata,extra\n" +
+		`c@example.com,pbkdf2_sha256$260000$seasalt$YlZ2Vggtqdc61YjArZuoApoBh9JNGYoDRBUGu6tcJQo=,true,github:1 google:2,"{""a"":1}",x` + "\n"
+	users, err := DecodeImportCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 {
+		t.Fatalf("decoded %d users, want 1", len(users))
+	}
+	user := users[0]
+	if user.Email != "c@example.com" || !user.EmailVerified || string(user.Metadata) != `{"a":1}` || len(user.Accounts) != 2 {
+		t.Errorf("user = %+v", user)
+	}
+	if ok, err := utils.VerifyPassword("lètmein", user.PasswordHash); !ok || err != nil {
+		t.Errorf("VerifyPassword on the imported hash = %v, %v", ok, err)
+	}
+
+	for name, bad := range map[string]string{
+		"no email column":   "name\nx\n",
+		"bad verified flag": "email,email_verified\nc@example.com,maybe\n",
+		"bad account":       "email,accounts\nc@example.com,github\n",
+	} {
+		if _, err := DecodeImportCSV(strings.NewReader(bad)); err == nil {
+			t.Errorf("%s: DecodeImportCSV accepted it", name)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package framework
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -14,8 +15,8 @@ type (
 		CreateAt time.Time `json:"create_at"`
 	}
 	RegisterRequest struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"required"`
 		Name     string `json:"user_name,omitempty"`
 		RoleName string `json:"role_name,omitempty"`
 		Image    string `json:"image,omitempty"`
@@ -23,6 +24,136 @@ type (
 	LoginRequest struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		// UserAgent and IPAddress are filled in by the handler and recorded
+		// on the server session.
+		UserAgent string `json:"-"`
+		IPAddress string `json:"-"`
+	}
+	RefreshRequest struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	// LogoutRequest carries whatever credentials the client presented. Any
+	// of them may be empty; Logout revokes the ones that are set.
+	LogoutRequest struct {
+		RefreshToken string `json:"refresh_token"`
+		AccessToken  string `json:"-"`
+		SessionID    string `json:"-"`
+		SessionToken string `json:"-"`
+	}
+
+	// PasswordResetRequest asks for a reset link. IPAddress is filled in by
+	// the handler.
+	PasswordResetRequest struct {
+		Email     string `json:"email" validate:"required,email"`
+		IPAddress string `json:"-"`
+	}
+	PasswordResetConfirmRequest struct {
+		Token       string `json:"token" validate:"required"`
+		NewPassword string `json:"new_password" validate:"required"`
+	}
+
+	// ChangePasswordRequest changes the signed-in user's password.
+	// UserAgent and IPAddress are filled in by the handler, as for
+	// LoginRequest.
+	ChangePasswordRequest struct {
+		CurrentPassword string `json:"current_password" validate:"required"`
+		NewPassword     string `json:"new_password" validate:"required"`
+		UserAgent       string `json:"-"`
+		IPAddress       string `json:"-"`
+	}
+
+	VerifyEmailRequest struct {
+		Token string `json:"token" validate:"required"`
+	}
+	// UnlockAccountRequest redeems the link emailed when an account locks.
+	// IPAddress is filled in by the handler.
+	UnlockAccountRequest struct {
+		Token     string `json:"token" validate:"required"`
+		IPAddress string `json:"-"`
+	}
+	ResendVerificationRequest struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	// MFAVerifyRequest completes a login that returned MFARequired. Code is
+	// either a TOTP code or one of the user's recovery codes.
+	MFAVerifyRequest struct {
+		MFAToken string `json:"mfa_token" validate:"required"`
+		Code     string `json:"code" validate:"required"`
+		// UserAgent and IPAddress are filled in by the handler, as for
+		// LoginRequest.
+		UserAgent string `json:"-"`
+		IPAddress string `json:"-"`
+	}
+	TOTPCodeRequest struct {
+		Code string `json:"code" validate:"required,len=6,numeric"`
+	}
+	// TOTPEnrollResponse is shown to the user once, to add the account to
+	// an authenticator app. QRCode is a base64 encoded PNG of OTPAuthURI.
+	// RecoveryCodes stand in for a code if the app is lost; only their
+	// hashes are kept.
+	TOTPEnrollResponse struct {
+		Secret        string   `json:"secret"`
+		OTPAuthURI    string   `json:"otpauth_uri"`
+		QRCode        string   `json:"qr_code"`
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	RecoveryCodesResponse struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	// PasskeyRegisterRequest finishes a passkey registration. Credential is
+	// the PublicKeyCredential from navigator.credentials.create, as JSON.
+	PasskeyRegisterRequest struct {
+		Name       string          `json:"name"`
+		Credential json.RawMessage `json:"credential" validate:"required"`
+	}
+	// PasskeyLoginRequest finishes a passkey login. Credential is the
+	// PublicKeyCredential from navigator.credentials.get; MFAToken is set
+	// when the passkey is the second factor of a password login.
+	PasskeyLoginRequest struct {
+		MFAToken   string          `json:"mfa_token,omitempty"`
+		Credential json.RawMessage `json:"credential" validate:"required"`
+		UserAgent  string          `json:"-"`
+		IPAddress  string          `json:"-"`
+	}
+	PasskeyMFARequest struct {
+		MFAToken string `json:"mfa_token" validate:"required"`
+	}
+	PasskeyInfo struct {
+		ID         string     `json:"id"`
+		Name       string     `json:"name"`
+		CreatedAt  time.Time  `json:"created_at"`
+		LastUsedAt *time.Time `json:"last_used_at"`
+	}
+	// ReauthRequest confirms the user's identity before a sensitive change:
+	// the password, or a TOTP code for users who have none.
+	ReauthRequest struct {
+		Password  string `json:"password,omitempty"`
+		Code      string `json:"code,omitempty"`
+		IPAddress string `json:"-"`
+	}
+	LinkedAccount struct {
+		ID        string    `json:"id"`
+		Provider  string    `json:"provider"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	// ProviderToken is a user's token for an OAuth provider's own APIs.
+	ProviderToken struct {
+		AccessToken string     `json:"access_token"`
+		TokenType   string     `json:"token_type"`
+		Scopes      []string   `json:"scopes"`
+		ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	}
+	// OAuthCallbackRequest is what a provider's redirect back carries, plus
+	// the binding cookie set when the flow began.
+	OAuthCallbackRequest struct {
+		Provider  string `json:"-"`
+		Code      string `json:"code" validate:"required"`
+		State     string `json:"state" validate:"required"`
+		Binding   string `json:"-"`
+		UserAgent string `json:"-"`
+		IPAddress string `json:"-"`
 	}
 
 	AuthResponse struct {
@@ -30,8 +161,51 @@ type (
 		AccessToken  string         `json:"access_token"`
 		RefreshToken string         `json:"refresh_token"`
 		SessionToken string         `json:"session_token,omitempty"`
+		// MFARequired is set instead of the tokens above when the user has
+		// a second factor; MFAToken is then passed to VerifyMFA along with
+		// a code, or to the passkey MFA ceremony. MFAMethods lists what the
+		// user can answer with: "totp", "recovery_code" and "passkey".
+		MFARequired bool     `json:"mfa_required,omitempty"`
+		MFAToken    string   `json:"mfa_token,omitempty"`
+		MFAMethods  []string `json:"mfa_methods,omitempty"`
 	}
 	RegisterResponse struct{}
+
+	// SessionInfo describes one of the user's active sessions.
+	SessionInfo struct {
+		ID         string    `json:"id"`
+		Browser    string    `json:"browser"`
+		OS         string    `json:"os"`
+		Device     string    `json:"device"`
+		UserAgent  string    `json:"user_agent"`
+		IPAddress  string    `json:"ip_address"`
+		CreatedAt  time.Time `json:"created_at"`
+		LastSeenAt time.Time `json:"last_seen_at"`
+		ExpiresAt  time.Time `json:"expires_at"`
+		Current    bool      `json:"current"`
+	}
+
+	// ImportUser is a user brought over from another system. PasswordHash
+	// must be in a format utils.VerifyPassword knows, or empty for users
+	// who only sign in through Accounts.
+	ImportUser struct {
+		Email         string          `json:"email" validate:"required,email"`
+		EmailVerified bool            `json:"email_verified"`
+		Name          string          `json:"name,omitempty"`
+		Image         string          `json:"image,omitempty"`
+		Role          string          `json:"role,omitempty"`
+		PasswordHash  string          `json:"password_hash,omitempty"`
+		Metadata      json.RawMessage `json:"metadata,omitempty"`
+		Accounts      []ImportAccount `json:"accounts,omitempty" validate:"dive"`
+	}
+	ImportAccount struct {
+		Provider   string `json:"provider" validate:"required"`
+		ProviderID string `json:"provider_id" validate:"required"`
+	}
+	ImportResult struct {
+		Users    int `json:"users"`
+		Accounts int `json:"accounts"`
+	}
 )
 
 var validate = validator.New()
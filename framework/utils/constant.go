@@ -1,5 +1,7 @@
 package utils
 
+import "time"
+
 // TokenType represents the type of token
 
 type (
@@ -7,12 +9,21 @@ type (
 	TokenContextContainer struct {
 		AccessToken  string
 		RefreshToken string
+		// AccessTokenID is the jti of AccessToken, used to revoke it early.
+		AccessTokenID   string
+		AccessExpiresAt time.Time
+		// RefreshTokenID is the jti of RefreshToken, used to record it server side.
+		RefreshTokenID   string
+		RefreshExpiresAt time.Time
 	}
 
 	// Claims represents the common claims for both JWT and PASETO
 	Claims struct {
-		UserID   string
-		Role     string
+		UserID string
+		Role   string
+		// FamilyID groups every refresh token descended from one login, so a
+		// reused token can revoke the whole chain.
+		FamilyID string
 		MetaData map[string]interface{}
 	}
 	GeneralResponse struct {
@@ -27,8 +38,15 @@ const (
 	UserId            string    = "user_id"
 	Role              string    = "role"
 	Exp               string    = "exp"
+	IssuedAt          string    = "iat"
+	Jti               string    = "jti"
+	FamilyId          string    = "fid"
+	SessionId         string    = "session_id"
 	JWT_ACCESS_TOKEN  TokenType = "access_token"
 	JWT_REFRESH_TOKEN TokenType = "refresh_token"
 	JWT               TokenType = "jwt"
 	PASETO            TokenType = "paseto"
 )
+
+// DefaultRefreshTokenDuration is used when GOAUTH_REFRESH_TOKEN_DURATION is unset.
+const DefaultRefreshTokenDuration = 24 * time.Hour
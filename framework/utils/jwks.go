@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+type (
+	// JWK is the public half of a signing key in RFC 7517 form.
+	JWK struct {
+		Kty string `json:"kty"`
+		Use string `json:"use,omitempty"`
+		Kid string `json:"kid,omitempty"`
+		Alg string `json:"alg,omitempty"`
+		// RSA
+		N string `json:"n,omitempty"`
+		E string `json:"e,omitempty"`
+		// EC and OKP
+		Crv string `json:"crv,omitempty"`
+		X   string `json:"x,omitempty"`
+		Y   string `json:"y,omitempty"`
+	}
+
+	// JWKSet is the document served at /.well-known/jwks.json.
+	JWKSet struct {
+		Keys []JWK `json:"keys"`
+	}
+
+	// JWKSProvider is implemented by key providers that can publish their
+	// verification keys.
+	JWKSProvider interface {
+		JWKS() JWKSet
+	}
+)
+
+// JWKS returns the public keys of the current key provider. Shared secrets
+// are never published, so an HMAC-only setup yields an empty set.
+func JWKS() (JWKSet, error) {
+	provider, err := currentKeyProvider()
+	if err != nil {
+		return JWKSet{}, err
+	}
+	if jwksProvider, ok := provider.(JWKSProvider); ok {
+		return jwksProvider.JWKS(), nil
+	}
+	return JWKSet{Keys: []JWK{}}, nil
+}
+
+// NewJWK converts the verification half of key to a JWK. It reports false for
+// symmetric keys.
+func NewJWK(key *JWTKey) (JWK, bool) {
+	jwk := JWK{Use: "sig", Kid: key.ID, Alg: key.Method.Alg()}
+
+	switch public := key.VerifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64URL(public.N.Bytes())
+		jwk.E = encodeBase64URL(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		ecdh, err := public.ECDH()
+		if err != nil {
+			return JWK{}, false
+		}
+		size := (public.Curve.Params().BitSize + 7) / 8
+		point := ecdh.Bytes()
+		jwk.Kty = "EC"
+		jwk.Crv = public.Curve.Params().Name
+		jwk.X = encodeBase64URL(point[1 : 1+size])
+		jwk.Y = encodeBase64URL(point[1+size:])
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeBase64URL(public)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+// PublicKey parses the key a JWK describes, as published by an identity
+// provider. Only the key types NewJWK produces are understood.
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: modulus: %w", j.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: exponent: %w", j.Kid, err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("jwk %q: invalid rsa key", j.Kid)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("jwk %q: unsupported curve %q", j.Kid, j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: x: %w", j.Kid, err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: y: %w", j.Kid, err)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("jwk %q: invalid ec point", j.Kid)
+		}
+		key, err := ecdsa.ParseUncompressedPublicKey(curve, append(append([]byte{4}, x...), y...))
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: %w", j.Kid, err)
+		}
+		return key, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("jwk %q: unsupported curve %q", j.Kid, j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: x: %w", j.Kid, err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("jwk %q: invalid ed25519 key", j.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, errors.New("jwk: unsupported key type " + j.Kty)
+}
+
+func encodeBase64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestJWKSRoundTrip(t *testing.T) {
+	tests := []struct {
+		alg    string
+		kty    string
+		crv    string
+		fields []string
+		// size is the decoded length of x and y, fixed by the curve.
+		size int
+	}{
+		{alg: "RS256", kty: "RSA", fields: []string{"n", "e"}},
+		{alg: "PS256", kty: "RSA", fields: []string{"n", "e"}},
+		{alg: "ES256", kty: "EC", crv: "P-256", fields: []string{"x", "y"}, size: 32},
+		{alg: "ES384", kty: "EC", crv: "P-384", fields: []string{"x", "y"}, size: 48},
+		{alg: "ES512", kty: "EC", crv: "P-521", fields: []string{"x", "y"}, size: 66},
+		{alg: "EdDSA", kty: "OKP", crv: "Ed25519", fields: []string{"x"}, size: 32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			privatePEM, _ := pemEncode(t, newPrivateKey(t, tt.alg))
+			key, err := ParsePrivateJWTKey(tt.alg, privatePEM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key.ID = "k1"
+			useKeyProvider(t, NewStaticKeyProvider(key))
+			tokens := newTestTokens(t)
+
+			set, err := JWKS()
+			if err != nil {
+				t.Fatal(err)
+			}
+			document, err := json.Marshal(set)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var published struct {
+				Keys []map[string]string `json:"keys"`
+			}
+			if err := json.Unmarshal(document, &published); err != nil {
+				t.Fatal(err)
+			}
+			if len(published.Keys) != 1 {
+				t.Fatalf("JWKS = %s, want one key", document)
+			}
+			jwk := published.Keys[0]
+			if jwk["kty"] != tt.kty || jwk["crv"] != tt.crv || jwk["kid"] != "k1" || jwk["alg"] != tt.alg || jwk["use"] != "sig" {
+				t.Errorf("JWK = %v", jwk)
+			}
+			for _, field := range tt.fields {
+				if jwk[field] == "" {
+					t.Errorf("JWK has no %s: %v", field, jwk)
+				}
+			}
+			// None of the private members of RFC 7518 section 6.
+			for _, field := range []string{"d", "p", "q", "dp", "dq", "qi", "k"} {
+				if _, ok := jwk[field]; ok {
+					t.Errorf("JWK publishes private member %s", field)
+				}
+			}
+			if tt.size > 0 {
+				for _, field := range tt.fields {
+					if raw, err := base64.RawURLEncoding.DecodeString(jwk[field]); err != nil || len(raw) != tt.size {
+						t.Errorf("%s is %d bytes, %v, want %d", field, len(raw), err, tt.size)
+					}
+				}
+			}
+
+			// A verifier built from the published document alone accepts
+			// the token.
+			var parsed JWKSet
+			if err := json.Unmarshal(document, &parsed); err != nil {
+				t.Fatal(err)
+			}
+			public, err := parsed.Keys[0].PublicKey()
+			if err != nil {
+				t.Fatalf("PublicKey: %v", err)
+			}
+			SetKeyProvider(NewStaticKeyProvider(&JWTKey{ID: parsed.Keys[0].Kid, Method: jwt.GetSigningMethod(parsed.Keys[0].Alg), VerifyKey: public}))
+			if _, err := validateJWT(tokens.AccessToken); err != nil {
+				t.Errorf("validateJWT against the published key: %v", err)
+			}
+		})
+	}
+}
+
+func TestJWKSOmitsSharedSecrets(t *testing.T) {
+	provider, err := NewHMACKeyProvider("HS256", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	useKeyProvider(t, provider)
+	set, err := JWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Keys) != 0 {
+		t.Errorf("JWKS of an HMAC provider = %+v, want no keys", set)
+	}
+	document, _ := json.Marshal(set)
+	if string(document) != `{"keys":[]}` {
+		t.Errorf("JWKS document = %s", document)
+	}
+
+	ring := NewKeyRing(time.Hour)
+	if err := ring.Add(&JWTKey{ID: "hmac", Method: jwt.SigningMethodHS256, SignKey: []byte("s"), VerifyKey: []byte("s")}); err != nil {
+		t.Fatal(err)
+	}
+	if set := ring.JWKS(); len(set.Keys) != 0 {
+		t.Errorf("JWKS of a ring holding a secret = %+v, want no keys", set)
+	}
+}
+
+func TestJWKPublicKeyRejects(t *testing.T) {
+	privatePEM, _ := pemEncode(t, newPrivateKey(t, "ES256"))
+	key, err := ParsePrivateJWTKey("ES256", privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, _ := NewJWK(key)
+
+	tests := []struct {
+		name string
+		jwk  func(JWK) JWK
+	}{
+		{"unknown kty", func(j JWK) JWK { j.Kty = "oct"; return j }},
+		{"unknown curve", func(j JWK) JWK { j.Crv = "P-192"; return j }},
+		{"short x", func(j JWK) JWK { j.X = j.X[:10]; return j }},
+		{"point off the curve", func(j JWK) JWK { j.X, j.Y = j.Y, j.X; return j }},
+		{"bad base64", func(j JWK) JWK { j.Y = "!!"; return j }},
+		{"rsa exponent of one", func(JWK) JWK { return JWK{Kty: "RSA", N: "AQAB", E: "AQ"} }},
+		{"rsa without modulus", func(JWK) JWK { return JWK{Kty: "RSA", E: "AQAB"} }},
+		{"short ed25519 key", func(JWK) JWK { return JWK{Kty: "OKP", Crv: "Ed25519", X: "AQAB"} }},
+		{"x25519", func(JWK) JWK { return JWK{Kty: "OKP", Crv: "X25519", X: valid.X} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if public, err := tt.jwk(valid).PublicKey(); err == nil {
+				t.Errorf("PublicKey = %v", public)
+			}
+		})
+	}
+}
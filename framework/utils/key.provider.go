@@ -0,0 +1,282 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type (
+	// JWTKey pins a signing method to its key material. SignKey is nil for
+	// verify-only keys, e.g. a downstream service holding only a public key.
+	// ID is written to the kid header of every token the key signs.
+	JWTKey struct {
+		ID        string
+		Method    jwt.SigningMethod
+		SignKey   interface{}
+		VerifyKey interface{}
+	}
+
+	// KeyProvider supplies the keys used to sign and verify JWTs.
+	KeyProvider interface {
+		// SigningKey returns the key new tokens are signed with.
+		SigningKey() (*JWTKey, error)
+		// VerificationKey returns the key the given unverified token must be
+		// checked against. The token's alg header must match the key's method.
+		VerificationKey(token *jwt.Token) (*JWTKey, error)
+	}
+
+	staticKeyProvider struct {
+		key *JWTKey
+	}
+)
+
+var (
+	ErrNoSigningKey      = errors.New("key provider has no signing key")
+	ErrUnexpectedKeyType = errors.New("key type does not match signing method")
+
+	keyProviderMu sync.RWMutex
+	keyProvider   KeyProvider
+)
+
+// SetKeyProvider installs the provider used by GenerateToken and ValidateToken
+// for JWTs. Without one, keys are read from the environment on first use and
+// kept from then on.
+func SetKeyProvider(provider KeyProvider) {
+	keyProviderMu.Lock()
+	defer keyProviderMu.Unlock()
+	keyProvider = provider
+}
+
+// currentKeyProvider returns the installed provider, building it from the
+// environment the first time. Key files are read once rather than on every
+// token. A failure is not kept, so a fixed environment is picked up by the
+// next call.
+func currentKeyProvider() (KeyProvider, error) {
+	keyProviderMu.RLock()
+	provider := keyProvider
+	keyProviderMu.RUnlock()
+	if provider != nil {
+		return provider, nil
+	}
+
+	keyProviderMu.Lock()
+	defer keyProviderMu.Unlock()
+	if keyProvider != nil {
+		return keyProvider, nil
+	}
+	provider, err := KeyProviderFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	keyProvider = provider
+	return provider, nil
+}
+
+// KeyProviderFromEnv builds a provider from the environment.
+//
+//	GOAUTH_JWT_ALGORITHM         HS256 (default), RS256, ES256, EdDSA, ...
+//	GOAUTH_JWT_SECRET            shared secret for HS* algorithms
+//	GOAUTH_JWT_PRIVATE_KEY_FILE  PEM private key for asymmetric algorithms
+//	GOAUTH_JWT_PUBLIC_KEY_FILE   PEM public key, used when no private key is set
+//	GOAUTH_JWT_KEY_ID            optional kid header for issued tokens
+func KeyProviderFromEnv() (KeyProvider, error) {
+	alg := os.Getenv("GOAUTH_JWT_ALGORITHM")
+	if alg == "" {
+		alg = jwt.SigningMethodHS256.Alg()
+	}
+
+	method := jwt.GetSigningMethod(alg)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", alg)
+	}
+
+	var (
+		key *JWTKey
+		err error
+	)
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		secret := os.Getenv("GOAUTH_JWT_SECRET")
+		if secret == "" {
+			return nil, errors.New("GOAUTH_JWT_SECRET not set in environment")
+		}
+		key = &JWTKey{Method: method, SignKey: []byte(secret), VerifyKey: []byte(secret)}
+	} else if path := os.Getenv("GOAUTH_JWT_PRIVATE_KEY_FILE"); path != "" {
+		key, err = readPEMKey(path, alg, ParsePrivateJWTKey)
+	} else if path := os.Getenv("GOAUTH_JWT_PUBLIC_KEY_FILE"); path != "" {
+		key, err = readPEMKey(path, alg, ParsePublicJWTKey)
+	} else {
+		err = errors.New("GOAUTH_JWT_PRIVATE_KEY_FILE or GOAUTH_JWT_PUBLIC_KEY_FILE not set in environment")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key.ID = os.Getenv("GOAUTH_JWT_KEY_ID")
+	return NewStaticKeyProvider(key), nil
+}
+
+// NewStaticKeyProvider returns a provider that always uses key.
+func NewStaticKeyProvider(key *JWTKey) KeyProvider {
+	return &staticKeyProvider{key: key}
+}
+
+// NewHMACKeyProvider returns a provider signing with a shared secret.
+func NewHMACKeyProvider(alg string, secret []byte) (KeyProvider, error) {
+	method, ok := jwt.GetSigningMethod(alg).(*jwt.SigningMethodHMAC)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an HMAC algorithm", alg)
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("HMAC secret cannot be empty")
+	}
+	return &staticKeyProvider{key: &JWTKey{Method: method, SignKey: secret, VerifyKey: secret}}, nil
+}
+
+// NewKeyProviderFromPEM returns a provider signing with the PEM encoded
+// private key. The public half is derived for verification.
+func NewKeyProviderFromPEM(alg string, privatePEM []byte) (KeyProvider, error) {
+	key, err := ParsePrivateJWTKey(alg, privatePEM)
+	if err != nil {
+		return nil, err
+	}
+	return &staticKeyProvider{key: key}, nil
+}
+
+// NewKeyProviderFromPEMFile is NewKeyProviderFromPEM reading from a file.
+func NewKeyProviderFromPEMFile(alg, path string) (KeyProvider, error) {
+	key, err := readPEMKey(path, alg, ParsePrivateJWTKey)
+	if err != nil {
+		return nil, err
+	}
+	return &staticKeyProvider{key: key}, nil
+}
+
+// NewPublicKeyProviderFromPEM returns a verify-only provider.
+func NewPublicKeyProviderFromPEM(alg string, publicPEM []byte) (KeyProvider, error) {
+	key, err := ParsePublicJWTKey(alg, publicPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &staticKeyProvider{key: key}, nil
+}
+
+// NewPublicKeyProviderFromPEMFile is NewPublicKeyProviderFromPEM reading from a file.
+func NewPublicKeyProviderFromPEMFile(alg, path string) (KeyProvider, error) {
+	key, err := readPEMKey(path, alg, ParsePublicJWTKey)
+	if err != nil {
+		return nil, err
+	}
+	return &staticKeyProvider{key: key}, nil
+}
+
+func readPEMKey(path, alg string, parse func(string, []byte) (*JWTKey, error)) (*JWTKey, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	return parse(alg, pemBytes)
+}
+
+// ParsePrivateJWTKey parses a PEM private key and checks it fits alg.
+func ParsePrivateJWTKey(alg string, privatePEM []byte) (*JWTKey, error) {
+	switch method := jwt.GetSigningMethod(alg).(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		private, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+		if err != nil {
+			return nil, err
+		}
+		return &JWTKey{Method: method, SignKey: private, VerifyKey: &private.PublicKey}, nil
+	case *jwt.SigningMethodECDSA:
+		private, err := jwt.ParseECPrivateKeyFromPEM(privatePEM)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkCurve(method, &private.PublicKey); err != nil {
+			return nil, err
+		}
+		return &JWTKey{Method: method, SignKey: private, VerifyKey: &private.PublicKey}, nil
+	case *jwt.SigningMethodEd25519:
+		private, err := jwt.ParseEdPrivateKeyFromPEM(privatePEM)
+		if err != nil {
+			return nil, err
+		}
+		edPrivate, ok := private.(ed25519.PrivateKey)
+		if !ok {
+			return nil, ErrUnexpectedKeyType
+		}
+		return &JWTKey{Method: method, SignKey: edPrivate, VerifyKey: edPrivate.Public()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported asymmetric JWT algorithm: %s", alg)
+	}
+}
+
+// ParsePublicJWTKey parses a PEM public key and checks it fits alg.
+func ParsePublicJWTKey(alg string, publicPEM []byte) (*JWTKey, error) {
+	switch method := jwt.GetSigningMethod(alg).(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		public, err := jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+		if err != nil {
+			return nil, err
+		}
+		return &JWTKey{Method: method, VerifyKey: public}, nil
+	case *jwt.SigningMethodECDSA:
+		public, err := jwt.ParseECPublicKeyFromPEM(publicPEM)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkCurve(method, public); err != nil {
+			return nil, err
+		}
+		return &JWTKey{Method: method, VerifyKey: public}, nil
+	case *jwt.SigningMethodEd25519:
+		public, err := jwt.ParseEdPublicKeyFromPEM(publicPEM)
+		if err != nil {
+			return nil, err
+		}
+		edPublic, ok := public.(ed25519.PublicKey)
+		if !ok {
+			return nil, ErrUnexpectedKeyType
+		}
+		return &JWTKey{Method: method, VerifyKey: edPublic}, nil
+	default:
+		return nil, fmt.Errorf("unsupported asymmetric JWT algorithm: %s", alg)
+	}
+}
+
+func checkCurve(method *jwt.SigningMethodECDSA, public *ecdsa.PublicKey) error {
+	if public.Curve.Params().BitSize != method.CurveBits {
+		return fmt.Errorf("%w: %s requires a %d-bit curve", ErrUnexpectedKeyType, method.Alg(), method.CurveBits)
+	}
+	return nil
+}
+
+func (p *staticKeyProvider) SigningKey() (*JWTKey, error) {
+	if p.key.SignKey == nil {
+		return nil, ErrNoSigningKey
+	}
+	return p.key, nil
+}
+
+func (p *staticKeyProvider) VerificationKey(token *jwt.Token) (*JWTKey, error) {
+	if token.Method.Alg() != p.key.Method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	if kid, _ := token.Header["kid"].(string); kid != "" && p.key.ID != "" && kid != p.key.ID {
+		return nil, ErrUnknownKeyID
+	}
+	return p.key, nil
+}
+
+func (p *staticKeyProvider) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if jwk, ok := NewJWK(p.key); ok {
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
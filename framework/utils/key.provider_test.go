@@ -0,0 +1,269 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// useKeyProvider installs provider for the test and drops it afterwards, so
+// the next test starts from the environment again.
+func useKeyProvider(t *testing.T, provider KeyProvider) {
+	t.Helper()
+	SetKeyProvider(provider)
+	t.Cleanup(func() { SetKeyProvider(nil) })
+}
+
+// newPrivateKey generates a key for alg.
+func newPrivateKey(t *testing.T, alg string) crypto.Signer {
+	t.Helper()
+	var (
+		key crypto.Signer
+		err error
+	)
+	switch alg {
+	case "RS256", "PS256":
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ES384":
+		key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "ES512":
+		key, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case "EdDSA":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		t.Fatalf("no test key for %s", alg)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// pemEncode returns the PKCS #8 private and PKIX public PEM blocks of key.
+func pemEncode(t *testing.T, key crypto.Signer) (privatePEM, publicPEM []byte) {
+	t.Helper()
+	private, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})
+}
+
+func newTestTokens(t *testing.T) *TokenContextContainer {
+	t.Helper()
+	tokens, err := GenerateToken(Claims{UserID: uuid.NewString(), Role: "user"}, JWT, time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	return tokens
+}
+
+func TestPEMKeyProviders(t *testing.T) {
+	for _, alg := range []string{"RS256", "PS256", "ES256", "ES384", "ES512", "EdDSA"} {
+		t.Run(alg, func(t *testing.T) {
+			privatePEM, publicPEM := pemEncode(t, newPrivateKey(t, alg))
+			signer, err := NewKeyProviderFromPEM(alg, privatePEM)
+			if err != nil {
+				t.Fatalf("NewKeyProviderFromPEM: %v", err)
+			}
+			useKeyProvider(t, signer)
+			tokens := newTestTokens(t)
+
+			// A downstream service holding only the public key verifies it.
+			verifier, err := NewPublicKeyProviderFromPEM(alg, publicPEM)
+			if err != nil {
+				t.Fatalf("NewPublicKeyProviderFromPEM: %v", err)
+			}
+			if _, err := verifier.SigningKey(); !errors.Is(err, ErrNoSigningKey) {
+				t.Errorf("SigningKey of a public key = %v, want ErrNoSigningKey", err)
+			}
+			SetKeyProvider(verifier)
+			token, err := validateJWT(tokens.AccessToken)
+			if err != nil || token.Method.Alg() != alg {
+				t.Fatalf("validateJWT = %v, %v", token, err)
+			}
+
+			// Another key of the same kind does not.
+			_, otherPublic := pemEncode(t, newPrivateKey(t, alg))
+			other, err := NewPublicKeyProviderFromPEM(alg, otherPublic)
+			if err != nil {
+				t.Fatal(err)
+			}
+			SetKeyProvider(other)
+			if _, err := validateJWT(tokens.AccessToken); err == nil {
+				t.Error("validateJWT accepted a token signed by another key")
+			}
+		})
+	}
+}
+
+func TestParseJWTKeyRejectsMismatches(t *testing.T) {
+	rsaPrivate, rsaPublic := pemEncode(t, newPrivateKey(t, "RS256"))
+	p384Private, p384Public := pemEncode(t, newPrivateKey(t, "ES384"))
+	edPrivate, _ := pemEncode(t, newPrivateKey(t, "EdDSA"))
+
+	tests := []struct {
+		name  string
+		alg   string
+		pem   []byte
+		parse func(string, []byte) (*JWTKey, error)
+		want  error
+	}{
+		{"ES256 with a P-384 key", "ES256", p384Private, ParsePrivateJWTKey, ErrUnexpectedKeyType},
+		{"ES256 with a P-384 public key", "ES256", p384Public, ParsePublicJWTKey, ErrUnexpectedKeyType},
+		{"RS256 with an EC key", "RS256", p384Private, ParsePrivateJWTKey, nil},
+		{"EdDSA with an RSA key", "EdDSA", rsaPrivate, ParsePrivateJWTKey, nil},
+		{"ES256 with an Ed25519 key", "ES256", edPrivate, ParsePrivateJWTKey, nil},
+		{"private parser with a public key", "RS256", rsaPublic, ParsePrivateJWTKey, nil},
+		{"HMAC algorithm", "HS256", rsaPrivate, ParsePrivateJWTKey, nil},
+		{"unknown algorithm", "XX256", rsaPublic, ParsePublicJWTKey, nil},
+		{"not PEM", "RS256", []byte("not a key"), ParsePublicJWTKey, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := tt.parse(tt.alg, tt.pem)
+			if err == nil {
+				t.Fatalf("parsed %+v", key)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateJWTRejectsAlgorithmConfusion(t *testing.T) {
+	_, publicPEM := pemEncode(t, newPrivateKey(t, "RS256"))
+	verifier, err := NewPublicKeyProviderFromPEM("RS256", publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useKeyProvider(t, verifier)
+	claims := jwt.MapClaims{Type: JWT_ACCESS_TOKEN, UserId: uuid.NewString(), Exp: time.Now().Add(time.Minute).Unix()}
+
+	// The classic attack: HMAC keyed with the published RSA public key.
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := validateJWT(forged); !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		t.Errorf("HS256 token keyed with the public key: %v, want ErrTokenSignatureInvalid", err)
+	}
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := validateJWT(unsigned); err == nil {
+		t.Error("validateJWT accepted an unsigned token")
+	}
+}
+
+func TestStaticKeyProviderKeyID(t *testing.T) {
+	secret := []byte("secret")
+	useKeyProvider(t, NewStaticKeyProvider(&JWTKey{ID: "k1", Method: jwt.SigningMethodHS256, SignKey: secret, VerifyKey: secret}))
+	tokens := newTestTokens(t)
+	token, err := validateJWT(tokens.AccessToken)
+	if err != nil || token.Header["kid"] != "k1" {
+		t.Fatalf("validateJWT = %v, %v", token, err)
+	}
+
+	other, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{UserId: uuid.NewString()}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A token without a kid is accepted by a single key provider...
+	if _, err := validateJWT(other); err != nil {
+		t.Errorf("validateJWT without a kid: %v", err)
+	}
+	// ...but one naming another key is not.
+	named := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{UserId: uuid.NewString()})
+	named.Header["kid"] = "k2"
+	signed, err := named.SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := validateJWT(signed); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("validateJWT with an unknown kid: %v, want ErrUnknownKeyID", err)
+	}
+
+	if _, err := NewHMACKeyProvider("RS256", secret); err == nil {
+		t.Error("NewHMACKeyProvider accepted RS256")
+	}
+	if _, err := NewHMACKeyProvider("HS256", nil); err == nil {
+		t.Error("NewHMACKeyProvider accepted an empty secret")
+	}
+}
+
+func TestKeyProviderFromEnv(t *testing.T) {
+	privatePEM, publicPEM := pemEncode(t, newPrivateKey(t, "ES256"))
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "private.pem")
+	publicPath := filepath.Join(dir, "public.pem")
+	if err := os.WriteFile(privatePath, privatePEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, publicPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		signs   bool
+		wantErr bool
+	}{
+		{"default HS256", map[string]string{"GOAUTH_JWT_SECRET": "secret"}, true, false},
+		{"HS256 without a secret", map[string]string{}, false, true},
+		{"private key file", map[string]string{"GOAUTH_JWT_ALGORITHM": "ES256", "GOAUTH_JWT_PRIVATE_KEY_FILE": privatePath, "GOAUTH_JWT_KEY_ID": "k1"}, true, false},
+		{"public key file", map[string]string{"GOAUTH_JWT_ALGORITHM": "ES256", "GOAUTH_JWT_PUBLIC_KEY_FILE": publicPath}, false, false},
+		{"missing key file", map[string]string{"GOAUTH_JWT_ALGORITHM": "ES256", "GOAUTH_JWT_PRIVATE_KEY_FILE": filepath.Join(dir, "missing.pem")}, false, true},
+		{"no key file", map[string]string{"GOAUTH_JWT_ALGORITHM": "ES256"}, false, true},
+		{"wrong curve", map[string]string{"GOAUTH_JWT_ALGORITHM": "ES384", "GOAUTH_JWT_PRIVATE_KEY_FILE": privatePath}, false, true},
+		{"unknown algorithm", map[string]string{"GOAUTH_JWT_ALGORITHM": "none"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"GOAUTH_JWT_ALGORITHM", "GOAUTH_JWT_SECRET", "GOAUTH_JWT_PRIVATE_KEY_FILE", "GOAUTH_JWT_PUBLIC_KEY_FILE", "GOAUTH_JWT_KEY_ID"} {
+				t.Setenv(name, tt.env[name])
+			}
+			provider, err := KeyProviderFromEnv()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("KeyProviderFromEnv succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			key, err := provider.SigningKey()
+			if tt.signs != (err == nil) {
+				t.Fatalf("SigningKey = %v, %v", key, err)
+			}
+			if tt.signs && key.ID != tt.env["GOAUTH_JWT_KEY_ID"] {
+				t.Errorf("key id = %q, want %q", key.ID, tt.env["GOAUTH_JWT_KEY_ID"])
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrMissingKeyID = errors.New("token has no kid header")
+	ErrUnknownKeyID = errors.New("token kid is not in the key ring")
+	ErrKeyRetired   = errors.New("token was signed with a retired key")
+)
+
+type (
+	// KeyRing is a KeyProvider holding several keys at once. New tokens are
+	// signed with the active key and carry its kid header; any key still in
+	// the ring can verify. A retired key keeps verifying for verifyWindow so
+	// tokens it already signed can run out their lifetime.
+	KeyRing struct {
+		mu           sync.RWMutex
+		active       string
+		keys         map[string]*ringKey
+		order        []string
+		verifyWindow time.Duration
+	}
+
+	ringKey struct {
+		key        *JWTKey
+		retiredAt  time.Time
+		verifyTill time.Time
+	}
+)
+
+// NewKeyRing returns an empty ring. verifyWindow should be at least the
+// longest lifetime of any token the ring signs, i.e. the refresh token's.
+func NewKeyRing(verifyWindow time.Duration) *KeyRing {
+	return &KeyRing{
+		keys:         make(map[string]*ringKey),
+		verifyWindow: verifyWindow,
+	}
+}
+
+// Add puts key into the ring for verification. The first key with a signing
+// half becomes the active key.
+func (r *KeyRing) Add(key *JWTKey) error {
+	if key.ID == "" {
+		return errors.New("key ring entries require a key id")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.keys[key.ID]; exists {
+		return fmt.Errorf("key %s is already in the ring", key.ID)
+	}
+	r.keys[key.ID] = &ringKey{key: key}
+	r.order = append(r.order, key.ID)
+	if r.active == "" && key.SignKey != nil {
+		r.active = key.ID
+	}
+	return nil
+}
+
+// Rotate adds key, makes it the signing key and retires the previous one.
+func (r *KeyRing) Rotate(key *JWTKey) error {
+	if key.SignKey == nil {
+		return ErrNoSigningKey
+	}
+	if err := r.Add(key); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous := r.active
+	r.active = key.ID
+	if previous != "" && previous != key.ID {
+		r.retireLocked(previous, time.Now())
+	}
+	return nil
+}
+
+// Retire stops the key from verifying once verifyWindow has passed. Retiring
+// the active key leaves the ring unable to sign until Rotate is called.
+func (r *KeyRing) Retire(kid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.keys[kid]; !exists {
+		return ErrUnknownKeyID
+	}
+	if r.active == kid {
+		r.active = ""
+	}
+	r.retireLocked(kid, time.Now())
+	return nil
+}
+
+// Remove drops the key immediately, invalidating every token it signed.
+func (r *KeyRing) Remove(kid string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.keys, kid)
+	for i, id := range r.order {
+		if id == kid {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	if r.active == kid {
+		r.active = ""
+	}
+}
+
+func (r *KeyRing) retireLocked(kid string, now time.Time) {
+	entry := r.keys[kid]
+	if !entry.retiredAt.IsZero() {
+		return
+	}
+	entry.retiredAt = now
+	entry.verifyTill = now.Add(r.verifyWindow)
+}
+
+func (r *KeyRing) SigningKey() (*JWTKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.active == "" {
+		return nil, ErrNoSigningKey
+	}
+	return r.keys[r.active].key, nil
+}
+
+func (r *KeyRing) VerificationKey(token *jwt.Token) (*JWTKey, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, ErrMissingKeyID
+	}
+
+	r.mu.RLock()
+	entry, exists := r.keys[kid]
+	r.mu.RUnlock()
+
+	if !exists {
+		return nil, ErrUnknownKeyID
+	}
+	if !entry.verifyTill.IsZero() && time.Now().After(entry.verifyTill) {
+		return nil, ErrKeyRetired
+	}
+	if token.Method.Alg() != entry.key.Method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return entry.key, nil
+}
+
+// JWKS publishes every key that can still verify, active key first.
+func (r *KeyRing) JWKS() JWKSet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	set := JWKSet{Keys: []JWK{}}
+	if entry, exists := r.keys[r.active]; exists {
+		if jwk, ok := NewJWK(entry.key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	for _, kid := range r.order {
+		entry := r.keys[kid]
+		if kid == r.active || (!entry.verifyTill.IsZero() && now.After(entry.verifyTill)) {
+			continue
+		}
+		if jwk, ok := NewJWK(entry.key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+var _ KeyProvider = (*KeyRing)(nil)
+var _ JWKSProvider = (*KeyRing)(nil)
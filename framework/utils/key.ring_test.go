@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func newRingKey(t *testing.T, kid string) *JWTKey {
+	t.Helper()
+	privatePEM, _ := pemEncode(t, newPrivateKey(t, "ES256"))
+	key, err := ParsePrivateJWTKey("ES256", privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.ID = kid
+	return key
+}
+
+func jwksKeyIDs(set JWKSet) []string {
+	ids := make([]string, 0, len(set.Keys))
+	for _, key := range set.Keys {
+		ids = append(ids, key.Kid)
+	}
+	return ids
+}
+
+func TestKeyRingRotate(t *testing.T) {
+	const window = 100 * time.Millisecond
+	ring := NewKeyRing(window)
+	if err := ring.Add(newRingKey(t, "k1")); err != nil {
+		t.Fatal(err)
+	}
+	useKeyProvider(t, ring)
+	old := newTestTokens(t)
+
+	if err := ring.Rotate(newRingKey(t, "k2")); err != nil {
+		t.Fatal(err)
+	}
+	current := newTestTokens(t)
+	token, err := validateJWT(current.AccessToken)
+	if err != nil || token.Header["kid"] != "k2" {
+		t.Fatalf("token signed after Rotate = %v, %v, want kid k2", token, err)
+	}
+	if _, err := validateJWT(old.AccessToken); err != nil {
+		t.Errorf("token of the retired key within the window: %v", err)
+	}
+	if got := jwksKeyIDs(ring.JWKS()); len(got) != 2 || got[0] != "k2" || got[1] != "k1" {
+		t.Errorf("JWKS kids within the window = %v, want [k2 k1]", got)
+	}
+
+	time.Sleep(2 * window)
+	if _, err := validateJWT(old.AccessToken); !errors.Is(err, ErrKeyRetired) {
+		t.Errorf("token of the retired key after the window: %v, want ErrKeyRetired", err)
+	}
+	if _, err := validateJWT(current.AccessToken); err != nil {
+		t.Errorf("token of the active key after the window: %v", err)
+	}
+	if got := jwksKeyIDs(ring.JWKS()); len(got) != 1 || got[0] != "k2" {
+		t.Errorf("JWKS kids after the window = %v, want [k2]", got)
+	}
+}
+
+func TestKeyRingVerificationKey(t *testing.T) {
+	ring := NewKeyRing(time.Hour)
+	if err := ring.Add(newRingKey(t, "k1")); err != nil {
+		t.Fatal(err)
+	}
+	useKeyProvider(t, ring)
+	tokens := newTestTokens(t)
+
+	sign := func(kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{UserId: uuid.NewString()})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(newRingKey(t, kid).SignKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	if _, err := validateJWT(sign("")); !errors.Is(err, ErrMissingKeyID) {
+		t.Errorf("token without a kid: %v, want ErrMissingKeyID", err)
+	}
+	if _, err := validateJWT(sign("k9")); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("token with an unknown kid: %v, want ErrUnknownKeyID", err)
+	}
+	if _, err := validateJWT(sign("k1")); err == nil {
+		t.Error("validateJWT accepted a token naming k1 but signed by another key")
+	}
+
+	// The kid picks a key, but the key still pins the algorithm.
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{UserId: uuid.NewString()})
+	hmac.Header["kid"] = "k1"
+	forged, err := hmac.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := validateJWT(forged); !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		t.Errorf("HS256 token naming an ES256 key: %v, want ErrTokenSignatureInvalid", err)
+	}
+
+	ring.Remove("k1")
+	if _, err := validateJWT(tokens.AccessToken); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("token of a removed key: %v, want ErrUnknownKeyID", err)
+	}
+	if _, err := ring.SigningKey(); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("SigningKey after removing the active key = %v, want ErrNoSigningKey", err)
+	}
+}
+
+func TestKeyRingManagement(t *testing.T) {
+	ring := NewKeyRing(time.Hour)
+	if err := ring.Add(&JWTKey{Method: jwt.SigningMethodHS256, SignKey: []byte("s"), VerifyKey: []byte("s")}); err == nil {
+		t.Error("Add accepted a key without an id")
+	}
+
+	// A verify-only key joins the ring but cannot sign.
+	public := newRingKey(t, "public")
+	public.SignKey = nil
+	if err := ring.Add(public); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ring.SigningKey(); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("SigningKey with only a public key = %v, want ErrNoSigningKey", err)
+	}
+	if err := ring.Rotate(public); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("Rotate to a public key = %v, want ErrNoSigningKey", err)
+	}
+
+	if err := ring.Add(newRingKey(t, "k1")); err != nil {
+		t.Fatal(err)
+	}
+	if key, err := ring.SigningKey(); err != nil || key.ID != "k1" {
+		t.Errorf("SigningKey = %v, %v, want k1", key, err)
+	}
+	if err := ring.Add(newRingKey(t, "k1")); err == nil {
+		t.Error("Add accepted a duplicate kid")
+	}
+	if err := ring.Retire("k9"); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("Retire of an unknown kid = %v, want ErrUnknownKeyID", err)
+	}
+	if err := ring.Retire("k1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ring.SigningKey(); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("SigningKey after retiring the active key = %v, want ErrNoSigningKey", err)
+	}
+}
@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+// Bounds on the parameters of a stored hash, so a hash that was imported or
+// tampered with cannot make a login allocate more than RFC 9106's first
+// recommendation (2 GiB), run for minutes, or match on a few bytes of key.
+const (
+	maxArgon2idMemory      = 2 * 1024 * 1024
+	maxArgon2idTime        = 16
+	maxArgon2idParallelism = 16
+	minArgon2idSaltLength  = 8
+	minArgon2idKeyLength   = 16
+)
+
+var ErrInvalidArgon2idHash = errors.New("invalid argon2id hash")
+
+// Argon2idParams tunes Argon2idHasher. Memory is in KiB. Hashes beyond 2 GiB
+// of memory, 16 passes or 16 lanes, or with a salt under 8 bytes or a key
+// under 16, are refused when verified.
+type Argon2idParams struct {
+	Memory      uint32
+	Time        uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follows the second recommendation of RFC 9106 for
+// servers that cannot spare gigabytes per login: 64 MiB, 3 passes, 4 lanes.
+func DefaultArgon2idParams() Argon2idParams {
+	return Argon2idParams{
+		Memory:      64 * 1024,
+		Time:        3,
+		Parallelism: 4,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// Argon2idHasher hashes passwords with Argon2id into PHC strings such as
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>.
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+// NewArgon2idHasher returns a hasher with params; zero fields take their
+// DefaultArgon2idParams value, and fields outside the bounds that can be
+// verified are moved to the nearest bound.
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	defaults := DefaultArgon2idParams()
+	if params.Memory == 0 {
+		params.Memory = defaults.Memory
+	}
+	if params.Time == 0 {
+		params.Time = defaults.Time
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = defaults.Parallelism
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = defaults.SaltLength
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = defaults.KeyLength
+	}
+	params.Memory = min(params.Memory, maxArgon2idMemory)
+	params.Time = min(params.Time, maxArgon2idTime)
+	params.Parallelism = min(params.Parallelism, maxArgon2idParallelism)
+	params.SaltLength = max(params.SaltLength, minArgon2idSaltLength)
+	params.KeyLength = max(params.KeyLength, minArgon2idKeyLength)
+	return &Argon2idHasher{params: params}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Time, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+	return encodeArgon2id(h.params, salt, key), nil
+}
+
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	return VerifyPassword(password, encoded)
+}
+
+// NeedsRehash is true for other algorithms and for Argon2id hashes whose cost
+// differs from the configured one, so costs can be raised, or lowered, over
+// time.
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Memory != h.params.Memory ||
+		params.Time != h.params.Time ||
+		params.Parallelism != h.params.Parallelism ||
+		uint32(len(salt)) < h.params.SaltLength ||
+		uint32(len(key)) < h.params.KeyLength
+}
+
+func verifyArgon2id(password, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	candidate := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(candidate, key) == 1, nil
+}
+
+func encodeArgon2id(params Argon2idParams, salt, key []byte) string {
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		params.Memory, params.Time, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key))
+}
+
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2idParams{}, nil, nil, ErrInvalidArgon2idHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2idParams{}, nil, nil, ErrInvalidArgon2idHash
+	}
+	var params Argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Parallelism); err != nil {
+		return Argon2idParams{}, nil, nil, ErrInvalidArgon2idHash
+	}
+	if params.Memory == 0 || params.Memory > maxArgon2idMemory ||
+		params.Time == 0 || params.Time > maxArgon2idTime ||
+		params.Parallelism == 0 || params.Parallelism > maxArgon2idParallelism {
+		return Argon2idParams{}, nil, nil, ErrInvalidArgon2idHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) < minArgon2idSaltLength {
+		return Argon2idParams{}, nil, nil, ErrInvalidArgon2idHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) < minArgon2idKeyLength {
+		return Argon2idParams{}, nil, nil, ErrInvalidArgon2idHash
+	}
+	params.SaltLength, params.KeyLength = uint32(len(salt)), uint32(len(key))
+	return params, salt, key, nil
+}
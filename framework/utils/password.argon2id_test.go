@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewArgon2idHasherKeepsHashesVerifiable(t *testing.T) {
+	h := NewArgon2idHasher(Argon2idParams{
+		Memory:      8 * 1024,
+		Time:        100,
+		Parallelism: 200,
+		SaltLength:  1,
+		KeyLength:   4,
+	})
+	want := Argon2idParams{Memory: 8 * 1024, Time: maxArgon2idTime, Parallelism: maxArgon2idParallelism, SaltLength: minArgon2idSaltLength, KeyLength: minArgon2idKeyLength}
+	if h.params != want {
+		t.Fatalf("params = %+v, want %+v", h.params, want)
+	}
+	encoded, err := h.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidatePasswordHash(encoded); err != nil {
+		t.Fatalf("ValidatePasswordHash(%s): %v", encoded, err)
+	}
+	if ok, err := h.Verify("password", encoded); !ok || err != nil {
+		t.Errorf("Verify = %v, %v", ok, err)
+	}
+}
+
+// cheapArgon2id keeps the tests fast; the parameters are far below anything
+// to use in production.
+var cheapArgon2id = Argon2idParams{Memory: 8 * 1024, Time: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	h := NewArgon2idHasher(cheapArgon2id)
+	hashWith := func(change func(*Argon2idParams)) string {
+		t.Helper()
+		params := cheapArgon2id
+		change(&params)
+		encoded, err := (&Argon2idHasher{params: params}).Hash("password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encoded
+	}
+	bcryptHash, err := NewBcryptHasher(4).Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+		want    bool
+	}{
+		{"same parameters", hashWith(func(*Argon2idParams) {}), false},
+		{"longer salt and key", hashWith(func(p *Argon2idParams) { p.SaltLength, p.KeyLength = 32, 64 }), false},
+		{"less memory", hashWith(func(p *Argon2idParams) { p.Memory = 4 * 1024 }), true},
+		{"more memory", hashWith(func(p *Argon2idParams) { p.Memory = 16 * 1024 }), true},
+		{"more passes", hashWith(func(p *Argon2idParams) { p.Time = 2 }), true},
+		{"more lanes", hashWith(func(p *Argon2idParams) { p.Parallelism = 2 }), true},
+		{"shorter salt", hashWith(func(p *Argon2idParams) { p.SaltLength = 8 }), true},
+		{"shorter key", hashWith(func(p *Argon2idParams) { p.KeyLength = 16 }), true},
+		{"bcrypt", bcryptHash, true},
+		{"garbage", "$argon2id$nonsense", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.NeedsRehash(tt.encoded); got != tt.want {
+				t.Errorf("NeedsRehash(%s) = %v, want %v", tt.encoded, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArgon2idVerify(t *testing.T) {
+	h := NewArgon2idHasher(cheapArgon2id)
+	encoded, err := h.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := h.Verify("Password", encoded); ok || err != nil {
+		t.Errorf("Verify with the wrong password = %v, %v", ok, err)
+	}
+
+	// Parameters out of bounds are refused before any work is done.
+	for _, tampered := range []string{
+		strings.Replace(encoded, "m=8192", "m=4194304", 1),
+		strings.Replace(encoded, "t=1", "t=1000", 1),
+		strings.Replace(encoded, "v=19", "v=16", 1),
+		encoded[:strings.LastIndex(encoded, "$")+1] + "c2hvcnQ",
+	} {
+		if ok, err := h.Verify("password", tampered); ok || !errors.Is(err, ErrInvalidArgon2idHash) {
+			t.Errorf("Verify(%s) = %v, %v, want ErrInvalidArgon2idHash", tampered, ok, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptHasher hashes passwords with bcrypt. Its modular crypt strings, such
+// as $2a$10$<salt+hash>, carry the algorithm and cost like a PHC string does.
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher returns a hasher with cost, or bcrypt.DefaultCost when it
+// is zero.
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (h *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	return VerifyPassword(password, encoded)
+}
+
+// NeedsRehash is true for other algorithms and for bcrypt hashes below the
+// configured cost.
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	if !isBcryptHash(encoded) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < h.cost
+}
+
+func isBcryptHash(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+func verifyBcrypt(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
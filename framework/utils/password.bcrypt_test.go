@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcryptNeedsRehash(t *testing.T) {
+	hash := func(cost int) string {
+		t.Helper()
+		encoded, err := NewBcryptHasher(cost).Hash("password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encoded
+	}
+	argon2idHash, err := NewArgon2idHasher(cheapArgon2id).Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewBcryptHasher(5)
+	tests := []struct {
+		name    string
+		encoded string
+		want    bool
+	}{
+		{"same cost", hash(5), false},
+		{"higher cost", hash(6), false},
+		{"lower cost", hash(4), true},
+		{"$2y$ prefix", "$2y$" + strings.TrimPrefix(hash(5), "$2a$"), false},
+		{"argon2id", argon2idHash, true},
+		{"garbage", "$2a$nonsense", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.NeedsRehash(tt.encoded); got != tt.want {
+				t.Errorf("NeedsRehash(%s) = %v, want %v", tt.encoded, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBcryptVerify(t *testing.T) {
+	h := NewBcryptHasher(4)
+	encoded, err := h.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for password, want := range map[string]bool{"password": true, "Password": false, "": false} {
+		if ok, err := h.Verify(password, encoded); ok != want || err != nil {
+			t.Errorf("Verify(%q) = %v, %v, want %v", password, ok, err, want)
+		}
+	}
+	// Providers-only users have no hash, which matches nothing.
+	if ok, err := h.Verify("", ""); ok || err != nil {
+		t.Errorf("Verify against no hash = %v, %v", ok, err)
+	}
+	if NewBcryptHasher(0).cost != bcrypt.DefaultCost {
+		t.Error("cost 0 is not bcrypt.DefaultCost")
+	}
+}
@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"strings"
+)
+
+// BreachedPasswords is a local list of passwords known from data breaches.
+// Nothing is sent over the network to check one.
+type BreachedPasswords interface {
+	Contains(password string) (bool, error)
+}
+
+var ErrInvalidBloomFilter = errors.New("invalid bloom filter")
+
+// bloomFilterMagic starts a file written by BloomFilter.WriteTo.
+const bloomFilterMagic = "GABF\x01"
+
+func passwordSHA1(password string) [sha1.Size]byte {
+	return sha1.Sum([]byte(password))
+}
+
+// SHA1File looks passwords up in a file of SHA-1 hashes sorted in ascending
+// order, one per line in uppercase hex, optionally followed by ":count" as
+// in the Have I Been Pwned download. It is searched in place, so the file
+// can be far larger than memory.
+type SHA1File struct {
+	file *os.File
+	size int64
+}
+
+// OpenSHA1File opens a sorted SHA-1 hash file. Close it when done.
+func OpenSHA1File(path string) (*SHA1File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &SHA1File{file: file, size: info.Size()}, nil
+}
+
+func (f *SHA1File) Close() error {
+	return f.file.Close()
+}
+
+func (f *SHA1File) Contains(password string) (bool, error) {
+	sum := passwordSHA1(password)
+	target := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	// Binary search over byte offsets: each probe reads the first line that
+	// starts at or after mid.
+	lo, hi := int64(0), f.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, hash, err := f.lineAfter(mid)
+		if errors.Is(err, io.EOF) {
+			hi = mid
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		switch strings.Compare(hash, target) {
+		case 0:
+			return true, nil
+		case -1:
+			lo = start + 1
+		default:
+			hi = mid
+		}
+	}
+	return false, nil
+}
+
+// lineAfter returns the offset and hash of the first line starting at or
+// after offset.
+func (f *SHA1File) lineAfter(offset int64) (int64, string, error) {
+	start := offset
+	if offset > 0 {
+		start = offset - 1
+	}
+	reader := bufio.NewReader(io.NewSectionReader(f.file, start, f.size-start))
+	if offset > 0 {
+		skipped, err := reader.ReadString('\n')
+		if err != nil {
+			return 0, "", io.EOF
+		}
+		start += int64(len(skipped))
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return 0, "", io.EOF
+	}
+	hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	return start, strings.ToUpper(hash), nil
+}
+
+// SHA1PrefixFiles looks passwords up in SHA-1 hashes split by their first
+// five hex characters, the way the Have I Been Pwned range API serves them
+// and its downloader saves them: <PREFIX>.txt holds one "SUFFIX:COUNT" line
+// per hash. Only the file of the password's prefix is read, so storage on a
+// network share learns no more than the range API would, one prefix out of
+// about a million, each shared by hundreds of passwords.
+type SHA1PrefixFiles struct {
+	fsys fs.FS
+}
+
+// NewSHA1PrefixFiles reads range files from fsys, e.g. os.DirFS(dir). A
+// complete download has a file for every prefix, so a missing one is an
+// error rather than a miss.
+func NewSHA1PrefixFiles(fsys fs.FS) *SHA1PrefixFiles {
+	return &SHA1PrefixFiles{fsys: fsys}
+}
+
+func (p *SHA1PrefixFiles) Contains(password string) (bool, error) {
+	sum := passwordSHA1(password)
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := p.fsys.Open(prefix + ".txt")
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		candidate, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		// Padded range responses add made-up suffixes with a count of 0.
+		if strings.EqualFold(candidate, suffix) && strings.TrimSpace(count) != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// BloomFilter is a compact breached password list that answers "maybe" or
+// "no": a small share of passwords not on the list are refused too. Build
+// one with NewBloomFilter and Add or AddSHA1, save it with WriteTo and load
+// it with ReadBloomFilter.
+type BloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes uint32
+}
+
+// NewBloomFilter sizes a filter for expected entries at falsePositiveRate,
+// e.g. 0.001 for one in a thousand.
+func NewBloomFilter(expected int, falsePositiveRate float64) *BloomFilter {
+	if expected < 1 {
+		expected = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.001
+	}
+	size := uint64(math.Ceil(-float64(expected) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := uint32(math.Max(1, math.Round(float64(size)/float64(expected)*math.Ln2)))
+	return &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+func (b *BloomFilter) Add(password string) {
+	b.add(passwordSHA1(password))
+}
+
+// AddSHA1 adds a password by its hex SHA-1 hash, as breach lists publish
+// them.
+func (b *BloomFilter) AddSHA1(hash string) error {
+	var sum [sha1.Size]byte
+	decoded, err := hex.DecodeString(strings.TrimSpace(hash))
+	if err != nil || len(decoded) != sha1.Size {
+		return ErrInvalidBloomFilter
+	}
+	copy(sum[:], decoded)
+	b.add(sum)
+	return nil
+}
+
+func (b *BloomFilter) Contains(password string) (bool, error) {
+	sum := passwordSHA1(password)
+	for i := uint32(0); i < b.hashes; i++ {
+		bit := b.bit(sum, i)
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (b *BloomFilter) add(sum [sha1.Size]byte) {
+	for i := uint32(0); i < b.hashes; i++ {
+		bit := b.bit(sum, i)
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// bit derives the i-th position from the SHA-1 by double hashing.
+func (b *BloomFilter) bit(sum [sha1.Size]byte, i uint32) uint64 {
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16]) | 1
+	return (h1 + uint64(i)*h2) % b.size
+}
+
+// WriteTo saves the filter in a form ReadBloomFilter loads.
+func (b *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, len(bloomFilterMagic)+12)
+	copy(header, bloomFilterMagic)
+	binary.LittleEndian.PutUint64(header[len(bloomFilterMagic):], b.size)
+	binary.LittleEndian.PutUint32(header[len(bloomFilterMagic)+8:], b.hashes)
+	written, err := w.Write(header)
+	if err != nil {
+		return int64(written), err
+	}
+	err = binary.Write(w, binary.LittleEndian, b.bits)
+	if err != nil {
+		return int64(written), err
+	}
+	return int64(written + 8*len(b.bits)), nil
+}
+
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	header := make([]byte, len(bloomFilterMagic)+12)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:len(bloomFilterMagic)]) != bloomFilterMagic {
+		return nil, ErrInvalidBloomFilter
+	}
+	size := binary.LittleEndian.Uint64(header[len(bloomFilterMagic):])
+	hashes := binary.LittleEndian.Uint32(header[len(bloomFilterMagic)+8:])
+	if size == 0 || hashes == 0 {
+		return nil, ErrInvalidBloomFilter
+	}
+	filter := &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+	if err := binary.Read(r, binary.LittleEndian, filter.bits); err != nil {
+		return nil, ErrInvalidBloomFilter
+	}
+	return filter, nil
+}
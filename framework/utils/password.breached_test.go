@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var breachedPasswords = []string{"password", "123456", "letmein", "correct horse battery staple"}
+
+func sha1Hex(password string) string {
+	sum := passwordSHA1(password)
+	return fmt.Sprintf("%X", sum[:])
+}
+
+func TestSHA1File(t *testing.T) {
+	var lines []string
+	for i, password := range breachedPasswords {
+		lines = append(lines, fmt.Sprintf("%s:%d", sha1Hex(password), i+1))
+	}
+	// Filler around them, so the search has something to skip.
+	for i := range 200 {
+		lines = append(lines, sha1Hex(fmt.Sprintf("filler-%d", i)))
+	}
+	slices.Sort(lines)
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := OpenSHA1File(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = list.Close() })
+
+	for _, password := range append(breachedPasswords, "filler-0", "filler-199") {
+		if found, err := list.Contains(password); !found || err != nil {
+			t.Errorf("Contains(%q) = %v, %v, want true", password, found, err)
+		}
+	}
+	for _, password := range []string{"", "Password", "filler-200", "a long unlisted passphrase"} {
+		if found, err := list.Contains(password); found || err != nil {
+			t.Errorf("Contains(%q) = %v, %v, want false", password, found, err)
+		}
+	}
+}
+
+// openRecorder records the name of every file opened.
+type openRecorder struct {
+	fs.FS
+	opened []string
+}
+
+func (r *openRecorder) Open(name string) (fs.File, error) {
+	r.opened = append(r.opened, name)
+	return r.FS.Open(name)
+}
+
+func TestSHA1PrefixFiles(t *testing.T) {
+	files := fstest.MapFS{}
+	add := func(hash, count string) {
+		name := hash[:5] + ".txt"
+		entry := hash[5:] + ":" + count + "\r\n"
+		if file, ok := files[name]; ok {
+			file.Data = append(file.Data, entry...)
+			return
+		}
+		files[name] = &fstest.MapFile{Data: []byte(entry)}
+	}
+	for _, password := range breachedPasswords {
+		add(sha1Hex(password), "42")
+	}
+	// A padding entry the range API made up, and a prefix file of which
+	// only the suffix differs.
+	add(sha1Hex("padded"), "0")
+	notListed := sha1Hex("not listed")
+	add(notListed[:5]+strings.Repeat("0", 35), "7")
+	// Lower case suffixes are matched too.
+	lower := sha1Hex("lower case")
+	add(lower[:5]+strings.ToLower(lower[5:]), "1")
+
+	recorder := &openRecorder{FS: files}
+	list := NewSHA1PrefixFiles(recorder)
+	for password, want := range map[string]bool{
+		"password":   true,
+		"letmein":    true,
+		"lower case": true,
+		"padded":     false,
+		"not listed": false,
+	} {
+		recorder.opened = nil
+		found, err := list.Contains(password)
+		if err != nil || found != want {
+			t.Errorf("Contains(%q) = %v, %v, want %v", password, found, err, want)
+		}
+		// Only the first five characters of the hash leave the process.
+		if want := []string{sha1Hex(password)[:5] + ".txt"}; !slices.Equal(recorder.opened, want) {
+			t.Errorf("Contains(%q) opened %v, want %v", password, recorder.opened, want)
+		}
+	}
+
+	// An incomplete download is an error, not a pass.
+	if found, err := list.Contains("no prefix file"); found || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Contains without a prefix file = %v, %v, want fs.ErrNotExist", found, err)
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	filter := NewBloomFilter(1000, 0.001)
+	for i := range 1000 {
+		filter.Add(fmt.Sprintf("breached-%d", i))
+	}
+	if err := filter.AddSHA1(strings.ToLower(sha1Hex("by hash"))); err != nil {
+		t.Fatal(err)
+	}
+	for _, hash := range []string{"", "not hex", sha1Hex("short")[:38]} {
+		if err := filter.AddSHA1(hash); !errors.Is(err, ErrInvalidBloomFilter) {
+			t.Errorf("AddSHA1(%q) = %v, want ErrInvalidBloomFilter", hash, err)
+		}
+	}
+
+	var saved bytes.Buffer
+	if _, err := filter.WriteTo(&saved); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadBloomFilter(bytes.NewReader(saved.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadBloomFilter: %v", err)
+	}
+
+	for name, filter := range map[string]*BloomFilter{"built": filter, "loaded": loaded} {
+		// No false negatives...
+		for i := range 1000 {
+			if found, _ := filter.Contains(fmt.Sprintf("breached-%d", i)); !found {
+				t.Fatalf("%s filter misses breached-%d", name, i)
+			}
+		}
+		if found, _ := filter.Contains("by hash"); !found {
+			t.Errorf("%s filter misses a password added by hash", name)
+		}
+		// ...and false positives near the rate asked for.
+		falsePositives := 0
+		for i := range 10000 {
+			if found, _ := filter.Contains(fmt.Sprintf("fine-%d", i)); found {
+				falsePositives++
+			}
+		}
+		if falsePositives > 50 {
+			t.Errorf("%s filter: %d false positives in 10000, want about 10", name, falsePositives)
+		}
+	}
+
+	for name, data := range map[string][]byte{
+		"empty":       nil,
+		"other magic": append([]byte("XXXX\x01"), saved.Bytes()[5:]...),
+		"truncated":   saved.Bytes()[:saved.Len()-1],
+		"zero size":   append([]byte(bloomFilterMagic), make([]byte, 12)...),
+	} {
+		if _, err := ReadBloomFilter(bytes.NewReader(data)); !errors.Is(err, ErrInvalidBloomFilter) {
+			t.Errorf("ReadBloomFilter(%s) = %v, want ErrInvalidBloomFilter", name, err)
+		}
+	}
+}
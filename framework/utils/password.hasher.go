@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher turns passwords into self-describing hash strings, so hashes
+// made with different algorithms or costs can be stored side by side and a
+// user's hash upgraded the next time they log in.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of a new password.
+	Hash(password string) (string, error)
+	// Verify reports whether password matches encoded, which may have been
+	// produced by any hasher VerifyPassword knows.
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash reports whether encoded should be replaced by the output of
+	// Hash: it uses another algorithm or other parameters.
+	NeedsRehash(encoded string) bool
+}
+
+var ErrUnknownPasswordHash = errors.New("unrecognised password hash format")
+
+// VerifyPassword checks password against a hash made by any of the built-in
+// hashers, or imported from Firebase, Auth0 or Django. An empty hash, which
+// users who only sign in through a provider have, matches no password.
+func VerifyPassword(password, encoded string) (bool, error) {
+	if encoded == "" {
+		return false, nil
+	}
+	verify := passwordVerifier(encoded)
+	if verify == nil {
+		return false, ErrUnknownPasswordHash
+	}
+	return verify(password, encoded)
+}
+
+// IsPasswordHash reports whether VerifyPassword recognises the format of
+// encoded.
+func IsPasswordHash(encoded string) bool {
+	return passwordVerifier(encoded) != nil
+}
+
+// ValidatePasswordHash checks that VerifyPassword can verify encoded: its
+// format is known and its parameters are within bounds. Nothing is hashed,
+// so it is cheap enough to run over a whole import.
+func ValidatePasswordHash(encoded string) error {
+	var err error
+	switch {
+	case strings.HasPrefix(encoded, argon2idPrefix):
+		_, _, _, err = decodeArgon2id(encoded)
+	case isBcryptHash(encoded):
+		_, err = bcrypt.Cost([]byte(encoded))
+	case strings.HasPrefix(encoded, firebaseScryptPrefix):
+		_, err = decodeFirebaseScrypt(encoded)
+	case strings.HasPrefix(encoded, djangoPBKDF2Prefix):
+		_, _, _, err = decodeDjangoPBKDF2(encoded)
+	default:
+		err = ErrUnknownPasswordHash
+	}
+	return err
+}
+
+func passwordVerifier(encoded string) func(password, encoded string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, argon2idPrefix):
+		return verifyArgon2id
+	case isBcryptHash(encoded):
+		return verifyBcrypt
+	case strings.HasPrefix(encoded, firebaseScryptPrefix):
+		return verifyFirebaseScrypt
+	case strings.HasPrefix(encoded, djangoPBKDF2Prefix):
+		return verifyDjangoPBKDF2
+	}
+	return nil
+}
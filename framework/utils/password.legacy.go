@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Hashes of users imported from other systems. They can only be verified;
+// VerifyPassword accepts them so the user can log in once, after which the
+// configured PasswordHasher replaces them. Auth0 exports bcrypt hashes, which
+// need nothing extra.
+const (
+	firebaseScryptPrefix = "$firescrypt$"
+	djangoPBKDF2Prefix   = "pbkdf2_sha256$"
+)
+
+// Limits on imported hashes, so one cannot make every login of its user
+// allocate gigabytes or spin for minutes. Firebase's console offers memory
+// costs up to 14 and up to 8 rounds, always with a parallelism of 1; Django
+// uses about a million iterations.
+const (
+	maxFirebaseScryptLogN     = 14
+	maxFirebaseScryptRounds   = 8
+	maxDjangoPBKDF2Iterations = 10_000_000
+	firebaseScryptParallelism = 1
+	firebaseScryptKeyLength   = 32
+)
+
+var (
+	ErrInvalidFirebaseScryptHash = errors.New("invalid firebase scrypt hash")
+	ErrInvalidDjangoPBKDF2Hash   = errors.New("invalid django pbkdf2_sha256 hash")
+)
+
+// FirebaseScryptParams are the project-wide "password hash parameters" shown
+// in the Firebase console, with SignerKey and SaltSeparator base64 encoded as
+// shown there.
+type FirebaseScryptParams struct {
+	SignerKey     string
+	SaltSeparator string
+	Rounds        int
+	MemCost       int
+}
+
+// FirebaseScryptHash combines the passwordHash and salt of a user in a
+// Firebase export with the project's params into one hash string:
+// $firescrypt$ln=<mem cost>,r=<rounds>,p=1$<salt>$<hash>$<separator>$<signer key>.
+func FirebaseScryptHash(params FirebaseScryptParams, salt, passwordHash string) (string, error) {
+	var decoded [4][]byte
+	for i, value := range []string{salt, passwordHash, params.SaltSeparator, params.SignerKey} {
+		b, err := decodeBase64(value)
+		if err != nil {
+			return "", ErrInvalidFirebaseScryptHash
+		}
+		decoded[i] = b
+	}
+	if !validFirebaseScryptCost(params.MemCost, params.Rounds, firebaseScryptParallelism) || len(decoded[1]) == 0 {
+		return "", ErrInvalidFirebaseScryptHash
+	}
+	encoded := fmt.Sprintf("%sln=%d,r=%d,p=%d", firebaseScryptPrefix, params.MemCost, params.Rounds, firebaseScryptParallelism)
+	for _, b := range decoded {
+		encoded += "$" + base64.RawStdEncoding.EncodeToString(b)
+	}
+	return encoded, nil
+}
+
+func validFirebaseScryptCost(logN, r, p int) bool {
+	return logN > 0 && logN <= maxFirebaseScryptLogN &&
+		r > 0 && r <= maxFirebaseScryptRounds &&
+		p == firebaseScryptParallelism
+}
+
+// firebaseScrypt is a decoded $firescrypt$ hash.
+type firebaseScrypt struct {
+	logN, r, p                       int
+	salt, hash, separator, signerKey []byte
+}
+
+func decodeFirebaseScrypt(encoded string) (firebaseScrypt, error) {
+	// "", "firescrypt", "ln=..,r=..,p=..", salt, hash, separator, signer key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 7 {
+		return firebaseScrypt{}, ErrInvalidFirebaseScryptHash
+	}
+	var h firebaseScrypt
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &h.logN, &h.r, &h.p); err != nil || !validFirebaseScryptCost(h.logN, h.r, h.p) {
+		return firebaseScrypt{}, ErrInvalidFirebaseScryptHash
+	}
+	var decoded [4][]byte
+	for i, value := range parts[3:] {
+		b, err := decodeBase64(value)
+		if err != nil {
+			return firebaseScrypt{}, ErrInvalidFirebaseScryptHash
+		}
+		decoded[i] = b
+	}
+	h.salt, h.hash, h.separator, h.signerKey = decoded[0], decoded[1], decoded[2], decoded[3]
+	if len(h.hash) == 0 {
+		return firebaseScrypt{}, ErrInvalidFirebaseScryptHash
+	}
+	return h, nil
+}
+
+// verifyFirebaseScrypt follows Firebase's modified scrypt: the scrypt key of
+// the password, salted with salt+separator, AES-256-CTR encrypts the signer
+// key, and the result must equal the stored hash.
+func verifyFirebaseScrypt(password, encoded string) (bool, error) {
+	h, err := decodeFirebaseScrypt(encoded)
+	if err != nil {
+		return false, err
+	}
+	key, err := scrypt.Key([]byte(password), append(h.salt, h.separator...), 1<<h.logN, h.r, h.p, firebaseScryptKeyLength)
+	if err != nil {
+		return false, ErrInvalidFirebaseScryptHash
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return false, err
+	}
+	candidate := make([]byte, len(h.signerKey))
+	cipher.NewCTR(block, make([]byte, aes.BlockSize)).XORKeyStream(candidate, h.signerKey)
+	return subtle.ConstantTimeCompare(candidate, h.hash) == 1, nil
+}
+
+// verifyDjangoPBKDF2 checks Django's pbkdf2_sha256$<iterations>$<salt>$<hash>,
+// which is stored as Django writes it.
+func verifyDjangoPBKDF2(password, encoded string) (bool, error) {
+	iterations, salt, hash, err := decodeDjangoPBKDF2(encoded)
+	if err != nil {
+		return false, err
+	}
+	candidate, err := pbkdf2.Key(sha256.New, password, []byte(salt), iterations, len(hash))
+	if err != nil {
+		return false, ErrInvalidDjangoPBKDF2Hash
+	}
+	return subtle.ConstantTimeCompare(candidate, hash) == 1, nil
+}
+
+func decodeDjangoPBKDF2(encoded string) (iterations int, salt string, hash []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 {
+		return 0, "", nil, ErrInvalidDjangoPBKDF2Hash
+	}
+	iterations, err = strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 || iterations > maxDjangoPBKDF2Iterations {
+		return 0, "", nil, ErrInvalidDjangoPBKDF2Hash
+	}
+	hash, err = base64.StdEncoding.DecodeString(parts[3])
+	if err != nil || len(hash) == 0 {
+		return 0, "", nil, ErrInvalidDjangoPBKDF2Hash
+	}
+	return iterations, parts[2], hash, nil
+}
+
+// decodeBase64 accepts standard base64 with or without padding, and the URL
+// alphabet some exports use.
+func decodeBase64(value string) ([]byte, error) {
+	value = strings.TrimRight(value, "=")
+	if b, err := base64.RawStdEncoding.DecodeString(value); err == nil {
+		return b, nil
+	}
+	return base64.RawURLEncoding.DecodeString(value)
+}
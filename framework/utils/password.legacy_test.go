@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// The sample project in the README of github.com/firebase/scrypt.
+var firebaseSample = struct {
+	params             FirebaseScryptParams
+	salt, hash, secret string
+}{
+	params: FirebaseScryptParams{
+		SignerKey:     "jxspr8Ki0RYycVU8zykbdLGjFQ3McFUH0uiiTvC8pVMXAn210wjLNmdZJzxUECKbm0QsEmYUSDzZvpjeJ9WmXA==",
+		SaltSeparator: "Bw==",
+		Rounds:        8,
+		MemCost:       14,
+	},
+	salt:   "42xEC+ixf3L2lw==",
+	hash:   "lSrfV15cpx95/sZS2W9c9Kp6i/LVgQNDNC/qzrCnh1SAyZvqmZqAjTdn3aoItz+VHjoZilo78198JAdRuid5lQ==",
+	secret: "user1password",
+}
+
+func TestVerifyPasswordKnownAnswers(t *testing.T) {
+	firebase, err := FirebaseScryptHash(firebaseSample.params, firebaseSample.salt, firebaseSample.hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, password, encoded string
+	}{
+		{"firebase scrypt", firebaseSample.secret, firebase},
+		// Django 3.2's own test of make_password.
+		{"django pbkdf2_sha256", "lètmein", "pbkdf2_sha256$260000$seasalt$YlZ2Vggtqdc61YjArZuoApoBh9JNGYoDRBUGu6tcJQo="},
+		// RFC 7914 section 11, as Django would store it.
+		{"pbkdf2_sha256 rfc 7914", "passwd", "pbkdf2_sha256$1$salt$VawEblbjCJ/sFpHCJUS2BflBhSFt3gRl5oudV8INrLxJypzM8Xm2RZkWZLOdd+8xfHG4RbHjC9UJESBB06GXgw=="},
+		// jBCrypt's test vectors, in the $2a$ form Auth0 exports.
+		{"auth0 bcrypt empty", "", "$2a$06$DCq7YPn5Rq63x1Lad4cll.TV4S6ytwfsfvkgY8jIucDrjc8deX1s."},
+		{"auth0 bcrypt", "abc", "$2a$06$If6bvum7DFjUnE9p2uDeDu0YHzrHM6tf.iqN8.yx.jNN1ILEf7h0i"},
+		{"auth0 bcrypt $2b$", "abc", "$2b$06$If6bvum7DFjUnE9p2uDeDu0YHzrHM6tf.iqN8.yx.jNN1ILEf7h0i"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePasswordHash(tt.encoded); err != nil {
+				t.Fatalf("ValidatePasswordHash: %v", err)
+			}
+			if ok, err := VerifyPassword(tt.password, tt.encoded); !ok || err != nil {
+				t.Errorf("VerifyPassword(right) = %v, %v", ok, err)
+			}
+			if ok, err := VerifyPassword(tt.password+"x", tt.encoded); ok || err != nil {
+				t.Errorf("VerifyPassword(wrong) = %v, %v", ok, err)
+			}
+		})
+	}
+}
+
+func TestFirebaseScryptHashBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		memCost int
+		rounds  int
+		ok      bool
+	}{
+		{"console default", 14, 8, true},
+		{"smallest", 1, 1, true},
+		{"mem cost above firebase's", 15, 8, false},
+		{"huge mem cost", 30, 8, false},
+		{"rounds above firebase's", 14, 9, false},
+		{"no rounds", 14, 0, false},
+		{"no mem cost", 0, 8, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := firebaseSample.params
+			params.MemCost, params.Rounds = tt.memCost, tt.rounds
+			_, err := FirebaseScryptHash(params, firebaseSample.salt, firebaseSample.hash)
+			if tt.ok && err != nil {
+				t.Errorf("FirebaseScryptHash: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidFirebaseScryptHash) {
+				t.Errorf("FirebaseScryptHash: %v, want ErrInvalidFirebaseScryptHash", err)
+			}
+		})
+	}
+}
+
+func TestVerifyPasswordRefusesCostlyHashes(t *testing.T) {
+	firebase, err := FirebaseScryptHash(firebaseSample.params, firebaseSample.salt, firebaseSample.hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	argon2id := "$argon2id$v=19$m=65536,t=3,p=4$c29tZXNhbHRzb21lc2FsdA$" + strings.Repeat("A", 43)
+	tests := []struct {
+		name    string
+		encoded string
+		want    error
+	}{
+		{"firebase mem cost", strings.Replace(firebase, "ln=14", "ln=30", 1), ErrInvalidFirebaseScryptHash},
+		{"firebase rounds", strings.Replace(firebase, "r=8", "r=1024", 1), ErrInvalidFirebaseScryptHash},
+		{"firebase parallelism", strings.Replace(firebase, "p=1", "p=64", 1), ErrInvalidFirebaseScryptHash},
+		{"django iterations", "pbkdf2_sha256$2000000000$salt$VawEblbjCJ/sFpHCJUS2Bw==", ErrInvalidDjangoPBKDF2Hash},
+		{"argon2id memory", strings.Replace(argon2id, "m=65536", "m=4194304", 1), ErrInvalidArgon2idHash},
+		{"argon2id time", strings.Replace(argon2id, "t=3", "t=1000", 1), ErrInvalidArgon2idHash},
+		{"argon2id lanes", strings.Replace(argon2id, "p=4", "p=255", 1), ErrInvalidArgon2idHash},
+		{"argon2id short key", "$argon2id$v=19$m=65536,t=3,p=4$c29tZXNhbHRzb21lc2FsdA$AAAA", ErrInvalidArgon2idHash},
+		{"argon2id short salt", "$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$" + strings.Repeat("A", 43), ErrInvalidArgon2idHash},
+		{"unknown", "md5$abc", ErrUnknownPasswordHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePasswordHash(tt.encoded); !errors.Is(err, tt.want) {
+				t.Errorf("ValidatePasswordHash: %v, want %v", err, tt.want)
+			}
+			if ok, err := VerifyPassword("password", tt.encoded); ok || !errors.Is(err, tt.want) {
+				t.Errorf("VerifyPassword = %v, %v, want %v", ok, err, tt.want)
+			}
+		})
+	}
+}
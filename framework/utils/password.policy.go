@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Password policy violation codes, for frontends to show their own messages.
+const (
+	PasswordTooShort      = "password_too_short"
+	PasswordTooLong       = "password_too_long"
+	PasswordMissingUpper  = "password_missing_uppercase"
+	PasswordMissingLower  = "password_missing_lowercase"
+	PasswordMissingDigit  = "password_missing_digit"
+	PasswordMissingSymbol = "password_missing_symbol"
+	PasswordTooWeak       = "password_too_weak"
+	PasswordContainsUser  = "password_contains_user_info"
+	PasswordBreached      = "password_breached"
+)
+
+// userInfoMinTokenLength keeps short names and email parts, which turn up
+// in passwords by chance, from being refused.
+const userInfoMinTokenLength = 3
+
+type (
+	// PasswordPolicy decides which new passwords are accepted. Lengths count
+	// characters, not bytes; zero disables a limit.
+	PasswordPolicy struct {
+		MinLength     int
+		MaxLength     int
+		RequireUpper  bool
+		RequireLower  bool
+		RequireDigit  bool
+		RequireSymbol bool
+		// MinStrength is the lowest PasswordStrength score accepted, 0 to 4.
+		MinStrength int
+		// DisallowUserInfo refuses passwords containing the user's email or
+		// name.
+		DisallowUserInfo bool
+		// Breached, when set, refuses passwords found in a list of known
+		// breached passwords, e.g. an OpenSHA1File, SHA1PrefixFiles or a
+		// BloomFilter.
+		Breached BreachedPasswords
+	}
+
+	PasswordViolation struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+
+	// PasswordPolicyError lists every rule a password broke.
+	PasswordPolicyError struct {
+		Violations []PasswordViolation
+	}
+)
+
+// DefaultPasswordPolicy is used when Config.PasswordPolicy is nil.
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		MinLength:        8,
+		MaxLength:        128,
+		DisallowUserInfo: true,
+	}
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return "password does not meet the policy: " + strings.Join(messages, "; ")
+}
+
+// Check returns a *PasswordPolicyError listing every rule password breaks.
+// userInputs are the user's email, name and the like. Any other error comes
+// from the breached password list.
+func (p *PasswordPolicy) Check(password string, userInputs ...string) error {
+	var violations []PasswordViolation
+	violate := func(code, message string) {
+		violations = append(violations, PasswordViolation{Code: code, Message: message})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		violate(PasswordTooShort, "password is too short")
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violate(PasswordTooLong, "password is too long")
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		violate(PasswordMissingUpper, "password needs an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		violate(PasswordMissingLower, "password needs a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		violate(PasswordMissingDigit, "password needs a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		violate(PasswordMissingSymbol, "password needs a symbol")
+	}
+
+	if p.DisallowUserInfo && containsUserInfo(password, userInputs) {
+		violate(PasswordContainsUser, "password must not contain your email or name")
+	}
+	if p.MinStrength > 0 && PasswordStrength(password, userInputs...) < p.MinStrength {
+		violate(PasswordTooWeak, "password is too easy to guess")
+	}
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violate(PasswordBreached, "password has appeared in a data breach")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func containsUserInfo(password string, userInputs []string) bool {
+	lower := strings.ToLower(password)
+	for _, token := range userInfoTokens(userInputs) {
+		if strings.Contains(lower, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// userInfoTokens splits emails and names into the lowercase parts a user is
+// likely to reuse: the whole input, the email's local part and each word.
+func userInfoTokens(userInputs []string) []string {
+	var tokens []string
+	add := func(token string) {
+		if utf8.RuneCountInString(token) >= userInfoMinTokenLength {
+			tokens = append(tokens, token)
+		}
+	}
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		add(input)
+		if local, _, ok := strings.Cut(input, "@"); ok {
+			add(local)
+			input = local
+		}
+		for _, word := range strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			add(word)
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// breachedList is a BreachedPasswords of fixed passwords, or failing with
+// err.
+type breachedList struct {
+	passwords []string
+	err       error
+}
+
+func (l breachedList) Contains(password string) (bool, error) {
+	return slices.Contains(l.passwords, password), l.err
+}
+
+// violationCodes returns the codes of a *PasswordPolicyError, or nil.
+func violationCodes(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("Check = %v, want a PasswordPolicyError", err)
+	}
+	var codes []string
+	for _, violation := range policyErr.Violations {
+		codes = append(codes, violation.Code)
+	}
+	return codes
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	user := []string{"alice.smith@example.com", "Alice Smith"}
+	strict := &PasswordPolicy{
+		MinLength:     12,
+		MaxLength:     64,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		MinStrength:   3,
+		Breached:      breachedList{passwords: []string{"Tr0ub4dor&3xyz"}},
+	}
+
+	tests := []struct {
+		name     string
+		policy   *PasswordPolicy
+		password string
+		want     []string
+	}{
+		{"default accepts", DefaultPasswordPolicy(), "plain enough", nil},
+		{"too short", DefaultPasswordPolicy(), "short", []string{PasswordTooShort}},
+		{"too long", DefaultPasswordPolicy(), strings.Repeat("x", 129), []string{PasswordTooLong}},
+		// Eight characters in ten bytes.
+		{"length in characters", DefaultPasswordPolicy(), "pässwörd", nil},
+		{"email", DefaultPasswordPolicy(), "x-alice.smith@example.com", []string{PasswordContainsUser}},
+		{"local part", DefaultPasswordPolicy(), "ALICE.SMITH-2024", []string{PasswordContainsUser}},
+		{"one name", DefaultPasswordPolicy(), "i am smithy!", []string{PasswordContainsUser}},
+		{"user info allowed", &PasswordPolicy{MinLength: 8}, "alice smith", nil},
+		{"every class missing", &PasswordPolicy{RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}, "    ",
+			[]string{PasswordMissingUpper, PasswordMissingLower, PasswordMissingDigit, PasswordMissingSymbol}},
+		{"other scripts count", &PasswordPolicy{RequireUpper: true, RequireLower: true, RequireDigit: true}, "Ωω٣", nil},
+		{"strict accepts", strict, "x7#Kq9!vZ2$mW", nil},
+		{"too weak", strict, "Password123!", []string{PasswordTooWeak}},
+		{"breached", strict, "Tr0ub4dor&3xyz", []string{PasswordBreached}},
+		{"all at once", strict, "alice", []string{PasswordTooShort, PasswordMissingUpper, PasswordMissingDigit, PasswordMissingSymbol, PasswordTooWeak}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := violationCodes(t, tt.policy.Check(tt.password, user...))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyShortUserInfo(t *testing.T) {
+	// Parts under three characters turn up by chance and are not refused.
+	if err := DefaultPasswordPolicy().Check("an alpine hike", "al@example.com", "Al Li"); err != nil {
+		t.Errorf("Check = %v", err)
+	}
+}
+
+func TestPasswordPolicyError(t *testing.T) {
+	err := (&PasswordPolicy{MinLength: 8, RequireDigit: true}).Check("short")
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("Check = %v", err)
+	}
+	for _, violation := range policyErr.Violations {
+		if violation.Message == "" || !strings.Contains(err.Error(), violation.Message) {
+			t.Errorf("error %q lacks the message of %s", err, violation.Code)
+		}
+	}
+
+	// A list that cannot be read fails the check without passing for a
+	// violation.
+	broken := &PasswordPolicy{Breached: breachedList{err: errors.New("disk on fire")}}
+	if err := broken.Check("anything"); err == nil || errors.As(err, &policyErr) {
+		t.Errorf("Check with a failing list = %v, want its error", err)
+	}
+}
@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// commonPasswords are the most used passwords and the words they are built
+// from. A match costs an attacker about as many guesses as the list is long.
+var commonPasswords = []string{
+	"password", "123456", "12345678", "123456789", "1234567890", "qwerty",
+	"qwertyuiop", "asdfgh", "asdfghjkl", "zxcvbnm", "111111", "000000",
+	"121212", "654321", "666666", "696969", "abc123", "1q2w3e4r", "1qaz2wsx",
+	"zaq12wsx", "qazwsx", "letmein", "welcome", "admin", "administrator",
+	"iloveyou", "monkey", "dragon", "football", "baseball", "soccer", "hockey",
+	"master", "sunshine", "princess", "shadow", "superman", "batman",
+	"trustno1", "login", "starwars", "hello", "freedom", "whatever",
+	"michael", "jordan", "harley", "hunter", "ranger", "buster", "killer",
+	"access", "secret", "summer", "winter", "spring", "autumn", "flower",
+	"cheese", "computer", "internet", "google", "changeme", "default",
+	"root", "test", "guest", "love", "money", "pokemon", "ninja", "mustang",
+	"charlie", "thomas", "tigger", "jessica", "pepper", "ginger", "daniel",
+	"andrew", "joshua", "matrix", "samsung", "apple", "orange", "banana",
+	"chocolate", "purple", "yankees", "cowboys", "liverpool", "chelsea",
+	"arsenal", "maggie", "robert", "jennifer", "william", "nicole", "ashley",
+	"hannah", "passport", "pass", "user", "super", "magic", "lovely",
+}
+
+const bruteForceCardinality = 10
+
+// keyboardRows catch runs like "qwer" and "7890" that are sequences on the
+// keyboard rather than in the alphabet.
+var keyboardRows = []string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"}
+
+// leetSubstitutions undo the character swaps people use to dress up words.
+var leetSubstitutions = strings.NewReplacer(
+	"@", "a", "4", "a", "3", "e", "1", "i", "!", "i", "0", "o",
+	"$", "s", "5", "s", "7", "t", "+", "t",
+)
+
+// PasswordStrength scores password from 0 (trivially guessed) to 4 (very
+// hard to guess), on the scale zxcvbn uses. It estimates the guesses an
+// attacker needs, charging common passwords, the user's own details,
+// repeats and keyboard or alphabet sequences far less than random
+// characters.
+func PasswordStrength(password string, userInputs ...string) int {
+	log10Guesses := passwordBits(password, userInputs) * math.Log10(2)
+	switch {
+	case log10Guesses < 3:
+		return 0
+	case log10Guesses < 6:
+		return 1
+	case log10Guesses < 8:
+		return 2
+	case log10Guesses < 10:
+		return 3
+	}
+	return 4
+}
+
+// passwordBits estimates log2 of the guesses needed for password.
+func passwordBits(password string, userInputs []string) float64 {
+	runes := []rune(strings.ToLower(password))
+	if len(runes) == 0 {
+		return 0
+	}
+	// Like zxcvbn, characters outside any pattern cost ten guesses each
+	// whatever their class, since attackers try likely characters first.
+	characterBits := math.Log2(bruteForceCardinality)
+
+	// Words from the list and the user's details are each charged as one
+	// pick from their list, plus a bit when dressed up in leetspeak.
+	words := append(append([]string{}, commonPasswords...), userInfoTokens(userInputs)...)
+	wordBits := math.Log2(float64(len(words)))
+	// Every substitution is one character for one, so indexes line up.
+	normalized := []rune(leetSubstitutions.Replace(string(runes)))
+
+	var bits float64
+	for i := 0; i < len(runes); {
+		if n := longestWordAt(runes, i, words); n > 0 {
+			bits += wordBits
+			i += n
+			continue
+		}
+		if n := longestWordAt(normalized, i, words); n > 0 {
+			bits += wordBits + 1
+			i += n
+			continue
+		}
+		if n := patternLength(runes, i); n >= 3 {
+			// The attacker picks a start, a direction and a length.
+			bits += characterBits + 1 + math.Log2(float64(n))
+			i += n
+			continue
+		}
+		bits += characterBits
+		i++
+	}
+	return bits
+}
+
+func longestWordAt(runes []rune, i int, words []string) int {
+	longest := 0
+	rest := string(runes[i:])
+	for _, word := range words {
+		n := len([]rune(word))
+		if n > longest && n >= 3 && strings.HasPrefix(rest, word) {
+			longest = n
+		}
+	}
+	return longest
+}
+
+// patternLength returns how many characters from i repeat the same
+// character, or step through the alphabet, digits or a keyboard row.
+func patternLength(runes []rune, i int) int {
+	if i+1 >= len(runes) {
+		return 1
+	}
+	n := 1
+	for i+n < len(runes) && runes[i+n] == runes[i] {
+		n++
+	}
+	if n > 1 {
+		return n
+	}
+	for _, step := range []int{1, -1} {
+		n = 1
+		for i+n < len(runes) && adjacent(runes[i+n-1], runes[i+n], step) {
+			n++
+		}
+		if n > 1 {
+			return n
+		}
+	}
+	return 1
+}
+
+func adjacent(a, b rune, step int) bool {
+	if b-a == rune(step) && (unicode.IsLetter(a) || unicode.IsDigit(a)) {
+		return true
+	}
+	for _, row := range keyboardRows {
+		ia, ib := strings.IndexRune(row, a), strings.IndexRune(row, b)
+		if ia >= 0 && ib >= 0 && ib-ia == step {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package utils
+
+import "testing"
+
+func TestPasswordStrength(t *testing.T) {
+	tests := []struct {
+		password string
+		want     int
+	}{
+		{"", 0},
+		{"password", 0},
+		{"P@ssw0rd", 0},
+		{"qwertyuiop", 0},
+		{"aaaaaaaaaaaa", 0},
+		{"abcdefgh", 0},
+		{"monkey123", 1},
+		{"zxcvbnm,./", 1},
+		{"9f8e7d6c", 3},
+		{"x7#Kq9!vZ2$m", 4},
+		{"correct horse battery staple", 4},
+	}
+	for _, tt := range tests {
+		if got := PasswordStrength(tt.password); got != tt.want {
+			t.Errorf("PasswordStrength(%q) = %d, want %d", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestPasswordStrengthUserInputs(t *testing.T) {
+	// Strong for a stranger, weak for the one person it describes.
+	inputs := []string{"alice.smith@example.com", "Alice Smith"}
+	if got := PasswordStrength("alicesmith"); got != 4 {
+		t.Errorf("PasswordStrength without the user = %d, want 4", got)
+	}
+	if got := PasswordStrength("alicesmith", inputs...); got != 1 {
+		t.Errorf("PasswordStrength with the user = %d, want 1", got)
+	}
+	if got := PasswordStrength("Al1c3Sm1th", inputs...); got > 2 {
+		t.Errorf("PasswordStrength of the user in leetspeak = %d, want at most 2", got)
+	}
+}
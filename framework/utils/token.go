@@ -1,13 +1,14 @@
 package utils
 
 import (
+	"encoding/json"
 	"errors"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/o1egl/paseto"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
@@ -20,8 +21,8 @@ func GenerateToken(claims Claims, tokenType TokenType, duration time.Duration) (
 	switch tokenType {
 	case JWT:
 		return generateJWT(claims, duration)
-	//case PASETO:
-	//	return generatePaseto(claims, duration)
+	case PASETO:
+		return generatePaseto(claims, duration)
 	default:
 		return nil, errors.New("unsupported token type")
 	}
@@ -32,70 +33,79 @@ func GenerateToken(claims Claims, tokenType TokenType, duration time.Duration) (
 // ----------------------
 
 func generateJWT(claims Claims, duration time.Duration) (*TokenContextContainer, error) {
-	jwtSecret := os.Getenv("GOAUTH_JWT_SECRET")
-	if jwtSecret == "" {
-		return nil, errors.New("JWT_SECRET not set in environment")
+	provider, err := currentKeyProvider()
+	if err != nil {
+		return nil, err
+	}
+	key, err := provider.SigningKey()
+	if err != nil {
+		return nil, err
 	}
 
-	accessDuration := time.Now().Add(duration).Unix()
-	accesstoken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		Type:   JWT_ACCESS_TOKEN,
-		UserId: claims.UserID,
-		Role:   claims.Role,
-		Exp:    accessDuration,
+	now := time.Now()
+	accessID := uuid.NewString()
+	accessExpiresAt := now.Add(duration)
+	accesstoken := jwt.NewWithClaims(key.Method, jwt.MapClaims{
+		Type:     JWT_ACCESS_TOKEN,
+		UserId:   claims.UserID,
+		Role:     claims.Role,
+		Exp:      accessExpiresAt.Unix(),
+		IssuedAt: now.Unix(),
+		Jti:      accessID,
 	})
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		Type:   JWT_REFRESH_TOKEN,
-		UserId: claims.UserID,
-		Role:   claims.Role,
-		Exp:    time.Now().Add(24 * time.Hour).Unix(),
+	refreshID := uuid.NewString()
+	refreshExpiresAt := now.Add(RefreshTokenDuration())
+	refreshToken := jwt.NewWithClaims(key.Method, jwt.MapClaims{
+		Type:     JWT_REFRESH_TOKEN,
+		UserId:   claims.UserID,
+		Role:     claims.Role,
+		Exp:      refreshExpiresAt.Unix(),
+		IssuedAt: now.Unix(),
+		Jti:      refreshID,
+		FamilyId: claims.FamilyID,
 	})
 
-	signedAccessToken, err := accesstoken.SignedString([]byte(jwtSecret))
+	if key.ID != "" {
+		accesstoken.Header["kid"] = key.ID
+		refreshToken.Header["kid"] = key.ID
+	}
+
+	signedAccessToken, err := accesstoken.SignedString(key.SignKey)
 	if err != nil {
 		log.Err(err).Msg("error signing access token")
 		return nil, err
 	}
-	signedRefreshToken, err := refreshToken.SignedString([]byte(jwtSecret))
+	signedRefreshToken, err := refreshToken.SignedString(key.SignKey)
 	if err != nil {
 		log.Err(err).Msg("error signing refresh token")
 		return nil, err
 	}
 	return &TokenContextContainer{
-		AccessToken:  signedAccessToken,
-		RefreshToken: signedRefreshToken,
+		AccessToken:      signedAccessToken,
+		RefreshToken:     signedRefreshToken,
+		AccessTokenID:    accessID,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshTokenID:   refreshID,
+		RefreshExpiresAt: refreshExpiresAt,
 	}, nil
 }
 
-// ----------------------
-// PASETO GENERATION
-// ----------------------
-
-//func generatePaseto(claims Claims, duration time.Duration) (*TokenContextContainer, error) {
-//	pasetoKey := os.Getenv("GOAUTH_PASETO_KEY")
-//	if pasetoKey == "" {
-//		return nil, errors.New("PASETO_KEY not set in environment")
-//	}
-//
-//	v2 := paseto.NewV2()
-//	jsonToken := paseto.JSONToken{
-//		Audience:   "",
-//		Issuer:     "",
-//		Jti:        "",
-//		Subject:    "",
-//		Expiration: time.Now().Add(duration),
-//		IssuedAt:   time.Time{},
-//		NotBefore:  time.Time{},
-//	}
-//
-//	return v2.Encrypt([]byte(pasetoKey), jsonToken, "")
-//}
+// RefreshTokenDuration returns GOAUTH_REFRESH_TOKEN_DURATION, falling back to
+// DefaultRefreshTokenDuration when unset or invalid.
+func RefreshTokenDuration() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("GOAUTH_REFRESH_TOKEN_DURATION")); err == nil && d > 0 {
+		return d
+	}
+	return DefaultRefreshTokenDuration
+}
 
 // ----------------------
 // TOKEN VALIDATION
 // ----------------------
 
+// ValidateToken validates a token of the given type. JWT tokens are returned
+// as *jwt.Token, PASETO tokens as their claims map.
 func ValidateToken(tokenString string, tokenType TokenType) (interface{}, error) {
 	switch tokenType {
 	case JWT:
@@ -107,21 +117,75 @@ func ValidateToken(tokenString string, tokenType TokenType) (interface{}, error)
 	}
 }
 
+// ValidateClaims validates a token of either type and returns its claims, so
+// callers need not care whether the deployment uses JWT or PASETO.
+func ValidateClaims(tokenString string, tokenType TokenType) (map[string]interface{}, error) {
+	switch tokenType {
+	case JWT:
+		token, err := validateJWT(tokenString)
+		if err != nil {
+			return nil, err
+		}
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok || !token.Valid {
+			return nil, jwt.ErrTokenInvalidClaims
+		}
+		return claims, nil
+	case PASETO:
+		return validatePaseto(tokenString)
+	default:
+		return nil, errors.New("unsupported token type")
+	}
+}
+
+// ClaimTime reads a time claim from either token format: JWTs carry numeric
+// dates, PASETO tokens RFC 3339 strings.
+func ClaimTime(claims map[string]interface{}, key string) (time.Time, bool) {
+	switch value := claims[key].(type) {
+	case float64:
+		return time.Unix(int64(value), 0), true
+	case int64:
+		return time.Unix(value, 0), true
+	case json.Number:
+		seconds, err := value.Int64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(seconds, 0), true
+	case string:
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
 // ----------------------
 // JWT VALIDATION
 // ----------------------
 
+// validateJWT verifies the token against the key chosen by the current
+// KeyProvider. The provider pins the algorithm, so a token whose alg header
+// differs from the key's method (e.g. HS256 signed with an RSA public key) is
+// rejected before any signature check.
 func validateJWT(tokenString string) (*jwt.Token, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		return nil, errors.New("JWT_SECRET not set in environment")
+	provider, err := currentKeyProvider()
+	if err != nil {
+		return nil, err
 	}
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrSignatureInvalid
+		key, err := provider.VerificationKey(token)
+		if err != nil {
+			return nil, err
 		}
-		return []byte(jwtSecret), nil
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return key.VerifyKey, nil
 	})
 	if err != nil {
 		return nil, err
@@ -130,31 +194,6 @@ func validateJWT(tokenString string) (*jwt.Token, error) {
 	return token, nil
 }
 
-// ----------------------
-// PASETO VALIDATION
-// ----------------------
-
-func validatePaseto(tokenString string) (*paseto.JSONToken, error) {
-	pasetoKey := os.Getenv("PASETO_KEY")
-	if pasetoKey == "" {
-		return nil, errors.New("PASETO_KEY not set in environment")
-	}
-
-	var jsonToken paseto.JSONToken
-	var footer string
-	v2 := paseto.NewV2()
-
-	if err := v2.Decrypt(tokenString, []byte(pasetoKey), &jsonToken, &footer); err != nil {
-		return nil, err
-	}
-
-	if jsonToken.Expiration.Before(time.Now()) {
-		return nil, errors.New("token expired")
-	}
-
-	return &jsonToken, nil
-}
-
 // ----------------------
 // TOKEN EXTRACTION
 // ----------------------
@@ -0,0 +1,253 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// PASETO purposes supported by GOAUTH_PASETO_PURPOSE.
+const (
+	PasetoLocal  = "local"
+	PasetoPublic = "public"
+)
+
+// pasetoFooter is the JSON footer attached to every PASETO token. The key id
+// lets verifiers pick the right key without trusting anything in the payload.
+type pasetoFooter struct {
+	KeyID string `json:"kid,omitempty"`
+}
+
+// pasetoKeys holds the v4 keys loaded from the environment. Only the keys
+// matching the configured purpose are populated.
+type pasetoKeys struct {
+	purpose   string
+	keyID     string
+	local     paseto.V4SymmetricKey
+	secret    paseto.V4AsymmetricSecretKey
+	public    paseto.V4AsymmetricPublicKey
+	hasSecret bool
+}
+
+var (
+	pasetoKeysMu     sync.RWMutex
+	loadedPasetoKeys *pasetoKeys
+)
+
+// ----------------------
+// PASETO KEYS
+// ----------------------
+
+// currentPasetoKeys returns the PASETO keys, reading them from the
+// environment the first time. Like currentKeyProvider, a failure is not kept,
+// so a fixed environment is picked up by the next call.
+func currentPasetoKeys() (*pasetoKeys, error) {
+	pasetoKeysMu.RLock()
+	keys := loadedPasetoKeys
+	pasetoKeysMu.RUnlock()
+	if keys != nil {
+		return keys, nil
+	}
+
+	pasetoKeysMu.Lock()
+	defer pasetoKeysMu.Unlock()
+	if loadedPasetoKeys != nil {
+		return loadedPasetoKeys, nil
+	}
+	keys, err := loadPasetoKeys()
+	if err != nil {
+		return nil, err
+	}
+	loadedPasetoKeys = keys
+	return keys, nil
+}
+
+// loadPasetoKeys reads the PASETO v4 key material from the environment.
+//
+//	GOAUTH_PASETO_PURPOSE      local (default) or public
+//	GOAUTH_PASETO_KEY          hex encoded 32 byte symmetric key (local)
+//	GOAUTH_PASETO_PRIVATE_KEY  hex encoded Ed25519 private key (public, signing)
+//	GOAUTH_PASETO_PUBLIC_KEY   hex encoded Ed25519 public key (public, verify only)
+//	GOAUTH_PASETO_KEY_ID       optional key id written to the token footer
+func loadPasetoKeys() (*pasetoKeys, error) {
+	keys := &pasetoKeys{
+		purpose: os.Getenv("GOAUTH_PASETO_PURPOSE"),
+		keyID:   os.Getenv("GOAUTH_PASETO_KEY_ID"),
+	}
+	if keys.purpose == "" {
+		keys.purpose = PasetoLocal
+	}
+
+	switch keys.purpose {
+	case PasetoLocal:
+		hexKey := os.Getenv("GOAUTH_PASETO_KEY")
+		if hexKey == "" {
+			return nil, errors.New("GOAUTH_PASETO_KEY not set in environment")
+		}
+		key, err := paseto.V4SymmetricKeyFromHex(hexKey)
+		if err != nil {
+			return nil, err
+		}
+		keys.local = key
+	case PasetoPublic:
+		if hexKey := os.Getenv("GOAUTH_PASETO_PRIVATE_KEY"); hexKey != "" {
+			secret, err := paseto.NewV4AsymmetricSecretKeyFromHex(hexKey)
+			if err != nil {
+				return nil, err
+			}
+			keys.secret = secret
+			keys.public = secret.Public()
+			keys.hasSecret = true
+		} else if hexKey := os.Getenv("GOAUTH_PASETO_PUBLIC_KEY"); hexKey != "" {
+			public, err := paseto.NewV4AsymmetricPublicKeyFromHex(hexKey)
+			if err != nil {
+				return nil, err
+			}
+			keys.public = public
+		} else {
+			return nil, errors.New("GOAUTH_PASETO_PRIVATE_KEY or GOAUTH_PASETO_PUBLIC_KEY not set in environment")
+		}
+	default:
+		return nil, errors.New("unsupported PASETO purpose: " + keys.purpose)
+	}
+
+	return keys, nil
+}
+
+func (k *pasetoKeys) encode(token paseto.Token) (string, error) {
+	if k.keyID != "" {
+		footer, err := json.Marshal(pasetoFooter{KeyID: k.keyID})
+		if err != nil {
+			return "", err
+		}
+		token.SetFooter(footer)
+	}
+
+	switch k.purpose {
+	case PasetoLocal:
+		return token.V4Encrypt(k.local, nil), nil
+	default:
+		if !k.hasSecret {
+			return "", errors.New("GOAUTH_PASETO_PRIVATE_KEY is required to sign PASETO tokens")
+		}
+		return token.V4Sign(k.secret, nil), nil
+	}
+}
+
+func (k *pasetoKeys) decode(parser paseto.Parser, tokenString string) (*paseto.Token, error) {
+	protocol := paseto.V4Local
+	if k.purpose == PasetoPublic {
+		protocol = paseto.V4Public
+	}
+
+	rawFooter, err := parser.UnsafeParseFooter(protocol, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.checkFooter(rawFooter); err != nil {
+		return nil, err
+	}
+
+	if k.purpose == PasetoLocal {
+		return parser.ParseV4Local(k.local, tokenString, nil)
+	}
+	return parser.ParseV4Public(k.public, tokenString, nil)
+}
+
+// checkFooter rejects tokens whose footer names a key other than ours.
+func (k *pasetoKeys) checkFooter(rawFooter []byte) error {
+	if k.keyID == "" {
+		return nil
+	}
+	var footer pasetoFooter
+	if len(rawFooter) > 0 {
+		if err := json.Unmarshal(rawFooter, &footer); err != nil {
+			return errors.New("malformed PASETO footer")
+		}
+	}
+	if footer.KeyID != k.keyID {
+		return errors.New("unknown PASETO key id")
+	}
+	return nil
+}
+
+// ----------------------
+// PASETO GENERATION
+// ----------------------
+
+func generatePaseto(claims Claims, duration time.Duration) (*TokenContextContainer, error) {
+	keys, err := currentPasetoKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	accessID := uuid.NewString()
+	accessExpiresAt := now.Add(duration)
+	accessClaims := newPasetoToken(claims, JWT_ACCESS_TOKEN, now, accessExpiresAt)
+	accessClaims.SetJti(accessID)
+	accessToken, err := keys.encode(accessClaims)
+	if err != nil {
+		log.Err(err).Msg("error signing access token")
+		return nil, err
+	}
+	refreshID := uuid.NewString()
+	refreshExpiresAt := now.Add(RefreshTokenDuration())
+	refreshClaims := newPasetoToken(claims, JWT_REFRESH_TOKEN, now, refreshExpiresAt)
+	refreshClaims.SetJti(refreshID)
+	refreshClaims.SetString(FamilyId, claims.FamilyID)
+	refreshToken, err := keys.encode(refreshClaims)
+	if err != nil {
+		log.Err(err).Msg("error signing refresh token")
+		return nil, err
+	}
+
+	return &TokenContextContainer{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		AccessTokenID:    accessID,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshTokenID:   refreshID,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+func newPasetoToken(claims Claims, tokenType TokenType, issuedAt, expiresAt time.Time) paseto.Token {
+	token := paseto.NewToken()
+	token.SetIssuedAt(issuedAt)
+	token.SetNotBefore(issuedAt)
+	token.SetExpiration(expiresAt)
+	token.SetString(Type, string(tokenType))
+	token.SetString(UserId, claims.UserID)
+	token.SetString(Role, claims.Role)
+	return token
+}
+
+// ----------------------
+// PASETO VALIDATION
+// ----------------------
+
+// validatePaseto decrypts or verifies a v4 token, enforces exp and nbf, and
+// returns its claims in the same shape as jwt.MapClaims.
+func validatePaseto(tokenString string) (map[string]interface{}, error) {
+	keys, err := currentPasetoKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	parser := paseto.NewParser()
+	parser.AddRule(paseto.NotBeforeNbf())
+
+	token, err := keys.decode(parser, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	return token.Claims(), nil
+}
@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/google/uuid"
+)
+
+// usePasetoEnv sets the PASETO environment and drops the cached keys, before
+// the test and after it.
+func usePasetoEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, name := range []string{"GOAUTH_PASETO_PURPOSE", "GOAUTH_PASETO_KEY", "GOAUTH_PASETO_PRIVATE_KEY", "GOAUTH_PASETO_PUBLIC_KEY", "GOAUTH_PASETO_KEY_ID"} {
+		t.Setenv(name, env[name])
+	}
+	resetPasetoKeys()
+	t.Cleanup(resetPasetoKeys)
+}
+
+func resetPasetoKeys() {
+	pasetoKeysMu.Lock()
+	defer pasetoKeysMu.Unlock()
+	loadedPasetoKeys = nil
+}
+
+func localPasetoEnv(key paseto.V4SymmetricKey, keyID string) map[string]string {
+	return map[string]string{"GOAUTH_PASETO_KEY": key.ExportHex(), "GOAUTH_PASETO_KEY_ID": keyID}
+}
+
+func publicPasetoEnv(secret paseto.V4AsymmetricSecretKey, keyID string) map[string]string {
+	return map[string]string{"GOAUTH_PASETO_PURPOSE": PasetoPublic, "GOAUTH_PASETO_PRIVATE_KEY": secret.ExportHex(), "GOAUTH_PASETO_KEY_ID": keyID}
+}
+
+func TestPasetoRoundTrip(t *testing.T) {
+	secret := paseto.NewV4AsymmetricSecretKey()
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"local", localPasetoEnv(paseto.NewV4SymmetricKey(), "")},
+		{"local with key id", localPasetoEnv(paseto.NewV4SymmetricKey(), "k1")},
+		{"public", publicPasetoEnv(secret, "")},
+		{"public with key id", publicPasetoEnv(secret, "k1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usePasetoEnv(t, tt.env)
+			claims := Claims{UserID: uuid.NewString(), Role: "admin", FamilyID: uuid.NewString()}
+			tokens, err := GenerateToken(claims, PASETO, time.Minute)
+			if err != nil {
+				t.Fatalf("GenerateToken: %v", err)
+			}
+
+			access, err := ValidateClaims(tokens.AccessToken, PASETO)
+			if err != nil {
+				t.Fatalf("ValidateClaims(access): %v", err)
+			}
+			if access[Type] != string(JWT_ACCESS_TOKEN) || access[UserId] != claims.UserID || access[Role] != claims.Role || access[Jti] != tokens.AccessTokenID {
+				t.Errorf("access claims = %v", access)
+			}
+			if exp, ok := ClaimTime(access, Exp); !ok || exp.Sub(tokens.AccessExpiresAt).Abs() > time.Second {
+				t.Errorf("access exp = %v, want %v", exp, tokens.AccessExpiresAt)
+			}
+
+			refresh, err := ValidateClaims(tokens.RefreshToken, PASETO)
+			if err != nil {
+				t.Fatalf("ValidateClaims(refresh): %v", err)
+			}
+			if refresh[Type] != string(JWT_REFRESH_TOKEN) || refresh[Jti] != tokens.RefreshTokenID || refresh[FamilyId] != claims.FamilyID {
+				t.Errorf("refresh claims = %v", refresh)
+			}
+		})
+	}
+}
+
+func TestPasetoPublicVerifyOnly(t *testing.T) {
+	secret := paseto.NewV4AsymmetricSecretKey()
+	usePasetoEnv(t, publicPasetoEnv(secret, ""))
+	tokens, err := GenerateToken(Claims{UserID: uuid.NewString()}, PASETO, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usePasetoEnv(t, map[string]string{"GOAUTH_PASETO_PURPOSE": PasetoPublic, "GOAUTH_PASETO_PUBLIC_KEY": secret.Public().ExportHex()})
+	if _, err := ValidateClaims(tokens.AccessToken, PASETO); err != nil {
+		t.Errorf("ValidateClaims with the public key: %v", err)
+	}
+	if _, err := GenerateToken(Claims{UserID: uuid.NewString()}, PASETO, time.Minute); err == nil {
+		t.Error("GenerateToken signed without a private key")
+	}
+}
+
+func TestPasetoRejects(t *testing.T) {
+	localKey := paseto.NewV4SymmetricKey()
+	secret := paseto.NewV4AsymmetricSecretKey()
+	now := time.Now()
+
+	// token builds a token by hand, so its times and footer can be anything.
+	token := func(issuedAt, notBefore, expiresAt time.Time, footer []byte) paseto.Token {
+		tok := paseto.NewToken()
+		tok.SetIssuedAt(issuedAt)
+		tok.SetNotBefore(notBefore)
+		tok.SetExpiration(expiresAt)
+		tok.SetString(Type, string(JWT_ACCESS_TOKEN))
+		tok.SetString(UserId, uuid.NewString())
+		if footer != nil {
+			tok.SetFooter(footer)
+		}
+		return tok
+	}
+	kidFooter := func(kid string) []byte {
+		footer, err := json.Marshal(pasetoFooter{KeyID: kid})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return footer
+	}
+	valid := token(now, now, now.Add(time.Minute), nil)
+
+	tests := []struct {
+		name  string
+		env   map[string]string
+		token string
+	}{
+		{"expired", localPasetoEnv(localKey, ""), func() string {
+			tok := token(now.Add(-time.Hour), now.Add(-time.Hour), now.Add(-time.Minute), nil)
+			return tok.V4Encrypt(localKey, nil)
+		}()},
+		{"not yet valid", localPasetoEnv(localKey, ""), func() string {
+			tok := token(now, now.Add(time.Hour), now.Add(2*time.Hour), nil)
+			return tok.V4Encrypt(localKey, nil)
+		}()},
+		{"another local key", localPasetoEnv(localKey, ""), valid.V4Encrypt(paseto.NewV4SymmetricKey(), nil)},
+		{"another signing key", publicPasetoEnv(secret, ""), valid.V4Sign(paseto.NewV4AsymmetricSecretKey(), nil)},
+		{"local token to a public verifier", publicPasetoEnv(secret, ""), valid.V4Encrypt(localKey, nil)},
+		{"public token to a local verifier", localPasetoEnv(localKey, ""), valid.V4Sign(secret, nil)},
+		{"footer names another key", localPasetoEnv(localKey, "k1"), func() string {
+			tok := token(now, now, now.Add(time.Minute), kidFooter("k2"))
+			return tok.V4Encrypt(localKey, nil)
+		}()},
+		{"footer without a key id", localPasetoEnv(localKey, "k1"), valid.V4Encrypt(localKey, nil)},
+		{"malformed footer", publicPasetoEnv(secret, "k1"), func() string {
+			tok := token(now, now, now.Add(time.Minute), []byte("k1"))
+			return tok.V4Sign(secret, nil)
+		}()},
+		{"not a token", localPasetoEnv(localKey, ""), "v4.local.nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usePasetoEnv(t, tt.env)
+			if claims, err := ValidateClaims(tt.token, PASETO); err == nil {
+				t.Errorf("ValidateClaims accepted it: %v", claims)
+			}
+		})
+	}
+}
+
+func TestPasetoKeysLoadedOnce(t *testing.T) {
+	usePasetoEnv(t, map[string]string{"GOAUTH_PASETO_KEY": "not hex"})
+	if _, err := GenerateToken(Claims{UserID: uuid.NewString()}, PASETO, time.Minute); err == nil {
+		t.Fatal("GenerateToken accepted a malformed key")
+	}
+
+	// A failure is not kept, so the fixed environment is read next time.
+	key := paseto.NewV4SymmetricKey()
+	t.Setenv("GOAUTH_PASETO_KEY", key.ExportHex())
+	tokens, err := GenerateToken(Claims{UserID: uuid.NewString()}, PASETO, time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateToken after fixing the key: %v", err)
+	}
+
+	// Once loaded, the keys no longer follow the environment.
+	t.Setenv("GOAUTH_PASETO_KEY", paseto.NewV4SymmetricKey().ExportHex())
+	if _, err := ValidateClaims(tokens.AccessToken, PASETO); err != nil {
+		t.Errorf("ValidateClaims after the environment changed: %v", err)
+	}
+}
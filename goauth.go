@@ -4,25 +4,68 @@ import (
 	"context"
 	"os"
 
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/janitor"
+	"github.com/SwanHtetAungPhyo/go-auth/db/services/lockout"
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
 	"github.com/SwanHtetAungPhyo/go-auth/initialization"
 	"github.com/SwanHtetAungPhyo/go-auth/third-party/email"
+	"github.com/SwanHtetAungPhyo/go-auth/third-party/oauth"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 )
 
+// GoogleOauth configures Sign in with Google. RedirectURL is the callback
+// registered with Google, CallBackURL where the browser lands afterwards; left
+// empty, the callback answers with JSON instead. Empty credentials are read
+// from GOAUTH_GOOGLE_*. AuthURL, TokenURL, JWKSURL and Issuer default to
+// Google's and only need setting to test against a stand-in.
 type GoogleOauth struct {
 	ClientID     string
 	ClientSecret string
 	RedirectURL  string
 	CallBackURL  string
+	AuthURL      string
+	TokenURL     string
+	JWKSURL      string
+	Issuer       string
 }
 
+// GithubOauth configures Sign in with GitHub, the same way as GoogleOauth;
+// empty credentials are read from GOAUTH_GITHUB_*. BaseURL points it at a
+// GitHub Enterprise Server, or at a stand-in in tests.
 type GithubOauth struct {
 	ClientID     string
 	ClientSecret string
 	RedirectURL  string
 	CallBackURL  string
+	BaseURL      string
+	APIURL       string
 }
+
+// OIDCProvider configures a generic OpenID Connect login, found through
+// IssuerURL's discovery document. Name is used in the /oauth/:provider routes
+// and stored with the linked accounts, so it must not change. RedirectURL and
+// CallBackURL work as for GoogleOauth.
+type OIDCProvider struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	CallBackURL  string
+	Scopes       []string
+	Claims       oauth.ClaimMapping
+}
+
+// WebAuthnConfig describes the site as a WebAuthn relying party. RPID is the
+// registrable domain, e.g. "example.com", and RPOrigins the exact origins the
+// pages calling navigator.credentials run on, e.g. "https://app.example.com".
+type WebAuthnConfig struct {
+	RPID          string
+	RPDisplayName string
+	RPOrigins     []string
+}
+
 type EmailConfig struct {
 	Type string
 }
@@ -39,6 +82,21 @@ type Config struct {
 	DNS       string
 	JwtAuth   bool
 	PestoAuth bool
+	// JwtKeyProvider overrides the GOAUTH_JWT_* environment keys, e.g. to
+	// sign with an RSA, ECDSA or Ed25519 key loaded by the application.
+	JwtKeyProvider utils.KeyProvider
+	// PasswordHasher hashes new passwords. Nil means Argon2id with
+	// utils.DefaultArgon2idParams; see WithPasswordHasher.
+	PasswordHasher utils.PasswordHasher
+	// PasswordPolicy decides which new passwords are accepted. Nil means
+	// utils.DefaultPasswordPolicy; see WithPasswordPolicy.
+	PasswordPolicy *utils.PasswordPolicy
+	// PasswordHistory is how many previous passwords a user may not reuse,
+	// besides the current one; see WithPasswordHistory.
+	PasswordHistory int
+	// LoginLockout slows down and locks logins after repeated failures. Nil
+	// means lockout.DefaultPolicy; see WithLoginLockout.
+	LoginLockout *lockout.Policy
 	//EmailSend           bool
 	Session             bool
 	SessionStoreAsRedis bool
@@ -46,11 +104,24 @@ type Config struct {
 	ThirdParty          ThirdPartyConfig
 	GithubOauth         *GithubOauth
 	GoogleOauth         *GoogleOauth
+	OIDCProviders       []OIDCProvider
+	WebAuthn            *WebAuthnConfig
 	EmailConfig         *EmailConfig
 	Payment             *Payment
 	redisClient         *redis.Client
 	EmailService        *email.EmailService
 	IsProduction        bool
+	// RequireEmailVerification makes Login refuse accounts whose email has
+	// not been verified yet.
+	RequireEmailVerification bool
+	// OAuthAutoLink lets a social login sign in to an existing user with the
+	// same email, provided both sides have verified it; see WithOAuthAutoLink.
+	OAuthAutoLink bool
+	// Janitor purges expired sessions and tokens in the background; see
+	// WithJanitor. Close stops it.
+	Janitor     bool
+	janitorOpts []janitor.Option
+	stopJanitor context.CancelFunc
 }
 
 type Option func(*Config)
@@ -76,25 +147,102 @@ func NewGoAuth(dsn string, config *Config, opts ...Option) *Config {
 		cfg.redisClient.Ping(context.Background())
 	}
 	// Database setup
-	_ = initialization.Database(cfg.DNS, cfg.SessionStoreAsRedis)
+	store := initialization.Database(cfg.DNS, cfg.SessionStoreAsRedis)
+
+	if cfg.Janitor {
+		ctx, cancel := context.WithCancel(context.Background())
+		cfg.stopJanitor = cancel
+		lockoutPolicy := cfg.LoginLockout
+		if lockoutPolicy == nil {
+			lockoutPolicy = lockout.DefaultPolicy()
+		}
+		janitorOpts := append([]janitor.Option{
+			janitor.WithRedisStore(cfg.SessionStoreAsRedis),
+			janitor.WithMaxTokenLifetime(utils.RefreshTokenDuration()),
+			janitor.WithLoginAttemptWindow(lockoutPolicy.Window),
+		}, cfg.janitorOpts...)
+		janitor.New(store, janitorOpts...).Start(ctx)
+	}
 
 	// Auth setup
 	if cfg.JwtAuth {
-		initialization.ValidateJwtAuth()
+		if cfg.JwtKeyProvider != nil {
+			utils.SetKeyProvider(cfg.JwtKeyProvider)
+		} else {
+			initialization.ValidateJwtAuth()
+		}
 	}
 	if cfg.PestoAuth {
 		initialization.ValidatePestoAuth()
 	}
 	if cfg.GoogleOauth != nil {
-		initialization.ValidateGoogleOauth()
+		google := cfg.GoogleOauth
+		google.ClientID = valueOrEnv(google.ClientID, "GOAUTH_GOOGLE_CLIENT_ID")
+		google.ClientSecret = valueOrEnv(google.ClientSecret, "GOAUTH_GOOGLE_CLIENT_SECRET")
+		google.RedirectURL = valueOrEnv(google.RedirectURL, "GOAUTH_GOOGLE_REDIRECT_URL")
+		google.CallBackURL = valueOrEnv(google.CallBackURL, "GOAUTH_GOOGLE_CALLBACK_URL")
+		initialization.ValidateGoogleOauth(google.ClientID, google.ClientSecret, google.RedirectURL)
+		initialization.ValidateOAuthState()
 	}
 	if cfg.GithubOauth != nil {
-		initialization.ValidateGithubOauth()
+		github := cfg.GithubOauth
+		github.ClientID = valueOrEnv(github.ClientID, "GOAUTH_GITHUB_CLIENT_ID")
+		github.ClientSecret = valueOrEnv(github.ClientSecret, "GOAUTH_GITHUB_CLIENT_SECRET")
+		github.RedirectURL = valueOrEnv(github.RedirectURL, "GOAUTH_GITHUB_REDIRECT_URL")
+		github.CallBackURL = valueOrEnv(github.CallBackURL, "GOAUTH_GITHUB_CALLBACK_URL")
+		github.BaseURL = valueOrEnv(github.BaseURL, "GOAUTH_GITHUB_BASE_URL")
+		initialization.ValidateGithubOauth(github.ClientID, github.ClientSecret, github.RedirectURL)
+		initialization.ValidateOAuthState()
+	}
+	if len(cfg.OIDCProviders) > 0 {
+		names := map[string]bool{"google": cfg.GoogleOauth != nil, "github": cfg.GithubOauth != nil}
+		for _, provider := range cfg.OIDCProviders {
+			initialization.ValidateOIDCProvider(provider.Name, provider.IssuerURL, provider.ClientID, provider.RedirectURL)
+			if names[provider.Name] {
+				log.Fatal().Str("provider", provider.Name).Msg("goauth: OAuth provider names must be unique")
+			}
+			names[provider.Name] = true
+		}
+		initialization.ValidateOAuthState()
+	}
+	if cfg.WebAuthn != nil {
+		initialization.ValidateWebAuthn(cfg.WebAuthn.RPID, cfg.WebAuthn.RPOrigins)
 	}
 
 	log.Info().Msg("Prepare necessary environment variables")
 	return cfg
 }
+
+func valueOrEnv(value, key string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(key)
+}
+
+// Close stops the background work started by NewGoAuth.
+func (c *Config) Close() {
+	if c.stopJanitor != nil {
+		c.stopJanitor()
+	}
+}
+
+// WithJanitor starts a background worker that purges expired sessions and
+// tokens. Replicas coordinate through a Postgres advisory lock, so it is safe
+// to enable everywhere.
+func WithJanitor(opts ...janitor.Option) Option {
+	return func(cfg *Config) {
+		cfg.Janitor = true
+		cfg.janitorOpts = opts
+	}
+}
+
+func WithRequireEmailVerification(require bool) Option {
+	return func(cfg *Config) {
+		cfg.RequireEmailVerification = require
+	}
+}
+
 func WithRedisAsSessionStore(redis bool) Option {
 	return func(cfg *Config) {
 		cfg.SessionStoreAsRedis = redis
@@ -123,8 +271,77 @@ func WithGoogleOauth(clientID, clientSecret, redirect, callback string) Option {
 	}
 }
 
+// WithOAuthAutoLink links a social login to the existing user with the same
+// email instead of refusing it, when the provider and goauth have both
+// verified the address. It is off by default: anyone who can get a provider
+// to vouch for an address takes over the account that owns it.
+func WithOAuthAutoLink(enabled bool) Option {
+	return func(c *Config) {
+		c.OAuthAutoLink = enabled
+	}
+}
+
+// WithOIDCProvider adds an OpenID Connect login. Call it once per provider.
+func WithOIDCProvider(provider OIDCProvider) Option {
+	return func(c *Config) {
+		c.OIDCProviders = append(c.OIDCProviders, provider)
+	}
+}
+
+// WithWebAuthn enables passkey registration and login, both passwordless and
+// as a second factor.
+func WithWebAuthn(rpID, rpDisplayName string, rpOrigins ...string) Option {
+	return func(c *Config) {
+		c.WebAuthn = &WebAuthnConfig{
+			RPID:          rpID,
+			RPDisplayName: rpDisplayName,
+			RPOrigins:     rpOrigins,
+		}
+	}
+}
+
 func WithJwtAuth(jwtAuth bool) Option {
 	return func(cfg *Config) {
 		cfg.JwtAuth = jwtAuth
 	}
 }
+
+// WithPasswordHasher sets how new passwords are hashed, e.g.
+// utils.NewArgon2idHasher with tuned parameters or utils.NewBcryptHasher.
+// Existing hashes keep working and are upgraded to it on their next login.
+func WithPasswordHasher(hasher utils.PasswordHasher) Option {
+	return func(cfg *Config) {
+		cfg.PasswordHasher = hasher
+	}
+}
+
+// WithPasswordPolicy sets the rules new passwords are checked against at
+// registration and password reset.
+func WithPasswordPolicy(policy *utils.PasswordPolicy) Option {
+	return func(cfg *Config) {
+		cfg.PasswordPolicy = policy
+	}
+}
+
+// WithPasswordHistory keeps the hashes of the user's last n passwords and
+// refuses them when the password is changed or reset.
+func WithPasswordHistory(n int) Option {
+	return func(cfg *Config) {
+		cfg.PasswordHistory = n
+	}
+}
+
+// WithLoginLockout sets when failed logins are slowed down and when they lock
+// an account or IP address. Pass &lockout.Policy{} to turn lockout off.
+func WithLoginLockout(policy *lockout.Policy) Option {
+	return func(cfg *Config) {
+		cfg.LoginLockout = policy
+	}
+}
+
+func WithJwtKeyProvider(provider utils.KeyProvider) Option {
+	return func(cfg *Config) {
+		cfg.JwtAuth = true
+		cfg.JwtKeyProvider = provider
+	}
+}
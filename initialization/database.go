@@ -41,13 +41,46 @@ func runMigrations(ctx context.Context, store *db.Store, dsn string, redisAsSess
 	if err := store.CreateAccountIndexes(ctx); err != nil {
 		return err
 	}
+	if err := store.CreateAccountTokenTable(ctx); err != nil {
+		return err
+	}
+	if err := store.CreatePasswordHistoryTable(ctx); err != nil {
+		return err
+	}
+	if err := store.CreatePasswordHistoryIndexes(ctx); err != nil {
+		return err
+	}
 	if err := store.CreateAuditLogTable(ctx); err != nil {
 		return err
 	}
+	if err := store.CreateRefreshTokenTable(ctx); err != nil {
+		return err
+	}
+	if err := store.CreateRefreshTokenIndexes(ctx); err != nil {
+		return err
+	}
+	if err := store.CreateRefreshTokenUserIndex(ctx); err != nil {
+		return err
+	}
+	if err := store.CreateTotpStepTable(ctx); err != nil {
+		return err
+	}
+	if err := store.CreateRecoveryCodeTable(ctx); err != nil {
+		return err
+	}
+	if err := store.CreateWebauthnCredentialTable(ctx); err != nil {
+		return err
+	}
+	if err := store.CreateWebauthnCredentialIndexes(ctx); err != nil {
+		return err
+	}
 	if !redisAsSessionStore {
 		if err := store.CreateSessionTable(ctx); err != nil {
 			return err
 		}
+		if err := store.AddSessionLastSeenColumn(ctx); err != nil {
+			return err
+		}
 		if err := store.CreateSessionIndexes(ctx); err != nil {
 			return err
 		}
@@ -57,6 +90,27 @@ func runMigrations(ctx context.Context, store *db.Store, dsn string, redisAsSess
 		if err := store.CreateEmailVerificationTable(ctx); err != nil {
 			return err
 		}
+		if err := store.CreateMfaChallengeTable(ctx); err != nil {
+			return err
+		}
+		if err := store.CreateWebauthnChallengeTable(ctx); err != nil {
+			return err
+		}
+		if err := store.CreateRevokedTokenTable(ctx); err != nil {
+			return err
+		}
+		if err := store.CreateUserRevocationTable(ctx); err != nil {
+			return err
+		}
+		if err := store.CreateAccountUnlockTable(ctx); err != nil {
+			return err
+		}
+		if err := store.CreateLoginAttemptTable(ctx); err != nil {
+			return err
+		}
+		if err := store.AddLoginAttemptPreviousFailedColumn(ctx); err != nil {
+			return err
+		}
 
 		log.Info().Msg("goauth: database migrations succeeded")
 	}
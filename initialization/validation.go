@@ -2,40 +2,71 @@ package initialization
 
 import (
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
 func ValidateJwtAuth() {
-	jwtSecret := GetEnv("GOAUTH_JWT_SECRET", "")
-	if jwtSecret == "" {
-		log.Fatal().Msg("goauth: jwt secret is required")
+	algorithm := GetEnv("GOAUTH_JWT_ALGORITHM", "HS256")
+	if strings.HasPrefix(algorithm, "HS") {
+		jwtSecret := GetEnv("GOAUTH_JWT_SECRET", "")
+		if jwtSecret == "" {
+			log.Fatal().Msg("goauth: jwt secret is required")
+		}
+	} else {
+		privateKey := GetEnv("GOAUTH_JWT_PRIVATE_KEY_FILE", "")
+		publicKey := GetEnv("GOAUTH_JWT_PUBLIC_KEY_FILE", "")
+		if privateKey == "" && publicKey == "" {
+			log.Fatal().Str("algorithm", algorithm).Msg("goauth: jwt private or public key file is required")
+		}
 	}
-	log.Info().Msg("goauth: using jwt authentication")
+	log.Info().Str("algorithm", algorithm).Msg("goauth: using jwt authentication")
 }
 
-func ValidateGoogleOauth() {
-	clientId := GetEnv("GOAUTH_GOOGLE_CLIENT_ID", "")
-	clientSecret := GetEnv("GOAUTH_GOOGLE_CLIENT_SECRET", "")
-	redirectURL := GetEnv("GOAUTH_GOOGLE_REDIRECT_URL", "")
-
+func ValidateGoogleOauth(clientId, clientSecret, redirectURL string) {
 	if clientId == "" || clientSecret == "" || redirectURL == "" {
 		log.Fatal().Msg("goauth: missing Google OAuth credentials")
 	}
 	log.Info().Msg("goauth: using google authentication")
 }
 
-func ValidateGithubOauth() {
-	clientId := GetEnv("GOAUTH_GITHUB_CLIENT_ID", "")
-	clientSecret := GetEnv("GOAUTH_GITHUB_CLIENT_SECRET", "")
-	redirectURL := GetEnv("GOAUTH_GITHUB_REDIRECT_URL", "")
-
+func ValidateGithubOauth(clientId, clientSecret, redirectURL string) {
 	if clientId == "" || clientSecret == "" || redirectURL == "" {
 		log.Fatal().Msg("goauth: missing Github OAuth credentials")
 	}
 	log.Info().Msg("goauth: using github authentication")
 }
+
+// oidcProviderName keeps provider names usable as a route segment.
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+func ValidateOIDCProvider(name, issuerURL, clientId, redirectURL string) {
+	if !oidcProviderName.MatchString(name) {
+		log.Fatal().Str("provider", name).Msg("goauth: OIDC provider name must be lowercase letters, digits, '-' or '_'")
+	}
+	if issuerURL == "" || clientId == "" || redirectURL == "" {
+		log.Fatal().Str("provider", name).Msg("goauth: missing OIDC issuer URL, client ID or redirect URL")
+	}
+	log.Info().Str("provider", name).Str("issuer", issuerURL).Msg("goauth: using OpenID Connect authentication")
+}
+
+// ValidateOAuthState checks the secret that signs the OAuth state parameter.
+// Every replica must share it, or callbacks fail on a different instance.
+func ValidateOAuthState() {
+	if len(GetEnv("GOAUTH_OAUTH_STATE_SECRET", "")) < 32 {
+		log.Fatal().Msg("goauth: GOAUTH_OAUTH_STATE_SECRET must be at least 32 characters")
+	}
+}
+func ValidateWebAuthn(rpID string, rpOrigins []string) {
+	if rpID == "" || len(rpOrigins) == 0 {
+		log.Fatal().Msg("goauth: WebAuthn needs a relying party ID and at least one origin")
+	}
+	log.Info().Str("rp_id", rpID).Msg("goauth: using WebAuthn passkeys")
+}
 func GetEnv(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
 		return value
@@ -44,12 +75,20 @@ func GetEnv(key, fallback string) string {
 }
 
 func ValidatePestoAuth() {
-	pestoKey := GetEnv("GOAUTH_PESTO_KEY", "")
-	pestoSecret := GetEnv("GOAUTH_PESTO_SECRET", "")
-	if pestoKey == "" || pestoSecret == "" {
-		log.Fatal().Msg("goauth: missing Pesto OAuth credentials")
+	purpose := GetEnv("GOAUTH_PASETO_PURPOSE", "local")
+	switch purpose {
+	case "local":
+		if GetEnv("GOAUTH_PASETO_KEY", "") == "" {
+			log.Fatal().Msg("goauth: GOAUTH_PASETO_KEY is required for v4.local PASETO tokens")
+		}
+	case "public":
+		if GetEnv("GOAUTH_PASETO_PRIVATE_KEY", "") == "" && GetEnv("GOAUTH_PASETO_PUBLIC_KEY", "") == "" {
+			log.Fatal().Msg("goauth: GOAUTH_PASETO_PRIVATE_KEY or GOAUTH_PASETO_PUBLIC_KEY is required for v4.public PASETO tokens")
+		}
+	default:
+		log.Fatal().Str("purpose", purpose).Msg("goauth: unsupported PASETO purpose")
 	}
-	log.Info().Msg("goauth: using Pesto authentication")
+	log.Info().Str("purpose", purpose).Msg("goauth: using PASETO v4 authentication")
 }
 func GetEnvDuration(key string, fallback time.Duration) time.Duration {
 	value := os.Getenv(key)
@@ -65,6 +104,20 @@ func GetEnvDuration(key string, fallback time.Duration) time.Duration {
 	return d
 }
 
+func GetEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("invalid integer for %s: %s, using fallback %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
 func ValidateRedis() {
 	redisHOST := GetEnv("GOAUTH_REDIS_HOST", "localhost")
 	redisPORT := GetEnv("GOAUTH_REDIS_PORT", "6379")
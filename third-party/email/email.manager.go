@@ -3,8 +3,10 @@ package email
 import (
 	"context"
 	"fmt"
+	"math"
 	"os"
 	"strconv"
+	"time"
 )
 
 // EmailManager provides a high-level interface for managing multiple email providers
@@ -223,14 +225,17 @@ func (es *EmailService) SendWelcomeEmail(ctx context.Context, to, name string) e
 		Send(ctx)
 }
 
-// SendPasswordResetEmail sends a password reset email
-func (es *EmailService) SendPasswordResetEmail(ctx context.Context, to, resetLink string) error {
+// SendPasswordResetEmail sends a password reset email. expiry is how long
+// the link stays valid and is shown to the user.
+func (es *EmailService) SendPasswordResetEmail(ctx context.Context, to, resetLink string, expiry time.Duration) error {
 	data := struct {
-		ResetLink   string
-		ExpiryHours int
+		ResetLink     string
+		ExpiryHours   int
+		ExpiryMinutes int
 	}{
-		ResetLink:   resetLink,
-		ExpiryHours: 24,
+		ResetLink:     resetLink,
+		ExpiryHours:   int(math.Ceil(expiry.Hours())),
+		ExpiryMinutes: int(math.Ceil(expiry.Minutes())),
 	}
 
 	return es.manager.NewBuilder().
@@ -242,6 +247,86 @@ func (es *EmailService) SendPasswordResetEmail(ctx context.Context, to, resetLin
 		Send(ctx)
 }
 
+// SendVerificationEmail sends the link that confirms the address belongs to
+// the user. expiry is how long the link stays valid.
+func (es *EmailService) SendVerificationEmail(ctx context.Context, to, verifyLink string, expiry time.Duration) error {
+	data := struct {
+		VerifyLink  string
+		ExpiryHours int
+	}{
+		VerifyLink:  verifyLink,
+		ExpiryHours: int(math.Ceil(expiry.Hours())),
+	}
+
+	return es.manager.NewBuilder().
+		To(to).
+		Subject("Verify Your Email Address").
+		BodyFromTemplate("templates/email_verification.html", data).
+		Tag("type", "email_verification").
+		Tag("security", "true").
+		Send(ctx)
+}
+
+// SendRecoveryCodeUsedEmail tells the user one of their two-factor recovery
+// codes was just used to sign in, and how many are left.
+func (es *EmailService) SendRecoveryCodeUsedEmail(ctx context.Context, to string, remaining int) error {
+	data := struct {
+		Remaining int
+	}{
+		Remaining: remaining,
+	}
+
+	return es.manager.NewBuilder().
+		To(to).
+		Subject("A Recovery Code Was Used").
+		BodyFromTemplate("templates/recovery_code_used.html", data).
+		Tag("type", "recovery_code_used").
+		Tag("security", "true").
+		Send(ctx)
+}
+
+// SendPasswordChangedEmail tells the user their password was just changed,
+// so they can act if it was not them.
+func (es *EmailService) SendPasswordChangedEmail(ctx context.Context, to, ipAddress string, changedAt time.Time) error {
+	data := struct {
+		IPAddress string
+		ChangedAt time.Time
+	}{
+		IPAddress: ipAddress,
+		ChangedAt: changedAt,
+	}
+
+	return es.manager.NewBuilder().
+		To(to).
+		Subject("Your Password Was Changed").
+		BodyFromTemplate("templates/password_changed.html", data).
+		Tag("type", "password_changed").
+		Tag("security", "true").
+		Send(ctx)
+}
+
+// SendAccountLockedEmail tells the user repeated failed logins locked their
+// account until lockedUntil, and sends the link that unlocks it at once.
+func (es *EmailService) SendAccountLockedEmail(ctx context.Context, to, unlockLink, ipAddress string, lockedUntil time.Time) error {
+	data := struct {
+		UnlockLink  string
+		IPAddress   string
+		LockedUntil time.Time
+	}{
+		UnlockLink:  unlockLink,
+		IPAddress:   ipAddress,
+		LockedUntil: lockedUntil,
+	}
+
+	return es.manager.NewBuilder().
+		To(to).
+		Subject("Your Account Was Locked").
+		BodyFromTemplate("templates/account_locked.html", data).
+		Tag("type", "account_locked").
+		Tag("security", "true").
+		Send(ctx)
+}
+
 // SendNotificationEmail sends a notification with fallback
 func (es *EmailService) SendNotificationEmail(ctx context.Context, to, subject, message string) error {
 	data := struct {
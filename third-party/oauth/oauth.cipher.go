@@ -0,0 +1,57 @@
+package oauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// tokenCipherVersion prefixes every ciphertext so the scheme or key can be
+// changed later without guessing what old rows hold.
+const tokenCipherVersion = 1
+
+var ErrTokenCipher = errors.New("oauth: cannot decrypt provider token")
+
+// TokenCipher encrypts provider tokens at rest with AES-256-GCM. The
+// associated data, e.g. the account ID, ties a ciphertext to its row, so
+// tokens cannot be moved between accounts in the database.
+type TokenCipher struct {
+	aead cipher.AEAD
+}
+
+func NewTokenCipher(key []byte) (*TokenCipher, error) {
+	if len(key) != 32 {
+		return nil, errors.New("oauth: provider token key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenCipher{aead: aead}, nil
+}
+
+func (t *TokenCipher) Seal(plaintext string, associatedData []byte) ([]byte, error) {
+	nonce := make([]byte, t.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append([]byte{tokenCipherVersion}, nonce...)
+	return t.aead.Seal(out, nonce, []byte(plaintext), associatedData), nil
+}
+
+func (t *TokenCipher) Open(ciphertext, associatedData []byte) (string, error) {
+	nonceSize := t.aead.NonceSize()
+	if len(ciphertext) < 1+nonceSize || ciphertext[0] != tokenCipherVersion {
+		return "", ErrTokenCipher
+	}
+	plaintext, err := t.aead.Open(nil, ciphertext[1:1+nonceSize], ciphertext[1+nonceSize:], associatedData)
+	if err != nil {
+		return "", ErrTokenCipher
+	}
+	return string(plaintext), nil
+}
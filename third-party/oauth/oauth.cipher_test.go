@@ -0,0 +1,89 @@
+package oauth
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func newTestCipher(t *testing.T, fill byte) *TokenCipher {
+	t.Helper()
+	cipher, err := NewTokenCipher(bytes.Repeat([]byte{fill}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cipher
+}
+
+func TestTokenCipherRoundTrip(t *testing.T) {
+	cipher := newTestCipher(t, 1)
+	account := []byte("account-1")
+	for _, plaintext := range []string{"ya29.access-token", ""} {
+		sealed, err := cipher.Seal(plaintext, account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sealed[0] != tokenCipherVersion {
+			t.Errorf("version byte = %d, want %d", sealed[0], tokenCipherVersion)
+		}
+		if plaintext != "" && bytes.Contains(sealed, []byte(plaintext)) {
+			t.Error("ciphertext contains the plaintext")
+		}
+		opened, err := cipher.Open(sealed, account)
+		if err != nil || opened != plaintext {
+			t.Errorf("Open = %q, %v, want %q", opened, err, plaintext)
+		}
+	}
+
+	// A fresh nonce per seal: the same token never encrypts the same way.
+	first, _ := cipher.Seal("token", account)
+	second, _ := cipher.Seal("token", account)
+	if bytes.Equal(first, second) {
+		t.Error("sealing twice gave the same ciphertext")
+	}
+}
+
+func TestTokenCipherOpenRejects(t *testing.T) {
+	cipher := newTestCipher(t, 1)
+	account := []byte("account-1")
+	sealed, err := cipher.Seal("ya29.access-token", account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modified := func(change func([]byte) []byte) []byte {
+		return change(bytes.Clone(sealed))
+	}
+
+	tests := []struct {
+		name           string
+		cipher         *TokenCipher
+		ciphertext     []byte
+		associatedData []byte
+	}{
+		{"another account's row", cipher, sealed, []byte("account-2")},
+		{"no associated data", cipher, sealed, nil},
+		{"another key", newTestCipher(t, 2), sealed, account},
+		{"unknown version", cipher, modified(func(b []byte) []byte { b[0] = tokenCipherVersion + 1; return b }), account},
+		{"no version", cipher, sealed[1:], account},
+		{"flipped ciphertext bit", cipher, modified(func(b []byte) []byte { b[len(b)-1] ^= 1; return b }), account},
+		{"flipped nonce bit", cipher, modified(func(b []byte) []byte { b[1] ^= 1; return b }), account},
+		{"truncated", cipher, sealed[:len(sealed)-1], account},
+		{"shorter than a nonce", cipher, sealed[:5], account},
+		{"empty", cipher, nil, account},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if plaintext, err := tt.cipher.Open(tt.ciphertext, tt.associatedData); !errors.Is(err, ErrTokenCipher) {
+				t.Errorf("Open = %q, %v, want ErrTokenCipher", plaintext, err)
+			}
+		})
+	}
+}
+
+func TestNewTokenCipherKeyLength(t *testing.T) {
+	for _, size := range []int{0, 16, 24, 31, 33} {
+		if _, err := NewTokenCipher(make([]byte, size)); err == nil {
+			t.Errorf("NewTokenCipher accepted a %d byte key", size)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrInvalidState   = errors.New("oauth: invalid or expired state")
+	ErrMissingIDToken = errors.New("oauth: token response has no id_token")
+	ErrInvalidIDToken = errors.New("oauth: invalid id token")
+	ErrNoRefreshToken = errors.New("oauth: token has no refresh token")
+)
+
+// defaultHTTPTimeout bounds every call to a provider when the caller does not
+// supply its own client.
+const defaultHTTPTimeout = 10 * time.Second
+
+type (
+	// Provider runs the authorization-code flow against one identity
+	// provider.
+	Provider interface {
+		// Name is stored in goauth_account.provider, so it must not change
+		// once users have signed in.
+		Name() string
+		// AuthCodeURL is where the browser is sent to start flow.
+		AuthCodeURL(flow Flow) string
+		// Authenticate redeems code and returns the verified identity.
+		Authenticate(ctx context.Context, code string, flow Flow) (Identity, error)
+		// Refresh trades token's refresh token for a new token set.
+		Refresh(ctx context.Context, token *oauth2.Token) (*oauth2.Token, error)
+	}
+
+	// Identity is what a provider vouches for after a successful flow.
+	Identity struct {
+		Provider      string
+		Subject       string
+		Email         string
+		EmailVerified bool
+		Name          string
+		Picture       string
+		// Token is the provider's own token set, e.g. for calling its APIs.
+		Token *oauth2.Token
+	}
+)
+
+func httpClientOrDefault(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return &http.Client{Timeout: defaultHTTPTimeout}
+}
+
+// refreshToken runs the refresh_token grant of config.
+func refreshToken(ctx context.Context, config oauth2.Config, client *http.Client, token *oauth2.Token) (*oauth2.Token, error) {
+	if token.RefreshToken == "" {
+		return nil, ErrNoRefreshToken
+	}
+	// An expired copy makes the token source refresh instead of returning
+	// the token as is.
+	expired := *token
+	expired.AccessToken = ""
+	return config.TokenSource(withHTTPClient(ctx, client), &expired).Token()
+}
+
+// withHTTPClient makes the oauth2 package use client for token requests.
+func withHTTPClient(ctx context.Context, client *http.Client) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, client)
+}
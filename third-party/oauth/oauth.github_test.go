@@ -0,0 +1,166 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// githubStandIn serves the parts of a GitHub Enterprise Server the login
+// flow calls. The token endpoint only accepts code together with the PKCE
+// verifier of flow.
+func githubStandIn(t *testing.T, flow Flow, code string, emails []githubEmail) *httptest.Server {
+	t.Helper()
+	const accessToken = "gho_standin"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.PostForm.Get("code") != code || r.PostForm.Get("code_verifier") != flow.Verifier {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": accessToken,
+			"token_type":   "bearer",
+			"scope":        "read:user,user:email",
+		})
+	})
+	authorized := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+accessToken {
+				http.Error(w, "bad credentials", http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+		}
+	}
+	mux.HandleFunc("GET /api/v3/user", authorized(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":         4242,
+			"login":      "octocat",
+			"avatar_url": "https://avatars.example.com/u/4242",
+		})
+	}))
+	mux.HandleFunc("GET /api/v3/user/emails", authorized(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(emails)
+	}))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGitHubAuthenticate(t *testing.T) {
+	signer := newTestSigner(t, time.Minute)
+	flow, err := signer.Begin(ProviderGitHub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		emails       []githubEmail
+		wantEmail    string
+		wantVerified bool
+	}{
+		{
+			name: "uses the verified primary email",
+			emails: []githubEmail{
+				{Email: "public@example.com", Verified: true},
+				{Email: "primary@example.com", Primary: true, Verified: true},
+			},
+			wantEmail:    "primary@example.com",
+			wantVerified: true,
+		},
+		{
+			name: "refuses an unverified primary email",
+			emails: []githubEmail{
+				{Email: "other@example.com", Verified: true},
+				{Email: "primary@example.com", Primary: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := githubStandIn(t, flow, "good-code", tt.emails)
+			github := NewGitHub(GitHubConfig{
+				ClientID:     "client",
+				ClientSecret: "secret",
+				RedirectURL:  "https://app.example.com/callback",
+				BaseURL:      server.URL,
+				HTTPClient:   server.Client(),
+			})
+
+			identity, err := github.Authenticate(context.Background(), "good-code", flow)
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if identity.Subject != "4242" || identity.Name != "octocat" {
+				t.Errorf("identity = %+v, want subject 4242 named octocat", identity)
+			}
+			if identity.Email != tt.wantEmail || identity.EmailVerified != tt.wantVerified {
+				t.Errorf("email = %q verified %v, want %q verified %v",
+					identity.Email, identity.EmailVerified, tt.wantEmail, tt.wantVerified)
+			}
+		})
+	}
+}
+
+func TestGitHubAuthenticateRejectsWrongVerifier(t *testing.T) {
+	signer := newTestSigner(t, time.Minute)
+	flow, err := signer.Begin(ProviderGitHub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := signer.Begin(ProviderGitHub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := githubStandIn(t, flow, "good-code", nil)
+	github := NewGitHub(GitHubConfig{BaseURL: server.URL, HTTPClient: server.Client()})
+
+	if _, err := github.Authenticate(context.Background(), "good-code", other); err == nil {
+		t.Fatal("Authenticate accepted a code with another flow's verifier")
+	}
+}
+
+func TestGitHubAuthCodeURL(t *testing.T) {
+	signer := newTestSigner(t, time.Minute)
+	flow, err := signer.Begin(ProviderGitHub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	github := NewGitHub(GitHubConfig{ClientID: "client", BaseURL: "https://ghe.example.com/"})
+
+	authURL, err := url.Parse(github.AuthCodeURL(flow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := authURL.Scheme + "://" + authURL.Host + authURL.Path; got != "https://ghe.example.com/login/oauth/authorize" {
+		t.Errorf("authorize endpoint = %s", got)
+	}
+	query := authURL.Query()
+	if query.Get("state") != flow.State {
+		t.Errorf("state = %q, want the flow's state", query.Get("state"))
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Errorf("authorize URL has no S256 code challenge: %s", authURL)
+	}
+}
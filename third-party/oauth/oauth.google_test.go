@@ -0,0 +1,156 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/third-party/oauth/oauthtest"
+)
+
+func newStandInGoogle(standIn *oauthtest.Google) *Google {
+	return NewGoogle(GoogleConfig{
+		ClientID:     standIn.ClientID,
+		ClientSecret: standIn.ClientSecret,
+		RedirectURL:  "https://app.example.com/auth/google/callback",
+		AuthURL:      standIn.AuthURL(),
+		TokenURL:     standIn.TokenURL(),
+		JWKSURL:      standIn.JWKSURL(),
+		Issuer:       standIn.URL,
+		HTTPClient:   standIn.Client(),
+	})
+}
+
+// authorizeGoogle runs the consent step of flow and returns the code the
+// callback received.
+func authorizeGoogle(t *testing.T, standIn *oauthtest.Google, google *Google, flow Flow) string {
+	t.Helper()
+	callback, err := standIn.Authorize(google.AuthCodeURL(flow))
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if state := callback.Query().Get("state"); state != flow.State {
+		t.Fatalf("callback state = %q, want the flow's state", state)
+	}
+	return callback.Query().Get("code")
+}
+
+func TestGoogleAuthenticate(t *testing.T) {
+	standIn := oauthtest.NewGoogle(t)
+	google := newStandInGoogle(standIn)
+	signer := newTestSigner(t, time.Minute)
+	flow, err := signer.Begin(ProviderGoogle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity, err := google.Authenticate(context.Background(), authorizeGoogle(t, standIn, google, flow), flow)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	want := Identity{
+		Provider:      ProviderGoogle,
+		Subject:       standIn.Subject,
+		Email:         standIn.Email,
+		EmailVerified: true,
+		Name:          standIn.Name,
+	}
+	identity.Token = nil
+	if identity != want {
+		t.Errorf("identity = %+v, want %+v", identity, want)
+	}
+}
+
+func TestGoogleAuthenticatePKCE(t *testing.T) {
+	standIn := oauthtest.NewGoogle(t)
+	google := newStandInGoogle(standIn)
+	signer := newTestSigner(t, time.Minute)
+	flow, err := signer.Begin(ProviderGoogle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := signer.Begin(ProviderGoogle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A code intercepted on its way to the callback is useless without the
+	// verifier of the flow that asked for it.
+	code := authorizeGoogle(t, standIn, google, flow)
+	if _, err := google.Authenticate(context.Background(), code, other); err == nil {
+		t.Fatal("Authenticate redeemed a code with another flow's verifier")
+	}
+
+	code = authorizeGoogle(t, standIn, google, flow)
+	if _, err := google.Authenticate(context.Background(), code, flow); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if _, err := google.Authenticate(context.Background(), code, flow); err == nil {
+		t.Fatal("Authenticate redeemed a code twice")
+	}
+}
+
+func TestGoogleAuthenticateVerifiesIDToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(*oauthtest.Google)
+	}{
+		{"forged signature", func(g *oauthtest.Google) { g.ForgeSignature = true }},
+		{"other audience", func(g *oauthtest.Google) { g.Claims = map[string]any{"aud": "someone-else"} }},
+		{"other issuer", func(g *oauthtest.Google) { g.Claims = map[string]any{"iss": "https://accounts.example.com"} }},
+		{"expired", func(g *oauthtest.Google) {
+			g.Claims = map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}
+		}},
+		{"no expiry", func(g *oauthtest.Google) { g.Claims = map[string]any{"exp": nil} }},
+		{"other nonce", func(g *oauthtest.Google) { g.Claims = map[string]any{"nonce": "replayed"} }},
+		{"no subject", func(g *oauthtest.Google) { g.Claims = map[string]any{"sub": nil} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := oauthtest.NewGoogle(t)
+			tt.tamper(standIn)
+			google := newStandInGoogle(standIn)
+			flow, err := newTestSigner(t, time.Minute).Begin(ProviderGoogle)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = google.Authenticate(context.Background(), authorizeGoogle(t, standIn, google, flow), flow)
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("Authenticate error = %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestGoogleAuthenticateEmailVerified(t *testing.T) {
+	tests := []struct {
+		name  string
+		claim any
+		want  bool
+	}{
+		{"false", false, false},
+		{"missing", nil, false},
+		{"string true", "true", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := oauthtest.NewGoogle(t)
+			standIn.Claims = map[string]any{"email_verified": tt.claim}
+			google := newStandInGoogle(standIn)
+			flow, err := newTestSigner(t, time.Minute).Begin(ProviderGoogle)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			identity, err := google.Authenticate(context.Background(), authorizeGoogle(t, standIn, google, flow), flow)
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if identity.EmailVerified != tt.want {
+				t.Errorf("EmailVerified = %v, want %v", identity.EmailVerified, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package oauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// idTokenLeeway absorbs clock skew between us and the provider.
+const idTokenLeeway = time.Minute
+
+// IDClaims are the OpenID Connect claims goauth reads from an ID token.
+type IDClaims struct {
+	jwt.RegisteredClaims
+	AuthorizedParty string    `json:"azp,omitempty"`
+	Nonce           string    `json:"nonce,omitempty"`
+	Email           string    `json:"email,omitempty"`
+	EmailVerified   claimBool `json:"email_verified,omitempty"`
+	Name            string    `json:"name,omitempty"`
+	Picture         string    `json:"picture,omitempty"`
+	// Raw holds every claim of the token, for mapping provider-specific
+	// claims.
+	Raw map[string]any `json:"-"`
+}
+
+// claimBool accepts both true and "true"; some providers send booleans as
+// strings.
+type claimBool bool
+
+func (b *claimBool) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case bool:
+		*b = claimBool(v)
+	case string:
+		*b = v == "true"
+	default:
+		*b = false
+	}
+	return nil
+}
+
+// IDTokenVerifier checks ID tokens issued to ClientID by one of issuers.
+type IDTokenVerifier struct {
+	keys     *RemoteKeySet
+	clientID string
+	issuers  []string
+}
+
+func NewIDTokenVerifier(keys *RemoteKeySet, clientID string, issuers ...string) *IDTokenVerifier {
+	return &IDTokenVerifier{keys: keys, clientID: clientID, issuers: issuers}
+}
+
+// Verify checks the signature, issuer, audience, expiry and nonce of raw.
+func (v *IDTokenVerifier) Verify(ctx context.Context, raw, nonce string) (IDClaims, error) {
+	var claims IDClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithAudience(v.clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(idTokenLeeway),
+	)
+	if err != nil {
+		return IDClaims{}, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	if !slices.Contains(v.issuers, claims.Issuer) {
+		return IDClaims{}, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, claims.Issuer)
+	}
+	if claims.Subject == "" {
+		return IDClaims{}, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != v.clientID {
+		return IDClaims{}, fmt.Errorf("%w: token was issued to %q", ErrInvalidIDToken, claims.AuthorizedParty)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return IDClaims{}, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	// The signature is verified, so the payload can be decoded again as is.
+	payload, err := jwt.NewParser().DecodeSegment(strings.Split(raw, ".")[1])
+	if err != nil {
+		return IDClaims{}, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+	if err := json.Unmarshal(payload, &claims.Raw); err != nil {
+		return IDClaims{}, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+	return claims, nil
+}
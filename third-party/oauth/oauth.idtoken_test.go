@@ -0,0 +1,161 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testClientID = "test-client"
+	testNonce    = "test-nonce"
+	testKeyID    = "k1"
+)
+
+// newTestVerifier returns a verifier for testClientID that trusts testIssuer,
+// and a function signing ID tokens with the key it trusts.
+func newTestVerifier(t *testing.T) (*IDTokenVerifier, func(jwt.MapClaims) string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, ok := utils.NewJWK(&utils.JWTKey{ID: testKeyID, Method: jwt.SigningMethodES256, VerifyKey: &key.PublicKey})
+	if !ok {
+		t.Fatal("NewJWK refused an ES256 key")
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(utils.JWKSet{Keys: []utils.JWK{jwk}})
+	}))
+	t.Cleanup(server.Close)
+
+	sign := func(claims jwt.MapClaims) string {
+		t.Helper()
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+		token.Header["kid"] = testKeyID
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	keys := NewRemoteKeySet(server.URL, server.Client())
+	return NewIDTokenVerifier(keys, testClientID, testIssuer, "issuer.example.com"), sign
+}
+
+// idTokenClaims returns valid claims for newTestVerifier's verifier, changed
+// by overrides; a nil value removes the claim.
+func idTokenClaims(overrides jwt.MapClaims) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   testIssuer,
+		"aud":   testClientID,
+		"sub":   "subject-1",
+		"nonce": testNonce,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"email": "user@example.com",
+		"tid":   "tenant-1",
+	}
+	for name, value := range overrides {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	return claims
+}
+
+func TestIDTokenVerifierAccepts(t *testing.T) {
+	verifier, sign := newTestVerifier(t)
+	tests := []struct {
+		name      string
+		overrides jwt.MapClaims
+	}{
+		{"single audience", nil},
+		{"audience list naming us as azp", jwt.MapClaims{"aud": []string{testClientID, "other-client"}, "azp": testClientID}},
+		{"audience list of one", jwt.MapClaims{"aud": []string{testClientID}}},
+		{"second issuer spelling", jwt.MapClaims{"iss": "issuer.example.com"}},
+		{"expired within the leeway", jwt.MapClaims{"exp": time.Now().Add(-idTokenLeeway / 2).Unix()}},
+		{"issued within the leeway ahead", jwt.MapClaims{"iat": time.Now().Add(idTokenLeeway / 2).Unix()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(context.Background(), sign(idTokenClaims(tt.overrides)), testNonce)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if claims.Subject != "subject-1" || claims.Email != "user@example.com" || claims.Raw["tid"] != "tenant-1" {
+				t.Errorf("claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestIDTokenVerifierRejects(t *testing.T) {
+	verifier, sign := newTestVerifier(t)
+	_, otherSign := newTestVerifier(t)
+	hmac := func(claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		token.Header["kid"] = testKeyID
+		signed, err := token.SignedString([]byte("secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	withKeyID := func(kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, idTokenClaims(nil))
+		token.Header["kid"] = kid
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		nonce string
+	}{
+		{"other issuer", sign(idTokenClaims(jwt.MapClaims{"iss": "https://evil.example.com"})), testNonce},
+		{"no issuer", sign(idTokenClaims(jwt.MapClaims{"iss": nil})), testNonce},
+		{"other audience", sign(idTokenClaims(jwt.MapClaims{"aud": "other-client"})), testNonce},
+		{"no audience", sign(idTokenClaims(jwt.MapClaims{"aud": nil})), testNonce},
+		{"audience list without us", sign(idTokenClaims(jwt.MapClaims{"aud": []string{"a", "b"}, "azp": "a"})), testNonce},
+		{"audience list without azp", sign(idTokenClaims(jwt.MapClaims{"aud": []string{testClientID, "other-client"}})), testNonce},
+		{"audience list naming another azp", sign(idTokenClaims(jwt.MapClaims{"aud": []string{testClientID, "other-client"}, "azp": "other-client"})), testNonce},
+		{"other nonce", sign(idTokenClaims(nil)), "another-nonce"},
+		{"no nonce", sign(idTokenClaims(jwt.MapClaims{"nonce": nil})), testNonce},
+		{"unexpected nonce", sign(idTokenClaims(nil)), ""},
+		{"no subject", sign(idTokenClaims(jwt.MapClaims{"sub": nil})), testNonce},
+		{"expired", sign(idTokenClaims(jwt.MapClaims{"exp": time.Now().Add(-2 * idTokenLeeway).Unix()})), testNonce},
+		{"no expiry", sign(idTokenClaims(jwt.MapClaims{"exp": nil})), testNonce},
+		{"issued in the future", sign(idTokenClaims(jwt.MapClaims{"iat": time.Now().Add(2 * idTokenLeeway).Unix()})), testNonce},
+		{"signed by another key", otherSign(idTokenClaims(nil)), testNonce},
+		{"unknown key id", withKeyID("k2"), testNonce},
+		{"hmac", hmac(idTokenClaims(nil)), testNonce},
+		{"not a jwt", "not.a.jwt", testNonce},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if claims, err := verifier.Verify(context.Background(), tt.token, tt.nonce); !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("Verify = %+v, %v, want ErrInvalidIDToken", claims, err)
+			}
+		})
+	}
+}
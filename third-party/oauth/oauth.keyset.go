@@ -0,0 +1,113 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// keySetTTL is how long fetched keys are trusted before a refetch.
+	keySetTTL = time.Hour
+	// keySetMinRefresh limits refetches triggered by unknown key IDs, so
+	// tokens with made-up kids cannot hammer the provider.
+	keySetMinRefresh = time.Minute
+)
+
+// RemoteKeySet caches the verification keys a provider publishes at its
+// jwks_uri and refetches them when they expire or an unknown kid turns up,
+// which is how providers roll their keys.
+type RemoteKeySet struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+func NewRemoteKeySet(url string, client *http.Client) *RemoteKeySet {
+	return &RemoteKeySet{url: url, client: httpClientOrDefault(client)}
+}
+
+// Key returns the key for kid. An empty kid matches only when the set holds a
+// single key.
+func (k *RemoteKeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stale := time.Since(k.fetchedAt) > keySetTTL
+	key, found := k.lookup(kid)
+	if found && !stale {
+		return key, nil
+	}
+	if stale || time.Since(k.fetchedAt) > keySetMinRefresh {
+		if err := k.fetch(ctx); err != nil {
+			if found {
+				// Keep serving the cached key through a provider outage.
+				log.Warn().Err(err).Str("jwks_uri", k.url).Msg("failed to refresh provider keys")
+				return key, nil
+			}
+			return nil, err
+		}
+		if key, found = k.lookup(kid); found {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("oauth: no key %q at %s", kid, k.url)
+}
+
+func (k *RemoteKeySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key, true
+		}
+	}
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+func (k *RemoteKeySet) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("oauth: fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oauth: fetch jwks: %s", resp.Status)
+	}
+
+	var set utils.JWKSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("oauth: decode jwks: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			// Providers may publish key types we do not support next to
+			// the ones they sign with.
+			log.Debug().Err(err).Str("jwks_uri", k.url).Msg("skipping provider key")
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	k.keys = keys
+	k.fetchedAt = time.Now()
+	return nil
+}
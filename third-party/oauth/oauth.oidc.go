@@ -0,0 +1,210 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// ClaimMapping names the claims the goauth_user email, name and image are
+// read from. Empty fields use the standard OpenID Connect claims.
+type ClaimMapping struct {
+	Email         string
+	EmailVerified string
+	Name          string
+	Image         string
+}
+
+func (m ClaimMapping) withDefaults() ClaimMapping {
+	m.Email = valueOr(m.Email, "email")
+	m.EmailVerified = valueOr(m.EmailVerified, "email_verified")
+	m.Name = valueOr(m.Name, "name")
+	m.Image = valueOr(m.Image, "picture")
+	return m
+}
+
+// OIDCConfig configures any OpenID Connect provider, e.g. Okta, Keycloak,
+// Azure AD or Auth0. Name identifies it in routes and goauth_account.
+type OIDCConfig struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes default to openid, email and profile; openid is always sent.
+	Scopes []string
+	Claims ClaimMapping
+
+	HTTPClient *http.Client
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// OIDC is a provider configured from its discovery document.
+type OIDC struct {
+	name        string
+	config      oauth2.Config
+	client      *http.Client
+	verifier    *IDTokenVerifier
+	userInfoURL string
+	claims      ClaimMapping
+}
+
+// NewOIDC fetches IssuerURL/.well-known/openid-configuration and builds the
+// provider from it.
+func NewOIDC(ctx context.Context, cfg OIDCConfig) (*OIDC, error) {
+	client := httpClientOrDefault(cfg.HTTPClient)
+	issuer := strings.TrimSuffix(cfg.IssuerURL, "/")
+
+	var doc discoveryDocument
+	if err := getJSON(ctx, client, issuer+"/.well-known/openid-configuration", "", &doc); err != nil {
+		return nil, fmt.Errorf("oauth: %s discovery: %w", cfg.Name, err)
+	}
+	// OpenID Connect Discovery requires the document to name the issuer it
+	// was fetched for; tolerate only a trailing slash difference.
+	if strings.TrimSuffix(doc.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oauth: %s discovery: issuer %q does not match %q", cfg.Name, doc.Issuer, cfg.IssuerURL)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("oauth: %s discovery: missing endpoints", cfg.Name)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	} else if !slices.Contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	return &OIDC{
+		name: cfg.Name,
+		config: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  doc.AuthorizationEndpoint,
+				TokenURL: doc.TokenEndpoint,
+			},
+		},
+		client:      client,
+		verifier:    NewIDTokenVerifier(NewRemoteKeySet(doc.JWKSURI, client), cfg.ClientID, doc.Issuer),
+		userInfoURL: doc.UserInfoEndpoint,
+		claims:      cfg.Claims.withDefaults(),
+	}, nil
+}
+
+func (o *OIDC) Name() string {
+	return o.name
+}
+
+func (o *OIDC) AuthCodeURL(flow Flow) string {
+	return o.config.AuthCodeURL(flow.State,
+		oauth2.S256ChallengeOption(flow.Verifier),
+		oauth2.SetAuthURLParam("nonce", flow.Nonce),
+	)
+}
+
+// Authenticate verifies the ID token and maps its claims. Providers that
+// leave the mapped claims out of the ID token, as some do unless asked, are
+// asked through the userinfo endpoint.
+func (o *OIDC) Authenticate(ctx context.Context, code string, flow Flow) (Identity, error) {
+	token, err := o.config.Exchange(withHTTPClient(ctx, o.client), code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return Identity{}, fmt.Errorf("oauth: %s code exchange: %w", o.name, err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return Identity{}, ErrMissingIDToken
+	}
+	idClaims, err := o.verifier.Verify(ctx, rawIDToken, flow.Nonce)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	claims := idClaims.Raw
+	if o.userInfoURL != "" && (claims[o.claims.Email] == nil || claims[o.claims.Name] == nil) {
+		var userInfo map[string]any
+		if err := getJSON(ctx, o.client, o.userInfoURL, token.AccessToken, &userInfo); err != nil {
+			return Identity{}, fmt.Errorf("oauth: %s userinfo: %w", o.name, err)
+		}
+		// Userinfo answers for whoever holds the access token; it must be
+		// the subject of the ID token.
+		if sub, _ := userInfo["sub"].(string); sub != idClaims.Subject {
+			return Identity{}, fmt.Errorf("oauth: %s userinfo: subject mismatch", o.name)
+		}
+		for name, value := range userInfo {
+			if _, ok := claims[name]; !ok {
+				claims[name] = value
+			}
+		}
+	}
+
+	identity := Identity{
+		Provider: o.name,
+		Subject:  idClaims.Subject,
+		Name:     claimString(claims, o.claims.Name),
+		Picture:  claimString(claims, o.claims.Image),
+		Token:    token,
+	}
+	if email := claimString(claims, o.claims.Email); email != "" {
+		identity.Email = email
+		identity.EmailVerified = claimTrue(claims, o.claims.EmailVerified)
+	}
+	return identity, nil
+}
+
+func claimString(claims map[string]any, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+func claimTrue(claims map[string]any, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	}
+	return false
+}
+
+// getJSON decodes a GET of url into out, sending accessToken as a bearer
+// token when set.
+func getJSON(ctx context.Context, client *http.Client, url, accessToken string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (o *OIDC) Refresh(ctx context.Context, token *oauth2.Token) (*oauth2.Token, error) {
+	return refreshToken(ctx, o.config, o.client, token)
+}
+
+var _ Provider = (*OIDC)(nil)
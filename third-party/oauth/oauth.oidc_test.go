@@ -0,0 +1,190 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/third-party/oauth/oauthtest"
+)
+
+func newStandInOIDC(t *testing.T, standIn *oauthtest.Google, claims ClaimMapping) *OIDC {
+	t.Helper()
+	provider, err := NewOIDC(context.Background(), OIDCConfig{
+		Name:         "standin",
+		IssuerURL:    standIn.URL,
+		ClientID:     standIn.ClientID,
+		ClientSecret: standIn.ClientSecret,
+		RedirectURL:  "https://app.example.com/auth/standin/callback",
+		Claims:       claims,
+		HTTPClient:   standIn.Client(),
+	})
+	if err != nil {
+		t.Fatalf("NewOIDC: %v", err)
+	}
+	return provider
+}
+
+// authenticateOIDC runs the whole authorization-code flow against standIn.
+func authenticateOIDC(t *testing.T, standIn *oauthtest.Google, provider *OIDC) (Identity, error) {
+	t.Helper()
+	flow, err := newTestSigner(t, time.Minute).Begin(provider.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback, err := standIn.Authorize(provider.AuthCodeURL(flow))
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	return provider.Authenticate(context.Background(), callback.Query().Get("code"), flow)
+}
+
+func TestNewOIDCDiscovery(t *testing.T) {
+	standIn := oauthtest.NewGoogle(t)
+	tests := []struct {
+		name       string
+		issuerURL  string
+		scopes     []string
+		wantScopes []string
+	}{
+		{"default scopes", standIn.URL, nil, []string{"openid", "email", "profile"}},
+		{"openid added", standIn.URL, []string{"email"}, []string{"openid", "email"}},
+		{"openid kept", standIn.URL, []string{"email", "openid"}, []string{"email", "openid"}},
+		{"trailing slash", standIn.URL + "/", nil, []string{"openid", "email", "profile"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewOIDC(context.Background(), OIDCConfig{
+				Name:       "standin",
+				IssuerURL:  tt.issuerURL,
+				ClientID:   standIn.ClientID,
+				Scopes:     tt.scopes,
+				HTTPClient: standIn.Client(),
+			})
+			if err != nil {
+				t.Fatalf("NewOIDC: %v", err)
+			}
+			authURL, err := url.Parse(provider.AuthCodeURL(Flow{State: "state", Nonce: "nonce", Verifier: "verifier"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			query := authURL.Query()
+			if got := authURL.Scheme + "://" + authURL.Host + authURL.Path; got != standIn.AuthURL() {
+				t.Errorf("authorization endpoint = %s, want %s", got, standIn.AuthURL())
+			}
+			if query.Get("nonce") != "nonce" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+				t.Errorf("authorization request %v lacks the nonce or PKCE challenge", query)
+			}
+			if !slices.Equal(provider.config.Scopes, tt.wantScopes) {
+				t.Errorf("scopes = %v, want %v", provider.config.Scopes, tt.wantScopes)
+			}
+		})
+	}
+}
+
+func TestNewOIDCRejectsDiscovery(t *testing.T) {
+	tests := []struct {
+		name string
+		// document returns the discovery document served at url, or nil
+		// for a 404.
+		document func(url string) map[string]string
+	}{
+		{"not found", func(string) map[string]string { return nil }},
+		{"other issuer", func(string) map[string]string {
+			return map[string]string{"issuer": "https://evil.example.com", "authorization_endpoint": "a", "token_endpoint": "t", "jwks_uri": "j"}
+		}},
+		{"no issuer", func(string) map[string]string {
+			return map[string]string{"authorization_endpoint": "a", "token_endpoint": "t", "jwks_uri": "j"}
+		}},
+		{"no jwks_uri", func(url string) map[string]string {
+			return map[string]string{"issuer": url, "authorization_endpoint": "a", "token_endpoint": "t"}
+		}},
+		{"no token endpoint", func(url string) map[string]string {
+			return map[string]string{"issuer": url, "authorization_endpoint": "a", "jwks_uri": "j"}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				document := tt.document(server.URL)
+				if r.URL.Path != "/.well-known/openid-configuration" || document == nil {
+					http.NotFound(w, r)
+					return
+				}
+				_ = json.NewEncoder(w).Encode(document)
+			}))
+			t.Cleanup(server.Close)
+
+			if _, err := NewOIDC(context.Background(), OIDCConfig{Name: "broken", IssuerURL: server.URL, HTTPClient: server.Client()}); err == nil {
+				t.Error("NewOIDC accepted the discovery document")
+			}
+		})
+	}
+}
+
+func TestOIDCAuthenticate(t *testing.T) {
+	standIn := oauthtest.NewGoogle(t)
+	standIn.Claims = map[string]any{"upn": "user@corp.example.com", "email_verified": "true"}
+	identity, err := authenticateOIDC(t, standIn, newStandInOIDC(t, standIn, ClaimMapping{Email: "upn"}))
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	want := Identity{
+		Provider:      "standin",
+		Subject:       standIn.Subject,
+		Email:         "user@corp.example.com",
+		EmailVerified: true,
+		Name:          standIn.Name,
+	}
+	if identity.Token == nil {
+		t.Error("identity has no provider token")
+	}
+	identity.Token = nil
+	if identity != want {
+		t.Errorf("identity = %+v, want %+v", identity, want)
+	}
+}
+
+func TestOIDCAuthenticateUserInfo(t *testing.T) {
+	t.Run("fills in missing claims", func(t *testing.T) {
+		standIn := oauthtest.NewGoogle(t)
+		// The ID token has neither email nor name; userinfo does, but
+		// must not override what the ID token says.
+		standIn.Claims = map[string]any{"email": nil, "name": nil, "email_verified": false}
+		standIn.UserInfo = map[string]any{"email_verified": true, "picture": "https://example.com/me.png"}
+		identity, err := authenticateOIDC(t, standIn, newStandInOIDC(t, standIn, ClaimMapping{}))
+		if err != nil {
+			t.Fatalf("Authenticate: %v", err)
+		}
+		if identity.Email != standIn.Email || identity.Name != standIn.Name || identity.Picture != "https://example.com/me.png" {
+			t.Errorf("identity = %+v, want the userinfo email, name and picture", identity)
+		}
+		if identity.EmailVerified {
+			t.Error("userinfo overrode email_verified of the ID token")
+		}
+	})
+
+	t.Run("other subject", func(t *testing.T) {
+		standIn := oauthtest.NewGoogle(t)
+		standIn.Claims = map[string]any{"name": nil}
+		standIn.UserInfo = map[string]any{"sub": "someone-else"}
+		if identity, err := authenticateOIDC(t, standIn, newStandInOIDC(t, standIn, ClaimMapping{})); err == nil {
+			t.Errorf("Authenticate = %+v, want the subject mismatch refused", identity)
+		}
+	})
+
+	t.Run("invalid ID token", func(t *testing.T) {
+		standIn := oauthtest.NewGoogle(t)
+		standIn.Claims = map[string]any{"aud": "someone-else"}
+		if _, err := authenticateOIDC(t, standIn, newStandInOIDC(t, standIn, ClaimMapping{})); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("Authenticate error = %v, want ErrInvalidIDToken", err)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package oauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Flow is the per-login state of an authorization-code flow. State travels
+// through the provider, Binding stays in a cookie on the browser that started
+// the flow, and Verifier and Nonce are derived from State so nothing has to be
+// stored server side. LinkUserID is set for flows that attach the provider
+// account to a signed-in user rather than log in.
+type Flow struct {
+	Provider   string
+	State      string
+	Binding    string
+	Verifier   string
+	Nonce      string
+	LinkUserID string
+}
+
+type statePayload struct {
+	Provider string `json:"p"`
+	// Binding is the SHA-256 of Flow.Binding, so the state alone is not
+	// enough to complete a flow started by someone else.
+	Binding    string `json:"b"`
+	ExpiresAt  int64  `json:"e"`
+	LinkUserID string `json:"u,omitempty"`
+}
+
+// StateSigner issues and checks HMAC-signed state parameters. Every replica
+// must share the secret.
+type StateSigner struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// MinStateSecretLength is the shortest secret NewStateSigner accepts. The
+// state, and the PKCE verifier and nonce derived from it, are only as hard to
+// forge as the secret is to guess.
+const MinStateSecretLength = 32
+
+// NewStateSigner returns a signer for flows that may take up to ttl. The
+// secret must be at least MinStateSecretLength bytes.
+func NewStateSigner(secret []byte, ttl time.Duration) (*StateSigner, error) {
+	if len(secret) < MinStateSecretLength {
+		return nil, fmt.Errorf("oauth: state secret must be at least %d bytes", MinStateSecretLength)
+	}
+	return &StateSigner{secret: secret, ttl: ttl}, nil
+}
+
+// TTL is how long a flow may take from Begin to Resume.
+func (s *StateSigner) TTL() time.Duration {
+	return s.ttl
+}
+
+// Begin starts a login flow for provider.
+func (s *StateSigner) Begin(provider string) (Flow, error) {
+	return s.begin(provider, "")
+}
+
+// BeginLink starts a flow that links the provider account to userID.
+func (s *StateSigner) BeginLink(provider, userID string) (Flow, error) {
+	return s.begin(provider, userID)
+}
+
+func (s *StateSigner) begin(provider, linkUserID string) (Flow, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return Flow{}, err
+	}
+	binding := base64.RawURLEncoding.EncodeToString(raw)
+
+	payload, err := json.Marshal(statePayload{
+		Provider:   provider,
+		Binding:    bindingHash(binding),
+		ExpiresAt:  time.Now().Add(s.ttl).Unix(),
+		LinkUserID: linkUserID,
+	})
+	if err != nil {
+		return Flow{}, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	state := encoded + "." + s.mac("state", encoded)
+
+	return s.flow(provider, state, binding, linkUserID), nil
+}
+
+// Resume checks that state was issued by Begin for provider, has not expired
+// and belongs to the browser holding binding.
+func (s *StateSigner) Resume(provider, state, binding string) (Flow, error) {
+	encoded, signature, ok := strings.Cut(state, ".")
+	if !ok || binding == "" || !hmac.Equal([]byte(signature), []byte(s.mac("state", encoded))) {
+		return Flow{}, ErrInvalidState
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Flow{}, ErrInvalidState
+	}
+	var payload statePayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return Flow{}, ErrInvalidState
+	}
+	if payload.Provider != provider || time.Now().Unix() > payload.ExpiresAt {
+		return Flow{}, ErrInvalidState
+	}
+	if subtle.ConstantTimeCompare([]byte(payload.Binding), []byte(bindingHash(binding))) != 1 {
+		return Flow{}, ErrInvalidState
+	}
+
+	return s.flow(provider, state, binding, payload.LinkUserID), nil
+}
+
+// flow derives the verifier and nonce from state. The verifier is 43
+// characters, the shortest RFC 7636 allows.
+func (s *StateSigner) flow(provider, state, binding, linkUserID string) Flow {
+	return Flow{
+		Provider:   provider,
+		State:      state,
+		Binding:    binding,
+		Verifier:   s.mac("pkce", state),
+		Nonce:      s.mac("nonce", state),
+		LinkUserID: linkUserID,
+	}
+}
+
+func (s *StateSigner) mac(purpose, value string) string {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(purpose))
+	h.Write([]byte{0})
+	h.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func bindingHash(binding string) string {
+	sum := sha256.Sum256([]byte(binding))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,85 @@
+package oauth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testStateSecret = []byte("test-secret-test-secret-test-sec")
+
+func newTestSigner(t *testing.T, ttl time.Duration) *StateSigner {
+	t.Helper()
+	signer, err := NewStateSigner(testStateSecret, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func TestNewStateSignerRefusesShortSecrets(t *testing.T) {
+	for _, secret := range []string{"", "test-secret", strings.Repeat("x", MinStateSecretLength-1)} {
+		if _, err := NewStateSigner([]byte(secret), time.Minute); err == nil {
+			t.Errorf("NewStateSigner accepted a %d byte secret", len(secret))
+		}
+	}
+	if _, err := NewStateSigner([]byte(strings.Repeat("x", MinStateSecretLength)), time.Minute); err != nil {
+		t.Errorf("NewStateSigner refused a %d byte secret: %v", MinStateSecretLength, err)
+	}
+}
+
+func TestStateSignerResume(t *testing.T) {
+	signer := newTestSigner(t, time.Minute)
+	flow, err := signer.Begin(ProviderGitHub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := signer.Resume(ProviderGitHub, flow.State, flow.Binding)
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if resumed.Verifier != flow.Verifier || resumed.Nonce != flow.Nonce {
+		t.Error("Resume derived a different verifier or nonce than Begin")
+	}
+
+	attacker, err := signer.Begin(ProviderGitHub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := newTestSigner(t, -time.Minute)
+	stale, err := expired.Begin(ProviderGitHub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherSecret, err := NewStateSigner([]byte("another-secret-another-secret-xx"), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		signer   *StateSigner
+		provider string
+		state    string
+		binding  string
+	}{
+		// A callback link forged from the attacker's own flow must not
+		// complete in the victim's browser.
+		{"state from another browser", signer, ProviderGitHub, attacker.State, flow.Binding},
+		{"missing binding cookie", signer, ProviderGitHub, flow.State, ""},
+		{"other provider", signer, ProviderGoogle, flow.State, flow.Binding},
+		{"tampered state", signer, ProviderGitHub, "x" + flow.State, flow.Binding},
+		{"other secret", otherSecret, ProviderGitHub, flow.State, flow.Binding},
+		{"expired", expired, ProviderGitHub, stale.State, stale.Binding},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.signer.Resume(tt.provider, tt.state, tt.binding); !errors.Is(err, ErrInvalidState) {
+				t.Errorf("Resume error = %v, want ErrInvalidState", err)
+			}
+		})
+	}
+}
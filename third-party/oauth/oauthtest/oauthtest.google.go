@@ -0,0 +1,298 @@
+// Package oauthtest runs local stand-ins for OAuth providers, so the full
+// authorization-code flow can be tested without reaching the real ones.
+package oauthtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SwanHtetAungPhyo/go-auth/framework/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const googleKeyID = "standin"
+
+// Google stands in for Google's OpenID Connect endpoints. Point a
+// GoogleConfig at AuthURL, TokenURL, JWKSURL and URL as the issuer, or an
+// OIDCConfig at URL, where it publishes a discovery document. It enforces
+// PKCE, hands out single-use codes and signs ID tokens for the identity in
+// its exported fields.
+type Google struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	// The identity put in the next ID token.
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+
+	// Claims overrides claims of the next ID token; a nil value removes
+	// the claim.
+	Claims map[string]any
+	// ForgeSignature signs the next ID token with a key that is not
+	// published at JWKSURL.
+	ForgeSignature bool
+	// UserInfo overrides claims the userinfo endpoint answers with; a nil
+	// value removes the claim.
+	UserInfo map[string]any
+
+	key    *ecdsa.PrivateKey
+	forged *ecdsa.PrivateKey
+
+	mu           sync.Mutex
+	codes        map[string]googleGrant
+	accessTokens map[string]bool
+}
+
+type googleGrant struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+}
+
+// NewGoogle starts a stand-in that is closed when the test ends.
+func NewGoogle(t testing.TB) *Google {
+	t.Helper()
+	g := &Google{
+		ClientID:      "standin-client",
+		ClientSecret:  "standin-secret",
+		Subject:       "108000000000000000001",
+		Email:         "user@example.com",
+		EmailVerified: true,
+		Name:          "Stand-in User",
+		key:           newKey(t),
+		forged:        newKey(t),
+		codes:         make(map[string]googleGrant),
+		accessTokens:  make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /authorize", g.authorize)
+	mux.HandleFunc("POST /token", g.token)
+	mux.HandleFunc("GET /jwks", g.jwks)
+	mux.HandleFunc("GET /userinfo", g.userInfo)
+	mux.HandleFunc("GET /.well-known/openid-configuration", g.discovery)
+	g.Server = httptest.NewServer(mux)
+	t.Cleanup(g.Close)
+	return g
+}
+
+func newKey(t testing.TB) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func (g *Google) AuthURL() string  { return g.URL + "/authorize" }
+func (g *Google) TokenURL() string { return g.URL + "/token" }
+func (g *Google) JWKSURL() string  { return g.URL + "/jwks" }
+
+// UserInfoURL is where the stand-in answers for the holder of an access
+// token it issued.
+func (g *Google) UserInfoURL() string { return g.URL + "/userinfo" }
+
+// Authorize plays the browser on the consent screen: it opens authCodeURL
+// and returns the callback URL the user would be redirected to, carrying
+// code and state.
+func (g *Google) Authorize(authCodeURL string) (*url.URL, error) {
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Get(authCodeURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		return nil, fmt.Errorf("oauthtest: authorize: %s", resp.Status)
+	}
+	return url.Parse(resp.Header.Get("Location"))
+}
+
+func (g *Google) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	switch {
+	case query.Get("client_id") != g.ClientID:
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	case query.Get("response_type") != "code":
+		http.Error(w, "unsupported response_type", http.StatusBadRequest)
+		return
+	case query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "":
+		http.Error(w, "missing S256 code_challenge", http.StatusBadRequest)
+		return
+	}
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirect.Host == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := rand.Text()
+	g.mu.Lock()
+	g.codes[code] = googleGrant{
+		redirectURI: redirect.String(),
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+	}
+	g.mu.Unlock()
+
+	callback := redirect.Query()
+	callback.Set("code", code)
+	callback.Set("state", query.Get("state"))
+	redirect.RawQuery = callback.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (g *Google) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != g.ClientID || clientSecret != g.ClientSecret {
+		tokenError(w, "invalid_client")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	g.mu.Lock()
+	grant, ok := g.codes[code]
+	delete(g.codes, code)
+	g.mu.Unlock()
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || grant.redirectURI != r.PostForm.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != grant.challenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	idToken, err := g.idToken(grant.nonce)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	accessToken := "ya29." + rand.Text()
+	g.mu.Lock()
+	g.accessTokens[accessToken] = true
+	g.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+func (g *Google) idToken(nonce string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            g.URL,
+		"aud":            g.ClientID,
+		"sub":            g.Subject,
+		"email":          g.Email,
+		"email_verified": g.EmailVerified,
+		"name":           g.Name,
+		"nonce":          nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+	for name, value := range g.Claims {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+
+	key := g.key
+	if g.ForgeSignature {
+		key = g.forged
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = googleKeyID
+	return token.SignedString(key)
+}
+
+func (g *Google) jwks(w http.ResponseWriter, _ *http.Request) {
+	jwk, ok := utils.NewJWK(&utils.JWTKey{
+		ID:        googleKeyID,
+		Method:    jwt.SigningMethodES256,
+		VerifyKey: &g.key.PublicKey,
+	})
+	if !ok {
+		http.Error(w, "oauthtest: cannot publish key", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(utils.JWKSet{Keys: []utils.JWK{jwk}})
+}
+
+func (g *Google) userInfo(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	g.mu.Lock()
+	known := ok && g.accessTokens[accessToken]
+	g.mu.Unlock()
+	if !known {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	claims := map[string]any{
+		"sub":            g.Subject,
+		"email":          g.Email,
+		"email_verified": g.EmailVerified,
+		"name":           g.Name,
+	}
+	for name, value := range g.UserInfo {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(claims)
+}
+
+func (g *Google) discovery(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 g.URL,
+		"authorization_endpoint": g.AuthURL(),
+		"token_endpoint":         g.TokenURL(),
+		"jwks_uri":               g.JWKSURL(),
+		"userinfo_endpoint":      g.UserInfoURL(),
+	})
+}